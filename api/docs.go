@@ -22,6 +22,50 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/enrichments": {
+            "get": {
+                "description": "List the enrichments supported by mc-terrarium with the providers and the variables of the tfVars of each.\nThe name of an enrichment is the path segments of its APIs (e.g., /tr/{trId}/sql-db, /tr/{trId}/vpn/gcp-aws).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "[Enrichment] Operations"
+                ],
+                "summary": "List the enrichments",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/model.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "list": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/model.EnrichmentKind"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    }
+                }
+            }
+        },
         "/httpVersion": {
             "get": {
                 "description": "Checks and logs the HTTP version of the incoming request to the server console.",
@@ -364,6 +408,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "503": {
                         "description": "Service Unavailable",
                         "schema": {
@@ -373,7 +429,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Create test environment",
+                "description": "Create test environment\nWith async=true, the request is accepted (202) with the Location of its status instead of waiting for the result.",
                 "consumes": [
                     "application/json"
                 ],
@@ -384,6 +440,27 @@ const docTemplate = `{
                     "[Test env] Test environment management"
                 ],
                 "summary": "Create test environment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Plan ID to apply the saved plan exactly (i.e., the request ID of the plan)",
+                        "name": "planId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Run in the background and check the status of the request",
+                        "name": "async",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
                 "responses": {
                     "201": {
                         "description": "Created",
@@ -391,12 +468,42 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.JobInfo"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the status of the request"
+                            }
+                        }
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "503": {
                         "description": "Service Unavailable",
                         "schema": {
@@ -406,7 +513,7 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Destroy test environment",
+                "description": "Destroy test environment\nWith async=true, the request is accepted (202) with the Location of its status instead of waiting for the result.",
                 "consumes": [
                     "application/json"
                 ],
@@ -417,19 +524,58 @@ const docTemplate = `{
                     "[Test env] Test environment management"
                 ],
                 "summary": "Destroy test environment",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Run in the background and check the status of the request",
+                        "name": "async",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.JobInfo"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the status of the request"
+                            }
+                        }
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "503": {
                         "description": "Service Unavailable",
                         "schema": {
@@ -452,6 +598,14 @@ const docTemplate = `{
                     "[Test env] Test environment management"
                 ],
                 "summary": "Clear the entire directory and configuration files",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -465,6 +619,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "503": {
                         "description": "Service Unavailable",
                         "schema": {
@@ -476,7 +642,7 @@ const docTemplate = `{
         },
         "/test-env/infracode": {
             "post": {
-                "description": "Create the infracode to configure test environment",
+                "description": "Create the infracode to configure test environment. The invalid tfVars (e.g., a region not of the provider) are responded in the errors with 422.",
                 "consumes": [
                     "application/json"
                 ],
@@ -496,6 +662,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.CreateInfracodeOfTestEnvRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -511,6 +683,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "503": {
                         "description": "Service Unavailable",
                         "schema": {
@@ -522,7 +712,7 @@ const docTemplate = `{
         },
         "/test-env/init": {
             "post": {
-                "description": "Initialize a multi-cloud terrarium for test environment",
+                "description": "Initialize a multi-cloud terrarium for test environment\nWith async=true, the request is accepted (202) with the Location of its status instead of waiting for the result.",
                 "consumes": [
                     "application/json"
                 ],
@@ -533,6 +723,21 @@ const docTemplate = `{
                     "[Test env] Test environment management"
                 ],
                 "summary": "Initialize a multi-cloud terrarium for test environment",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Run in the background and check the status of the request",
+                        "name": "async",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
                 "responses": {
                     "201": {
                         "description": "Created",
@@ -540,12 +745,36 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.JobInfo"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the status of the request"
+                            }
+                        }
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "503": {
                         "description": "Service Unavailable",
                         "schema": {
@@ -557,7 +786,7 @@ const docTemplate = `{
         },
         "/test-env/plan": {
             "post": {
-                "description": "Check the infracode and show changes of test environment",
+                "description": "Check the infracode and show changes of test environment. The plan is saved with the request ID as the plan ID and summarized with sensitive values masked. The saved plan is removed when it is applied or goes stale, and up to 10 plans are kept.",
                 "consumes": [
                     "application/json"
                 ],
@@ -568,11 +797,31 @@ const docTemplate = `{
                     "[Test env] Test environment management"
                 ],
                 "summary": "Check the infracode and show changes of test environment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/model.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "object": {
+                                            "$ref": "#/definitions/model.PlanSummary"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
@@ -581,6 +830,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "503": {
                         "description": "Service Unavailable",
                         "schema": {
@@ -590,9 +851,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/test-env/request/{requestId}/status": {
-            "get": {
-                "description": "Get the status of the request to configure test environment",
+        "/test-env/request/{requestId}": {
+            "delete": {
+                "description": "Cancel the running request (e.g., apply or destroy) to configure test environment.\nThe tofu process is interrupted to release the state lock cleanly and killed after a grace period.",
                 "consumes": [
                     "application/json"
                 ],
@@ -602,7 +863,7 @@ const docTemplate = `{
                 "tags": [
                     "[Test env] Test environment management"
                 ],
-                "summary": "Get the status of the request to configure test environment",
+                "summary": "Cancel the running request to configure test environment",
                 "parameters": [
                     {
                         "type": "string",
@@ -610,11 +871,17 @@ const docTemplate = `{
                         "name": "requestId",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
@@ -625,6 +892,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "503": {
                         "description": "Service Unavailable",
                         "schema": {
@@ -634,22 +913,56 @@ const docTemplate = `{
                 }
             }
         },
-        "/tofuVersion": {
+        "/test-env/request/{requestId}/logs": {
             "get": {
-                "description": "Check Tofu version",
+                "description": "Stream the logs of the request to configure test environment as Server-Sent Events (SSE).\nEach line is sent as a \"log\" event, and the status of the request is sent as a \"status\" event at the end.\nSet follow=true to keep the stream until the request is completed.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "text/event-stream"
                 ],
                 "tags": [
-                    "[System] Utility"
+                    "[Test env] Test environment management"
+                ],
+                "summary": "Stream the logs of the request to configure test environment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Request ID",
+                        "name": "requestId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Follow the logs until the request is completed",
+                        "name": "follow",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Resume the stream from the last event ID (i.e., offset)",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
                 ],
-                "summary": "Check Tofu version",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Server-Sent Events of the logs and status of the request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
@@ -663,9 +976,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/tr": {
+        "/test-env/request/{requestId}/status": {
             "get": {
-                "description": "Read all terrarium",
+                "description": "Get the status of the request to configure test environment",
                 "consumes": [
                     "application/json"
                 ],
@@ -673,17 +986,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[Terrarium] An environment to enrich the multi-cloud infrastructure"
+                    "[Test env] Test environment management"
+                ],
+                "summary": "Get the status of the request to configure test environment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Request ID",
+                        "name": "requestId",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Read all terrarium",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.TerrariumInfo"
-                            }
+                            "$ref": "#/definitions/model.JobInfo"
                         }
                     },
                     "400": {
@@ -692,16 +1011,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
-                    "503": {
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "503": {
                         "description": "Service Unavailable",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Issue/create a terrarium",
+            }
+        },
+        "/tofuVersion": {
+            "get": {
+                "description": "Check the selected IaC engine (i.e., OpenTofu or Terraform) and its version",
                 "consumes": [
                     "application/json"
                 ],
@@ -709,31 +1036,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[Terrarium] An environment to enrich the multi-cloud infrastructure"
-                ],
-                "summary": "Issue/create a terrarium",
-                "parameters": [
-                    {
-                        "description": "Information for a new terrarium",
-                        "name": "TerrariumInfo",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.TerrariumInfo"
-                        }
-                    }
+                    "[System] Utility"
                 ],
+                "summary": "Check Tofu version",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/model.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "object": {
+                                            "$ref": "#/definitions/model.EngineVersion"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "503": {
@@ -745,9 +1067,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/tr/{trId}": {
+        "/tr": {
             "get": {
-                "description": "Read a terrarium",
+                "description": "Read all terrarium, which are filtered by the labels and the enrichments, and sorted by the ID by default.\nIf limit is given, the cursor of the next page is returned in the X-Next-Cursor header until the last page.\nOnly the terrariums of the tenant of the caller are listed, and the API user lists all of them unless acting as a tenant.",
                 "consumes": [
                     "application/json"
                 ],
@@ -757,22 +1079,91 @@ const docTemplate = `{
                 "tags": [
                     "[Terrarium] An environment to enrich the multi-cloud infrastructure"
                 ],
-                "summary": "Read a terrarium",
+                "summary": "Read all terrarium",
                 "parameters": [
                     {
                         "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
-                        "in": "path",
-                        "required": true
+                        "description": "Label selector (e.g., team=infra,env!=dev,owner,!temporary)",
+                        "name": "labelSelector",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name or kind of an enrichment in the terrarium (e.g., vpn/gcp-aws or vpn)",
+                        "name": "enrichments",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Provider of an enrichment in the terrarium (e.g., aws)",
+                        "name": "provider",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "Empty",
+                            "Initialized",
+                            "Configured",
+                            "Planned",
+                            "Applying",
+                            "Applied",
+                            "Destroying",
+                            "Destroyed",
+                            "Failed"
+                        ],
+                        "type": "string",
+                        "description": "Lifecycle state of an enrichment in the terrarium",
+                        "name": "state",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "-id",
+                            "createdAt",
+                            "-createdAt",
+                            "updatedAt",
+                            "-updatedAt"
+                        ],
+                        "type": "string",
+                        "description": "Field to sort by, prefixed by '-' for the descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Max number of terrariums to return (0 for no limit)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the next page (from the X-Next-Cursor header)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant to act as, only for the API user",
+                        "name": "x-tenant",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.TerrariumInfo"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.TerrariumInfo"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Cursor of the next page if there are more terrariums"
+                            }
                         }
                     },
                     "400": {
@@ -781,6 +1172,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "503": {
                         "description": "Service Unavailable",
                         "schema": {
@@ -789,8 +1186,8 @@ const docTemplate = `{
                     }
                 }
             },
-            "delete": {
-                "description": "Erase the entire terrarium including directories and configuration files",
+            "post": {
+                "description": "Issue/create a terrarium.\nThe ID must be a DNS label up to 26 characters (lowercase alphanumerics and '-', starting with a letter),\nand it is generated (e.g., tr-k3x9q2ab) if omitted.\nIf ttl (e.g., 72h, 7d) or expiresAt is given, the terrarium is destroyed and erased automatically when it expires,\nand an expiring event is notified before (see POST /tr/{trId}/ttl to extend it).\nThe terrarium belongs to the tenant of the caller, and it is rejected (403) if the tenant has reached its quota.",
                 "consumes": [
                     "application/json"
                 ],
@@ -800,22 +1197,35 @@ const docTemplate = `{
                 "tags": [
                     "[Terrarium] An environment to enrich the multi-cloud infrastructure"
                 ],
-                "summary": "Erase the entire terrarium including directories and configuration files",
+                "summary": "Issue/create a terrarium",
                 "parameters": [
+                    {
+                        "description": "Information for a new terrarium",
+                        "name": "TerrariumInfo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.TerrariumInfo"
+                        }
+                    },
                     {
                         "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
-                        "in": "path",
-                        "required": true
+                        "description": "Tenant to act as, only for the API user (default: the default tenant)",
+                        "name": "x-tenant",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "$ref": "#/definitions/model.TerrariumInfo"
                         }
                     },
                     "400": {
@@ -824,6 +1234,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "503": {
                         "description": "Service Unavailable",
                         "schema": {
@@ -833,47 +1261,87 @@ const docTemplate = `{
                 }
             }
         },
-        "/tr/{trId}/message-broker": {
-            "get": {
-                "description": "Get resource info of Message Broker",
+        "/tr/import": {
+            "post": {
+                "description": "Import a terrarium from a bundle exported by another server, which is verified by the checksums in the manifest.\nThe bundle is uploaded as the form file \"bundle\" or as the request body (application/gzip).\nThe credentials are copied from the secrets of this server and each enrichment is initialized, keeping the lifecycle states.\nIf the ID is in use, it is rejected (409) unless onConflict=rename, which imports it with a generated ID.\nOnly the API user can import (403), since the infracode in the bundle runs with the credentials of this server.\nThe terrarium is imported into the tenant by the x-tenant header within its quota (403).\nWith async=true, the enrichments are initialized in the background, and the import is accepted (202)\nwith the requests initializing them and the Location of the imported terrarium.",
                 "consumes": [
-                    "application/json"
+                    "multipart/form-data",
+                    "application/gzip"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "[Message Broker] Operations"
+                    "[Terrarium] An environment to enrich the multi-cloud infrastructure"
                 ],
-                "summary": "Get resource info of Message Broker",
+                "summary": "Import a terrarium from a bundle",
                 "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Bundle of a terrarium (tar.gz)",
+                        "name": "bundle",
+                        "in": "formData"
+                    },
                     {
                         "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
+                        "description": "ID to import the terrarium as, instead of the ID in the bundle",
                         "name": "trId",
-                        "in": "path",
-                        "required": true
+                        "in": "query"
                     },
                     {
+                        "enum": [
+                            "fail",
+                            "rename"
+                        ],
                         "type": "string",
-                        "default": "refined",
-                        "description": "Resource info by detail (refined, raw)",
-                        "name": "detail",
+                        "default": "fail",
+                        "description": "What to do if the ID is in use",
+                        "name": "onConflict",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Initialize the enrichments in the background",
+                        "name": "async",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "custom request ID",
+                        "description": "Custom request ID",
                         "name": "x-request-id",
                         "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant to act as, only for the API user (default: the default tenant)",
+                        "name": "x-tenant",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "$ref": "#/definitions/model.ImportResult"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.ImportResult"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the imported terrarium"
+                            }
                         }
                     },
                     "400": {
@@ -882,22 +1350,36 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     },
-                    "503": {
-                        "description": "Service Unavailable",
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Create Message Broker",
+            }
+        },
+        "/tr/{trId}": {
+            "get": {
+                "description": "Read a terrarium with the enrichments in it and the lifecycle state of each enrichment (Empty, Initialized, Configured, Planned, Applying, Applied, Destroying, Destroyed or Failed).",
                 "consumes": [
                     "application/json"
                 ],
@@ -905,9 +1387,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[Message Broker] Operations"
+                    "[Terrarium] An environment to enrich the multi-cloud infrastructure"
                 ],
-                "summary": "Create Message Broker",
+                "summary": "Read a terrarium",
                 "parameters": [
                     {
                         "type": "string",
@@ -916,19 +1398,19 @@ const docTemplate = `{
                         "name": "trId",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
-                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "$ref": "#/definitions/model.TerrariumInfo"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Resource version of the terrarium for If-Match"
+                            }
                         }
                     },
                     "400": {
@@ -937,6 +1419,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -952,7 +1440,7 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Destroy Message Broker",
+                "description": "Erase the entire terrarium including directories and configuration files.\nIt is rejected (409) with the remaining resources if any resources remain in the state of the enrichments.\nUse cascade=true to destroy the enrichments in dependency order before erasing,\nor force=true (only for the API user, 403 for a tenant) to archive the states instead of discarding them and erase without destroying.\nThe queued requests of the terrarium are completed before erasing it, and the new ones are rejected (409) until it is erased.",
                 "consumes": [
                     "application/json"
                 ],
@@ -960,9 +1448,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[Message Broker] Operations"
+                    "[Terrarium] An environment to enrich the multi-cloud infrastructure"
                 ],
-                "summary": "Destroy Message Broker",
+                "summary": "Erase the entire terrarium including directories and configuration files",
                 "parameters": [
                     {
                         "type": "string",
@@ -972,11 +1460,31 @@ const docTemplate = `{
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Destroy the remaining resources of the enrichments before erasing",
+                        "name": "cascade",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Archive the states and erase without destroying the remaining resources",
+                        "name": "force",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "Custom request ID",
                         "name": "x-request-id",
                         "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -992,6 +1500,39 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/model.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "list": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/model.EnrichmentResources"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1005,21 +1546,20 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/tr/{trId}/message-broker/env": {
-            "post": {
-                "description": "Initialize a multi-cloud terrarium for Message Broker (e.g., AWS MQ Broker (ActiveMQ))",
+            },
+            "patch": {
+                "description": "Update the description and the labels of a terrarium by a JSON merge patch (RFC 7396),\nwhere null removes a label (e.g., {\"labels\": {\"env\": \"prod\", \"temporary\": null}}).\nThe other fields are read-only. The ETag of the terrarium is returned by reading or patching it.\nIf If-Match is given with the ETag, the patch is rejected (412) if the terrarium has been modified since then.",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "application/merge-patch+json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "[Message Broker] Operations"
+                    "[Terrarium] An environment to enrich the multi-cloud infrastructure"
                 ],
-                "summary": "Initialize a multi-cloud terrarium for Message Broker (e.g., AWS MQ Broker (ActiveMQ))",
+                "summary": "Update the description and the labels of a terrarium",
                 "parameters": [
                     {
                         "type": "string",
@@ -1030,27 +1570,32 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "enum": [
-                            "aws"
-                        ],
                         "type": "string",
-                        "default": "aws",
-                        "description": "Provider",
-                        "name": "provider",
-                        "in": "query"
+                        "description": "ETag of the terrarium to update, which is returned by reading it",
+                        "name": "If-Match",
+                        "in": "header"
                     },
                     {
-                        "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
-                        "in": "header"
+                        "description": "JSON merge patch of the description and the labels",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.TerrariumInfo"
+                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "$ref": "#/definitions/model.TerrariumInfo"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Resource version of the patched terrarium"
+                            }
                         }
                     },
                     "400": {
@@ -1059,32 +1604,46 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     },
-                    "503": {
-                        "description": "Service Unavailable",
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Clear the entire directory and configuration files",
+            }
+        },
+        "/tr/{trId}/export": {
+            "get": {
+                "description": "Export a terrarium as a bundle (tar.gz) to import it on another server.\nThe bundle has the terrarium info and the infracode (templates and tfvars), the lock file and the states of each enrichment\nwith a manifest of the checksums and the version of mc-terrarium. The credentials are not exported.\nIt is rejected (409) while any enrichment is applying or destroying.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/gzip"
                 ],
                 "tags": [
-                    "[Message Broker] Operations"
+                    "[Terrarium] An environment to enrich the multi-cloud infrastructure"
                 ],
-                "summary": "Clear the entire directory and configuration files",
+                "summary": "Export a terrarium as a bundle",
                 "parameters": [
                     {
                         "type": "string",
@@ -1093,29 +1652,13 @@ const docTemplate = `{
                         "name": "trId",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "enum": [
-                            "force"
-                        ],
-                        "type": "string",
-                        "default": "",
-                        "description": "Action",
-                        "name": "action",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
-                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Bundle of the terrarium ({trId}.tar.gz)",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "type": "file"
                         }
                     },
                     "400": {
@@ -1124,14 +1667,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     },
-                    "503": {
-                        "description": "Service Unavailable",
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
@@ -1139,9 +1688,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/tr/{trId}/message-broker/infracode": {
+        "/tr/{trId}/ttl": {
             "post": {
-                "description": "Create the infracode for Message Broker",
+                "description": "Extend the expiration time of a terrarium by the ttl (e.g., 24h, 7d) from the current expiration time\n(or from now if it does not expire), or set a new expiration time by expiresAt.\nThe expiring event is notified again before the new expiration time.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1149,9 +1698,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[Message Broker] Operations"
+                    "[Terrarium] An environment to enrich the multi-cloud infrastructure"
                 ],
-                "summary": "Create the infracode for Message Broker",
+                "summary": "Extend the TTL of a terrarium",
                 "parameters": [
                     {
                         "type": "string",
@@ -1162,26 +1711,26 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Parameters of infracode for Message Broker",
-                        "name": "ParamsForInfracode",
+                        "description": "TTL to extend or new expiration time",
+                        "name": "ExtendTtlRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.CreateInfracodeOfMessageBrokerRequest"
+                            "$ref": "#/definitions/model.ExtendTtlRequest"
                         }
                     },
                     {
                         "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
                         "in": "header"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "$ref": "#/definitions/model.TerrariumInfo"
                         }
                     },
                     "400": {
@@ -1190,14 +1739,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     },
-                    "503": {
-                        "description": "Service Unavailable",
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
@@ -1205,9 +1760,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/tr/{trId}/message-broker/plan": {
-            "post": {
-                "description": "Check and show changes by the current infracode",
+        "/tr/{trId}/webhooks": {
+            "get": {
+                "description": "Read all webhooks receiving the events of a terrarium. The secrets are not shown.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1215,9 +1770,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[Message Broker] Operations"
+                    "[Webhook] Notifications of the events of the jobs and the terrariums"
                 ],
-                "summary": "Check and show changes by the current infracode",
+                "summary": "Read all webhooks of a terrarium",
                 "parameters": [
                     {
                         "type": "string",
@@ -1226,19 +1781,16 @@ const docTemplate = `{
                         "name": "trId",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
-                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Webhook"
+                            }
                         }
                     },
                     "400": {
@@ -1252,19 +1804,11 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
                     }
                 }
-            }
-        },
-        "/tr/{trId}/message-broker/request/{requestId}": {
-            "get": {
-                "description": "Check the status of a specific request by its ID",
+            },
+            "post": {
+                "description": "Create a webhook receiving the events of a terrarium (see POST /webhooks), which is deleted when the terrarium is erased.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1272,9 +1816,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[Message Broker] Operations"
+                    "[Webhook] Notifications of the events of the jobs and the terrariums"
                 ],
-                "summary": "Check the status of a specific request by its ID",
+                "summary": "Create a webhook of a terrarium",
                 "parameters": [
                     {
                         "type": "string",
@@ -1284,19 +1828,27 @@ const docTemplate = `{
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "description": "URL and types of the events to receive (all if empty, e.g., job.* for the job events)",
+                        "name": "WebhookRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.WebhookRequest"
+                        }
+                    },
                     {
                         "type": "string",
-                        "description": "Request ID",
-                        "name": "requestId",
-                        "in": "path",
-                        "required": true
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "$ref": "#/definitions/model.Webhook"
                         }
                     },
                     "400": {
@@ -1305,14 +1857,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     },
-                    "503": {
-                        "description": "Service Unavailable",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
@@ -1320,9 +1872,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/tr/{trId}/object-storage": {
+        "/tr/{trId}/{enrichment}": {
             "get": {
-                "description": "Get resource info of Object Storage",
+                "description": "Get resource info of an enrichment, which is the output of the enrichment (refined) or the resources in the state (raw).",
                 "consumes": [
                     "application/json"
                 ],
@@ -1330,9 +1882,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[Object Storage] Operations"
+                    "[Enrichment] Operations"
                 ],
-                "summary": "Get resource info of Object Storage",
+                "summary": "Get resource info of an enrichment",
                 "parameters": [
                     {
                         "type": "string",
@@ -1342,6 +1894,14 @@ const docTemplate = `{
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "default": "sql-db",
+                        "description": "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)",
+                        "name": "enrichment",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "type": "string",
                         "default": "refined",
@@ -1351,7 +1911,7 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "custom request ID",
+                        "description": "Custom request ID",
                         "name": "x-request-id",
                         "in": "header"
                     }
@@ -1369,6 +1929,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1384,7 +1950,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Create Object Storage",
+                "description": "Create the resources of an enrichment by applying the infracode, and return the resource info.\nWith async=true, the request is accepted (202) with the Location of its status instead of waiting for the result.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1392,9 +1958,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[Object Storage] Operations"
+                    "[Enrichment] Operations"
                 ],
-                "summary": "Create Object Storage",
+                "summary": "Create the resources of an enrichment",
                 "parameters": [
                     {
                         "type": "string",
@@ -1404,11 +1970,38 @@ const docTemplate = `{
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "default": "sql-db",
+                        "description": "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)",
+                        "name": "enrichment",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "type": "string",
                         "description": "Custom request ID",
                         "name": "x-request-id",
                         "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Plan ID to apply the saved plan exactly (i.e., the request ID of the plan)",
+                        "name": "planId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Run in the background and check the status of the request",
+                        "name": "async",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -1418,12 +2011,36 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.JobInfo"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the status of the request"
+                            }
+                        }
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1439,7 +2056,7 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Destroy Object Storage",
+                "description": "Destroy the resources of an enrichment, except the imported ones (e.g., the route table of vpn/gcp-aws).\nWith async=true, the request is accepted (202) with the Location of its status instead of waiting for the result.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1447,9 +2064,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[Object Storage] Operations"
+                    "[Enrichment] Operations"
                 ],
-                "summary": "Destroy Object Storage",
+                "summary": "Destroy the resources of an enrichment",
                 "parameters": [
                     {
                         "type": "string",
@@ -1459,26 +2076,71 @@ const docTemplate = `{
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "default": "sql-db",
+                        "description": "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)",
+                        "name": "enrichment",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Run in the background and check the status of the request",
+                        "name": "async",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "Custom request ID",
                         "name": "x-request-id",
                         "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.JobInfo"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the status of the request"
+                            }
+                        }
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1494,9 +2156,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/tr/{trId}/object-storage/env": {
+        "/tr/{trId}/{enrichment}/env": {
             "post": {
-                "description": "Initialize a multi-cloud terrarium for Object Storage (e.g., AWS S3 Bucket, Azure Blob Storage)",
+                "description": "Initialize an enrichment of a terrarium (e.g., sql-db, vpn/gcp-aws) by its templates.\nThe provider is required if the enrichment has the providers (see GET /enrichments).\nWith async=true, the request is accepted (202) with the Location of its status instead of waiting for the result.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1504,9 +2166,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[Object Storage] Operations"
+                    "[Enrichment] Operations"
                 ],
-                "summary": "Initialize a multi-cloud terrarium for Object Storage (e.g., AWS S3 Bucket, Azure Blob Storage)",
+                "summary": "Initialize an enrichment of a terrarium",
                 "parameters": [
                     {
                         "type": "string",
@@ -1517,21 +2179,37 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "enum": [
-                            "aws",
-                            "azure"
-                        ],
                         "type": "string",
-                        "default": "aws",
-                        "description": "Provider",
+                        "default": "sql-db",
+                        "description": "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)",
+                        "name": "enrichment",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Provider (e.g., aws, azure, gcp, ncp)",
                         "name": "provider",
                         "in": "query"
                     },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Run in the background and check the status of the request",
+                        "name": "async",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "Custom request ID",
                         "name": "x-request-id",
                         "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -1541,12 +2219,42 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.JobInfo"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the status of the request"
+                            }
+                        }
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1562,7 +2270,7 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Clear the entire directory and configuration files",
+                "description": "Clear the entire directory and configuration files of an enrichment. It is rejected (409) while any resources remain in the state, so destroy them first.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1570,9 +2278,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[Object Storage] Operations"
+                    "[Enrichment] Operations"
                 ],
-                "summary": "Clear the entire directory and configuration files",
+                "summary": "Clear the entire directory and configuration files of an enrichment",
                 "parameters": [
                     {
                         "type": "string",
@@ -1583,20 +2291,24 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "enum": [
-                            "force"
-                        ],
                         "type": "string",
-                        "default": "",
-                        "description": "Action",
-                        "name": "action",
-                        "in": "query"
+                        "default": "sql-db",
+                        "description": "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)",
+                        "name": "enrichment",
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "string",
                         "description": "Custom request ID",
                         "name": "x-request-id",
                         "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -1612,6 +2324,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1627,9 +2351,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/tr/{trId}/object-storage/infracode": {
+        "/tr/{trId}/{enrichment}/infracode": {
             "post": {
-                "description": "Create the infracode for Object Storage",
+                "description": "Create the infracode of an enrichment by the tfVars, whose variables are by the enrichment (see GET /enrichments).\nThe terrarium ID in the tfVars is set by the path parameter if it is empty.\nThe tfVars are validated by the rules of the variables (e.g., the region of the provider, CIDR blocks, the password policy),\nand the invalid ones are responded in the errors with 422 (INVALID_TFVARS).",
                 "consumes": [
                     "application/json"
                 ],
@@ -1637,9 +2361,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[Object Storage] Operations"
+                    "[Enrichment] Operations"
                 ],
-                "summary": "Create the infracode for Object Storage",
+                "summary": "Create the infracode of an enrichment",
                 "parameters": [
                     {
                         "type": "string",
@@ -1650,12 +2374,20 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Parameters of infracode for Object Storage",
+                        "type": "string",
+                        "default": "sql-db",
+                        "description": "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)",
+                        "name": "enrichment",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Parameters of infracode for the enrichment",
                         "name": "ParamsForInfracode",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.CreateInfracodeOfObjectStorageRequest"
+                            "$ref": "#/definitions/model.CreateInfracodeOfEnrichmentRequest"
                         }
                     },
                     {
@@ -1663,6 +2395,12 @@ const docTemplate = `{
                         "description": "Custom request ID",
                         "name": "x-request-id",
                         "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -1678,8 +2416,26 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
@@ -1693,9 +2449,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/tr/{trId}/object-storage/plan": {
+        "/tr/{trId}/{enrichment}/plan": {
             "post": {
-                "description": "Check and show changes by the current infracode",
+                "description": "Check and show changes by the current infracode of an enrichment. The plan is saved with the request ID as the plan ID and summarized with sensitive values masked. The saved plan is removed when it is applied or goes stale, and up to 10 plans are kept.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1703,9 +2459,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[Object Storage] Operations"
+                    "[Enrichment] Operations"
                 ],
-                "summary": "Check and show changes by the current infracode",
+                "summary": "Check and show changes by the current infracode of an enrichment",
                 "parameters": [
                     {
                         "type": "string",
@@ -1715,18 +2471,44 @@ const docTemplate = `{
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "default": "sql-db",
+                        "description": "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)",
+                        "name": "enrichment",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "type": "string",
                         "description": "Custom request ID",
                         "name": "x-request-id",
                         "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/model.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "object": {
+                                            "$ref": "#/definitions/model.PlanSummary"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
@@ -1735,6 +2517,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1750,7 +2544,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/tr/{trId}/object-storage/request/{requestId}": {
+        "/tr/{trId}/{enrichment}/request/{requestId}": {
             "get": {
                 "description": "Check the status of a specific request by its ID",
                 "consumes": [
@@ -1760,7 +2554,7 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[Object Storage] Operations"
+                    "[Enrichment] Operations"
                 ],
                 "summary": "Check the status of a specific request by its ID",
                 "parameters": [
@@ -1772,6 +2566,14 @@ const docTemplate = `{
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "default": "sql-db",
+                        "description": "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)",
+                        "name": "enrichment",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "type": "string",
                         "description": "Request ID",
@@ -1784,7 +2586,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "$ref": "#/definitions/model.JobInfo"
                         }
                     },
                     "400": {
@@ -1793,6 +2595,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1806,11 +2614,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/tr/{trId}/sql-db": {
-            "get": {
-                "description": "Get resource info of SQL database",
+            },
+            "delete": {
+                "description": "Cancel a specific running request (e.g., apply or destroy) by its ID.\nThe tofu process is interrupted to release the state lock cleanly and killed after a grace period.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1818,9 +2624,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[SQL Database] Operations"
+                    "[Enrichment] Operations"
                 ],
-                "summary": "Get resource info of SQL database",
+                "summary": "Cancel a specific running request by its ID",
                 "parameters": [
                     {
                         "type": "string",
@@ -1832,21 +2638,29 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "default": "refined",
-                        "description": "Resource info by detail (refined, raw)",
-                        "name": "detail",
-                        "in": "query"
+                        "default": "sql-db",
+                        "description": "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)",
+                        "name": "enrichment",
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "custom request ID",
-                        "name": "x-request-id",
+                        "description": "Request ID",
+                        "name": "requestId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
                         "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
@@ -1857,6 +2671,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1870,19 +2696,21 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Create SQL database",
+            }
+        },
+        "/tr/{trId}/{enrichment}/request/{requestId}/logs": {
+            "get": {
+                "description": "Stream the logs of a specific request by its ID as Server-Sent Events (SSE).\nEach line is sent as a \"log\" event, and the status of the request is sent as a \"status\" event at the end.\nSet follow=true to keep the stream until the request is completed.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "text/event-stream"
                 ],
                 "tags": [
-                    "[SQL Database] Operations"
+                    "[Enrichment] Operations"
                 ],
-                "summary": "Create SQL database",
+                "summary": "Stream the logs of a specific request by its ID",
                 "parameters": [
                     {
                         "type": "string",
@@ -1894,16 +2722,38 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
+                        "default": "sql-db",
+                        "description": "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)",
+                        "name": "enrichment",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Request ID",
+                        "name": "requestId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Follow the logs until the request is completed",
+                        "name": "follow",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Resume the stream from the last event ID (i.e., offset)",
+                        "name": "Last-Event-ID",
                         "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Server-Sent Events of the logs and status of the request",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -1912,6 +2762,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1925,9 +2781,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Destroy SQL database",
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "description": "Read all webhooks of the tenant of the caller (all the tenants for the API user), including the ones of the terrariums.\nThe secrets are not shown.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1935,22 +2793,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[SQL Database] Operations"
+                    "[Webhook] Notifications of the events of the jobs and the terrariums"
                 ],
-                "summary": "Destroy SQL database",
+                "summary": "Read all webhooks",
                 "parameters": [
                     {
                         "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
+                        "description": "Tenant to act as, only for the API user",
+                        "name": "x-tenant",
                         "in": "header"
                     }
                 ],
@@ -1958,13 +2808,10 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Webhook"
+                            }
                         }
                     },
                     "500": {
@@ -1972,19 +2819,11 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
                     }
                 }
-            }
-        },
-        "/tr/{trId}/sql-db/env": {
+            },
             "post": {
-                "description": "Initialize a multi-cloud terrarium for SQL database",
+                "description": "Create a webhook receiving the events of all the terrariums of the tenant of the caller (all the tenants for the API user)\ninstead of polling the requests: job.started, job.succeeded, job.failed, job.cancelled, job.interrupted,\nenrichment.state_changed, terrarium.expiring, terrarium.expired, terrarium.reaped, terrarium.reap_failed and terrarium.erased.\nEach event is posted as JSON with the X-Terrarium-Event, X-Terrarium-Delivery, X-Terrarium-Timestamp and X-Terrarium-Signature headers,\nwhere the signature is sha256= and the hex-encoded HMAC-SHA256 of {timestamp}.{body} by the secret.\nA failed delivery is retried with the exponential backoff, and moved to the dead letters after the last attempt.\nThe URL is rejected (400) if its host is a loopback, link-local or private address (unless webhook.allowprivate),\nand the redirects of the receiver are not followed.\nThe secret is generated if it is not given, and only returned in this response.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1992,35 +2831,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[SQL Database] Operations"
+                    "[Webhook] Notifications of the events of the jobs and the terrariums"
                 ],
-                "summary": "Initialize a multi-cloud terrarium for SQL database",
+                "summary": "Create a webhook",
                 "parameters": [
                     {
-                        "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
-                        "in": "path",
-                        "required": true
+                        "description": "URL and types of the events to receive (all if empty, e.g., job.* for the job events)",
+                        "name": "WebhookRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.WebhookRequest"
+                        }
                     },
                     {
-                        "enum": [
-                            "aws",
-                            "azure",
-                            "gcp",
-                            "ncp"
-                        ],
                         "type": "string",
-                        "default": "aws",
-                        "description": "Provider",
-                        "name": "provider",
-                        "in": "query"
+                        "description": "Tenant to act as, only for the API user",
+                        "name": "x-tenant",
+                        "in": "header"
                     },
                     {
                         "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
                         "in": "header"
                     }
                 ],
@@ -2028,7 +2861,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "$ref": "#/definitions/model.Webhook"
                         }
                     },
                     "400": {
@@ -2042,17 +2875,13 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
                     }
                 }
-            },
-            "delete": {
-                "description": "Clear the entire directory and configuration files",
+            }
+        },
+        "/webhooks/{webhookId}": {
+            "get": {
+                "description": "Read a webhook without the secret.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2060,32 +2889,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[SQL Database] Operations"
+                    "[Webhook] Notifications of the events of the jobs and the terrariums"
                 ],
-                "summary": "Clear the entire directory and configuration files",
+                "summary": "Read a webhook",
                 "parameters": [
                     {
                         "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
+                        "description": "Webhook ID",
+                        "name": "webhookId",
                         "in": "path",
                         "required": true
                     },
-                    {
-                        "enum": [
-                            "force"
-                        ],
-                        "type": "string",
-                        "default": "",
-                        "description": "Action",
-                        "name": "action",
-                        "in": "query"
-                    },
                     {
                         "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
+                        "description": "Tenant to act as, only for the API user",
+                        "name": "x-tenant",
                         "in": "header"
                     }
                 ],
@@ -2093,11 +2911,11 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "$ref": "#/definitions/model.Webhook"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
@@ -2107,19 +2925,11 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
                     }
                 }
-            }
-        },
-        "/tr/{trId}/sql-db/infracode": {
-            "post": {
-                "description": "Create the infracode for SQL database",
+            },
+            "delete": {
+                "description": "Delete a webhook with its dead letters. The deliveries in progress are not stopped.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2127,43 +2937,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[SQL Database] Operations"
+                    "[Webhook] Notifications of the events of the jobs and the terrariums"
                 ],
-                "summary": "Create the infracode for SQL database",
+                "summary": "Delete a webhook",
                 "parameters": [
                     {
                         "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
+                        "description": "Webhook ID",
+                        "name": "webhookId",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Parameters of infracode for SQL database",
-                        "name": "ParamsForInfracode",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.CreateInfracodeOfSqlDbRequest"
-                        }
+                        "type": "string",
+                        "description": "Tenant to act as, only for the API user",
+                        "name": "x-tenant",
+                        "in": "header"
                     },
                     {
                         "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
                         "in": "header"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
@@ -2173,19 +2979,13 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
                     }
                 }
             }
         },
-        "/tr/{trId}/sql-db/plan": {
-            "post": {
-                "description": "Check and show changes by the current infracode",
+        "/webhooks/{webhookId}/dead-letters": {
+            "get": {
+                "description": "Read the deliveries of a webhook which failed after the last attempt or by a permanent error (e.g., 404).",
                 "consumes": [
                     "application/json"
                 ],
@@ -2193,22 +2993,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[SQL Database] Operations"
+                    "[Webhook] Notifications of the events of the jobs and the terrariums"
                 ],
-                "summary": "Check and show changes by the current infracode",
+                "summary": "Read the dead letters of a webhook",
                 "parameters": [
                     {
                         "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
+                        "description": "Webhook ID",
+                        "name": "webhookId",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
+                        "description": "Tenant to act as, only for the API user",
+                        "name": "x-tenant",
                         "in": "header"
                     }
                 ],
@@ -2216,11 +3015,14 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.WebhookDelivery"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
@@ -2230,19 +3032,13 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
                     }
                 }
             }
         },
-        "/tr/{trId}/sql-db/request/{requestId}": {
-            "get": {
-                "description": "Check the status of a specific request by its ID",
+        "/webhooks/{webhookId}/dead-letters/{deliveryId}": {
+            "delete": {
+                "description": "Delete a delivery from the dead letters without redelivering it.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2250,87 +3046,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[SQL Database] Operations"
+                    "[Webhook] Notifications of the events of the jobs and the terrariums"
                 ],
-                "summary": "Check the status of a specific request by its ID",
+                "summary": "Delete a dead letter of a webhook",
                 "parameters": [
                     {
                         "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
+                        "description": "Webhook ID",
+                        "name": "webhookId",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Request ID",
-                        "name": "requestId",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    }
-                }
-            }
-        },
-        "/tr/{trId}/vpn/gcp-aws": {
-            "get": {
-                "description": "Get resource info to configure GCP to AWS VPN tunnels",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "[VPN] GCP to AWS VPN tunnel configuration"
-                ],
-                "summary": "Get resource info to configure GCP to AWS VPN tunnels",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
+                        "description": "Delivery ID",
+                        "name": "deliveryId",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "default": "refined",
-                        "description": "Resource info by detail (refined, raw)",
-                        "name": "detail",
-                        "in": "query"
+                        "description": "Tenant to act as, only for the API user",
+                        "name": "x-tenant",
+                        "in": "header"
                     },
                     {
                         "type": "string",
-                        "description": "custom request ID",
-                        "name": "x-request-id",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
                         "in": "header"
                     }
                 ],
@@ -2341,8 +3084,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.Response"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
@@ -2352,17 +3095,13 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
                     }
                 }
-            },
+            }
+        },
+        "/webhooks/{webhookId}/dead-letters/{deliveryId}/redeliver": {
             "post": {
-                "description": "Create network resources for VPN tunnel in GCP and AWS",
+                "description": "Remove a delivery from the dead letters and deliver it again with the same delivery ID to the current URL of the webhook.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2370,146 +3109,46 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[VPN] GCP to AWS VPN tunnel configuration"
+                    "[Webhook] Notifications of the events of the jobs and the terrariums"
                 ],
-                "summary": "Create network resources for VPN tunnel in GCP and AWS",
+                "summary": "Redeliver a dead letter of a webhook",
                 "parameters": [
                     {
                         "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
+                        "description": "Webhook ID",
+                        "name": "webhookId",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
-                        "in": "header"
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    }
-                }
-            },
-            "delete": {
-                "description": "Destroy network resources that were used to configure GCP as an AWS VPN tunnel",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "[VPN] GCP to AWS VPN tunnel configuration"
-                ],
-                "summary": "Destroy network resources that were used to configure GCP as an AWS VPN tunnel",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
+                        "description": "Delivery ID",
+                        "name": "deliveryId",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
+                        "description": "Tenant to act as, only for the API user",
+                        "name": "x-tenant",
                         "in": "header"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    }
-                }
-            }
-        },
-        "/tr/{trId}/vpn/gcp-aws/env": {
-            "post": {
-                "description": "Initialize a multi-cloud terrarium for GCP to AWS VPN tunnel",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "[VPN] GCP to AWS VPN tunnel configuration"
-                ],
-                "summary": "Initialize a multi-cloud terrarium for GCP to AWS VPN tunnel",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
-                        "in": "path",
-                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
                         "in": "header"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "$ref": "#/definitions/model.WebhookDelivery"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
@@ -2519,17 +3158,13 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
                     }
                 }
-            },
-            "delete": {
-                "description": "Clear the entire directory and configuration files",
+            }
+        },
+        "/webhooks/{webhookId}/deliveries": {
+            "get": {
+                "description": "Read the recent deliveries of a webhook by this server, the newest first, with the status (Pending, Retrying, Delivered or Dead),\nthe number of attempts and the last error. They are kept in memory up to 50 per webhook.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2537,22 +3172,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[VPN] GCP to AWS VPN tunnel configuration"
+                    "[Webhook] Notifications of the events of the jobs and the terrariums"
                 ],
-                "summary": "Clear the entire directory and configuration files",
+                "summary": "Read the recent deliveries of a webhook",
                 "parameters": [
                     {
                         "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
+                        "description": "Webhook ID",
+                        "name": "webhookId",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
+                        "description": "Tenant to act as, only for the API user",
+                        "name": "x-tenant",
                         "in": "header"
                     }
                 ],
@@ -2560,11 +3194,14 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.WebhookDelivery"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
@@ -2574,19 +3211,13 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
                     }
                 }
             }
         },
-        "/tr/{trId}/vpn/gcp-aws/infracode": {
+        "/webhooks/{webhookId}/ping": {
             "post": {
-                "description": "Create the infracode to configure GCP to AWS VPN tunnels",
+                "description": "Deliver a test event (webhook.ping) to a webhook. Check the result by the deliveries of the webhook.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2594,43 +3225,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "[VPN] GCP to AWS VPN tunnel configuration"
+                    "[Webhook] Notifications of the events of the jobs and the terrariums"
                 ],
-                "summary": "Create the infracode to configure GCP to AWS VPN tunnels",
+                "summary": "Ping a webhook",
                 "parameters": [
                     {
                         "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
+                        "description": "Webhook ID",
+                        "name": "webhookId",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Parameters requied to create the infracode to configure GCP to AWS VPN tunnels",
-                        "name": "ParamsForInfracode",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.CreateInfracodeOfGcpAwsVpnRequest"
-                        }
+                        "type": "string",
+                        "description": "Tenant to act as, only for the API user",
+                        "name": "x-tenant",
+                        "in": "header"
                     },
                     {
                         "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
+                        "description": "Key to replay the response of the request retried with it (kept for 24 hours)",
+                        "name": "Idempotency-Key",
                         "in": "header"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/model.Response"
+                            "$ref": "#/definitions/model.WebhookDelivery"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
@@ -2640,601 +3267,27 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.Response"
                         }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
                     }
                 }
             }
+        }
+    },
+    "definitions": {
+        "handler.CreateUserRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
         },
-        "/tr/{trId}/vpn/gcp-aws/plan": {
-            "post": {
-                "description": "Check and show changes by the current infracode to configure GCP to AWS VPN tunnels",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "[VPN] GCP to AWS VPN tunnel configuration"
-                ],
-                "summary": "Check and show changes by the current infracode to configure GCP to AWS VPN tunnels",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
-                        "in": "header"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    }
-                }
-            }
-        },
-        "/tr/{trId}/vpn/gcp-aws/request/{requestId}": {
-            "get": {
-                "description": "Check the status of a specific request by its ID",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "[VPN] GCP to AWS VPN tunnel configuration"
-                ],
-                "summary": "Check the status of a specific request by its ID",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Request ID",
-                        "name": "requestId",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    }
-                }
-            }
-        },
-        "/tr/{trId}/vpn/gcp-azure": {
-            "get": {
-                "description": "Get resource info to configure GCP to Azure VPN tunnels",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "[VPN] GCP to Azure VPN tunnel configuration (under development)"
-                ],
-                "summary": "Get resource info to configure GCP to Azure VPN tunnels",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "default": "refined",
-                        "description": "Resource info by detail (refined, raw)",
-                        "name": "detail",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
-                        "in": "header"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    }
-                }
-            },
-            "post": {
-                "description": "Create network resources for VPN tunnel in GCP and Azure",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "[VPN] GCP to Azure VPN tunnel configuration (under development)"
-                ],
-                "summary": "Create network resources for VPN tunnel in GCP and Azure",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
-                        "in": "header"
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    }
-                }
-            },
-            "delete": {
-                "description": "Destroy network resources that were used to configure GCP as an Azure VPN tunnel",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "[VPN] GCP to Azure VPN tunnel configuration (under development)"
-                ],
-                "summary": "Destroy network resources that were used to configure GCP as an Azure VPN tunnel",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
-                        "in": "header"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    }
-                }
-            }
-        },
-        "/tr/{trId}/vpn/gcp-azure/env": {
-            "post": {
-                "description": "Initialize a multi-cloud terrarium for GCP to Azure VPN tunnel",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "[VPN] GCP to Azure VPN tunnel configuration (under development)"
-                ],
-                "summary": "Initialize a multi-cloud terrarium for GCP to Azure VPN tunnel",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
-                        "in": "header"
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    }
-                }
-            },
-            "delete": {
-                "description": "Clear the entire directory and configuration files",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "[VPN] GCP to Azure VPN tunnel configuration (under development)"
-                ],
-                "summary": "Clear the entire directory and configuration files",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
-                        "in": "header"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    }
-                }
-            }
-        },
-        "/tr/{trId}/vpn/gcp-azure/infracode": {
-            "post": {
-                "description": "Create the infracode to configure GCP to Azure VPN tunnels",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "[VPN] GCP to Azure VPN tunnel configuration (under development)"
-                ],
-                "summary": "Create the infracode to configure GCP to Azure VPN tunnels",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Parameters requied to create the infracode to configure GCP to Azure VPN tunnels",
-                        "name": "ParamsForInfracode",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.CreateInfracodeOfGcpAzureVpnRequest"
-                        }
-                    },
-                    {
-                        "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
-                        "in": "header"
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    }
-                }
-            }
-        },
-        "/tr/{trId}/vpn/gcp-azure/plan": {
-            "post": {
-                "description": "Check and show changes by the current infracode to configure GCP to Azure VPN tunnels",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "[VPN] GCP to Azure VPN tunnel configuration (under development)"
-                ],
-                "summary": "Check and show changes by the current infracode to configure GCP to Azure VPN tunnels",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Custom request ID",
-                        "name": "x-request-id",
-                        "in": "header"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    }
-                }
-            }
-        },
-        "/tr/{trId}/vpn/gcp-azure/request/{requestId}": {
-            "get": {
-                "description": "Check the status of a specific request by its ID",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "[VPN] GCP to Azure VPN tunnel configuration (under development)"
-                ],
-                "summary": "Check the status of a specific request by its ID",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "default": "tr01",
-                        "description": "Terrarium ID",
-                        "name": "trId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Request ID",
-                        "name": "requestId",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/model.Response"
-                        }
-                    }
-                }
-            }
-        }
-    },
-    "definitions": {
-        "handler.CreateUserRequest": {
+        "handler.GetUserResponse": {
             "type": "object",
             "properties": {
                 "email": {
@@ -3248,7 +3301,18 @@ const docTemplate = `{
                 }
             }
         },
-        "handler.GetUserResponse": {
+        "handler.GetUsersResponse": {
+            "type": "object",
+            "properties": {
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.MyUser"
+                    }
+                }
+            }
+        },
+        "handler.PatchUserRequest": {
             "type": "object",
             "properties": {
                 "email": {
@@ -3262,18 +3326,21 @@ const docTemplate = `{
                 }
             }
         },
-        "handler.GetUsersResponse": {
+        "handler.PatchUserResponse": {
             "type": "object",
             "properties": {
-                "users": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/model.MyUser"
-                    }
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
                 }
             }
         },
-        "handler.PatchUserRequest": {
+        "handler.UpdateUserRequest": {
             "type": "object",
             "properties": {
                 "email": {
@@ -3287,7 +3354,7 @@ const docTemplate = `{
                 }
             }
         },
-        "handler.PatchUserResponse": {
+        "handler.UpdateUserResponse": {
             "type": "object",
             "properties": {
                 "email": {
@@ -3301,21 +3368,385 @@ const docTemplate = `{
                 }
             }
         },
-        "handler.UpdateUserRequest": {
+        "model.AttributeChange": {
+            "type": "object",
+            "properties": {
+                "after": {},
+                "before": {},
+                "name": {
+                    "type": "string",
+                    "example": "tags"
+                },
+                "sensitive": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "unknown": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "model.ChangeSummary": {
+            "type": "object",
+            "properties": {
+                "add": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "change": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "import": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "operation": {
+                    "type": "string",
+                    "example": "apply"
+                },
+                "remove": {
+                    "type": "integer",
+                    "example": 0
+                }
+            }
+        },
+        "model.CreateInfracodeOfEnrichmentRequest": {
+            "type": "object",
+            "properties": {
+                "tfVars": {
+                    "type": "object"
+                }
+            }
+        },
+        "model.CreateInfracodeOfTestEnvRequest": {
+            "type": "object",
+            "properties": {
+                "tfVars": {
+                    "$ref": "#/definitions/model.TfVarsTestEnv"
+                }
+            }
+        },
+        "model.DiagnosticMessage": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string",
+                    "example": "aws_vpn_gateway.vpn_gw"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": ""
+                },
+                "severity": {
+                    "type": "string",
+                    "example": "error"
+                },
+                "summary": {
+                    "type": "string",
+                    "example": "Error creating VPN Gateway"
+                }
+            }
+        },
+        "model.EngineVersion": {
+            "type": "object",
+            "properties": {
+                "binary": {
+                    "type": "string",
+                    "example": "tofu"
+                },
+                "engine": {
+                    "type": "string",
+                    "example": "opentofu"
+                },
+                "minVersion": {
+                    "type": "string",
+                    "example": "1.6.0"
+                },
+                "raw": {
+                    "type": "string",
+                    "example": "OpenTofu v1.8.0\non linux_amd64"
+                },
+                "satisfiesMinVersion": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "version": {
+                    "$ref": "#/definitions/model.SemanticVersion"
+                }
+            }
+        },
+        "model.EnrichmentInfo": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "kind": {
+                    "type": "string",
+                    "example": "vpn"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "vpn/gcp-aws"
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "gcp-aws"
+                },
+                "state": {
+                    "type": "string",
+                    "example": "Applied"
+                },
+                "stateReason": {
+                    "type": "string",
+                    "example": "the request (reqId: 1712345678901234567) is Failed"
+                },
+                "updatedAt": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "workingDir": {
+                    "type": "string",
+                    "example": ".terrarium/tr01/vpn/gcp-aws"
+                }
+            }
+        },
+        "model.EnrichmentKind": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "sql-db"
+                },
+                "outputName": {
+                    "type": "string",
+                    "example": "sql_db_info"
+                },
+                "providers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "aws",
+                        "azure",
+                        "gcp",
+                        "ncp"
+                    ]
+                },
+                "tfVars": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.TfVarsField"
+                    }
+                },
+                "title": {
+                    "type": "string",
+                    "example": "SQL database"
+                }
+            }
+        },
+        "model.EnrichmentResources": {
+            "type": "object",
+            "properties": {
+                "enrichments": {
+                    "type": "string",
+                    "example": "vpn/gcp-aws"
+                },
+                "resources": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "aws_vpn_gateway.vpn_gw"
+                    ]
+                },
+                "state": {
+                    "type": "string",
+                    "example": "Applied"
+                }
+            }
+        },
+        "model.ExtendTtlRequest": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "type": "string",
+                    "example": "2024-01-05T00:00:00Z"
+                },
+                "ttl": {
+                    "type": "string",
+                    "example": "24h"
+                }
+            }
+        },
+        "model.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "csp_region"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "must be a region of aws, not 'ap-northeast-9'"
+                },
+                "rule": {
+                    "type": "string",
+                    "example": "region"
+                }
+            }
+        },
+        "model.ImportResult": {
+            "type": "object",
+            "properties": {
+                "requests": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "terrarium": {
+                    "$ref": "#/definitions/model.TerrariumInfo"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "the bundle is exported by another version (v0.0.9)"
+                    ]
+                }
+            }
+        },
+        "model.JobInfo": {
+            "type": "object",
+            "properties": {
+                "args": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "-auto-approve"
+                    ]
+                },
+                "command": {
+                    "type": "string",
+                    "example": "tofu"
+                },
+                "diagnostics": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.DiagnosticMessage"
+                    }
+                },
+                "endedAt": {
+                    "type": "string",
+                    "example": "2024-01-01T00:10:00Z"
+                },
+                "enrichments": {
+                    "type": "string",
+                    "example": "sql-db"
+                },
+                "error": {
+                    "type": "string",
+                    "example": ""
+                },
+                "exitCode": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "id": {
+                    "type": "string",
+                    "example": "1712345678901234567"
+                },
+                "logPath": {
+                    "type": "string",
+                    "example": "/app/.terrarium/tr01/sql-db/runningLogs/1712345678901234567.log"
+                },
+                "progress": {
+                    "$ref": "#/definitions/model.JobProgress"
+                },
+                "queuePosition": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "queuedAt": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "server": {
+                    "type": "string",
+                    "example": "mc-terrarium-7d9f-1a2b3c4d"
+                },
+                "startedAt": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "stateLock": {
+                    "$ref": "#/definitions/model.StateLock"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "Running"
+                },
+                "subcommand": {
+                    "type": "string",
+                    "example": "apply"
+                },
+                "terrariumId": {
+                    "type": "string",
+                    "example": "tr01"
+                }
+            }
+        },
+        "model.JobProgress": {
             "type": "object",
             "properties": {
-                "email": {
-                    "type": "string"
+                "changeSummary": {
+                    "$ref": "#/definitions/model.ChangeSummary"
                 },
-                "id": {
-                    "type": "integer"
+                "currentResource": {
+                    "type": "string",
+                    "example": "aws_vpn_gateway.vpn_gw"
                 },
-                "name": {
-                    "type": "string"
+                "diagnostics": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.DiagnosticMessage"
+                    }
+                },
+                "operation": {
+                    "type": "string",
+                    "example": "apply"
+                },
+                "resources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ResourceProgress"
+                    }
+                },
+                "resourcesCompleted": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "resourcesFailed": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "resourcesTotal": {
+                    "type": "integer",
+                    "example": 10
                 }
             }
         },
-        "handler.UpdateUserResponse": {
+        "model.MyUser": {
             "type": "object",
             "properties": {
                 "email": {
@@ -3329,75 +3760,140 @@ const docTemplate = `{
                 }
             }
         },
-        "model.CreateInfracodeOfGcpAwsVpnRequest": {
-            "type": "object",
-            "properties": {
-                "tfVars": {
-                    "$ref": "#/definitions/model.TfVarsGcpAwsVpnTunnel"
-                }
-            }
-        },
-        "model.CreateInfracodeOfGcpAzureVpnRequest": {
-            "type": "object",
-            "properties": {
-                "tfVars": {
-                    "$ref": "#/definitions/model.TfVarsGcpAzureVpnTunnel"
-                }
-            }
-        },
-        "model.CreateInfracodeOfMessageBrokerRequest": {
-            "type": "object",
-            "properties": {
-                "tfVars": {
-                    "$ref": "#/definitions/model.TfVarsMessageBroker"
-                }
-            }
-        },
-        "model.CreateInfracodeOfObjectStorageRequest": {
-            "type": "object",
-            "properties": {
-                "tfVars": {
-                    "$ref": "#/definitions/model.TfVarsMessageBroker"
-                }
-            }
-        },
-        "model.CreateInfracodeOfSqlDbRequest": {
+        "model.PlanSummary": {
             "type": "object",
             "properties": {
-                "tfVars": {
-                    "$ref": "#/definitions/model.TfVarsSqlDb"
+                "add": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "change": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "destroy": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "hasDestroy": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "planId": {
+                    "type": "string",
+                    "example": "1712345678901234567"
+                },
+                "resourceChanges": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.PlannedResourceChange"
+                    }
                 }
             }
         },
-        "model.CreateInfracodeOfTestEnvRequest": {
+        "model.PlannedResourceChange": {
             "type": "object",
             "properties": {
-                "tfVars": {
-                    "$ref": "#/definitions/model.TfVarsTestEnv"
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete",
+                        "replace",
+                        "read"
+                    ],
+                    "example": "create"
+                },
+                "actions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "create"
+                    ]
+                },
+                "address": {
+                    "type": "string",
+                    "example": "aws_vpn_gateway.vpn_gw"
+                },
+                "changedAttributes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AttributeChange"
+                    }
+                },
+                "mode": {
+                    "type": "string",
+                    "example": "managed"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "vpn_gw"
+                },
+                "providerName": {
+                    "type": "string",
+                    "example": "registry.opentofu.org/hashicorp/aws"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "aws_vpn_gateway"
                 }
             }
         },
-        "model.MyUser": {
+        "model.ResourceProgress": {
             "type": "object",
             "properties": {
-                "email": {
-                    "type": "string"
+                "action": {
+                    "type": "string",
+                    "example": "create"
                 },
-                "id": {
-                    "type": "integer"
+                "address": {
+                    "type": "string",
+                    "example": "aws_vpn_gateway.vpn_gw"
                 },
-                "name": {
-                    "type": "string"
+                "elapsedSeconds": {
+                    "type": "number",
+                    "example": 60
+                },
+                "endedAt": {
+                    "type": "string",
+                    "example": "2024-01-01T00:01:00Z"
+                },
+                "startedAt": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "InProgress"
                 }
             }
         },
         "model.Response": {
             "type": "object",
             "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "TERRARIUM_NOT_FOUND"
+                },
                 "details": {
                     "type": "string",
                     "example": "Any details"
                 },
+                "diagnostics": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.DiagnosticMessage"
+                    }
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.FieldError"
+                    }
+                },
                 "list": {
                     "type": "array",
                     "items": {}
@@ -3420,66 +3916,156 @@ const docTemplate = `{
                 }
             }
         },
+        "model.SemanticVersion": {
+            "type": "object",
+            "properties": {
+                "major": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "minor": {
+                    "type": "integer",
+                    "example": 8
+                },
+                "patch": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "prerelease": {
+                    "type": "string",
+                    "example": ""
+                },
+                "version": {
+                    "type": "string",
+                    "example": "1.8.0"
+                }
+            }
+        },
+        "model.StateLock": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "2f4e3c2a-1b2c-3d4e-5f60-718293a4b5c6"
+                },
+                "operation": {
+                    "type": "string",
+                    "example": "OperationTypeApply"
+                },
+                "path": {
+                    "type": "string",
+                    "example": "terraform.tfstate"
+                },
+                "who": {
+                    "type": "string",
+                    "example": "root@mc-terrarium"
+                }
+            }
+        },
         "model.TerrariumInfo": {
             "type": "object",
-            "required": [
-                "id"
-            ],
             "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "readOnly": true,
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "createdBy": {
+                    "type": "string",
+                    "readOnly": true,
+                    "example": "default"
+                },
                 "description": {
                     "type": "string",
                     "default": "This terrarium enriches ...",
                     "example": "This terrarium enriches ..."
                 },
                 "enrichments": {
-                    "type": "string"
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.EnrichmentInfo"
+                    },
+                    "readOnly": true
+                },
+                "expiresAt": {
+                    "type": "string",
+                    "example": "2024-01-04T00:00:00Z"
+                },
+                "expiryWarnedAt": {
+                    "type": "string",
+                    "readOnly": true,
+                    "example": "2024-01-03T23:00:00Z"
                 },
                 "id": {
                     "type": "string",
                     "default": "tr01",
                     "example": "tr01"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "tenant": {
+                    "type": "string",
+                    "readOnly": true,
+                    "example": "team-a"
+                },
+                "ttl": {
+                    "type": "string",
+                    "example": "72h"
+                },
+                "updatedAt": {
+                    "type": "string",
+                    "readOnly": true,
+                    "example": "2024-01-01T00:00:00Z"
                 }
             }
         },
-        "model.TfVarsGcpAwsVpnTunnel": {
+        "model.TfVarsField": {
             "type": "object",
             "properties": {
-                "aws-region": {
+                "example": {
                     "type": "string",
-                    "default": "ap-northeast-2",
                     "example": "ap-northeast-2"
                 },
-                "aws-subnet-id": {
-                    "type": "string",
-                    "example": "subnet-xxxxx"
-                },
-                "aws-vpc-id": {
+                "name": {
                     "type": "string",
-                    "example": "vpc-xxxxx"
+                    "example": "csp_region"
                 },
-                "gcp-region": {
-                    "type": "string",
-                    "default": "asia-northeast3",
-                    "example": "asia-northeast3"
+                "required": {
+                    "type": "boolean",
+                    "example": true
                 },
-                "gcp-vpc-network-name": {
+                "rules": {
+                    "description": "Rules of the value, which are checked when creating the infracode (e.g., region of the provider)",
                     "type": "string",
-                    "default": "tr-gcp-vpc",
-                    "example": "tr-gcp-vpc"
+                    "example": "required,region"
                 },
-                "terrarium-id": {
+                "type": {
                     "type": "string",
-                    "example": ""
+                    "example": "string"
                 }
             }
         },
-        "model.TfVarsGcpAzureVpnTunnel": {
+        "model.TfVarsTestEnv": {
             "type": "object",
+            "required": [
+                "aws-region",
+                "azure-region",
+                "azure-resource-group-name",
+                "gcp-region"
+            ],
             "properties": {
-                "azure-gateway-subnet-cidr-block": {
+                "aws-region": {
                     "type": "string",
-                    "default": "192.168.130.0/24",
-                    "example": "192.168.130.0/24"
+                    "default": "ap-northeast-2",
+                    "example": "ap-northeast-2"
                 },
                 "azure-region": {
                     "type": "string",
@@ -3489,125 +4075,186 @@ const docTemplate = `{
                 "azure-resource-group-name": {
                     "type": "string",
                     "default": "tr-rg-01",
+                    "maxLength": 90,
                     "example": "tr-rg-01"
                 },
-                "azure-virtual-network-name": {
-                    "type": "string",
-                    "default": "tr-azure-vnet",
-                    "example": "tr-azure-vnet"
-                },
                 "gcp-region": {
                     "type": "string",
                     "default": "asia-northeast3",
                     "example": "asia-northeast3"
-                },
-                "gcp-vpc-network-name": {
-                    "type": "string",
-                    "default": "tr-gcp-vpc",
-                    "example": "tr-gcp-vpc"
-                },
-                "terrarium-id": {
-                    "type": "string",
-                    "example": ""
                 }
             }
         },
-        "model.TfVarsMessageBroker": {
+        "model.Webhook": {
             "type": "object",
             "properties": {
-                "csp_region": {
+                "createdAt": {
                     "type": "string",
-                    "example": "ap-northeast-2"
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "notify the infra team"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "job.succeeded",
+                        "job.failed",
+                        "terrarium.expiring"
+                    ]
                 },
-                "csp_vnet_id": {
+                "id": {
                     "type": "string",
-                    "example": "vpc-12345678"
+                    "example": "wh-k3x9q2ab"
                 },
-                "terrarium_id": {
+                "secret": {
+                    "description": "Secret to sign the deliveries, which is only shown when the webhook is created",
                     "type": "string",
-                    "example": ""
+                    "example": "6f1c2a..."
+                },
+                "tenant": {
+                    "type": "string",
+                    "example": "default"
+                },
+                "terrariumId": {
+                    "type": "string",
+                    "example": "tr01"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/terrarium"
                 }
             }
         },
-        "model.TfVarsSqlDb": {
+        "model.WebhookDelivery": {
             "type": "object",
             "properties": {
-                "csp_region": {
+                "attempts": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "createdAt": {
                     "type": "string",
-                    "example": "ap-northeast-2"
+                    "example": "2024-01-01T00:10:00Z"
+                },
+                "deliveredAt": {
+                    "type": "string",
+                    "example": "2024-01-01T00:10:00Z"
+                },
+                "event": {
+                    "$ref": "#/definitions/model.WebhookEvent"
                 },
-                "csp_resource_group": {
+                "id": {
                     "type": "string",
-                    "example": "rg-12345678"
+                    "example": "ev-4f9a1c2e7b3d8a6f0e5c9b2d1a7f3e8c-wh-k3x9q2ab"
                 },
-                "csp_subnet1_id": {
+                "lastAttemptAt": {
                     "type": "string",
-                    "example": "subnet-1234abcd"
+                    "example": "2024-01-01T00:10:00Z"
                 },
-                "csp_subnet2_id": {
+                "lastError": {
                     "type": "string",
-                    "example": "subnet-abcd1234"
+                    "example": ""
+                },
+                "lastStatusCode": {
+                    "type": "integer",
+                    "example": 200
                 },
-                "csp_vnet_id": {
+                "nextAttemptAt": {
                     "type": "string",
-                    "example": "vpc-12345678"
+                    "example": "2024-01-01T00:10:02Z"
                 },
-                "db_admin_password": {
+                "status": {
                     "type": "string",
-                    "example": "Password1234!"
+                    "example": "Delivered"
                 },
-                "db_admin_username": {
+                "url": {
                     "type": "string",
-                    "example": "mydbadmin"
+                    "example": "https://example.com/hooks/terrarium"
                 },
-                "db_engine_port": {
-                    "type": "integer",
-                    "example": 3306
+                "webhookId": {
+                    "type": "string",
+                    "example": "wh-k3x9q2ab"
+                }
+            }
+        },
+        "model.WebhookEvent": {
+            "type": "object",
+            "properties": {
+                "enrichments": {
+                    "type": "string",
+                    "example": "sql-db"
+                },
+                "from": {
+                    "description": "States of an enrichment.state_changed event",
+                    "type": "string",
+                    "example": "Applying"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "ev-4f9a1c2e7b3d8a6f0e5c9b2d1a7f3e8c"
                 },
-                "db_engine_version": {
+                "job": {
+                    "description": "Job of a job event (job.*)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.JobInfo"
+                        }
+                    ]
+                },
+                "message": {
                     "type": "string",
-                    "example": "8.0.39"
+                    "example": "the request (reqId: 1712345678901234567) to apply is Success"
                 },
-                "db_instance_spec": {
+                "terrariumId": {
                     "type": "string",
-                    "example": "db.t3.micro"
+                    "example": "tr01"
                 },
-                "egress_cidr_block": {
+                "time": {
                     "type": "string",
-                    "example": "0.0.0.0/0"
+                    "example": "2024-01-01T00:10:00Z"
                 },
-                "ingress_cidr_block": {
+                "to": {
                     "type": "string",
-                    "example": "0.0.0.0/0"
+                    "example": "Applied"
                 },
-                "terrarium_id": {
+                "type": {
                     "type": "string",
-                    "example": ""
+                    "example": "job.succeeded"
                 }
             }
         },
-        "model.TfVarsTestEnv": {
+        "model.WebhookRequest": {
             "type": "object",
             "properties": {
-                "aws-region": {
+                "description": {
                     "type": "string",
-                    "default": "ap-northeast-2",
-                    "example": "ap-northeast-2"
+                    "example": "notify the infra team"
                 },
-                "azure-region": {
-                    "type": "string",
-                    "default": "koreacentral",
-                    "example": "koreacentral"
+                "events": {
+                    "description": "Types of the events to receive, where a type can end with '*' (e.g., job.*), and all the events if empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "job.succeeded",
+                        "job.failed",
+                        "terrarium.expiring"
+                    ]
                 },
-                "azure-resource-group-name": {
+                "secret": {
+                    "description": "Secret to sign the deliveries, which is generated if it is empty",
                     "type": "string",
-                    "default": "tr-rg-01",
-                    "example": "tr-rg-01"
+                    "example": ""
                 },
-                "gcp-region": {
+                "url": {
                     "type": "string",
-                    "default": "asia-northeast3",
-                    "example": "asia-northeast3"
+                    "example": "https://example.com/hooks/terrarium"
                 }
             }
         }
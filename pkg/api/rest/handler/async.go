@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// asyncRequested returns whether a long-running operation (i.e., init, apply, destroy and import)
+// is requested to run in the background by the query param "async", which waits for the result by default.
+func asyncRequested(c echo.Context) (bool, error) {
+	async := c.QueryParam("async")
+	if async == "" {
+		return false, nil
+	}
+	v, err := strconv.ParseBool(async)
+	if err != nil {
+		return false, newError(model.ErrCodeInvalidRequest, "invalid request, async (%s) must be true or false", async)
+	}
+	return v, nil
+}
+
+// runTofuCommand runs the tofu command of a long-running operation,
+// which is queued and returns immediately if async, otherwise waits for the result.
+func runTofuCommand(async bool, trId, enrichments, reqId string, args ...string) (string, error) {
+	if async {
+		return tofu.ExecuteTofuCommandAsync(trId, enrichments, reqId, args...)
+	}
+	return tofu.ExecuteTofuCommand(trId, enrichments, reqId, args...)
+}
+
+// acceptRequest responds 202 Accepted with the job of a request running in the background,
+// and the URL of the request status in the Location header.
+func acceptRequest(c echo.Context, reqId, statusPath string) error {
+
+	job, err := tofu.GetJob(reqId)
+	if err != nil {
+		err2 := fmt.Errorf("failed to read the request (reqId: %s)", reqId)
+		log.Error().Err(err).Msg(err2.Error())
+		return c.JSON(failureWithMessage(err, err2.Error()))
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, statusPath)
+
+	log.Debug().Msgf("%+v", job) // debug
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// enrichmentRequestPath returns the URL of the status of a request of an enrichment.
+func enrichmentRequestPath(trId, enrichments, reqId string) string {
+	return fmt.Sprintf("/terrarium/tr/%s/%s/request/%s", trId, enrichments, reqId)
+}
+
+// testEnvRequestPath returns the URL of the status of a request of the test environment.
+func testEnvRequestPath(reqId string) string {
+	return fmt.Sprintf("/terrarium/test-env/request/%s/status", reqId)
+}
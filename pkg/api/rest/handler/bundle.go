@@ -0,0 +1,155 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/terrarium"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// ExportTerrarium godoc
+// @Summary Export a terrarium as a bundle
+// @Description Export a terrarium as a bundle (tar.gz) to import it on another server.
+// @Description The bundle has the terrarium info and the infracode (templates and tfvars), the lock file and the states of each enrichment
+// @Description with a manifest of the checksums and the version of mc-terrarium. The credentials are not exported.
+// @Description It is rejected (409) while any enrichment is applying or destroying.
+// @Tags [Terrarium] An environment to enrich the multi-cloud infrastructure
+// @Accept  json
+// @Produce  application/gzip
+// @Param trId path string true "Terrarium ID" default(tr01)
+// @Success 200 {file} file "Bundle of the terrarium ({trId}.tar.gz)"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 409 {object} model.Response "Conflict"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Router /tr/{trId}/export [get]
+func ExportTerrarium(c echo.Context) error {
+
+	trId := c.Param("trId")
+	if trId == "" {
+		return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "require the terrarium ID")))
+	}
+
+	bundle, err := terrarium.NewBundle(trId)
+	if err != nil {
+		log.Warn().Err(err).Msgf("failed to export the terrarium (trId: %s)", trId)
+		return c.JSON(failure(err))
+	}
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "application/gzip")
+	w.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", trId+".tar.gz"))
+	w.WriteHeader(http.StatusOK)
+
+	if err := bundle.Write(w); err != nil {
+		// The response has been started, so the client gets a truncated bundle which fails to be verified
+		log.Error().Err(err).Msgf("failed to write the bundle of the terrarium (trId: %s)", trId)
+	}
+
+	return nil
+}
+
+// ImportTerrarium godoc
+// @Summary Import a terrarium from a bundle
+// @Description Import a terrarium from a bundle exported by another server, which is verified by the checksums in the manifest.
+// @Description The bundle is uploaded as the form file "bundle" or as the request body (application/gzip).
+// @Description The credentials are copied from the secrets of this server and each enrichment is initialized, keeping the lifecycle states.
+// @Description If the ID is in use, it is rejected (409) unless onConflict=rename, which imports it with a generated ID.
+// @Description Only the API user can import (403), since the infracode in the bundle runs with the credentials of this server.
+// @Description The terrarium is imported into the tenant by the x-tenant header within its quota (403).
+// @Description With async=true, the enrichments are initialized in the background, and the import is accepted (202)
+// @Description with the requests initializing them and the Location of the imported terrarium.
+// @Tags [Terrarium] An environment to enrich the multi-cloud infrastructure
+// @Accept  multipart/form-data
+// @Accept  application/gzip
+// @Produce  json
+// @Param bundle formData file false "Bundle of a terrarium (tar.gz)"
+// @Param trId query string false "ID to import the terrarium as, instead of the ID in the bundle"
+// @Param onConflict query string false "What to do if the ID is in use" Enums(fail, rename) default(fail)
+// @Param async query bool false "Initialize the enrichments in the background" default(false)
+// @Param x-request-id header string false "Custom request ID"
+// @Param x-tenant header string false "Tenant to act as, only for the API user (default: the default tenant)"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 200 {object} model.ImportResult "OK"
+// @Success 202 {object} model.ImportResult "Accepted"
+// @Header 202 {string} Location "URL of the imported terrarium"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 403 {object} model.Response "Forbidden"
+// @Failure 409 {object} model.Response "Conflict"
+// @Failure 422 {object} model.Response "Unprocessable Entity"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Router /tr/import [post]
+func ImportTerrarium(c echo.Context) error {
+
+	onConflict := c.QueryParam("onConflict")
+	if onConflict != "" && onConflict != "fail" && onConflict != "rename" {
+		err := newError(model.ErrCodeInvalidRequest, "invalid onConflict (%s), use fail or rename", onConflict)
+		return c.JSON(failure(err))
+	}
+
+	async, err := asyncRequested(c)
+	if err != nil {
+		return c.JSON(failure(err))
+	}
+
+	// Get the request ID
+	reqId := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	var body io.Reader = c.Request().Body
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("bundle")
+		if err != nil {
+			return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "require the bundle file (bundle)")))
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			err2 := fmt.Errorf("failed to open the bundle file")
+			log.Error().Err(err).Msg(err2.Error())
+			return c.JSON(failureWithMessage(err, err2.Error()))
+		}
+		defer file.Close()
+		body = file
+	}
+
+	opts := terrarium.ImportOptions{
+		TrId:   c.QueryParam("trId"),
+		Rename: onConflict == "rename",
+		ReqId:  reqId,
+		Tenant: callerTenant(c),
+		Async:  async,
+	}
+
+	result, err := terrarium.ImportTerrarium(body, opts)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to import the terrarium")
+		return c.JSON(failure(err))
+	}
+
+	for _, warning := range result.Warnings {
+		log.Warn().Msgf("importing the terrarium (trId: %s): %s", result.Terrarium.Id, warning)
+	}
+
+	if async {
+		c.Response().Header().Set(echo.HeaderLocation, "/terrarium/tr/"+result.Terrarium.Id)
+		return c.JSON(http.StatusAccepted, result)
+	}
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,882 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/enrichment"
+	"github.com/cloud-barista/mc-terrarium/pkg/tenant"
+	"github.com/cloud-barista/mc-terrarium/pkg/terrarium"
+	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+	"github.com/tidwall/gjson"
+)
+
+// enrichmentOf returns the descriptor of the enrichment in the path,
+// which has the two segments of :enrichment and :variant for a VPN (e.g., vpn/gcp-aws).
+// The name is also accepted as an escaped segment (e.g., vpn%2Fgcp-aws).
+func enrichmentOf(c echo.Context) (enrichment.Descriptor, error) {
+	name, err := url.PathUnescape(c.Param("enrichment"))
+	if err != nil {
+		return enrichment.Descriptor{}, fmt.Errorf("%w (enrichments: %s)", enrichment.ErrUnknownEnrichment, c.Param("enrichment"))
+	}
+	if variant := c.Param("variant"); variant != "" {
+		name += "/" + variant
+	}
+	return enrichment.Get(name)
+}
+
+// existingEnrichment returns an enrichment of a terrarium and its working directory, which must exist.
+func existingEnrichment(trId, name string) (model.EnrichmentInfo, string, error) {
+
+	// Read the enrichments of the terrarium
+	enrichmentInfo, err := terrarium.ReadEnrichmentInfo(trId, name)
+	if err != nil {
+		return model.EnrichmentInfo{}, "", err
+	}
+
+	// Check if the working directory exists
+	workingDir, err := terrarium.TerrariumPath(trId, name)
+	if err != nil {
+		return model.EnrichmentInfo{}, "", err
+	}
+	if _, err := os.Stat(workingDir); os.IsNotExist(err) {
+		return model.EnrichmentInfo{}, "", fmt.Errorf("working directory does not exist (trId: %s, enrichments: %s)", trId, name)
+	}
+
+	return enrichmentInfo, workingDir, nil
+}
+
+// ListEnrichments godoc
+// @Summary List the enrichments
+// @Description List the enrichments supported by mc-terrarium with the providers and the variables of the tfVars of each.
+// @Description The name of an enrichment is the path segments of its APIs (e.g., /tr/{trId}/sql-db, /tr/{trId}/vpn/gcp-aws).
+// @Tags [Enrichment] Operations
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.Response{list=[]model.EnrichmentKind} "OK"
+// @Failure 503 {object} model.Response "Service Unavailable"
+// @Router /enrichments [get]
+func ListEnrichments(c echo.Context) error {
+
+	list := []interface{}{}
+	for _, d := range enrichment.List() {
+		list = append(list, d.Info())
+	}
+
+	res := model.Response{
+		Success: true,
+		Message: fmt.Sprintf("%d enrichments are supported", len(list)),
+		List:    list,
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// InitEnvOfEnrichment godoc
+// @Summary Initialize an enrichment of a terrarium
+// @Description Initialize an enrichment of a terrarium (e.g., sql-db, vpn/gcp-aws) by its templates.
+// @Description The provider is required if the enrichment has the providers (see GET /enrichments).
+// @Description With async=true, the request is accepted (202) with the Location of its status instead of waiting for the result.
+// @Tags [Enrichment] Operations
+// @Accept json
+// @Produce json
+// @Param trId path string true "Terrarium ID" default(tr01)
+// @Param enrichment path string true "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)" default(sql-db)
+// @Param provider query string false "Provider (e.g., aws, azure, gcp, ncp)"
+// @Param async query bool false "Run in the background and check the status of the request" default(false)
+// @Param x-request-id header string false "Custom request ID"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 201 {object} model.Response "Created"
+// @Success 202 {object} model.JobInfo "Accepted"
+// @Header 202 {string} Location "URL of the status of the request"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 403 {object} model.Response "Forbidden"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 409 {object} model.Response "Conflict"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Failure 503 {object} model.Response "Service Unavailable"
+// @Router /tr/{trId}/{enrichment}/env [post]
+func InitEnvOfEnrichment(c echo.Context) error {
+
+	trId := c.Param("trId")
+	if trId == "" {
+		err := newError(model.ErrCodeInvalidRequest, "invalid request, terrarium ID (trId: %s) is required", trId)
+		log.Warn().Msg(err.Error())
+		return c.JSON(failure(err))
+	}
+
+	d, err := enrichmentOf(c)
+	if err != nil {
+		log.Warn().Err(err).Msg("")
+		return c.JSON(failure(err))
+	}
+	enrichments := d.Name
+
+	provider := c.QueryParam("provider")
+	if err := d.ValidateProvider(provider); err != nil {
+		log.Warn().Err(err).Msg("")
+		return c.JSON(failureWithMessage(err, "invalid request, "+err.Error()))
+	}
+
+	async, err := asyncRequested(c)
+	if err != nil {
+		log.Warn().Err(err).Msg("")
+		return c.JSON(failure(err))
+	}
+
+	// Get the request ID
+	reqId := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	// Read the terrarium information
+	_, err = terrarium.ReadTerrariumInfo(trId)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to read terrarium information")
+		return c.JSON(failure(err))
+	}
+
+	// Add the enrichments to the terrarium information if they can be initialized (e.g., not while applying)
+	err = terrarium.AddEnrichment(trId, enrichments, provider)
+	if err != nil {
+		if errors.Is(err, tenant.ErrQuotaExceeded) || errors.Is(err, terrarium.ErrInvalidTransition) {
+			log.Warn().Err(err).Msg("failed to add the enrichments")
+			return c.JSON(failure(err))
+		}
+		err2 := fmt.Errorf("failed to update terrarium information")
+		log.Error().Err(err).Msg(err2.Error())
+		return c.JSON(failureWithMessage(err, err2.Error()))
+	}
+
+	// Create a working directory for the enrichments
+	workingDir, err := terrarium.TerrariumPath(trId, enrichments)
+	if err != nil {
+		log.Warn().Err(err).Msg("invalid working directory")
+		return c.JSON(failure(err))
+	}
+	if err := os.MkdirAll(workingDir, 0755); err != nil {
+		err2 := fmt.Errorf("failed to create a working directory")
+		log.Error().Err(err).Msg(err2.Error())
+		return c.JSON(failureWithMessage(err, err2.Error()))
+	}
+
+	// Copy template files to the working directory (overwrite)
+	err = tofu.CopyFiles(d.TemplatePath(provider), workingDir)
+	if err != nil {
+		err2 := fmt.Errorf("failed to copy template files to working directory")
+		log.Error().Err(err).Msg(err2.Error())
+		return c.JSON(failureWithMessage(err, err2.Error()))
+	}
+
+	// Run the hook of the enrichments (e.g., copy the credentials)
+	if d.AfterInit != nil {
+		target := enrichment.Target{TrId: trId, Name: enrichments, Provider: provider, ReqId: reqId, WorkingDir: workingDir}
+		if err := d.AfterInit(target); err != nil {
+			log.Error().Err(err).Msg("failed to prepare the working directory")
+			return c.JSON(failure(err))
+		}
+	}
+
+	// global option to set working dir: -chdir=/home/ubuntu/dev/cloud-barista/mc-terrarium/.terrarium/{trId}/{enrichments}
+	// init: subcommand
+	ret, err := runTofuCommand(async, trId, enrichments, reqId, "-chdir="+workingDir, "init")
+	if err != nil {
+		err2 := fmt.Errorf("failed to initialize an infrastructure terrarium")
+		log.Error().Err(err).Msg(err2.Error())
+		return c.JSON(failureWithMessage(err, err2.Error()))
+	}
+	if async {
+		return acceptRequest(c, reqId, enrichmentRequestPath(trId, enrichments, reqId))
+	}
+	res := model.Response{
+		Success: true,
+		Message: fmt.Sprintf("the infrastructure terrarium is successfully initialized for %s", d.Title),
+		Detail:  ret,
+	}
+
+	log.Debug().Msgf("%+v", res) // debug
+
+	return c.JSON(http.StatusCreated, res)
+}
+
+// ClearEnvOfEnrichment godoc
+// @Summary Clear the entire directory and configuration files of an enrichment
+// @Description Clear the entire directory and configuration files of an enrichment. It is rejected (409) while any resources remain in the state, so destroy them first.
+// @Tags [Enrichment] Operations
+// @Accept  json
+// @Produce  json
+// @Param trId path string true "Terrarium ID" default(tr01)
+// @Param enrichment path string true "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)" default(sql-db)
+// @Param x-request-id header string false "Custom request ID"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 200 {object} model.Response "OK"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 409 {object} model.Response "Conflict"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Failure 503 {object} model.Response "Service Unavailable"
+// @Router /tr/{trId}/{enrichment}/env [delete]
+func ClearEnvOfEnrichment(c echo.Context) error {
+
+	trId := c.Param("trId")
+	if trId == "" {
+		err := newError(model.ErrCodeInvalidRequest, "invalid request, terrarium ID (trId: %s) is required", trId)
+		log.Warn().Msg(err.Error())
+		return c.JSON(failure(err))
+	}
+
+	d, err := enrichmentOf(c)
+	if err != nil {
+		log.Warn().Err(err).Msg("")
+		return c.JSON(failure(err))
+	}
+	enrichments := d.Name
+
+	_, workingDir, err := existingEnrichment(trId, enrichments)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to read the enrichments of the terrarium")
+		return c.JSON(failure(err))
+	}
+
+	// Check if no resources remain in the state not to orphan them
+	if err := terrarium.CheckClear(trId, enrichments); err != nil {
+		log.Warn().Err(err).Msg("failed to clear the enrichments")
+		return c.JSON(failure(err))
+	}
+
+	err = os.RemoveAll(workingDir)
+	if err != nil {
+		err2 := fmt.Errorf("failed to remove working directory and all configuration files")
+		log.Error().Err(err).Msg(err2.Error())
+		return c.JSON(failureWithMessage(err, err2.Error()))
+	}
+
+	// Remove the enrichments from the terrarium information
+	err = terrarium.RemoveEnrichment(trId, enrichments)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to remove the enrichments from terrarium information")
+	}
+
+	res := model.Response{
+		Success: true,
+		Message: "successfully remove all in the working directory",
+	}
+	log.Debug().Msgf("%+v", res) // debug
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// CreateInfracodeOfEnrichment godoc
+// @Summary Create the infracode of an enrichment
+// @Description Create the infracode of an enrichment by the tfVars, whose variables are by the enrichment (see GET /enrichments).
+// @Description The terrarium ID in the tfVars is set by the path parameter if it is empty.
+// @Description The tfVars are validated by the rules of the variables (e.g., the region of the provider, CIDR blocks, the password policy),
+// @Description and the invalid ones are responded in the errors with 422 (INVALID_TFVARS).
+// @Tags [Enrichment] Operations
+// @Accept  json
+// @Produce  json
+// @Param trId path string true "Terrarium ID" default(tr01)
+// @Param enrichment path string true "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)" default(sql-db)
+// @Param ParamsForInfracode body model.CreateInfracodeOfEnrichmentRequest true "Parameters of infracode for the enrichment"
+// @Param x-request-id header string false "Custom request ID"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 201 {object} model.Response "Created"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 409 {object} model.Response "Conflict"
+// @Failure 422 {object} model.Response "Unprocessable Entity"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Failure 503 {object} model.Response "Service Unavailable"
+// @Router /tr/{trId}/{enrichment}/infracode [post]
+func CreateInfracodeOfEnrichment(c echo.Context) error {
+
+	trId := c.Param("trId")
+	if trId == "" {
+		err := newError(model.ErrCodeInvalidRequest, "invalid request, terrarium ID (trId: %s) is required", trId)
+		log.Warn().Msg(err.Error())
+		return c.JSON(failure(err))
+	}
+
+	d, err := enrichmentOf(c)
+	if err != nil {
+		log.Warn().Err(err).Msg("")
+		return c.JSON(failure(err))
+	}
+	enrichments := d.Name
+
+	req := new(model.CreateInfracodeOfEnrichmentRequest)
+	if err := c.Bind(req); err != nil {
+		err2 := newError(model.ErrCodeInvalidRequest, "invalid request format, %v", err)
+		log.Warn().Err(err).Msg("invalid request format")
+		return c.JSON(failure(err2))
+	}
+
+	enrichmentInfo, workingDir, err := existingEnrichment(trId, enrichments)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to read the enrichments of the terrarium")
+		return c.JSON(failure(err))
+	}
+
+	// Decode and validate the tfVars of the enrichments for the provider
+	// (the terrarium ID is set by the path param if it is empty)
+	tfVars, err := d.DecodeTfVars(req.TfVars, trId, enrichmentInfo.Provider)
+	if err != nil {
+		err2 := &Error{Code: model.ErrCodeInvalidTfVars, Message: fmt.Sprintf("invalid tfVars of %s: %v", enrichments, err), Err: err}
+		log.Warn().Err(err).Msg("invalid tfVars")
+		return c.JSON(failure(err2))
+	}
+	log.Debug().Msgf("%+v", tfVars) // debug
+
+	// Move the enrichments to the Configured state (e.g., not while applying)
+	if _, err := terrarium.TransitionEnrichment(trId, enrichments, terrarium.StateConfigured); err != nil {
+		log.Warn().Err(err).Msg("invalid transition of the enrichments")
+		return c.JSON(failure(err))
+	}
+
+	// Save the tfVars to a file
+	// Note
+	// Terraform also automatically loads a number of variable definitions files
+	// if they are present:
+	// - Files named exactly terraform.tfvars or terraform.tfvars.json.
+	// - Any files with names ending in .auto.tfvars or .auto.tfvars.json.
+	tfVarsPath := workingDir + "/terraform.tfvars.json"
+	err = tofu.SaveTfVarsToFile(tfVars, tfVarsPath)
+	if err != nil {
+		err2 := fmt.Errorf("failed to save tfVars to a file")
+		log.Error().Err(err).Msg(err2.Error())
+		return c.JSON(failureWithMessage(err, err2.Error()))
+	}
+
+	res := model.Response{
+		Success: true,
+		Message: fmt.Sprintf("the infracode for %s is successfully created", d.Title),
+	}
+
+	log.Debug().Msgf("%+v", res) // debug
+
+	return c.JSON(http.StatusCreated, res)
+}
+
+// CheckInfracodeOfEnrichment godoc
+// @Summary Check and show changes by the current infracode of an enrichment
+// @Description Check and show changes by the current infracode of an enrichment. The plan is saved with the request ID as the plan ID and summarized with sensitive values masked. The saved plan is removed when it is applied or goes stale, and up to 10 plans are kept.
+// @Tags [Enrichment] Operations
+// @Accept  json
+// @Produce  json
+// @Param trId path string true "Terrarium ID" default(tr01)
+// @Param enrichment path string true "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)" default(sql-db)
+// @Param x-request-id header string false "Custom request ID"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 200 {object} model.Response{object=model.PlanSummary} "OK"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 409 {object} model.Response "Conflict"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Failure 503 {object} model.Response "Service Unavailable"
+// @Router /tr/{trId}/{enrichment}/plan [post]
+func CheckInfracodeOfEnrichment(c echo.Context) error {
+
+	trId := c.Param("trId")
+	if trId == "" {
+		err := newError(model.ErrCodeInvalidRequest, "invalid request, terrarium ID (trId: %s) is required", trId)
+		log.Warn().Msg(err.Error())
+		return c.JSON(failure(err))
+	}
+
+	d, err := enrichmentOf(c)
+	if err != nil {
+		log.Warn().Err(err).Msg("")
+		return c.JSON(failure(err))
+	}
+	enrichments := d.Name
+
+	// Get the request ID
+	reqId := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	_, workingDir, err := existingEnrichment(trId, enrichments)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to read the enrichments of the terrarium")
+		return c.JSON(failure(err))
+	}
+
+	// Check if the enrichments can be planned (e.g., after creating the infracode)
+	if err := terrarium.CheckTransition(trId, enrichments, terrarium.StatePlanned); err != nil {
+		log.Warn().Err(err).Msg("invalid transition of the enrichments")
+		return c.JSON(failure(err))
+	}
+
+	// global option to set working dir: -chdir=/home/ubuntu/dev/cloud-barista/mc-terrarium/.terrarium/{trId}/{enrichments}
+	// subcommand: plan -out=plans/{reqId}.tfplan, and then show -json plans/{reqId}.tfplan
+	summary, ret, err := tofu.ExecuteTofuPlan(trId, enrichments, reqId, workingDir)
+	if err != nil {
+		err2 := fmt.Errorf("failed to check the infracode of %s", d.Title)
+		log.Error().Err(err).Msg(err2.Error()) // error
+		return c.JSON(failureWithMessage(err, err2.Error()))
+	}
+	res := model.Response{
+		Success: true,
+		Message: "the infracode checking process is successfully completed",
+		Detail:  ret,
+		Object:  toObject(summary),
+	}
+
+	log.Debug().Msgf("%+v", res) // debug
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// CreateEnrichment godoc
+// @Summary Create the resources of an enrichment
+// @Description Create the resources of an enrichment by applying the infracode, and return the resource info.
+// @Description With async=true, the request is accepted (202) with the Location of its status instead of waiting for the result.
+// @Tags [Enrichment] Operations
+// @Accept  json
+// @Produce  json
+// @Param trId path string true "Terrarium ID" default(tr01)
+// @Param enrichment path string true "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)" default(sql-db)
+// @Param x-request-id header string false "Custom request ID"
+// @Param planId query string false "Plan ID to apply the saved plan exactly (i.e., the request ID of the plan)"
+// @Param async query bool false "Run in the background and check the status of the request" default(false)
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 200 {object} model.Response "OK"
+// @Success 202 {object} model.JobInfo "Accepted"
+// @Header 202 {string} Location "URL of the status of the request"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 409 {object} model.Response "Conflict"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Failure 503 {object} model.Response "Service Unavailable"
+// @Router /tr/{trId}/{enrichment} [post]
+func CreateEnrichment(c echo.Context) error {
+
+	trId := c.Param("trId")
+	if trId == "" {
+		err := newError(model.ErrCodeInvalidRequest, "invalid request, terrarium ID (trId: %s) is required", trId)
+		log.Warn().Msg(err.Error())
+		return c.JSON(failure(err))
+	}
+
+	d, err := enrichmentOf(c)
+	if err != nil {
+		log.Warn().Err(err).Msg("")
+		return c.JSON(failure(err))
+	}
+	enrichments := d.Name
+
+	async, err := asyncRequested(c)
+	if err != nil {
+		log.Warn().Err(err).Msg("")
+		return c.JSON(failure(err))
+	}
+
+	// Get the request ID
+	reqId := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	_, workingDir, err := existingEnrichment(trId, enrichments)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to read the enrichments of the terrarium")
+		return c.JSON(failure(err))
+	}
+
+	// Apply the saved plan if the plan ID is given
+	args, err := applyArgs(c, workingDir)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to apply the saved plan")
+		return c.JSON(failure(err))
+	}
+
+	// Move the enrichments to the Applying state, which is moved to Applied or Failed by the result
+	prevState, err := terrarium.TransitionEnrichment(trId, enrichments, terrarium.StateApplying)
+	if err != nil {
+		log.Warn().Err(err).Msg("invalid transition of the enrichments")
+		return c.JSON(failure(err))
+	}
+
+	// global option to set working dir: -chdir=/home/ubuntu/dev/cloud-barista/mc-terrarium/.terrarium/{trId}/{enrichments}
+	// subcommand: apply
+	_, err = runTofuCommand(async, trId, enrichments, reqId, args...)
+	if err != nil {
+		rollbackTransition(trId, enrichments, terrarium.StateApplying, prevState)
+		err2 := fmt.Errorf("failed to create the resources of %s", d.Title)
+		log.Error().Err(err).Msg(err2.Error()) // error
+		return c.JSON(failureWithMessage(err, err2.Error()))
+	}
+	if async {
+		return acceptRequest(c, reqId, enrichmentRequestPath(trId, enrichments, reqId))
+	}
+
+	return refinedResourceInfo(c, d, trId, reqId, workingDir)
+}
+
+// refinedResourceInfo responds the resource info of an enrichment specified as the output in the state file.
+func refinedResourceInfo(c echo.Context, d enrichment.Descriptor, trId, reqId, workingDir string) error {
+
+	// global option to set working dir: -chdir=/home/ubuntu/dev/cloud-barista/mc-terrarium/.terrarium/{trId}/{enrichments}
+	// output: subcommand
+	ret, err := tofu.ExecuteTofuCommand(trId, d.Name, reqId, "-chdir="+workingDir, "output", "-json", d.OutputName)
+	if err != nil {
+		err2 := fmt.Errorf("failed to read resource info (detail: %s) specified as 'output' in the state file", "refined")
+		log.Error().Err(err).Msg(err2.Error())
+		return c.JSON(failureWithMessage(err, err2.Error()))
+	}
+
+	var resourceInfo map[string]interface{}
+	err = json.Unmarshal([]byte(ret), &resourceInfo)
+	if err != nil {
+		log.Error().Err(err).Msg("") // error
+		return c.JSON(failureWithMessage(err, "failed to unmarshal resource info"))
+	}
+
+	res := model.Response{
+		Success: true,
+		Message: "refined read resource info (map)",
+		Object:  resourceInfo,
+	}
+	log.Debug().Msgf("%+v", res) // debug
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetResourceInfoOfEnrichment godoc
+// @Summary Get resource info of an enrichment
+// @Description Get resource info of an enrichment, which is the output of the enrichment (refined) or the resources in the state (raw).
+// @Tags [Enrichment] Operations
+// @Accept  json
+// @Produce  json
+// @Param trId path string true "Terrarium ID" default(tr01)
+// @Param enrichment path string true "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)" default(sql-db)
+// @Param detail query string false "Resource info by detail (refined, raw)" default(refined)
+// @Param x-request-id header string false "Custom request ID"
+// @Success 200 {object} model.Response "OK"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Failure 503 {object} model.Response "Service Unavailable"
+// @Router /tr/{trId}/{enrichment} [get]
+func GetResourceInfoOfEnrichment(c echo.Context) error {
+
+	trId := c.Param("trId")
+	if trId == "" {
+		err := newError(model.ErrCodeInvalidRequest, "invalid request, terrarium ID (trId: %s) is required", trId)
+		log.Warn().Msg(err.Error())
+		return c.JSON(failure(err))
+	}
+
+	d, err := enrichmentOf(c)
+	if err != nil {
+		log.Warn().Err(err).Msg("")
+		return c.JSON(failure(err))
+	}
+	enrichments := d.Name
+
+	// Use this struct like the enum
+	var DetailOptions = struct {
+		Refined string
+		Raw     string
+	}{
+		Refined: "refined",
+		Raw:     "raw",
+	}
+
+	// valid detail options
+	validDetailOptions := map[string]bool{
+		DetailOptions.Refined: true,
+		DetailOptions.Raw:     true,
+	}
+
+	detail := c.QueryParam("detail")
+	detail = strings.ToLower(detail)
+
+	if detail == "" || !validDetailOptions[detail] {
+		err := fmt.Errorf("invalid detail (%s), use the default (%s)", detail, DetailOptions.Refined)
+		log.Warn().Msg(err.Error())
+		detail = DetailOptions.Refined
+	}
+
+	// Get the request ID
+	reqId := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	_, workingDir, err := existingEnrichment(trId, enrichments)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to read the enrichments of the terrarium")
+		return c.JSON(failure(err))
+	}
+
+	// Get the resource info by the detail option
+	if detail == DetailOptions.Refined {
+		return refinedResourceInfo(c, d, trId, reqId, workingDir)
+	}
+
+	// global option to set working dir: -chdir=/home/ubuntu/dev/cloud-barista/mc-terrarium/.terrarium/{trId}/{enrichments}
+	// show: subcommand
+	// Get resource info from the state or plan file
+	ret, err := tofu.ExecuteTofuCommand(trId, enrichments, reqId, "-chdir="+workingDir, "show", "-json")
+	if err != nil {
+		err2 := fmt.Errorf("failed to read resource info (detail: %s) from the state or plan file", DetailOptions.Raw)
+		log.Error().Err(err).Msg(err2.Error()) // error
+		return c.JSON(failureWithMessage(err, err2.Error()))
+	}
+
+	// Parse the resource info
+	resourcesString := gjson.Get(ret, "values.root_module.resources").String()
+	if resourcesString == "" {
+		err2 := fmt.Errorf("could not find resource info (trId: %s)", trId)
+		log.Warn().Msg(err2.Error())
+		return c.JSON(failureWithMessage(err, err2.Error()))
+	}
+
+	var resourceInfoList []interface{}
+	err = json.Unmarshal([]byte(resourcesString), &resourceInfoList)
+	if err != nil {
+		log.Error().Err(err).Msg("") // error
+		return c.JSON(failureWithMessage(err, "failed to unmarshal resource info"))
+	}
+
+	res := model.Response{
+		Success: true,
+		Message: "raw resource info (list)",
+		List:    resourceInfoList,
+	}
+	log.Debug().Msgf("%+v", res) // debug
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// DestroyEnrichment godoc
+// @Summary Destroy the resources of an enrichment
+// @Description Destroy the resources of an enrichment, except the imported ones (e.g., the route table of vpn/gcp-aws).
+// @Description With async=true, the request is accepted (202) with the Location of its status instead of waiting for the result.
+// @Tags [Enrichment] Operations
+// @Accept  json
+// @Produce  json
+// @Param trId path string true "Terrarium ID" default(tr01)
+// @Param enrichment path string true "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)" default(sql-db)
+// @Param async query bool false "Run in the background and check the status of the request" default(false)
+// @Param x-request-id header string false "Custom request ID"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 201 {object} model.Response "Created"
+// @Success 202 {object} model.JobInfo "Accepted"
+// @Header 202 {string} Location "URL of the status of the request"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 409 {object} model.Response "Conflict"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Failure 503 {object} model.Response "Service Unavailable"
+// @Router /tr/{trId}/{enrichment} [delete]
+func DestroyEnrichment(c echo.Context) error {
+
+	trId := c.Param("trId")
+	if trId == "" {
+		err := newError(model.ErrCodeInvalidRequest, "invalid request, terrarium ID (trId: %s) is required", trId)
+		log.Warn().Msg(err.Error())
+		return c.JSON(failure(err))
+	}
+
+	d, err := enrichmentOf(c)
+	if err != nil {
+		log.Warn().Err(err).Msg("")
+		return c.JSON(failure(err))
+	}
+	enrichments := d.Name
+
+	async, err := asyncRequested(c)
+	if err != nil {
+		log.Warn().Err(err).Msg("")
+		return c.JSON(failure(err))
+	}
+
+	// Get the request ID
+	reqId := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	enrichmentInfo, workingDir, err := existingEnrichment(trId, enrichments)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to read the enrichments of the terrarium")
+		return c.JSON(failure(err))
+	}
+
+	// Move the enrichments to the Destroying state, which is moved to Destroyed or Failed by the result
+	prevState, err := terrarium.TransitionEnrichment(trId, enrichments, terrarium.StateDestroying)
+	if err != nil {
+		log.Warn().Err(err).Msg("invalid transition of the enrichments")
+		return c.JSON(failure(err))
+	}
+
+	// Run the hook of the enrichments (e.g., remove the imported resources from the state)
+	if d.BeforeDestroy != nil {
+		target := enrichment.Target{TrId: trId, Name: enrichments, Provider: enrichmentInfo.Provider, ReqId: reqId, WorkingDir: workingDir}
+		if err := d.BeforeDestroy(target); err != nil {
+			rollbackTransition(trId, enrichments, terrarium.StateDestroying, prevState)
+			log.Error().Err(err).Msg("failed to prepare destroying the enrichments") // error
+			return c.JSON(failure(err))
+		}
+	}
+
+	// Destroy the infrastructure
+	// global option to set working dir: -chdir=/home/ubuntu/dev/cloud-barista/mc-terrarium/.terrarium/{trId}/{enrichments}
+	// subcommand: destroy
+	ret, err := runTofuCommand(async, trId, enrichments, reqId, "-chdir="+workingDir, "destroy", "-auto-approve", "-json")
+	if err != nil {
+		rollbackTransition(trId, enrichments, terrarium.StateDestroying, prevState)
+		err2 := fmt.Errorf("failed to destroy the resources of %s", d.Title)
+		log.Error().Err(err).Msg(err2.Error()) // error
+		return c.JSON(failureWithMessage(err, err2.Error()))
+	}
+	if async {
+		return acceptRequest(c, reqId, enrichmentRequestPath(trId, enrichments, reqId))
+	}
+	res := model.Response{
+		Success: true,
+		Message: fmt.Sprintf("the destroying process is successfully completed (trId: %s, enrichments: %s)", trId, enrichments),
+		Detail:  ret,
+	}
+
+	log.Debug().Msgf("%+v", res) // debug
+
+	return c.JSON(http.StatusCreated, res)
+}
+
+// requestOfEnrichment checks the request of an enrichment in the path.
+func requestOfEnrichment(c echo.Context) (string, model.JobInfo, error) {
+
+	trId := c.Param("trId")
+	reqId := c.Param("requestId")
+	if trId == "" || reqId == "" {
+		return "", model.JobInfo{}, newError(model.ErrCodeInvalidRequest, "invalid request, terrarium ID (trId: %s) and request ID (requestId: %s) are required", trId, reqId)
+	}
+
+	d, err := enrichmentOf(c)
+	if err != nil {
+		return "", model.JobInfo{}, err
+	}
+
+	// Read the terrarium information
+	if _, err := terrarium.ReadTerrariumInfo(trId); err != nil {
+		return "", model.JobInfo{}, err
+	}
+
+	// Get the job record of the request
+	job, err := tofu.GetJob(reqId)
+	if err != nil || job.TerrariumId != trId || job.Enrichments != d.Name {
+		return "", model.JobInfo{}, fmt.Errorf("%w (trId: %s, enrichments: %s, reqId: %s)", tofu.ErrJobNotFound, trId, d.Name, reqId)
+	}
+	return reqId, job, nil
+}
+
+// GetRequestStatusOfEnrichment godoc
+// @Summary Check the status of a specific request by its ID
+// @Description Check the status of a specific request by its ID
+// @Tags [Enrichment] Operations
+// @Accept  json
+// @Produce  json
+// @Param trId path string true "Terrarium ID" default(tr01)
+// @Param enrichment path string true "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)" default(sql-db)
+// @Param requestId path string true "Request ID"
+// @Success 200 {object} model.JobInfo "OK"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Failure 503 {object} model.Response "Service Unavailable"
+// @Router /tr/{trId}/{enrichment}/request/{requestId} [get]
+func GetRequestStatusOfEnrichment(c echo.Context) error {
+
+	_, job, err := requestOfEnrichment(c)
+	if err != nil {
+		log.Warn().Err(err).Msg("")
+		return c.JSON(failure(err))
+	}
+
+	log.Debug().Msgf("%+v", job) // debug
+
+	return c.JSON(http.StatusOK, job)
+}
+
+// GetRequestLogsOfEnrichment godoc
+// @Summary Stream the logs of a specific request by its ID
+// @Description Stream the logs of a specific request by its ID as Server-Sent Events (SSE).
+// @Description Each line is sent as a "log" event, and the status of the request is sent as a "status" event at the end.
+// @Description Set follow=true to keep the stream until the request is completed.
+// @Tags [Enrichment] Operations
+// @Accept  json
+// @Produce  text/event-stream
+// @Param trId path string true "Terrarium ID" default(tr01)
+// @Param enrichment path string true "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)" default(sql-db)
+// @Param requestId path string true "Request ID"
+// @Param follow query bool false "Follow the logs until the request is completed" default(false)
+// @Param Last-Event-ID header string false "Resume the stream from the last event ID (i.e., offset)"
+// @Success 200 {string} string "Server-Sent Events of the logs and status of the request"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Failure 503 {object} model.Response "Service Unavailable"
+// @Router /tr/{trId}/{enrichment}/request/{requestId}/logs [get]
+func GetRequestLogsOfEnrichment(c echo.Context) error {
+
+	reqId, _, err := requestOfEnrichment(c)
+	if err != nil {
+		log.Warn().Err(err).Msg("")
+		return c.JSON(failure(err))
+	}
+
+	return streamRequestLog(c, reqId)
+}
+
+// CancelRequestOfEnrichment godoc
+// @Summary Cancel a specific running request by its ID
+// @Description Cancel a specific running request (e.g., apply or destroy) by its ID.
+// @Description The tofu process is interrupted to release the state lock cleanly and killed after a grace period.
+// @Tags [Enrichment] Operations
+// @Accept  json
+// @Produce  json
+// @Param trId path string true "Terrarium ID" default(tr01)
+// @Param enrichment path string true "Enrichment (e.g., sql-db, object-storage, message-broker, vpn/gcp-aws, vpn/gcp-azure)" default(sql-db)
+// @Param requestId path string true "Request ID"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 202 {object} model.Response "Accepted"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 409 {object} model.Response "Conflict"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Failure 503 {object} model.Response "Service Unavailable"
+// @Router /tr/{trId}/{enrichment}/request/{requestId} [delete]
+func CancelRequestOfEnrichment(c echo.Context) error {
+
+	reqId, _, err := requestOfEnrichment(c)
+	if err != nil {
+		log.Warn().Err(err).Msg("")
+		return c.JSON(failure(err))
+	}
+
+	// Cancel the running request
+	err = tofu.CancelJob(reqId)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to cancel the request")
+		return c.JSON(failure(err))
+	}
+
+	res := model.Response{
+		Success: true,
+		Message: "the request (id: " + reqId + ") is being cancelled, please check the status of the request",
+	}
+
+	log.Debug().Msgf("%+v", res) // debug
+
+	return c.JSON(http.StatusAccepted, res)
+}
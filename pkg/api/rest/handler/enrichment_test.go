@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/terrarium"
+	"github.com/labstack/echo/v4"
+)
+
+// The invalid tfVars are responded with 422 and the field, the rule and the message of each.
+func TestCreateInfracodeOfEnrichmentInvalidTfVars(t *testing.T) {
+	trId := "tr-tfvars"
+	setUpTerrarium(t, trId)
+	if err := terrarium.AddEnrichment(trId, "sql-db", "aws"); err != nil {
+		t.Fatal(err)
+	}
+	workingDir, err := terrarium.TerrariumPath(trId, "sql-db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(workingDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"tfVars": {"csp_region": "ap-northeast-9", "csp_vnet_id": "vpc-1234abcd", "csp_subnet1_id": "subnet-1234abcd",
+		"csp_subnet2_id": "subnet-abcd1234", "db_engine_port": 70000, "db_admin_username": "mydbadmin", "db_admin_password": "password"}}`
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/terrarium/tr/"+trId+"/sql-db/infracode", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("trId", "enrichment")
+	c.SetParamValues(trId, "sql-db")
+
+	if err := CreateInfracodeOfEnrichment(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body.String())
+	}
+
+	var res struct {
+		Success bool               `json:"success"`
+		Code    string             `json:"code"`
+		Errors  []model.FieldError `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("failed to decode the response (%s): %v", rec.Body.String(), err)
+	}
+	if res.Success || res.Code != model.ErrCodeInvalidTfVars {
+		t.Errorf("(success, code) = (%v, %s), want (false, %s)", res.Success, res.Code, model.ErrCodeInvalidTfVars)
+	}
+
+	var got []string
+	for _, f := range res.Errors {
+		got = append(got, f.Field+"/"+f.Rule)
+		if f.Message == "" {
+			t.Errorf("message of %s is empty", f.Field)
+		}
+	}
+	want := []string{"csp_region/region", "db_engine_port/port", "db_admin_password/password"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("errors = %v, want %v", got, want)
+	}
+}
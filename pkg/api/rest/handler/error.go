@@ -0,0 +1,250 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/enrichment"
+	"github.com/cloud-barista/mc-terrarium/pkg/store"
+	"github.com/cloud-barista/mc-terrarium/pkg/tenant"
+	"github.com/cloud-barista/mc-terrarium/pkg/terrarium"
+	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
+	"github.com/cloud-barista/mc-terrarium/pkg/webhook"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// Error is an error of a request with the code (e.g., model.ErrCodeInvalidRequest),
+// which is responded with the HTTP status of the code.
+type Error struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// newError returns an error of a request with the code.
+func newError(code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// errorStatus is the HTTP status of each error code.
+var errorStatus = map[string]int{
+	model.ErrCodeInvalidRequest:           http.StatusBadRequest,
+	model.ErrCodeInvalidProvider:          http.StatusBadRequest,
+	model.ErrCodeUnauthorized:             http.StatusUnauthorized,
+	model.ErrCodeForbidden:                http.StatusForbidden,
+	model.ErrCodeQuotaExceeded:            http.StatusForbidden,
+	model.ErrCodeNotFound:                 http.StatusNotFound,
+	model.ErrCodeTerrariumNotFound:        http.StatusNotFound,
+	model.ErrCodeEnrichmentNotFound:       http.StatusNotFound,
+	model.ErrCodeUnknownEnrichment:        http.StatusNotFound,
+	model.ErrCodeRequestNotFound:          http.StatusNotFound,
+	model.ErrCodePlanNotFound:             http.StatusNotFound,
+	model.ErrCodeWebhookNotFound:          http.StatusNotFound,
+	model.ErrCodeDeliveryNotFound:         http.StatusNotFound,
+	model.ErrCodeMethodNotAllowed:         http.StatusMethodNotAllowed,
+	model.ErrCodeTerrariumExists:          http.StatusConflict,
+	model.ErrCodeTerrariumExpired:         http.StatusConflict,
+	model.ErrCodeInvalidTransition:        http.StatusConflict,
+	model.ErrCodeEnrichmentBusy:           http.StatusConflict,
+	model.ErrCodeResourcesRemain:          http.StatusConflict,
+	model.ErrCodePlanStale:                http.StatusConflict,
+	model.ErrCodeRequestNotRunning:        http.StatusConflict,
+	model.ErrCodeRequestCancelled:         http.StatusConflict,
+	model.ErrCodeRevisionConflict:         http.StatusConflict,
+	model.ErrCodeTerrariumLocked:          http.StatusConflict,
+	model.ErrCodeRequestIdInUse:           http.StatusConflict,
+	model.ErrCodeIdempotencyKeyInProgress: http.StatusConflict,
+	model.ErrCodePreconditionFailed:       http.StatusPreconditionFailed,
+	model.ErrCodeUnsupportedMediaType:     http.StatusUnsupportedMediaType,
+	model.ErrCodeInvalidTfVars:            http.StatusUnprocessableEntity,
+	model.ErrCodeInvalidBundle:            http.StatusUnprocessableEntity,
+	model.ErrCodeIdempotencyKeyReused:     http.StatusUnprocessableEntity,
+	model.ErrCodeTooManyRequests:          http.StatusTooManyRequests,
+	model.ErrCodeTofuInitFailed:           http.StatusInternalServerError,
+	model.ErrCodeTofuPlanFailed:           http.StatusInternalServerError,
+	model.ErrCodeTofuApplyFailed:          http.StatusInternalServerError,
+	model.ErrCodeTofuDestroyFailed:        http.StatusInternalServerError,
+	model.ErrCodeTofuCommandFailed:        http.StatusInternalServerError,
+	model.ErrCodeInternal:                 http.StatusInternalServerError,
+	model.ErrCodeQueueFull:                http.StatusServiceUnavailable,
+	model.ErrCodeNotReady:                 http.StatusServiceUnavailable,
+	model.ErrCodeEngineUnavailable:        http.StatusServiceUnavailable,
+}
+
+// errorCode returns the code of an error by its kind, which is INTERNAL_ERROR if unknown.
+func errorCode(err error) string {
+	var reqErr *Error
+	var transitionErr *terrarium.TransitionError
+	var cmdErr *tofu.CommandError
+
+	switch {
+	case errors.As(err, &reqErr):
+		return reqErr.Code
+
+	case errors.As(err, &transitionErr):
+		switch {
+		case len(transitionErr.Resources) > 0:
+			return model.ErrCodeResourcesRemain
+		case transitionErr.From == terrarium.StateApplying, transitionErr.From == terrarium.StateDestroying:
+			return model.ErrCodeEnrichmentBusy
+		}
+		return model.ErrCodeInvalidTransition
+
+	case errors.Is(err, terrarium.ErrInvalidId), errors.Is(err, terrarium.ErrInvalidLabel),
+		errors.Is(err, terrarium.ErrInvalidListOptions), errors.Is(err, terrarium.ErrInvalidTtl),
+		errors.Is(err, terrarium.ErrInvalidPatch), errors.Is(err, tenant.ErrInvalidTenant), errors.Is(err, tofu.ErrInvalidPlanId),
+		errors.Is(err, webhook.ErrInvalidWebhook):
+		return model.ErrCodeInvalidRequest
+	case errors.Is(err, enrichment.ErrInvalidProvider):
+		return model.ErrCodeInvalidProvider
+	case errors.Is(err, tenant.ErrQuotaExceeded):
+		return model.ErrCodeQuotaExceeded
+	case errors.Is(err, terrarium.ErrTerrariumNotFound):
+		return model.ErrCodeTerrariumNotFound
+	case errors.Is(err, terrarium.ErrEnrichmentNotFound):
+		return model.ErrCodeEnrichmentNotFound
+	case errors.Is(err, enrichment.ErrUnknownEnrichment):
+		return model.ErrCodeUnknownEnrichment
+	case errors.Is(err, tofu.ErrJobNotFound):
+		return model.ErrCodeRequestNotFound
+	case errors.Is(err, tofu.ErrPlanNotFound):
+		return model.ErrCodePlanNotFound
+	case errors.Is(err, webhook.ErrWebhookNotFound):
+		return model.ErrCodeWebhookNotFound
+	case errors.Is(err, webhook.ErrDeliveryNotFound):
+		return model.ErrCodeDeliveryNotFound
+	case errors.Is(err, terrarium.ErrTerrariumExists):
+		return model.ErrCodeTerrariumExists
+	case errors.Is(err, terrarium.ErrExpired):
+		return model.ErrCodeTerrariumExpired
+	case errors.Is(err, tofu.ErrPlanStale):
+		return model.ErrCodePlanStale
+	case errors.Is(err, tofu.ErrJobNotRunning):
+		return model.ErrCodeRequestNotRunning
+	case errors.Is(err, tofu.ErrTerrariumLocked):
+		return model.ErrCodeTerrariumLocked
+	case errors.Is(err, tofu.ErrRequestIdInUse):
+		return model.ErrCodeRequestIdInUse
+	case errors.Is(err, store.ErrConflict):
+		return model.ErrCodeRevisionConflict
+	case errors.Is(err, terrarium.ErrPreconditionFailed):
+		return model.ErrCodePreconditionFailed
+	case errors.Is(err, terrarium.ErrInvalidBundle):
+		return model.ErrCodeInvalidBundle
+	case errors.Is(err, tofu.ErrQueueFull):
+		return model.ErrCodeQueueFull
+	case errors.Is(err, context.Canceled):
+		return model.ErrCodeRequestCancelled
+
+	case errors.As(err, &cmdErr):
+		switch cmdErr.Subcommand {
+		case "init":
+			return model.ErrCodeTofuInitFailed
+		case "plan", "show":
+			return model.ErrCodeTofuPlanFailed
+		case "apply":
+			return model.ErrCodeTofuApplyFailed
+		case "destroy":
+			return model.ErrCodeTofuDestroyFailed
+		}
+		return model.ErrCodeTofuCommandFailed
+	}
+	return model.ErrCodeInternal
+}
+
+// failure returns the HTTP status and the response of a failed request by the code of the error,
+// with the output and the diagnostics of the tofu command if it failed, or the invalid fields of the tfVars.
+func failure(err error) (int, model.Response) {
+	return failureWithMessage(err, err.Error())
+}
+
+// failureWithMessage returns the HTTP status and the response of a failed request like failure,
+// but with the given message (e.g., not to expose the internal error).
+func failureWithMessage(err error, message string) (int, model.Response) {
+
+	code := errorCode(err)
+	res := model.Response{Success: false, Code: code, Message: message}
+
+	var cmdErr *tofu.CommandError
+	if errors.As(err, &cmdErr) {
+		res.Detail = cmdErr.Output
+		res.Diagnostics = cmdErr.Diagnostics
+	}
+	var validationErr *model.ValidationError
+	if errors.As(err, &validationErr) {
+		res.Errors = validationErr.Fields
+	}
+	return errorStatus[code], res
+}
+
+// httpErrorCodes is the error code of each HTTP status of the errors of echo.
+var httpErrorCodes = map[int]string{
+	http.StatusBadRequest:           model.ErrCodeInvalidRequest,
+	http.StatusUnauthorized:         model.ErrCodeUnauthorized,
+	http.StatusForbidden:            model.ErrCodeForbidden,
+	http.StatusNotFound:             model.ErrCodeNotFound,
+	http.StatusMethodNotAllowed:     model.ErrCodeMethodNotAllowed,
+	http.StatusUnsupportedMediaType: model.ErrCodeUnsupportedMediaType,
+	http.StatusTooManyRequests:      model.ErrCodeTooManyRequests,
+}
+
+// HTTPErrorHandler responds the errors of echo and the middlewares (e.g., no route, unauthorized, too many requests)
+// as the failed responses with the error codes.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	message := http.StatusText(status)
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		status = he.Code
+		message = fmt.Sprintf("%v", he.Message)
+	} else {
+		log.Error().Err(err).Msg("unhandled error")
+	}
+
+	code, ok := httpErrorCodes[status]
+	switch {
+	case ok:
+	case status < http.StatusInternalServerError:
+		code = model.ErrCodeInvalidRequest
+	default:
+		code = model.ErrCodeInternal
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = c.JSON(status, model.Response{Success: false, Code: code, Message: message})
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to respond the error")
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/store"
+	"github.com/cloud-barista/mc-terrarium/pkg/tenant"
+	"github.com/cloud-barista/mc-terrarium/pkg/terrarium"
+	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
+)
+
+// errorCodeStatus pins the HTTP status of each error code, which the clients rely on.
+var errorCodeStatus = map[string]int{
+	model.ErrCodeInvalidRequest:           http.StatusBadRequest,
+	model.ErrCodeInvalidProvider:          http.StatusBadRequest,
+	model.ErrCodeUnauthorized:             http.StatusUnauthorized,
+	model.ErrCodeForbidden:                http.StatusForbidden,
+	model.ErrCodeQuotaExceeded:            http.StatusForbidden,
+	model.ErrCodeNotFound:                 http.StatusNotFound,
+	model.ErrCodeTerrariumNotFound:        http.StatusNotFound,
+	model.ErrCodeEnrichmentNotFound:       http.StatusNotFound,
+	model.ErrCodeUnknownEnrichment:        http.StatusNotFound,
+	model.ErrCodeRequestNotFound:          http.StatusNotFound,
+	model.ErrCodePlanNotFound:             http.StatusNotFound,
+	model.ErrCodeWebhookNotFound:          http.StatusNotFound,
+	model.ErrCodeDeliveryNotFound:         http.StatusNotFound,
+	model.ErrCodeMethodNotAllowed:         http.StatusMethodNotAllowed,
+	model.ErrCodeTerrariumExists:          http.StatusConflict,
+	model.ErrCodeTerrariumExpired:         http.StatusConflict,
+	model.ErrCodeInvalidTransition:        http.StatusConflict,
+	model.ErrCodeEnrichmentBusy:           http.StatusConflict,
+	model.ErrCodeResourcesRemain:          http.StatusConflict,
+	model.ErrCodePlanStale:                http.StatusConflict,
+	model.ErrCodeRequestNotRunning:        http.StatusConflict,
+	model.ErrCodeRequestCancelled:         http.StatusConflict,
+	model.ErrCodeRevisionConflict:         http.StatusConflict,
+	model.ErrCodeTerrariumLocked:          http.StatusConflict,
+	model.ErrCodeRequestIdInUse:           http.StatusConflict,
+	model.ErrCodeIdempotencyKeyInProgress: http.StatusConflict,
+	model.ErrCodePreconditionFailed:       http.StatusPreconditionFailed,
+	model.ErrCodeUnsupportedMediaType:     http.StatusUnsupportedMediaType,
+	model.ErrCodeInvalidTfVars:            http.StatusUnprocessableEntity,
+	model.ErrCodeInvalidBundle:            http.StatusUnprocessableEntity,
+	model.ErrCodeIdempotencyKeyReused:     http.StatusUnprocessableEntity,
+	model.ErrCodeTooManyRequests:          http.StatusTooManyRequests,
+	model.ErrCodeTofuInitFailed:           http.StatusInternalServerError,
+	model.ErrCodeTofuPlanFailed:           http.StatusInternalServerError,
+	model.ErrCodeTofuApplyFailed:          http.StatusInternalServerError,
+	model.ErrCodeTofuDestroyFailed:        http.StatusInternalServerError,
+	model.ErrCodeTofuCommandFailed:        http.StatusInternalServerError,
+	model.ErrCodeInternal:                 http.StatusInternalServerError,
+	model.ErrCodeQueueFull:                http.StatusServiceUnavailable,
+	model.ErrCodeNotReady:                 http.StatusServiceUnavailable,
+	model.ErrCodeEngineUnavailable:        http.StatusServiceUnavailable,
+}
+
+// declaredErrorCodes returns the values of the ErrCode* constants declared in the model package.
+func declaredErrorCodes(t *testing.T) []string {
+	t.Helper()
+
+	f, err := parser.ParseFile(token.NewFileSet(), "../model/error.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var codes []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok {
+			return true
+		}
+		for i, name := range spec.Names {
+			if !strings.HasPrefix(name.Name, "ErrCode") || i >= len(spec.Values) {
+				continue
+			}
+			lit, ok := spec.Values[i].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				t.Fatalf("%s is not a string literal", name.Name)
+			}
+			code, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			codes = append(codes, code)
+		}
+		return true
+	})
+	return codes
+}
+
+func TestErrorStatus(t *testing.T) {
+	codes := declaredErrorCodes(t)
+	if len(codes) != len(errorCodeStatus) {
+		t.Errorf("declared error codes = %d, pinned = %d, pin the status of a new code here", len(codes), len(errorCodeStatus))
+	}
+	if len(errorStatus) != len(errorCodeStatus) {
+		t.Errorf("error codes with a status = %d, pinned = %d", len(errorStatus), len(errorCodeStatus))
+	}
+
+	for _, code := range codes {
+		t.Run(code, func(t *testing.T) {
+			want, pinned := errorCodeStatus[code]
+			if !pinned {
+				t.Fatalf("the status of %s is not pinned", code)
+			}
+			if got, ok := errorStatus[code]; !ok || got != want {
+				t.Errorf("status of %s = %d, want %d", code, got, want)
+			}
+		})
+	}
+}
+
+// The errors of the packages are responded with their codes and statuses.
+func TestFailure(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantCode   string
+		wantStatus int
+	}{
+		{err: terrarium.ErrInvalidId, wantCode: model.ErrCodeInvalidRequest, wantStatus: http.StatusBadRequest},
+		{err: tenant.ErrQuotaExceeded, wantCode: model.ErrCodeQuotaExceeded, wantStatus: http.StatusForbidden},
+		{err: terrarium.ErrTerrariumNotFound, wantCode: model.ErrCodeTerrariumNotFound, wantStatus: http.StatusNotFound},
+		{err: tofu.ErrJobNotFound, wantCode: model.ErrCodeRequestNotFound, wantStatus: http.StatusNotFound},
+		{err: &terrarium.TransitionError{From: terrarium.StateApplying}, wantCode: model.ErrCodeEnrichmentBusy, wantStatus: http.StatusConflict},
+		{err: &terrarium.TransitionError{From: terrarium.StateEmpty}, wantCode: model.ErrCodeInvalidTransition, wantStatus: http.StatusConflict},
+		{err: tofu.ErrTerrariumLocked, wantCode: model.ErrCodeTerrariumLocked, wantStatus: http.StatusConflict},
+		{err: tofu.ErrRequestIdInUse, wantCode: model.ErrCodeRequestIdInUse, wantStatus: http.StatusConflict},
+		{err: store.ErrConflict, wantCode: model.ErrCodeRevisionConflict, wantStatus: http.StatusConflict},
+		{err: terrarium.ErrPreconditionFailed, wantCode: model.ErrCodePreconditionFailed, wantStatus: http.StatusPreconditionFailed},
+		{err: terrarium.ErrInvalidBundle, wantCode: model.ErrCodeInvalidBundle, wantStatus: http.StatusUnprocessableEntity},
+		{err: &Error{Code: model.ErrCodeInvalidTfVars, Err: &model.ValidationError{}}, wantCode: model.ErrCodeInvalidTfVars, wantStatus: http.StatusUnprocessableEntity},
+		{err: &tofu.CommandError{Subcommand: "apply"}, wantCode: model.ErrCodeTofuApplyFailed, wantStatus: http.StatusInternalServerError},
+		{err: tofu.ErrQueueFull, wantCode: model.ErrCodeQueueFull, wantStatus: http.StatusServiceUnavailable},
+		{err: fmt.Errorf("unknown"), wantCode: model.ErrCodeInternal, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wantCode, func(t *testing.T) {
+			status, res := failure(fmt.Errorf("wrapped: %w", tt.err))
+			if status != tt.wantStatus || res.Code != tt.wantCode {
+				t.Errorf("failure(%v) = (%d, %s), want (%d, %s)", tt.err, status, res.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"github.com/cloud-barista/mc-terrarium/pkg/terrarium"
+	"github.com/rs/zerolog/log"
+)
+
+// rollbackTransition moves an enrichment back to the previous state if the command has not been run
+// (e.g., the queue is full), and only logs the error since the request has already failed.
+func rollbackTransition(trId, enrichments, state, previous string) {
+	if err := terrarium.RollbackTransition(trId, enrichments, state, previous); err != nil {
+		log.Warn().Err(err).Msgf("failed to roll back the state of the enrichments (trId: %s, enrichments: %s)", trId, enrichments)
+	}
+}
@@ -0,0 +1,34 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
+	"github.com/labstack/echo/v4"
+)
+
+// applyArgs returns the arguments of the apply command in a working directory.
+// If the query param "planId" is given, the saved plan is verified and applied exactly as it was reviewed.
+func applyArgs(c echo.Context, workingDir string) ([]string, error) {
+	args := []string{"-chdir=" + workingDir, "apply", "-auto-approve", "-json"}
+
+	planId := c.QueryParam("planId")
+	if planId == "" {
+		return args, nil
+	}
+	if err := tofu.VerifyPlan(workingDir, planId); err != nil {
+		return nil, err
+	}
+	return append(args, tofu.PlanFileArg(planId)), nil
+}
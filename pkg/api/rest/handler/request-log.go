@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// streamRequestLog streams the log of a request as Server-Sent Events (SSE).
+// Each line is sent as a "log" event whose ID is the offset in the log file,
+// so a client can resume the stream by the Last-Event-ID header.
+// If the query param "follow" is true, the stream is kept until the request is completed.
+// Finally, the job record is sent as a "status" event and the stream is closed.
+func streamRequestLog(c echo.Context, reqId string) error {
+
+	follow, _ := strconv.ParseBool(c.QueryParam("follow"))
+
+	var offset int64
+	if lastEventId := c.Request().Header.Get("Last-Event-ID"); lastEventId != "" {
+		if v, err := strconv.ParseInt(lastEventId, 10, 64); err == nil && v > 0 {
+			offset = v
+		}
+	}
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	job, err := tofu.FollowJobLog(c.Request().Context(), reqId, offset, follow, func(line string, offset int64) error {
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: log\ndata: %s\n\n", offset, line); err != nil {
+			return err
+		}
+		w.Flush()
+		return nil
+	})
+	if err != nil {
+		log.Warn().Err(err).Msgf("stopped streaming the log of the request (reqId: %s)", reqId)
+		if c.Request().Context().Err() != nil {
+			// The client has gone
+			return nil
+		}
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", strings.ReplaceAll(err.Error(), "\n", " "))
+	}
+
+	status, err := json.Marshal(job)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to marshal the job record")
+		return nil
+	}
+	fmt.Fprintf(w, "event: status\ndata: %s\n\n", status)
+	w.Flush()
+
+	return nil
+}
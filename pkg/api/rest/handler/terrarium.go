@@ -14,110 +14,278 @@ limitations under the License.
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
-	"github.com/cloud-barista/mc-terrarium/pkg/config"
 	"github.com/cloud-barista/mc-terrarium/pkg/terrarium"
+	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog/log"
 )
 
 // IssueTerrarium godoc
 // @Summary Issue/create a terrarium
-// @Description Issue/create a terrarium
+// @Description Issue/create a terrarium.
+// @Description The ID must be a DNS label up to 26 characters (lowercase alphanumerics and '-', starting with a letter),
+// @Description and it is generated (e.g., tr-k3x9q2ab) if omitted.
+// @Description If ttl (e.g., 72h, 7d) or expiresAt is given, the terrarium is destroyed and erased automatically when it expires,
+// @Description and an expiring event is notified before (see POST /tr/{trId}/ttl to extend it).
+// @Description The terrarium belongs to the tenant of the caller, and it is rejected (403) if the tenant has reached its quota.
 // @Tags [Terrarium] An environment to enrich the multi-cloud infrastructure
 // @Accept  json
 // @Produce  json
 // @Param TerrariumInfo body model.TerrariumInfo true "Information for a new terrarium"
-// @Success 200 {object} model.Response "OK"
+// @Param x-tenant header string false "Tenant to act as, only for the API user (default: the default tenant)"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 200 {object} model.TerrariumInfo "OK"
 // @Failure 400 {object} model.Response "Bad Request"
+// @Failure 403 {object} model.Response "Forbidden"
+// @Failure 409 {object} model.Response "Conflict"
+// @Failure 500 {object} model.Response "Internal Server Error"
 // @Failure 503 {object} model.Response "Service Unavailable"
 // @Router /tr [post]
 func IssueTerrarium(c echo.Context) error {
 
 	reqTrInfo := new(model.TerrariumInfo)
 	if err := c.Bind(reqTrInfo); err != nil {
-		res := model.Response{Success: false, Message: "failed to bind the request"}
-		return c.JSON(http.StatusBadRequest, res)
+		return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "failed to bind the request")))
 	}
 
-	projectRoot := config.Terrarium.Root
+	// The enrichments are added by initializing each of them
+	reqTrInfo.Enrichments = nil
 
-	trId := reqTrInfo.Id
+	// The terrarium belongs to the tenant of the caller
+	reqTrInfo.Tenant = callerTenant(c)
 
-	// Create the the working directory if it dosen't exist
-	workingDir := projectRoot + "/.terrarium/" + trId
-	if _, err := os.Stat(workingDir); os.IsNotExist(err) {
-		err := os.MkdirAll(workingDir, 0755)
-		if err != nil {
-			err2 := fmt.Errorf("failed to create a working directory")
-			log.Error().Err(err).Msg(err2.Error())
-			res := model.Response{Success: false, Message: err2.Error()}
-			return c.JSON(http.StatusInternalServerError, res)
-		}
+	// The creator is the API user if the basic auth is enabled
+	if username, _, ok := c.Request().BasicAuth(); ok {
+		reqTrInfo.CreatedBy = username
 	}
 
-	err := terrarium.IssueTerrarium(*reqTrInfo)
+	// Issue the terrarium with the given ID or a generated one
+	issued, err := terrarium.IssueTerrarium(*reqTrInfo)
+	if err != nil {
+		return c.JSON(failure(err))
+	}
+	trId := issued.Id
 
+	// Create the the working directory if it dosen't exist
+	workingDir, err := terrarium.TerrariumPath(trId)
+	if err == nil {
+		err = os.MkdirAll(workingDir, 0755)
+	}
 	if err != nil {
-		res := model.Response{Success: false, Message: err.Error()}
-		return c.JSON(http.StatusInternalServerError, res)
+		err2 := fmt.Errorf("failed to create a working directory")
+		log.Error().Err(err).Msg(err2.Error())
+		if err := terrarium.DeleteTerrariumInfo(trId); err != nil {
+			log.Warn().Err(err).Msgf("failed to delete the terrarium info (trId: %s)", trId)
+		}
+		return c.JSON(failureWithMessage(err, err2.Error()))
 	}
 
-	trInfo, err := terrarium.ReadTerrariumInfo(trId)
+	trInfo, revision, err := terrarium.ReadTerrariumInfoWithRevision(trId)
 	if err != nil {
-		text := fmt.Sprintf("no terrarium with the given ID (trId: %s)", trId)
-		res := model.Response{Success: true, Message: text}
-		return c.JSON(http.StatusBadRequest, res)
+		return c.JSON(failureWithMessage(err, fmt.Sprintf("no terrarium with the given ID (trId: %s)", trId)))
 	}
 
+	c.Response().Header().Set("ETag", formatETag(revision))
+
 	return c.JSON(http.StatusOK, trInfo)
 }
 
 // ReadAllTerrarium godoc
 // @Summary Read all terrarium
-// @Description Read all terrarium
+// @Description Read all terrarium, which are filtered by the labels and the enrichments, and sorted by the ID by default.
+// @Description If limit is given, the cursor of the next page is returned in the X-Next-Cursor header until the last page.
+// @Description Only the terrariums of the tenant of the caller are listed, and the API user lists all of them unless acting as a tenant.
 // @Tags [Terrarium] An environment to enrich the multi-cloud infrastructure
 // @Accept  json
 // @Produce  json
+// @Param labelSelector query string false "Label selector (e.g., team=infra,env!=dev,owner,!temporary)"
+// @Param enrichments query string false "Name or kind of an enrichment in the terrarium (e.g., vpn/gcp-aws or vpn)"
+// @Param provider query string false "Provider of an enrichment in the terrarium (e.g., aws)"
+// @Param state query string false "Lifecycle state of an enrichment in the terrarium" Enums(Empty, Initialized, Configured, Planned, Applying, Applied, Destroying, Destroyed, Failed)
+// @Param sort query string false "Field to sort by, prefixed by '-' for the descending order" Enums(id, -id, createdAt, -createdAt, updatedAt, -updatedAt)
+// @Param limit query int false "Max number of terrariums to return (0 for no limit)" default(0)
+// @Param cursor query string false "Cursor of the next page (from the X-Next-Cursor header)"
+// @Param x-tenant header string false "Tenant to act as, only for the API user"
 // @Success 200 {array} model.TerrariumInfo "OK"
+// @Header 200 {string} X-Next-Cursor "Cursor of the next page if there are more terrariums"
 // @Failure 400 {object} model.Response "Bad Request"
+// @Failure 500 {object} model.Response "Internal Server Error"
 // @Failure 503 {object} model.Response "Service Unavailable"
 // @Router /tr [get]
 func ReadAllTerrarium(c echo.Context) error {
 
-	trInfoList, _ := terrarium.ReadAllTerrariumInfo()
+	opts := terrarium.ListOptions{
+		Tenant:        callerTenant(c),
+		LabelSelector: c.QueryParam("labelSelector"),
+		Enrichments:   c.QueryParam("enrichments"),
+		Provider:      c.QueryParam("provider"),
+		State:         c.QueryParam("state"),
+		Sort:          c.QueryParam("sort"),
+		Cursor:        c.QueryParam("cursor"),
+	}
+	if limit := c.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "invalid limit (%s)", limit)))
+		}
+		opts.Limit = n
+	}
+
+	trInfoList, next, err := terrarium.ListTerrariumInfo(opts)
+	if err != nil {
+		if errors.Is(err, terrarium.ErrInvalidListOptions) {
+			return c.JSON(failure(err))
+		}
+		err2 := fmt.Errorf("failed to read the terrariums")
+		log.Error().Err(err).Msg(err2.Error())
+		return c.JSON(failureWithMessage(err, err2.Error()))
+	}
+
+	if next != "" {
+		c.Response().Header().Set("X-Next-Cursor", next)
+	}
+	if trInfoList == nil {
+		trInfoList = []model.TerrariumInfo{}
+	}
 
 	return c.JSON(http.StatusOK, trInfoList)
 }
 
 // ReadTerrarium godoc
 // @Summary Read a terrarium
-// @Description Read a terrarium
+// @Description Read a terrarium with the enrichments in it and the lifecycle state of each enrichment (Empty, Initialized, Configured, Planned, Applying, Applied, Destroying, Destroyed or Failed).
 // @Tags [Terrarium] An environment to enrich the multi-cloud infrastructure
 // @Accept  json
 // @Produce  json
 // @Param trId path string true "Terrarium ID" default(tr01)
 // @Success 200 {object} model.TerrariumInfo "OK"
+// @Header 200 {string} ETag "Resource version of the terrarium for If-Match"
 // @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
 // @Failure 503 {object} model.Response "Service Unavailable"
 // @Router /tr/{trId} [get]
 func ReadTerrarium(c echo.Context) error {
 
 	trId := c.Param("trId")
 	if trId == "" {
-		res := model.Response{Success: false, Message: "require the terrarium ID"}
-		return c.JSON(http.StatusBadRequest, res)
+		return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "require the terrarium ID")))
 	}
 
-	trInfo, err := terrarium.ReadTerrariumInfo(trId)
+	trInfo, revision, err := terrarium.ReadTerrariumInfoWithRevision(trId)
 	if err != nil {
-		text := fmt.Sprintf("no terrarium with the given ID (trId: %s)", trId)
-		res := model.Response{Success: true, Message: text}
-		return c.JSON(http.StatusBadRequest, res)
+		return c.JSON(failureWithMessage(err, fmt.Sprintf("no terrarium with the given ID (trId: %s)", trId)))
+	}
+
+	c.Response().Header().Set("ETag", formatETag(revision))
+
+	return c.JSON(http.StatusOK, trInfo)
+}
+
+// PatchTerrarium godoc
+// @Summary Update the description and the labels of a terrarium
+// @Description Update the description and the labels of a terrarium by a JSON merge patch (RFC 7396),
+// @Description where null removes a label (e.g., {"labels": {"env": "prod", "temporary": null}}).
+// @Description The other fields are read-only. The ETag of the terrarium is returned by reading or patching it.
+// @Description If If-Match is given with the ETag, the patch is rejected (412) if the terrarium has been modified since then.
+// @Tags [Terrarium] An environment to enrich the multi-cloud infrastructure
+// @Accept  json
+// @Accept  application/merge-patch+json
+// @Produce  json
+// @Param trId path string true "Terrarium ID" default(tr01)
+// @Param If-Match header string false "ETag of the terrarium to update, which is returned by reading it"
+// @Param patch body model.TerrariumInfo true "JSON merge patch of the description and the labels"
+// @Success 200 {object} model.TerrariumInfo "OK"
+// @Header 200 {string} ETag "Resource version of the patched terrarium"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 412 {object} model.Response "Precondition Failed"
+// @Failure 415 {object} model.Response "Unsupported Media Type"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Router /tr/{trId} [patch]
+func PatchTerrarium(c echo.Context) error {
+
+	trId := c.Param("trId")
+	if trId == "" {
+		return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "require the terrarium ID")))
+	}
+
+	contentType, _, _ := strings.Cut(c.Request().Header.Get(echo.HeaderContentType), ";")
+	contentType = strings.TrimSpace(contentType)
+	if contentType != "" && contentType != mimeMergePatchJSON && contentType != echo.MIMEApplicationJSON {
+		err := newError(model.ErrCodeUnsupportedMediaType, "unsupported content type (%s), use %s", contentType, mimeMergePatchJSON)
+		return c.JSON(failure(err))
+	}
+
+	revision, err := parseIfMatch(c.Request().Header.Get("If-Match"))
+	if err != nil {
+		return c.JSON(failure(err))
+	}
+
+	patch, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "failed to read the request")))
+	}
+
+	trInfo, newRevision, err := terrarium.PatchTerrariumInfo(trId, patch, revision)
+	if err != nil {
+		status, res := failure(err)
+		if status == http.StatusInternalServerError {
+			log.Error().Err(err).Msgf("failed to patch the terrarium (trId: %s)", trId)
+		}
+		return c.JSON(status, res)
+	}
+
+	c.Response().Header().Set("ETag", formatETag(newRevision))
+
+	return c.JSON(http.StatusOK, trInfo)
+}
+
+// ExtendTerrariumTtl godoc
+// @Summary Extend the TTL of a terrarium
+// @Description Extend the expiration time of a terrarium by the ttl (e.g., 24h, 7d) from the current expiration time
+// @Description (or from now if it does not expire), or set a new expiration time by expiresAt.
+// @Description The expiring event is notified again before the new expiration time.
+// @Tags [Terrarium] An environment to enrich the multi-cloud infrastructure
+// @Accept  json
+// @Produce  json
+// @Param trId path string true "Terrarium ID" default(tr01)
+// @Param ExtendTtlRequest body model.ExtendTtlRequest true "TTL to extend or new expiration time"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 200 {object} model.TerrariumInfo "OK"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 409 {object} model.Response "Conflict"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Router /tr/{trId}/ttl [post]
+func ExtendTerrariumTtl(c echo.Context) error {
+
+	trId := c.Param("trId")
+	if trId == "" {
+		return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "require the terrarium ID")))
+	}
+
+	req := new(model.ExtendTtlRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "failed to bind the request")))
+	}
+
+	trInfo, err := terrarium.ExtendTtl(trId, *req)
+	if err != nil {
+		status, res := failure(err)
+		if status == http.StatusInternalServerError {
+			log.Error().Err(err).Msgf("failed to extend the TTL of the terrarium (trId: %s)", trId)
+		}
+		return c.JSON(status, res)
 	}
 
 	return c.JSON(http.StatusOK, trInfo)
@@ -125,47 +293,122 @@ func ReadTerrarium(c echo.Context) error {
 
 // EraseTerrarium godoc
 // @Summary Erase the entire terrarium including directories and configuration files
-// @Description Erase the entire terrarium including directories and configuration files
+// @Description Erase the entire terrarium including directories and configuration files.
+// @Description It is rejected (409) with the remaining resources if any resources remain in the state of the enrichments.
+// @Description Use cascade=true to destroy the enrichments in dependency order before erasing,
+// @Description or force=true (only for the API user, 403 for a tenant) to archive the states instead of discarding them and erase without destroying.
+// @Description The queued requests of the terrarium are completed before erasing it, and the new ones are rejected (409) until it is erased.
 // @Tags [Terrarium] An environment to enrich the multi-cloud infrastructure
 // @Accept  json
 // @Produce  json
 // @Param trId path string true "Terrarium ID" default(tr01)
+// @Param cascade query bool false "Destroy the remaining resources of the enrichments before erasing" default(false)
+// @Param force query bool false "Archive the states and erase without destroying the remaining resources" default(false)
+// @Param x-request-id header string false "Custom request ID"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
 // @Success 200 {object} model.Response "OK"
 // @Failure 400 {object} model.Response "Bad Request"
+// @Failure 403 {object} model.Response "Forbidden"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 409 {object} model.Response{list=[]model.EnrichmentResources} "Conflict"
+// @Failure 500 {object} model.Response "Internal Server Error"
 // @Failure 503 {object} model.Response "Service Unavailable"
 // @Router /tr/{trId} [delete]
 func EraseTerrarium(c echo.Context) error {
 
 	trId := c.Param("trId")
 	if trId == "" {
-		res := model.Response{Success: false, Message: "require the terrarium ID"}
-		return c.JSON(http.StatusBadRequest, res)
+		return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "require the terrarium ID")))
 	}
 
-	projectRoot := config.Terrarium.Root
+	cascade := c.QueryParam("cascade") == "true"
+	force := c.QueryParam("force") == "true"
+	if cascade && force {
+		return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "cascade and force cannot be used together")))
+	}
+	// Erasing without destroying leaves the cloud resources, so it is only for the administrator
+	if force && !callerIsAdmin(c) {
+		return c.JSON(failure(newError(model.ErrCodeForbidden, "force=true is only for the API user, destroy the resources or use cascade=true")))
+	}
+
+	// Get the request ID
+	reqId := c.Response().Header().Get(echo.HeaderXRequestID)
 
 	// Check if the working directory exists
-	workingDir := projectRoot + "/.terrarium/" + trId
+	workingDir, err := terrarium.TerrariumPath(trId)
+	if err != nil {
+		log.Warn().Err(err).Msg("invalid working directory")
+		return c.JSON(failure(err))
+	}
 	if _, err := os.Stat(workingDir); os.IsNotExist(err) {
-		text := fmt.Sprintf("not exist terrarium (id: %v)", trId)
-		res := model.Response{Success: false, Message: text}
-		return c.JSON(http.StatusBadRequest, res)
+		return c.JSON(failure(fmt.Errorf("%w (trId: %s)", terrarium.ErrTerrariumNotFound, trId)))
 	}
 
-	err := os.RemoveAll(workingDir)
+	// Lock the terrarium until it is erased, which waits for the queued requests of it and rejects the new ones
+	lock, err := tofu.LockTerrarium(c.Request().Context(), trId, reqId)
 	if err != nil {
-		res := model.Response{Success: false, Message: "failed to erase the entire terrarium"}
-		return c.JSON(http.StatusInternalServerError, res)
+		log.Warn().Err(err).Msg("failed to lock the terrarium")
+		return c.JSON(failure(err))
 	}
+	defer lock.Unlock()
 
-	err = terrarium.DeleteTerrariumInfo(trId)
+	// Check if no enrichments are applying or destroying
+	if err := terrarium.CheckErase(trId); err != nil {
+		log.Warn().Err(err).Msg("failed to erase the terrarium")
+		return c.JSON(failure(err))
+	}
+
+	// Check the resources remaining in the state of each enrichment
+	remaining, err := terrarium.ListRemainingResources(trId)
+	if err != nil {
+		err2 := fmt.Errorf("failed to read the states of the terrarium (trId: %s)", trId)
+		log.Error().Err(err).Msg(err2.Error())
+		return c.JSON(failureWithMessage(err, err2.Error()))
+	}
+
+	archiveDir := ""
+	if len(remaining) > 0 {
+		switch {
+		case cascade:
+			err := terrarium.DestroyEnrichments(trId, lock, remaining)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to destroy the enrichments of the terrarium")
+				status, res := failure(err)
+				if remaining, err2 := terrarium.ListRemainingResources(trId); err2 == nil {
+					res.List = toList(remaining)
+				}
+				return c.JSON(status, res)
+			}
+
+		case force:
+			archiveDir, err = terrarium.ArchiveStates(trId)
+			if err != nil {
+				err2 := fmt.Errorf("failed to archive the states of the terrarium (trId: %s)", trId)
+				log.Error().Err(err).Msg(err2.Error())
+				return c.JSON(failureWithMessage(err, err2.Error()))
+			}
+
+		default:
+			text := fmt.Sprintf("cannot erase the terrarium (trId: %s) since resources remain in the state of %d enrichment(s), "+
+				"destroy them first or use cascade=true", trId, len(remaining))
+			log.Warn().Msg(text)
+			status, res := failure(newError(model.ErrCodeResourcesRemain, "%s", text))
+			res.List = toList(remaining)
+			return c.JSON(status, res)
+		}
+	}
+
+	err = terrarium.RemoveTerrarium(trId)
 	if err != nil {
-		res := model.Response{Success: false, Message: err.Error()}
-		return c.JSON(http.StatusInternalServerError, res)
+		log.Error().Err(err).Msg("failed to erase the entire terrarium")
+		return c.JSON(failure(err))
 	}
 
 	text := fmt.Sprintf("successfully erased the entire terrarium (trId: %v)", trId)
 	res := model.Response{Success: true, Message: text}
+	if archiveDir != "" {
+		res.Detail = fmt.Sprintf("the states are archived to %s", archiveDir)
+	}
 	log.Debug().Msgf("%+v", res) // debug
 
 	return c.JSON(http.StatusOK, res)
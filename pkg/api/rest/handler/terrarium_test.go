@@ -0,0 +1,183 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/cloud-barista/mc-terrarium/pkg/tenant"
+	"github.com/cloud-barista/mc-terrarium/pkg/terrarium"
+	"github.com/labstack/echo/v4"
+)
+
+// setUpTerrarium opens the store in a temporary project root, and issues a terrarium with its directory.
+func setUpTerrarium(t *testing.T, trId string) {
+	t.Helper()
+
+	config.Terrarium.Root = t.TempDir()
+	if err := terrarium.InitStore(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { terrarium.CloseStore() })
+
+	if _, err := terrarium.IssueTerrarium(model.TerrariumInfo{Id: trId}); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := terrarium.TerrariumPath(trId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// eraseTerrarium calls EraseTerrarium as a caller set by middlewares.TenantScope.
+func eraseTerrarium(t *testing.T, trId, query, tenantName string, admin bool) (int, model.Response) {
+	t.Helper()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/terrarium/tr/"+trId+"?"+query, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("trId")
+	c.SetParamValues(trId)
+	c.Set(tenant.ContextKey, tenantName)
+	c.Set(tenant.ContextKeyAdmin, admin)
+
+	if err := EraseTerrarium(c); err != nil {
+		t.Fatal(err)
+	}
+
+	var res model.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("failed to decode the response (%s): %v", rec.Body.String(), err)
+	}
+	return rec.Code, res
+}
+
+func TestEraseTerrariumForce(t *testing.T) {
+	tests := []struct {
+		name       string
+		tenant     string
+		admin      bool
+		wantStatus int
+		wantCode   string
+		wantErased bool
+	}{
+		{name: "tenant", tenant: tenant.Default, admin: false, wantStatus: http.StatusForbidden, wantCode: model.ErrCodeForbidden},
+		{name: "admin", tenant: "", admin: true, wantStatus: http.StatusOK, wantErased: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trId := "tr-force"
+			setUpTerrarium(t, trId)
+
+			status, res := eraseTerrarium(t, trId, "force=true", tt.tenant, tt.admin)
+			if status != tt.wantStatus || res.Code != tt.wantCode {
+				t.Errorf("EraseTerrarium() = (%d, %s), want (%d, %s): %s", status, res.Code, tt.wantStatus, tt.wantCode, res.Message)
+			}
+
+			_, err := terrarium.ReadTerrariumInfo(trId)
+			if erased := errors.Is(err, terrarium.ErrTerrariumNotFound); erased != tt.wantErased {
+				t.Errorf("erased = %v, want %v (err: %v)", erased, tt.wantErased, err)
+			}
+		})
+	}
+}
+
+// patchTerrarium calls PatchTerrarium with the If-Match header, and returns the ETag of the response.
+func patchTerrarium(t *testing.T, trId, ifMatch, patch string) (int, string, *httptest.ResponseRecorder) {
+	t.Helper()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPatch, "/terrarium/tr/"+trId, strings.NewReader(patch))
+	req.Header.Set(echo.HeaderContentType, mimeMergePatchJSON)
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("trId")
+	c.SetParamValues(trId)
+
+	if err := PatchTerrarium(c); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, rec.Header().Get("ETag"), rec
+}
+
+func TestPatchTerrariumIfMatch(t *testing.T) {
+	trId := "tr-patch"
+	setUpTerrarium(t, trId)
+
+	_, revision, err := terrarium.ReadTerrariumInfoWithRevision(trId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := formatETag(revision)
+
+	status, newETag, rec := patchTerrarium(t, trId, etag, `{"labels": {"env": "prod"}}`)
+	if status != http.StatusOK || newETag == "" || newETag == etag {
+		t.Fatalf("PatchTerrarium() = (%d, ETag %s), want (200, a new ETag): %s", status, newETag, rec.Body.String())
+	}
+
+	tests := []struct {
+		name       string
+		ifMatch    string
+		patch      string
+		wantStatus int
+		wantCode   string
+		wantEnv    string
+	}{
+		{name: "stale", ifMatch: etag, patch: `{"labels": {"env": "dev"}}`, wantStatus: http.StatusPreconditionFailed, wantCode: model.ErrCodePreconditionFailed, wantEnv: "prod"},
+		{name: "unknown", ifMatch: `"999"`, patch: `{"labels": {"env": "dev"}}`, wantStatus: http.StatusPreconditionFailed, wantCode: model.ErrCodePreconditionFailed, wantEnv: "prod"},
+		{name: "weak", ifMatch: "W/" + newETag, patch: `{"labels": {"env": "dev"}}`, wantStatus: http.StatusBadRequest, wantCode: model.ErrCodeInvalidRequest, wantEnv: "prod"},
+		{name: "current", ifMatch: newETag, patch: `{"labels": {"env": "stage"}}`, wantStatus: http.StatusOK, wantEnv: "stage"},
+		{name: "any", ifMatch: "*", patch: `{"labels": {"env": "dev"}}`, wantStatus: http.StatusOK, wantEnv: "dev"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _, rec := patchTerrarium(t, trId, tt.ifMatch, tt.patch)
+
+			var res model.Response
+			if status != http.StatusOK {
+				if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+					t.Fatalf("failed to decode the response (%s): %v", rec.Body.String(), err)
+				}
+			}
+			if status != tt.wantStatus || res.Code != tt.wantCode {
+				t.Errorf("PatchTerrarium() = (%d, %s), want (%d, %s): %s", status, res.Code, tt.wantStatus, tt.wantCode, res.Message)
+			}
+
+			trInfo, err := terrarium.ReadTerrariumInfo(trId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if env := trInfo.Labels["env"]; env != tt.wantEnv {
+				t.Errorf("label env = %q, want %q", env, tt.wantEnv)
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
 	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/cloud-barista/mc-terrarium/pkg/terrarium"
 	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog/log"
@@ -31,18 +32,35 @@ import (
 // ////////////////////////////////////////////////////
 // Test env
 
+// testEnvNotFound returns the error when the test environment is not initialized.
+func testEnvNotFound() error {
+	return fmt.Errorf("%w (trId: test-env), initialize the test environment first", terrarium.ErrTerrariumNotFound)
+}
+
 // InitTerrariumForTestEnv godoc
 // @Summary Initialize a multi-cloud terrarium for test environment
 // @Description Initialize a multi-cloud terrarium for test environment
+// @Description With async=true, the request is accepted (202) with the Location of its status instead of waiting for the result.
 // @Tags [Test env] Test environment management
 // @Accept  json
 // @Produce  json
+// @Param async query bool false "Run in the background and check the status of the request" default(false)
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
 // @Success 201 {object} model.Response "Created"
+// @Success 202 {object} model.JobInfo "Accepted"
+// @Header 202 {string} Location "URL of the status of the request"
 // @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
 // @Failure 503 {object} model.Response "Service Unavailable"
 // @Router /test-env/init [post]
 func InitTerrariumForTestEnv(c echo.Context) error {
 
+	async, err := asyncRequested(c)
+	if err != nil {
+		return c.JSON(failure(err))
+	}
+
 	// Get the request ID
 	reqId := c.Response().Header().Get(echo.HeaderXRequestID)
 
@@ -51,18 +69,16 @@ func InitTerrariumForTestEnv(c echo.Context) error {
 	if _, err := os.Stat(workingDir); os.IsNotExist(err) {
 		err := os.MkdirAll(workingDir, 0755)
 		if err != nil {
-			res := model.Response{Success: false, Message: "Failed to create directory"}
-			return c.JSON(http.StatusInternalServerError, res)
+			return c.JSON(failureWithMessage(err, "Failed to create directory"))
 		}
 	}
 
 	// Copy template files to the working directory (overwrite)
 	templateTfsPath := projectRoot + "/templates/test-env"
 
-	err := tofu.CopyFiles(templateTfsPath, workingDir)
+	err = tofu.CopyFiles(templateTfsPath, workingDir)
 	if err != nil {
-		res := model.Response{Success: false, Message: "Failed to copy template files to working directory"}
-		return c.JSON(http.StatusInternalServerError, res)
+		return c.JSON(failureWithMessage(err, "Failed to copy template files to working directory"))
 	}
 
 	// Always overwrite credential-gcp.json
@@ -71,24 +87,25 @@ func InitTerrariumForTestEnv(c echo.Context) error {
 	err = tofu.CopyGCPCredentials(gcpCredentialPath)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to copy gcp credentials to init")
-		res := model.Response{Success: false, Message: "Failed to copy gcp credentials to init"}
-		return c.JSON(http.StatusInternalServerError, res)
+		return c.JSON(failureWithMessage(err, "Failed to copy gcp credentials to init"))
 	}
 
 	azureCredentialPath := workingDir + "/credential-azure.env"
 	err = tofu.CopyAzureCredentials(azureCredentialPath)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to copy azure credentials to init")
-		res := model.Response{Success: false, Message: "Failed to copy azure credentials to init"}
-		return c.JSON(http.StatusInternalServerError, res)
+		return c.JSON(failureWithMessage(err, "Failed to copy azure credentials to init"))
 	}
 
 	// global option to set working dir: -chdir=/home/ubuntu/dev/cloud-barista/mc-terrarium/.terrarium/test-env
 	// init: subcommand
-	ret, err := tofu.ExecuteTofuCommand("test-env", reqId, "-chdir="+workingDir, "init")
+	ret, err := runTofuCommand(async, "test-env", "test-env", reqId, "-chdir="+workingDir, "init")
 	if err != nil {
-		res := model.Response{Success: false, Message: "Failed to init"}
-		return c.JSON(http.StatusInternalServerError, res)
+		log.Error().Err(err).Msg("Failed to init") // error
+		return c.JSON(failureWithMessage(err, "Failed to init"))
+	}
+	if async {
+		return acceptRequest(c, reqId, testEnvRequestPath(reqId))
 	}
 	res := model.Response{
 		Success: true,
@@ -107,8 +124,11 @@ func InitTerrariumForTestEnv(c echo.Context) error {
 // @Tags [Test env] Test environment management
 // @Accept  json
 // @Produce  json
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
 // @Success 200 {object} model.Response "OK"
 // @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
 // @Failure 503 {object} model.Response "Service Unavailable"
 // @Router /test-env/env [delete]
 func ClearTestEnv(c echo.Context) error {
@@ -118,15 +138,12 @@ func ClearTestEnv(c echo.Context) error {
 	// Check if the working directory exists
 	workingDir := projectRoot + "/.terrarium/test-env"
 	if _, err := os.Stat(workingDir); os.IsNotExist(err) {
-		text := "Not exist test-env"
-		res := model.Response{Success: false, Message: text}
-		return c.JSON(http.StatusBadRequest, res)
+		return c.JSON(failure(testEnvNotFound()))
 	}
 
 	err := os.RemoveAll(workingDir)
 	if err != nil {
-		res := model.Response{Success: false, Message: "Failed to clear entire directory and configuration files"}
-		return c.JSON(http.StatusInternalServerError, res)
+		return c.JSON(failureWithMessage(err, "Failed to clear entire directory and configuration files"))
 	}
 
 	text := "Successfully cleared all in the test environment"
@@ -145,6 +162,8 @@ func ClearTestEnv(c echo.Context) error {
 // @Param detail query string false "Resource info by detail (refined, raw)" default(refined)
 // @Success 200 {object} model.Response "OK"
 // @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
 // @Failure 503 {object} model.Response "Service Unavailable"
 // @Router /test-env [get]
 func GetResouceInfoOfTestEnv(c echo.Context) error {
@@ -181,13 +200,8 @@ func GetResouceInfoOfTestEnv(c echo.Context) error {
 	// Check if the working directory exists
 	workingDir := projectRoot + "/.terrarium/test-env"
 	if _, err := os.Stat(workingDir); os.IsNotExist(err) {
-		err2 := fmt.Errorf("working directory dose not exist")
-		log.Warn().Err(err).Msg(err2.Error())
-		res := model.Response{
-			Success: false,
-			Message: err2.Error(),
-		}
-		return c.JSON(http.StatusInternalServerError, res)
+		log.Warn().Err(err).Msg("working directory dose not exist")
+		return c.JSON(failure(testEnvNotFound()))
 	}
 
 	// Get the resource info by the detail option
@@ -197,26 +211,18 @@ func GetResouceInfoOfTestEnv(c echo.Context) error {
 
 		// global option to set working dir: -chdir=/home/ubuntu/dev/cloud-barista/mc-terrarium/.terrarium/{trId}/vpn/gcp-aws
 		// show: subcommand
-		ret, err := tofu.ExecuteTofuCommand("test-env", reqId, "-chdir="+workingDir, "output", "-json")
+		ret, err := tofu.ExecuteTofuCommand("test-env", "test-env", reqId, "-chdir="+workingDir, "output", "-json")
 		if err != nil {
 			err2 := fmt.Errorf("failed to read resource info (detail: %s) specified as 'output' in the state file", DetailOptions.Refined)
 			log.Error().Err(err).Msg(err2.Error())
-			res := model.Response{
-				Success: false,
-				Message: err2.Error(),
-			}
-			return c.JSON(http.StatusInternalServerError, res)
+			return c.JSON(failureWithMessage(err, err2.Error()))
 		}
 
 		var resourceInfo map[string]interface{}
 		err = json.Unmarshal([]byte(ret), &resourceInfo)
 		if err != nil {
 			log.Error().Err(err).Msg("") // error
-			res := model.Response{
-				Success: false,
-				Message: "failed to unmarshal resource info",
-			}
-			return c.JSON(http.StatusInternalServerError, res)
+			return c.JSON(failureWithMessage(err, "failed to unmarshal resource info"))
 		}
 
 		res := model.Response{
@@ -234,15 +240,11 @@ func GetResouceInfoOfTestEnv(c echo.Context) error {
 		// global option to set working dir: -chdir=/home/ubuntu/dev/cloud-barista/mc-terrarium/.terrarium/{trId}/vpn/gcp-aws
 		// show: subcommand
 		// Get resource info from the state or plan file
-		ret, err := tofu.ExecuteTofuCommand("test-env", reqId, "-chdir="+workingDir, "show", "-json")
+		ret, err := tofu.ExecuteTofuCommand("test-env", "test-env", reqId, "-chdir="+workingDir, "show", "-json")
 		if err != nil {
 			err2 := fmt.Errorf("failed to read resource info (detail: %s) from the state or plan file", DetailOptions.Raw)
 			log.Error().Err(err).Msg(err2.Error()) // error
-			res := model.Response{
-				Success: false,
-				Message: err2.Error(),
-			}
-			return c.JSON(http.StatusInternalServerError, res)
+			return c.JSON(failureWithMessage(err, err2.Error()))
 		}
 
 		// Parse the resource info
@@ -250,10 +252,7 @@ func GetResouceInfoOfTestEnv(c echo.Context) error {
 		if resourcesString == "" {
 			err2 := fmt.Errorf("could not find resource info (trId: %s)", "test-env")
 			log.Warn().Msg(err2.Error())
-			res := model.Response{
-				Success: false,
-				Message: err2.Error(),
-			}
+			res := model.Response{Success: false, Message: err2.Error()}
 			return c.JSON(http.StatusOK, res)
 		}
 
@@ -261,11 +260,7 @@ func GetResouceInfoOfTestEnv(c echo.Context) error {
 		err = json.Unmarshal([]byte(resourcesString), &resourceInfoList)
 		if err != nil {
 			log.Error().Err(err).Msg("") // error
-			res := model.Response{
-				Success: false,
-				Message: "failed to unmarshal resource info",
-			}
-			return c.JSON(http.StatusInternalServerError, res)
+			return c.JSON(failureWithMessage(err, "failed to unmarshal resource info"))
 		}
 
 		res := model.Response{
@@ -277,33 +272,38 @@ func GetResouceInfoOfTestEnv(c echo.Context) error {
 
 		return c.JSON(http.StatusOK, res)
 	default:
-		err2 := fmt.Errorf("invalid detail option (%s)", detail)
+		err2 := newError(model.ErrCodeInvalidRequest, "invalid detail option (%s)", detail)
 		log.Warn().Err(err2).Msg("") // warn
-		res := model.Response{
-			Success: false,
-			Message: err2.Error(),
-		}
-		return c.JSON(http.StatusBadRequest, res)
+		return c.JSON(failure(err2))
 	}
 }
 
-// CreateInfrcodeOfGcpAzureVpn godoc
+// CreateInfracodeOfTestEnv godoc
 // @Summary Create the infracode to configure test environment
-// @Description Create the infracode to configure test environment
+// @Description Create the infracode to configure test environment. The invalid tfVars (e.g., a region not of the provider) are responded in the errors with 422.
 // @Tags [Test env] Test environment management
 // @Accept  json
 // @Produce  json
 // @Param ParamsForInfracode body model.CreateInfracodeOfTestEnvRequest true "Parameters requied to create the infracode to configure test environment"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
 // @Success 201 {object} model.Response "Created"
 // @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 422 {object} model.Response "Unprocessable Entity"
+// @Failure 500 {object} model.Response "Internal Server Error"
 // @Failure 503 {object} model.Response "Service Unavailable"
 // @Router /test-env/infracode [post]
 func CreateInfracodeOfTestEnv(c echo.Context) error {
 
 	req := new(model.CreateInfracodeOfTestEnvRequest)
 	if err := c.Bind(req); err != nil {
-		res := model.Response{Success: false, Message: "Invalid request"}
-		return c.JSON(http.StatusBadRequest, res)
+		return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "Invalid request")))
+	}
+
+	if err := model.ValidateTfVars(&req.TfVars, ""); err != nil {
+		err2 := &Error{Code: model.ErrCodeInvalidTfVars, Message: fmt.Sprintf("invalid tfVars of test environment: %v", err), Err: err}
+		log.Warn().Err(err).Msg("invalid tfVars")
+		return c.JSON(failure(err2))
 	}
 
 	projectRoot := config.Terrarium.Root
@@ -311,9 +311,7 @@ func CreateInfracodeOfTestEnv(c echo.Context) error {
 	// Check if the working directory exists
 	workingDir := projectRoot + "/.terrarium/test-env"
 	if _, err := os.Stat(workingDir); os.IsNotExist(err) {
-		text := "Not exist test environment"
-		res := model.Response{Success: false, Message: text}
-		return c.JSON(http.StatusBadRequest, res)
+		return c.JSON(failure(testEnvNotFound()))
 	}
 
 	// Save the tfVars to a file
@@ -326,8 +324,7 @@ func CreateInfracodeOfTestEnv(c echo.Context) error {
 
 	err := tofu.SaveTestEnvTfVarsToFile(req.TfVars, tfVarsPath)
 	if err != nil {
-		res := model.Response{Success: false, Message: "Failed to save tfVars to a file"}
-		return c.JSON(http.StatusInternalServerError, res)
+		return c.JSON(failureWithMessage(err, "Failed to save tfVars to a file"))
 	}
 
 	res := model.Response{Success: true, Message: "Successfully created the infracode to configure test environment"}
@@ -339,12 +336,15 @@ func CreateInfracodeOfTestEnv(c echo.Context) error {
 
 // CheckInfracodeOfTestEnv godoc
 // @Summary Check the infracode and show changes of test environment
-// @Description Check the infracode and show changes of test environment
+// @Description Check the infracode and show changes of test environment. The plan is saved with the request ID as the plan ID and summarized with sensitive values masked. The saved plan is removed when it is applied or goes stale, and up to 10 plans are kept.
 // @Tags [Test env] Test environment management
 // @Accept json
 // @Produce json
-// @Success 200 {object} model.Response "OK"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 200 {object} model.Response{object=model.PlanSummary} "OK"
 // @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
 // @Failure 503 {object} model.Response "Service Unavailable"
 // @Router /test-env/plan [post]
 func CheckInfracodeOfTestEnv(c echo.Context) error {
@@ -357,21 +357,17 @@ func CheckInfracodeOfTestEnv(c echo.Context) error {
 	// Check if the working directory exists
 	workingDir := projectRoot + "/.terrarium/test-env"
 	if _, err := os.Stat(workingDir); os.IsNotExist(err) {
-		text := "Not exist test environemt"
-		res := model.Response{Success: false, Message: text}
-		return c.JSON(http.StatusBadRequest, res)
+		return c.JSON(failure(testEnvNotFound()))
 	}
 
 	// global option to set working dir: -chdir=/home/ubuntu/dev/cloud-barista/mc-terrarium/.terrarium/test-env
-	// subcommand: plan
-	ret, err := tofu.ExecuteTofuCommand("test-env", reqId, "-chdir="+workingDir, "plan")
+	// subcommand: plan -out=plans/{reqId}.tfplan, and then show -json plans/{reqId}.tfplan
+	summary, ret, err := tofu.ExecuteTofuPlan("test-env", "test-env", reqId, workingDir)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to plan") // error
-		text := fmt.Sprintf("Failed to plan\n(ret: %s)", ret)
-		res := model.Response{Success: false, Message: text}
-		return c.JSON(http.StatusInternalServerError, res)
+		return c.JSON(failureWithMessage(err, "Failed to plan"))
 	}
-	res := model.Response{Success: true, Message: ret}
+	res := model.Response{Success: true, Message: ret, Object: toObject(summary)}
 
 	log.Debug().Msgf("%+v", res) // debug
 
@@ -381,15 +377,29 @@ func CheckInfracodeOfTestEnv(c echo.Context) error {
 // CreateTestEnv godoc
 // @Summary Create test environment
 // @Description Create test environment
+// @Description With async=true, the request is accepted (202) with the Location of its status instead of waiting for the result.
 // @Tags [Test env] Test environment management
 // @Accept  json
 // @Produce  json
+// @Param planId query string false "Plan ID to apply the saved plan exactly (i.e., the request ID of the plan)"
+// @Param async query bool false "Run in the background and check the status of the request" default(false)
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
 // @Success 201 {object} model.Response "Created"
+// @Success 202 {object} model.JobInfo "Accepted"
+// @Header 202 {string} Location "URL of the status of the request"
 // @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 409 {object} model.Response "Conflict"
+// @Failure 500 {object} model.Response "Internal Server Error"
 // @Failure 503 {object} model.Response "Service Unavailable"
 // @Router /test-env [post]
 func CreateTestEnv(c echo.Context) error {
 
+	async, err := asyncRequested(c)
+	if err != nil {
+		return c.JSON(failure(err))
+	}
+
 	// Get the request ID
 	reqId := c.Response().Header().Get(echo.HeaderXRequestID)
 
@@ -398,19 +408,27 @@ func CreateTestEnv(c echo.Context) error {
 	// Check if the working directory exists
 	workingDir := projectRoot + "/.terrarium/test-env"
 	if _, err := os.Stat(workingDir); os.IsNotExist(err) {
-		text := "Not exist test environment"
-		res := model.Response{Success: false, Message: text}
-		return c.JSON(http.StatusBadRequest, res)
+		return c.JSON(failure(testEnvNotFound()))
+	}
+
+	// Apply the saved plan if the plan ID is given
+	args, err := applyArgs(c, workingDir)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to apply the saved plan")
+		return c.JSON(failure(err))
 	}
 
 	// global option to set working dir: -chdir=/home/ubuntu/dev/cloud-barista/mc-terrarium/.terrarium/test-env
 	// subcommand: apply
-	ret, err := tofu.ExecuteTofuCommandAsync("test-env", reqId, "-chdir="+workingDir, "apply", "-auto-approve")
+	ret, err := runTofuCommand(async, "test-env", "test-env", reqId, args...)
 	if err != nil {
-		res := model.Response{Success: false, Message: "Failed to deploy test environment"}
-		return c.JSON(http.StatusInternalServerError, res)
+		log.Error().Err(err).Msg("Failed to deploy test environment") // error
+		return c.JSON(failureWithMessage(err, "Failed to deploy test environment"))
+	}
+	if async {
+		return acceptRequest(c, reqId, testEnvRequestPath(reqId))
 	}
-	res := model.Response{Success: true, Message: ret}
+	res := model.Response{Success: true, Message: "successfully deployed test environment", Detail: ret}
 
 	log.Debug().Msgf("%+v", res) // debug
 
@@ -420,15 +438,27 @@ func CreateTestEnv(c echo.Context) error {
 // DestroyTestEnv godoc
 // @Summary Destroy test environment
 // @Description Destroy test environment
+// @Description With async=true, the request is accepted (202) with the Location of its status instead of waiting for the result.
 // @Tags [Test env] Test environment management
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} model.Response "OK"
+// @Param async query bool false "Run in the background and check the status of the request" default(false)
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 201 {object} model.Response "Created"
+// @Success 202 {object} model.JobInfo "Accepted"
+// @Header 202 {string} Location "URL of the status of the request"
 // @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
 // @Failure 503 {object} model.Response "Service Unavailable"
 // @Router /test-env [delete]
 func DestroyTestEnv(c echo.Context) error {
 
+	async, err := asyncRequested(c)
+	if err != nil {
+		return c.JSON(failure(err))
+	}
+
 	// Get the request ID
 	reqId := c.Response().Header().Get(echo.HeaderXRequestID)
 
@@ -437,22 +467,21 @@ func DestroyTestEnv(c echo.Context) error {
 	// Check if the working directory exists
 	workingDir := projectRoot + "/.terrarium/test-env"
 	if _, err := os.Stat(workingDir); os.IsNotExist(err) {
-		text := "Not exist test environment"
-		res := model.Response{Success: false, Message: text}
-		return c.JSON(http.StatusBadRequest, res)
+		return c.JSON(failure(testEnvNotFound()))
 	}
 
 	// Destroy the infrastructure
 	// global option to set working dir: -chdir=/home/ubuntu/dev/cloud-barista/mc-terrarium/.terrarium/test-env
 	// subcommand: destroy
-	ret, err := tofu.ExecuteTofuCommandAsync("test-env", reqId, "-chdir="+workingDir, "destroy", "-auto-approve")
+	ret, err := runTofuCommand(async, "test-env", "test-env", reqId, "-chdir="+workingDir, "destroy", "-auto-approve", "-json")
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to destroy") // error
-		text := fmt.Sprintf("Failed to destroy: %s", ret)
-		res := model.Response{Success: false, Message: text}
-		return c.JSON(http.StatusInternalServerError, res)
+		return c.JSON(failureWithMessage(err, "Failed to destroy test environment"))
+	}
+	if async {
+		return acceptRequest(c, reqId, testEnvRequestPath(reqId))
 	}
-	res := model.Response{Success: true, Message: ret}
+	res := model.Response{Success: true, Message: "successfully destroyed test environment", Detail: ret}
 
 	log.Debug().Msgf("%+v", res) // debug
 
@@ -466,39 +495,98 @@ func DestroyTestEnv(c echo.Context) error {
 // @Accept  json
 // @Produce  json
 // @Param requestId path string true "Request ID"
-// @Success 200 {object} model.Response "OK"
+// @Success 200 {object} model.JobInfo "OK"
 // @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
 // @Failure 503 {object} model.Response "Service Unavailable"
 // @Router /test-env/request/{requestId}/status [get]
 func GetRequestStatusOfTestEnv(c echo.Context) error {
 
 	reqId := c.Param("requestId")
 	if reqId == "" {
-		res := model.Response{Success: false, Message: "Require the request ID"}
-		return c.JSON(http.StatusBadRequest, res)
+		return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "Require the request ID")))
 	}
 
-	projectRoot := config.Terrarium.Root
-	// Check if the working directory exists
-	workingDir := projectRoot + "/.terrarium/test-env"
-	if _, err := os.Stat(workingDir); os.IsNotExist(err) {
-		text := "Not exist test environment"
-		res := model.Response{Success: false, Message: text}
-		return c.JSON(http.StatusBadRequest, res)
+	// Get the job record of the request
+	job, err := tofu.GetJob(reqId)
+	if err != nil || job.TerrariumId != "test-env" {
+		return c.JSON(failure(fmt.Errorf("%w (reqId: %s)", tofu.ErrJobNotFound, reqId)))
 	}
 
-	statusLogFile := fmt.Sprintf("%s/runningLogs/%s.log", workingDir, reqId)
+	log.Debug().Msgf("%+v", job) // debug
 
-	// Check the statusReport of the request
-	statusReport, err := tofu.GetRunningStatus("test-env", statusLogFile)
+	return c.JSON(http.StatusOK, job)
+}
+
+// GetRequestLogsOfTestEnv godoc
+// @Summary Stream the logs of the request to configure test environment
+// @Description Stream the logs of the request to configure test environment as Server-Sent Events (SSE).
+// @Description Each line is sent as a "log" event, and the status of the request is sent as a "status" event at the end.
+// @Description Set follow=true to keep the stream until the request is completed.
+// @Tags [Test env] Test environment management
+// @Accept  json
+// @Produce  text/event-stream
+// @Param requestId path string true "Request ID"
+// @Param follow query bool false "Follow the logs until the request is completed" default(false)
+// @Param Last-Event-ID header string false "Resume the stream from the last event ID (i.e., offset)"
+// @Success 200 {string} string "Server-Sent Events of the logs and status of the request"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 503 {object} model.Response "Service Unavailable"
+// @Router /test-env/request/{requestId}/logs [get]
+func GetRequestLogsOfTestEnv(c echo.Context) error {
+
+	reqId := c.Param("requestId")
+	if reqId == "" {
+		return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "Require the request ID")))
+	}
+
+	// Get the job record of the request
+	job, err := tofu.GetJob(reqId)
+	if err != nil || job.TerrariumId != "test-env" {
+		return c.JSON(failure(fmt.Errorf("%w (reqId: %s)", tofu.ErrJobNotFound, reqId)))
+	}
+
+	return streamRequestLog(c, reqId)
+}
+
+// CancelRequestOfTestEnv godoc
+// @Summary Cancel the running request to configure test environment
+// @Description Cancel the running request (e.g., apply or destroy) to configure test environment.
+// @Description The tofu process is interrupted to release the state lock cleanly and killed after a grace period.
+// @Tags [Test env] Test environment management
+// @Accept  json
+// @Produce  json
+// @Param requestId path string true "Request ID"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 202 {object} model.Response "Accepted"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 409 {object} model.Response "Conflict"
+// @Failure 503 {object} model.Response "Service Unavailable"
+// @Router /test-env/request/{requestId} [delete]
+func CancelRequestOfTestEnv(c echo.Context) error {
+
+	reqId := c.Param("requestId")
+	if reqId == "" {
+		return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "Require the request ID")))
+	}
+
+	// Get the job record of the request
+	job, err := tofu.GetJob(reqId)
+	if err != nil || job.TerrariumId != "test-env" {
+		return c.JSON(failure(fmt.Errorf("%w (reqId: %s)", tofu.ErrJobNotFound, reqId)))
+	}
+
+	// Cancel the running request
+	err = tofu.CancelJob(reqId)
 	if err != nil {
-		res := model.Response{Success: false, Message: "Failed to get the status of the request"}
-		return c.JSON(http.StatusInternalServerError, res)
+		return c.JSON(failure(err))
 	}
 
-	res := model.Response{Success: true, Message: statusReport}
+	res := model.Response{Success: true, Message: "the request is being cancelled, please check the status of the request"}
 
 	log.Debug().Msgf("%+v", res) // debug
 
-	return c.JSON(http.StatusOK, res)
+	return c.JSON(http.StatusAccepted, res)
 }
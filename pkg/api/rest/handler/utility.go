@@ -14,10 +14,15 @@ limitations under the License.
 package handler
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
 	"github.com/cloud-barista/mc-terrarium/pkg/readyz"
+	"github.com/cloud-barista/mc-terrarium/pkg/tenant"
 	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog/log"
@@ -36,9 +41,7 @@ import (
 func Readyz(c echo.Context) error {
 	res := model.Response{}
 	if !readyz.IsReady() {
-		res.Success = false
-		res.Message = "mc-terrarium server is NOT ready"
-		return c.JSON(http.StatusServiceUnavailable, &res)
+		return c.JSON(failure(newError(model.ErrCodeNotReady, "mc-terrarium server is NOT ready")))
 	}
 	res.Success = true
 	res.Message = "mc-terrarium server is ready"
@@ -67,24 +70,96 @@ func HTTPVersion(c echo.Context) error {
 
 // TofuVersion godoc
 // @Summary Check Tofu version
-// @Description Check Tofu version
+// @Description Check the selected IaC engine (i.e., OpenTofu or Terraform) and its version
 // @Tags [System] Utility
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} model.Response
+// @Success 200 {object} model.Response{object=model.EngineVersion}
 // @Failure 503 {object} model.Response
 // @Router /tofuVersion [get]
 func TofuVersion(c echo.Context) error {
 
 	ret, err := tofu.GetTofuVersion()
 	if err != nil {
-		res := model.Response{Success: false, Message: "failed to get Tofu version"}
-		return c.JSON(http.StatusInternalServerError, res)
+		log.Error().Err(err).Msg("failed to get Tofu version")
+		return c.JSON(failure(&Error{Code: model.ErrCodeEngineUnavailable, Message: "failed to get Tofu version", Err: err}))
 	}
 
-	res := model.Response{Success: true, Message: ret}
+	res := model.Response{Success: true, Message: ret.Raw, Object: toObject(ret)}
 
 	log.Debug().Msgf("%+v", res) // debug
 
 	return c.JSON(http.StatusOK, res)
 }
+
+// toObject converts a struct to the object of a response.
+func toObject(v interface{}) map[string]interface{} {
+	var object map[string]interface{}
+
+	data, err := json.Marshal(v)
+	if err == nil {
+		err = json.Unmarshal(data, &object)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to convert to the object of a response")
+		return nil
+	}
+	return object
+}
+
+// toList converts a slice (e.g., []model.EnrichmentResources) to the list of a response.
+func toList(v interface{}) []interface{} {
+	var list []interface{}
+
+	data, err := json.Marshal(v)
+	if err == nil {
+		err = json.Unmarshal(data, &list)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to convert to the list of a response")
+		return nil
+	}
+	return list
+}
+
+// Media type of a JSON merge patch (RFC 7396)
+const mimeMergePatchJSON = "application/merge-patch+json"
+
+// formatETag returns the (strong) entity tag of a resource version (e.g., "42").
+func formatETag(revision int64) string {
+	return fmt.Sprintf("%q", strconv.FormatInt(revision, 10))
+}
+
+// parseIfMatch returns the resource version in the If-Match header (e.g., "42"),
+// or 0 if the header is empty or "*" (i.e., any version).
+func parseIfMatch(header string) (int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.Contains(header, ",") {
+		return 0, newError(model.ErrCodeInvalidRequest, "invalid If-Match (%s), only a single ETag is supported", header)
+	}
+	if strings.HasPrefix(header, "W/") {
+		return 0, newError(model.ErrCodeInvalidRequest, "invalid If-Match (%s), a weak ETag cannot be used to update", header)
+	}
+	revision, err := strconv.ParseInt(strings.Trim(header, `"`), 10, 64)
+	if err != nil || revision <= 0 {
+		return 0, newError(model.ErrCodeInvalidRequest, "invalid If-Match (%s), use the ETag of the resource", header)
+	}
+	return revision, nil
+}
+
+// callerIsAdmin returns whether the caller is the administrator (i.e., the API user or the auth is disabled),
+// who is set by middlewares.TenantScope.
+func callerIsAdmin(c echo.Context) bool {
+	admin, _ := c.Get(tenant.ContextKeyAdmin).(bool)
+	return admin
+}
+
+// callerTenant returns the tenant of the caller (see middlewares.TenantScope),
+// which is empty for the API user not acting as a tenant (i.e., all the tenants).
+func callerTenant(c echo.Context) string {
+	name, _ := c.Get(tenant.ContextKey).(string)
+	return name
+}
@@ -0,0 +1,337 @@
+/*
+Copyright 2019 The Cloud-Barista Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/terrarium"
+	"github.com/cloud-barista/mc-terrarium/pkg/webhook"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// CreateWebhook godoc
+// @Summary Create a webhook
+// @Description Create a webhook receiving the events of all the terrariums of the tenant of the caller (all the tenants for the API user)
+// @Description instead of polling the requests: job.started, job.succeeded, job.failed, job.cancelled, job.interrupted,
+// @Description enrichment.state_changed, terrarium.expiring, terrarium.expired, terrarium.reaped, terrarium.reap_failed and terrarium.erased.
+// @Description Each event is posted as JSON with the X-Terrarium-Event, X-Terrarium-Delivery, X-Terrarium-Timestamp and X-Terrarium-Signature headers,
+// @Description where the signature is sha256= and the hex-encoded HMAC-SHA256 of {timestamp}.{body} by the secret.
+// @Description A failed delivery is retried with the exponential backoff, and moved to the dead letters after the last attempt.
+// @Description The URL is rejected (400) if its host is a loopback, link-local or private address (unless webhook.allowprivate),
+// @Description and the redirects of the receiver are not followed.
+// @Description The secret is generated if it is not given, and only returned in this response.
+// @Tags [Webhook] Notifications of the events of the jobs and the terrariums
+// @Accept  json
+// @Produce  json
+// @Param WebhookRequest body model.WebhookRequest true "URL and types of the events to receive (all if empty, e.g., job.* for the job events)"
+// @Param x-tenant header string false "Tenant to act as, only for the API user"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 201 {object} model.Webhook "Created"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Router /webhooks [post]
+func CreateWebhook(c echo.Context) error {
+	return createWebhook(c, "")
+}
+
+// CreateWebhookOfTerrarium godoc
+// @Summary Create a webhook of a terrarium
+// @Description Create a webhook receiving the events of a terrarium (see POST /webhooks), which is deleted when the terrarium is erased.
+// @Tags [Webhook] Notifications of the events of the jobs and the terrariums
+// @Accept  json
+// @Produce  json
+// @Param trId path string true "Terrarium ID" default(tr01)
+// @Param WebhookRequest body model.WebhookRequest true "URL and types of the events to receive (all if empty, e.g., job.* for the job events)"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 201 {object} model.Webhook "Created"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Router /tr/{trId}/webhooks [post]
+func CreateWebhookOfTerrarium(c echo.Context) error {
+
+	trId := c.Param("trId")
+	if trId == "" {
+		return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "require the terrarium ID")))
+	}
+	return createWebhook(c, trId)
+}
+
+// createWebhook creates a webhook of the caller, which is for a terrarium if trId is not empty.
+func createWebhook(c echo.Context, trId string) error {
+
+	req := new(model.WebhookRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "failed to bind the request")))
+	}
+
+	// A webhook of a terrarium belongs to the tenant of the terrarium
+	tenantName := callerTenant(c)
+	if trId != "" {
+		var err error
+		if tenantName, err = terrarium.TenantOf(trId); err != nil {
+			return c.JSON(failure(err))
+		}
+	}
+
+	wh, err := webhook.Create(*req, tenantName, trId)
+	if err != nil {
+		status, res := failure(err)
+		if status == http.StatusInternalServerError {
+			log.Error().Err(err).Msg("failed to create the webhook")
+		}
+		return c.JSON(status, res)
+	}
+
+	return c.JSON(http.StatusCreated, wh)
+}
+
+// ReadAllWebhooks godoc
+// @Summary Read all webhooks
+// @Description Read all webhooks of the tenant of the caller (all the tenants for the API user), including the ones of the terrariums.
+// @Description The secrets are not shown.
+// @Tags [Webhook] Notifications of the events of the jobs and the terrariums
+// @Accept  json
+// @Produce  json
+// @Param x-tenant header string false "Tenant to act as, only for the API user"
+// @Success 200 {array} model.Webhook "OK"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Router /webhooks [get]
+func ReadAllWebhooks(c echo.Context) error {
+	return readAllWebhooks(c, "")
+}
+
+// ReadAllWebhooksOfTerrarium godoc
+// @Summary Read all webhooks of a terrarium
+// @Description Read all webhooks receiving the events of a terrarium. The secrets are not shown.
+// @Tags [Webhook] Notifications of the events of the jobs and the terrariums
+// @Accept  json
+// @Produce  json
+// @Param trId path string true "Terrarium ID" default(tr01)
+// @Success 200 {array} model.Webhook "OK"
+// @Failure 400 {object} model.Response "Bad Request"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Router /tr/{trId}/webhooks [get]
+func ReadAllWebhooksOfTerrarium(c echo.Context) error {
+
+	trId := c.Param("trId")
+	if trId == "" {
+		return c.JSON(failure(newError(model.ErrCodeInvalidRequest, "require the terrarium ID")))
+	}
+	return readAllWebhooks(c, trId)
+}
+
+// readAllWebhooks reads the webhooks of the caller, which are of a terrarium if trId is not empty.
+func readAllWebhooks(c echo.Context, trId string) error {
+
+	webhooks, err := webhook.List(callerTenant(c), trId)
+	if err != nil {
+		err2 := fmt.Errorf("failed to read the webhooks")
+		log.Error().Err(err).Msg(err2.Error())
+		return c.JSON(failureWithMessage(err, err2.Error()))
+	}
+
+	return c.JSON(http.StatusOK, webhooks)
+}
+
+// ReadWebhook godoc
+// @Summary Read a webhook
+// @Description Read a webhook without the secret.
+// @Tags [Webhook] Notifications of the events of the jobs and the terrariums
+// @Accept  json
+// @Produce  json
+// @Param webhookId path string true "Webhook ID"
+// @Param x-tenant header string false "Tenant to act as, only for the API user"
+// @Success 200 {object} model.Webhook "OK"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Router /webhooks/{webhookId} [get]
+func ReadWebhook(c echo.Context) error {
+
+	wh, err := webhook.Get(c.Param("webhookId"), callerTenant(c))
+	if err != nil {
+		return c.JSON(failure(err))
+	}
+
+	return c.JSON(http.StatusOK, wh)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Delete a webhook with its dead letters. The deliveries in progress are not stopped.
+// @Tags [Webhook] Notifications of the events of the jobs and the terrariums
+// @Accept  json
+// @Produce  json
+// @Param webhookId path string true "Webhook ID"
+// @Param x-tenant header string false "Tenant to act as, only for the API user"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 200 {object} model.Response "OK"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Router /webhooks/{webhookId} [delete]
+func DeleteWebhook(c echo.Context) error {
+
+	webhookId := c.Param("webhookId")
+	if err := webhook.Delete(webhookId, callerTenant(c)); err != nil {
+		status, res := failure(err)
+		if status == http.StatusInternalServerError {
+			log.Error().Err(err).Msgf("failed to delete the webhook (webhookId: %s)", webhookId)
+		}
+		return c.JSON(status, res)
+	}
+
+	res := model.Response{
+		Success: true,
+		Message: fmt.Sprintf("successfully deleted the webhook (webhookId: %s)", webhookId),
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// PingWebhook godoc
+// @Summary Ping a webhook
+// @Description Deliver a test event (webhook.ping) to a webhook. Check the result by the deliveries of the webhook.
+// @Tags [Webhook] Notifications of the events of the jobs and the terrariums
+// @Accept  json
+// @Produce  json
+// @Param webhookId path string true "Webhook ID"
+// @Param x-tenant header string false "Tenant to act as, only for the API user"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 202 {object} model.WebhookDelivery "Accepted"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Router /webhooks/{webhookId}/ping [post]
+func PingWebhook(c echo.Context) error {
+
+	d, err := webhook.Ping(c.Param("webhookId"), callerTenant(c))
+	if err != nil {
+		return c.JSON(failure(err))
+	}
+
+	return c.JSON(http.StatusAccepted, d)
+}
+
+// ReadWebhookDeliveries godoc
+// @Summary Read the recent deliveries of a webhook
+// @Description Read the recent deliveries of a webhook by this server, the newest first, with the status (Pending, Retrying, Delivered or Dead),
+// @Description the number of attempts and the last error. They are kept in memory up to 50 per webhook.
+// @Tags [Webhook] Notifications of the events of the jobs and the terrariums
+// @Accept  json
+// @Produce  json
+// @Param webhookId path string true "Webhook ID"
+// @Param x-tenant header string false "Tenant to act as, only for the API user"
+// @Success 200 {array} model.WebhookDelivery "OK"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Router /webhooks/{webhookId}/deliveries [get]
+func ReadWebhookDeliveries(c echo.Context) error {
+
+	deliveries, err := webhook.ListDeliveries(c.Param("webhookId"), callerTenant(c))
+	if err != nil {
+		return c.JSON(failure(err))
+	}
+
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+// ReadWebhookDeadLetters godoc
+// @Summary Read the dead letters of a webhook
+// @Description Read the deliveries of a webhook which failed after the last attempt or by a permanent error (e.g., 404).
+// @Tags [Webhook] Notifications of the events of the jobs and the terrariums
+// @Accept  json
+// @Produce  json
+// @Param webhookId path string true "Webhook ID"
+// @Param x-tenant header string false "Tenant to act as, only for the API user"
+// @Success 200 {array} model.WebhookDelivery "OK"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Router /webhooks/{webhookId}/dead-letters [get]
+func ReadWebhookDeadLetters(c echo.Context) error {
+
+	webhookId := c.Param("webhookId")
+	deliveries, err := webhook.ListDeadLetters(webhookId, callerTenant(c))
+	if err != nil {
+		status, res := failure(err)
+		if status == http.StatusInternalServerError {
+			log.Error().Err(err).Msgf("failed to read the dead letters of the webhook (webhookId: %s)", webhookId)
+		}
+		return c.JSON(status, res)
+	}
+
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+// RedeliverWebhookDeadLetter godoc
+// @Summary Redeliver a dead letter of a webhook
+// @Description Remove a delivery from the dead letters and deliver it again with the same delivery ID to the current URL of the webhook.
+// @Tags [Webhook] Notifications of the events of the jobs and the terrariums
+// @Accept  json
+// @Produce  json
+// @Param webhookId path string true "Webhook ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Param x-tenant header string false "Tenant to act as, only for the API user"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 202 {object} model.WebhookDelivery "Accepted"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Router /webhooks/{webhookId}/dead-letters/{deliveryId}/redeliver [post]
+func RedeliverWebhookDeadLetter(c echo.Context) error {
+
+	webhookId, deliveryId := c.Param("webhookId"), c.Param("deliveryId")
+	d, err := webhook.Redeliver(webhookId, deliveryId, callerTenant(c))
+	if err != nil {
+		status, res := failure(err)
+		if status == http.StatusInternalServerError {
+			log.Error().Err(err).Msgf("failed to redeliver the dead letter (webhookId: %s, deliveryId: %s)", webhookId, deliveryId)
+		}
+		return c.JSON(status, res)
+	}
+
+	return c.JSON(http.StatusAccepted, d)
+}
+
+// DeleteWebhookDeadLetter godoc
+// @Summary Delete a dead letter of a webhook
+// @Description Delete a delivery from the dead letters without redelivering it.
+// @Tags [Webhook] Notifications of the events of the jobs and the terrariums
+// @Accept  json
+// @Produce  json
+// @Param webhookId path string true "Webhook ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Param x-tenant header string false "Tenant to act as, only for the API user"
+// @Param Idempotency-Key header string false "Key to replay the response of the request retried with it (kept for 24 hours)"
+// @Success 200 {object} model.Response "OK"
+// @Failure 404 {object} model.Response "Not Found"
+// @Failure 500 {object} model.Response "Internal Server Error"
+// @Router /webhooks/{webhookId}/dead-letters/{deliveryId} [delete]
+func DeleteWebhookDeadLetter(c echo.Context) error {
+
+	webhookId, deliveryId := c.Param("webhookId"), c.Param("deliveryId")
+	if err := webhook.DeleteDeadLetter(webhookId, deliveryId, callerTenant(c)); err != nil {
+		status, res := failure(err)
+		if status == http.StatusInternalServerError {
+			log.Error().Err(err).Msgf("failed to delete the dead letter (webhookId: %s, deliveryId: %s)", webhookId, deliveryId)
+		}
+		return c.JSON(status, res)
+	}
+
+	res := model.Response{
+		Success: true,
+		Message: fmt.Sprintf("successfully deleted the dead letter (webhookId: %s, deliveryId: %s)", webhookId, deliveryId),
+	}
+	return c.JSON(http.StatusOK, res)
+}
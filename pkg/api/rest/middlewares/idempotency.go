@@ -0,0 +1,226 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/idempotency"
+	"github.com/cloud-barista/mc-terrarium/pkg/tenant"
+	"github.com/cloud-barista/mc-terrarium/pkg/terrarium"
+	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Max size of a response kept to replay, over which the request can be retried with the same key
+	maxReplayBodySize = 1 << 20
+	// Max size of a request body kept in memory to pass it to the handler, over which it is kept in a temporary file
+	maxMemoryBodySize = 1 << 20
+	// Max size of a request body to fingerprint, which is the max size of a bundle to import
+	maxRequestBodySize = terrarium.MaxBundleSize
+)
+
+// Headers of a response kept to replay
+var replayHeaders = []string{echo.HeaderContentType, echo.HeaderLocation, "ETag", echo.HeaderRetryAfter}
+
+// Idempotency replays the response of a POST or DELETE request retried with the same Idempotency-Key header
+// (e.g., after a network failure) instead of running it again (see pkg/idempotency).
+// The request with the key in progress is rejected (409) with its current job, and the key with
+// a different request (i.e., method, path, query or body) is rejected (422).
+// Only the responses of the requests which may have changed something (2xx and 500) are kept,
+// so that a rejected request (e.g., 400, 409, 503) can be retried with the same key.
+// The request body is fingerprinted as a stream up to maxRequestBodySize, and a replayed 202 has the current job of the request.
+func Idempotency(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		req := c.Request()
+		key := req.Header.Get(idempotency.HeaderKey)
+		if key == "" || (req.Method != http.MethodPost && req.Method != http.MethodDelete) || !idempotency.Enabled() {
+			return next(c)
+		}
+		if err := idempotency.ValidateKey(key); err != nil {
+			res := model.Response{Success: false, Code: model.ErrCodeInvalidRequest, Message: err.Error()}
+			return c.JSON(http.StatusBadRequest, res)
+		}
+
+		// Keep the body while fingerprinting it to pass it to the handler
+		body := &bodySpool{}
+		defer body.Close()
+		limited := http.MaxBytesReader(c.Response(), req.Body, maxRequestBodySize)
+		fingerprint, err := idempotency.Fingerprint(req.Method, req.URL.Path, req.URL.Query().Encode(), io.TeeReader(limited, body))
+		if err != nil {
+			message := "failed to read the request body"
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				message = fmt.Sprintf("the request body is larger than %d bytes", maxRequestBodySize)
+			}
+			res := model.Response{Success: false, Code: model.ErrCodeInvalidRequest, Message: message}
+			return c.JSON(http.StatusBadRequest, res)
+		}
+		if req.Body, err = body.Reader(); err != nil {
+			err2 := errors.New("failed to read the request body")
+			log.Error().Err(err).Msg(err2.Error())
+			res := model.Response{Success: false, Code: model.ErrCodeInternal, Message: err2.Error()}
+			return c.JSON(http.StatusInternalServerError, res)
+		}
+
+		tenantName, _ := c.Get(tenant.ContextKey).(string)
+		reqId := c.Response().Header().Get(echo.HeaderXRequestID)
+
+		rec, err := idempotency.Begin(tenantName, key, fingerprint, reqId)
+		switch {
+		case errors.Is(err, idempotency.ErrKeyReused):
+			res := model.Response{Success: false, Code: model.ErrCodeIdempotencyKeyReused, Message: err.Error()}
+			return c.JSON(http.StatusUnprocessableEntity, res)
+		case errors.Is(err, idempotency.ErrInProgress):
+			res := model.Response{Success: false, Code: model.ErrCodeIdempotencyKeyInProgress, Message: err.Error()}
+			if rec != nil {
+				if job, err := tofu.GetJob(rec.RequestId); err == nil {
+					res.Object = jobObject(job)
+				}
+			}
+			return c.JSON(http.StatusConflict, res)
+		case err != nil:
+			err2 := fmt.Errorf("failed to check the idempotency key (%s)", key)
+			log.Error().Err(err).Msg(err2.Error())
+			res := model.Response{Success: false, Code: model.ErrCodeInternal, Message: err2.Error()}
+			return c.JSON(http.StatusInternalServerError, res)
+		case rec != nil:
+			for _, name := range replayHeaders {
+				if value := rec.Header.Get(name); value != "" {
+					c.Response().Header().Set(name, value)
+				}
+			}
+			c.Response().Header().Set(idempotency.HeaderReplayed, "true")
+			if job, ok := acceptedJob(rec); ok {
+				return c.JSON(http.StatusAccepted, job)
+			}
+			return c.Blob(rec.StatusCode, rec.Header.Get(echo.HeaderContentType), rec.Body)
+		}
+
+		// Keep the response while writing it
+		w := &captureWriter{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = w
+
+		err = next(c)
+
+		status := c.Response().Status
+		keep := err == nil && c.Response().Committed && w.body.Len() <= maxReplayBodySize &&
+			((status >= 200 && status <= 299) || status == http.StatusInternalServerError)
+		if !keep {
+			if err2 := idempotency.Release(tenantName, key); err2 != nil {
+				log.Error().Err(err2).Msgf("failed to release the idempotency key (%s)", key)
+			}
+			return err
+		}
+
+		header := make(http.Header)
+		for _, name := range replayHeaders {
+			if value := c.Response().Header().Get(name); value != "" {
+				header.Set(name, value)
+			}
+		}
+		if err2 := idempotency.Complete(tenantName, key, status, header, w.body.Bytes()); err2 != nil {
+			log.Error().Err(err2).Msgf("failed to keep the response of the idempotency key (%s)", key)
+		}
+		return nil
+	}
+}
+
+// captureWriter writes a response while keeping its body.
+type captureWriter struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	if w.body.Len() <= maxReplayBodySize {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// acceptedJob returns the current job of a request accepted (202) with its job,
+// which has been updated since the response was kept (e.g., running or done).
+func acceptedJob(rec *idempotency.Record) (model.JobInfo, bool) {
+	if rec.StatusCode != http.StatusAccepted || rec.RequestId == "" {
+		return model.JobInfo{}, false
+	}
+	var accepted model.JobInfo
+	if err := json.Unmarshal(rec.Body, &accepted); err != nil || accepted.Id != rec.RequestId {
+		// Not a job (e.g., the result of an import)
+		return model.JobInfo{}, false
+	}
+	job, err := tofu.GetJob(rec.RequestId)
+	if err != nil {
+		log.Warn().Err(err).Msgf("failed to read the request (reqId: %s) to replay", rec.RequestId)
+		return model.JobInfo{}, false
+	}
+	return job, true
+}
+
+// bodySpool keeps a request body written to it, in memory up to maxMemoryBodySize and in a temporary file over it.
+type bodySpool struct {
+	buf  bytes.Buffer
+	file *os.File
+}
+
+func (s *bodySpool) Write(b []byte) (int, error) {
+	if s.file == nil && s.buf.Len()+len(b) <= maxMemoryBodySize {
+		return s.buf.Write(b)
+	}
+	if s.file == nil {
+		file, err := os.CreateTemp("", "mc-terrarium-body-")
+		if err != nil {
+			return 0, err
+		}
+		s.file = file
+		if _, err := s.file.Write(s.buf.Bytes()); err != nil {
+			return 0, err
+		}
+		s.buf.Reset()
+	}
+	return s.file.Write(b)
+}
+
+// Reader returns the body kept so far from the beginning.
+func (s *bodySpool) Reader() (io.ReadCloser, error) {
+	if s.file == nil {
+		return io.NopCloser(bytes.NewReader(s.buf.Bytes())), nil
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	// Closed by Close after the handler
+	return io.NopCloser(s.file), nil
+}
+
+// Close deletes the temporary file, if any.
+func (s *bodySpool) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+// jobObject returns a job as the object of a response.
+func jobObject(job model.JobInfo) map[string]interface{} {
+	var obj map[string]interface{}
+	data, err := json.Marshal(job)
+	if err != nil || json.Unmarshal(data, &obj) != nil {
+		return nil
+	}
+	return obj
+}
@@ -0,0 +1,293 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/cloud-barista/mc-terrarium/pkg/idempotency"
+	"github.com/cloud-barista/mc-terrarium/pkg/store"
+	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
+	"github.com/labstack/echo/v4"
+)
+
+// setUpIdempotency keeps the records in memory for a test, and returns a server which responds by the handler
+// through the Idempotency middleware.
+func setUpIdempotency(t *testing.T, handler echo.HandlerFunc) *echo.Echo {
+	t.Helper()
+
+	idempotency.SetStore(store.NewMemoryStore())
+	t.Cleanup(func() { idempotency.SetStore(nil) })
+
+	e := echo.New()
+	e.POST("/tr/:trId/vpn/aws", handler, Idempotency)
+	return e
+}
+
+// serve sends a POST request with the Idempotency-Key header to the server.
+func serve(e *echo.Echo, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(idempotency.HeaderKey, key)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// responseCode returns the error code of a failed response.
+func responseCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var res model.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("failed to decode the response (%s): %v", rec.Body.String(), err)
+	}
+	return res.Code
+}
+
+// Only the responses of 2xx and 500 are replayed, and the key of the other ones can be retried.
+func TestIdempotencyReplay(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		wantReplayed bool
+	}{
+		{name: "created", status: http.StatusCreated, wantReplayed: true},
+		{name: "accepted", status: http.StatusAccepted, wantReplayed: true},
+		{name: "internal server error", status: http.StatusInternalServerError, wantReplayed: true},
+		{name: "bad request", status: http.StatusBadRequest},
+		{name: "conflict", status: http.StatusConflict},
+		{name: "unprocessable entity", status: http.StatusUnprocessableEntity},
+		{name: "service unavailable", status: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			e := setUpIdempotency(t, func(c echo.Context) error {
+				n := atomic.AddInt32(&calls, 1)
+				c.Response().Header().Set("ETag", `"1"`)
+				return c.JSON(tt.status, map[string]int32{"call": n})
+			})
+
+			first := serve(e, "/tr/tr01/vpn/aws", "key-1", `{"tfVars":{}}`)
+			second := serve(e, "/tr/tr01/vpn/aws", "key-1", `{"tfVars":{}}`)
+
+			wantCalls := int32(2)
+			if tt.wantReplayed {
+				wantCalls = 1
+			}
+			if calls != wantCalls {
+				t.Errorf("calls of the handler = %d, want %d", calls, wantCalls)
+			}
+			if second.Code != tt.status {
+				t.Errorf("status of the retry = %d, want %d", second.Code, tt.status)
+			}
+			replayed := second.Header().Get(idempotency.HeaderReplayed) == "true"
+			if replayed != tt.wantReplayed {
+				t.Errorf("replayed = %v, want %v", replayed, tt.wantReplayed)
+			}
+			if tt.wantReplayed {
+				if second.Body.String() != first.Body.String() {
+					t.Errorf("replayed body = %s, want %s", second.Body.String(), first.Body.String())
+				}
+				if etag := second.Header().Get("ETag"); etag != `"1"` {
+					t.Errorf("replayed ETag = %s, want %s", etag, `"1"`)
+				}
+			}
+		})
+	}
+}
+
+// The key used by a different request is rejected (422), while the other keys and requests are not affected.
+func TestIdempotencyKeyReused(t *testing.T) {
+	var calls int32
+	e := setUpIdempotency(t, func(c echo.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return c.JSON(http.StatusCreated, map[string]string{"trId": c.Param("trId")})
+	})
+
+	if rec := serve(e, "/tr/tr01/vpn/aws", "key-1", `{"tfVars":{"region":"us-east-1"}}`); rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		key        string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "different body", path: "/tr/tr01/vpn/aws", key: "key-1", body: `{"tfVars":{"region":"us-west-1"}}`,
+			wantStatus: http.StatusUnprocessableEntity, wantCode: model.ErrCodeIdempotencyKeyReused},
+		{name: "different path", path: "/tr/tr02/vpn/aws", key: "key-1", body: `{"tfVars":{"region":"us-east-1"}}`,
+			wantStatus: http.StatusUnprocessableEntity, wantCode: model.ErrCodeIdempotencyKeyReused},
+		{name: "different query", path: "/tr/tr01/vpn/aws?detail=refined", key: "key-1", body: `{"tfVars":{"region":"us-east-1"}}`,
+			wantStatus: http.StatusUnprocessableEntity, wantCode: model.ErrCodeIdempotencyKeyReused},
+		{name: "another key", path: "/tr/tr02/vpn/aws", key: "key-2", body: `{"tfVars":{"region":"us-east-1"}}`,
+			wantStatus: http.StatusCreated},
+		{name: "invalid key", path: "/tr/tr01/vpn/aws", key: "key\x01", body: `{}`,
+			wantStatus: http.StatusBadRequest, wantCode: model.ErrCodeInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(e, tt.path, tt.key, tt.body)
+			code := ""
+			if rec.Code != http.StatusCreated {
+				code = responseCode(t, rec)
+			}
+			if rec.Code != tt.wantStatus || code != tt.wantCode {
+				t.Errorf("response = (%d, %s), want (%d, %s): %s", rec.Code, code, tt.wantStatus, tt.wantCode, rec.Body.String())
+			}
+		})
+	}
+
+	if calls != 2 {
+		t.Errorf("calls of the handler = %d, want 2 (the first request and another key)", calls)
+	}
+}
+
+// The retry while the request of the key is in progress is rejected (409), and is replayed after it is done.
+func TestIdempotencyInProgress(t *testing.T) {
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	e := setUpIdempotency(t, func(c echo.Context) error {
+		close(started)
+		<-finish
+		return c.JSON(http.StatusAccepted, map[string]string{"status": "Queued"})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- serve(e, "/tr/tr01/vpn/aws", "key-1", `{}`) }()
+	<-started
+
+	rec := serve(e, "/tr/tr01/vpn/aws", "key-1", `{}`)
+	if code := responseCode(t, rec); rec.Code != http.StatusConflict || code != model.ErrCodeIdempotencyKeyInProgress {
+		t.Errorf("response in progress = (%d, %s), want (%d, %s)", rec.Code, code, http.StatusConflict, model.ErrCodeIdempotencyKeyInProgress)
+	}
+
+	close(finish)
+	if first := <-done; first.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d: %s", first.Code, http.StatusAccepted, first.Body.String())
+	}
+
+	rec = serve(e, "/tr/tr01/vpn/aws", "key-1", `{}`)
+	if rec.Code != http.StatusAccepted || rec.Header().Get(idempotency.HeaderReplayed) != "true" {
+		t.Errorf("response after done = (%d, replayed %q), want (%d, replayed)", rec.Code, rec.Header().Get(idempotency.HeaderReplayed), http.StatusAccepted)
+	}
+}
+
+// A body over the memory limit (e.g., a bundle to import) is fingerprinted and passed to the handler as it is.
+func TestIdempotencyLargeBody(t *testing.T) {
+	var calls int32
+	e := setUpIdempotency(t, func(c echo.Context) error {
+		atomic.AddInt32(&calls, 1)
+		fileHeader, err := c.FormFile("bundle")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusCreated, map[string]int{"size": len(data)})
+	})
+
+	bundle := bytes.Repeat([]byte("bundle"), maxMemoryBodySize/3)
+	upload := func(bundle []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		if err := w.SetBoundary("bundle-boundary"); err != nil {
+			t.Fatal(err)
+		}
+		part, err := w.CreateFormFile("bundle", "tr01.tar.gz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(bundle)
+		w.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/tr/tr01/vpn/aws", &body)
+		req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+		req.Header.Set(idempotency.HeaderKey, "key-1")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := upload(bundle)
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), fmt.Sprintf(`"size":%d`, len(bundle))) {
+		t.Fatalf("response = (%d, %s), want (%d, size %d)", rec.Code, rec.Body.String(), http.StatusCreated, len(bundle))
+	}
+	if rec = upload(bundle); rec.Header().Get(idempotency.HeaderReplayed) != "true" {
+		t.Errorf("response of the same bundle = (%d, replayed %q), want replayed", rec.Code, rec.Header().Get(idempotency.HeaderReplayed))
+	}
+
+	bundle[len(bundle)-1] = 'x'
+	if rec = upload(bundle); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status of another bundle = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+	if calls != 1 {
+		t.Errorf("calls of the handler = %d, want 1", calls)
+	}
+}
+
+// A replayed 202 has the current job of the request instead of the job when it was accepted.
+func TestIdempotencyReplayAcceptedJob(t *testing.T) {
+	root := config.Terrarium.Root
+	config.Terrarium.Root = t.TempDir()
+	tofu.SetExecutor(tofu.NewFakeExecutor())
+	t.Cleanup(func() {
+		config.Terrarium.Root = root
+		tofu.SetExecutor(tofu.NewOpenTofuExecutor(config.Terrarium.Executor))
+	})
+
+	e := setUpIdempotency(t, func(c echo.Context) error {
+		reqId := c.Response().Header().Get(echo.HeaderXRequestID)
+		return c.JSON(http.StatusAccepted, model.JobInfo{Id: reqId, TerrariumId: "tr01", Status: tofu.JobStatusQueued})
+	})
+	e.Pre(RequestIdAndDetailsIssuer)
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/tr/tr01/vpn/aws", strings.NewReader(`{}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderXRequestID, "req-idempotency-accepted")
+		req.Header.Set(idempotency.HeaderKey, "key-1")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send(); rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body.String())
+	}
+	if _, err := tofu.ExecuteTofuCommand("tr01", "vpn/aws", "req-idempotency-accepted", "validate"); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := send()
+	var job model.JobInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+		t.Fatalf("failed to decode the replayed job (%s): %v", rec.Body.String(), err)
+	}
+	if rec.Code != http.StatusAccepted || rec.Header().Get(idempotency.HeaderReplayed) != "true" || job.Status != tofu.JobStatusSuccess {
+		t.Errorf("replayed response = (%d, replayed %q, status %s), want (%d, replayed, %s)",
+			rec.Code, rec.Header().Get(idempotency.HeaderReplayed), job.Status, http.StatusAccepted, tofu.JobStatusSuccess)
+	}
+}
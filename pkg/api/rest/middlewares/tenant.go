@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/tenant"
+	"github.com/cloud-barista/mc-terrarium/pkg/terrarium"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// TenantScope resolves the tenant of the caller and scopes the access to the terrariums by it.
+// The tenant is set by the basic auth validator (see tenant.Authenticate), and the caller is the administrator
+// if it is not set (i.e., the API user or the auth is disabled), who can act as a tenant by the X-Tenant header.
+// A terrarium of another tenant is not found (404) so that its existence is not disclosed,
+// and the test environment and the import of a terrarium are only for the administrator.
+func TenantScope(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+
+		name, _ := c.Get(tenant.ContextKey).(string)
+		admin := name == ""
+		header := c.Request().Header.Get(tenant.HeaderTenant)
+		if admin {
+			if header != "" && !tenant.Exists(header) {
+				text := fmt.Sprintf("not existed the tenant (%s: %s)", tenant.HeaderTenant, header)
+				res := model.Response{Success: false, Code: model.ErrCodeInvalidRequest, Message: text}
+				return c.JSON(http.StatusBadRequest, res)
+			}
+			name = header
+		} else if header != "" && header != name {
+			text := fmt.Sprintf("not allowed to act as another tenant (%s: %s)", tenant.HeaderTenant, header)
+			res := model.Response{Success: false, Code: model.ErrCodeForbidden, Message: text}
+			return c.JSON(http.StatusForbidden, res)
+		}
+		c.Set(tenant.ContextKey, name)
+		c.Set(tenant.ContextKeyAdmin, admin)
+
+		if !admin && strings.HasPrefix(c.Path(), "/terrarium/test-env") {
+			res := model.Response{Success: false, Code: model.ErrCodeForbidden, Message: "the test environment is only for the API user"}
+			return c.JSON(http.StatusForbidden, res)
+		}
+
+		// The infracode in an imported bundle runs with the credentials of this server
+		if !admin && c.Path() == "/terrarium/tr/import" {
+			res := model.Response{Success: false, Code: model.ErrCodeForbidden, Message: "importing a terrarium is only for the API user"}
+			return c.JSON(http.StatusForbidden, res)
+		}
+
+		// Scope the terrarium in the path to the tenant, except the administrator not acting as a tenant
+		trId := c.Param("trId")
+		if trId == "" || name == "" {
+			return next(c)
+		}
+		owner, err := terrarium.TenantOf(trId)
+		if err != nil {
+			if errors.Is(err, terrarium.ErrTerrariumNotFound) {
+				return next(c)
+			}
+			err2 := fmt.Errorf("failed to read the tenant of the terrarium (trId: %s)", trId)
+			log.Error().Err(err).Msg(err2.Error())
+			res := model.Response{Success: false, Code: model.ErrCodeInternal, Message: err2.Error()}
+			return c.JSON(http.StatusInternalServerError, res)
+		}
+		if owner != name {
+			log.Warn().Msgf("the tenant (%s) requested the terrarium (trId: %s) of another tenant", name, trId)
+			text := fmt.Sprintf("%s (trId: %s)", terrarium.ErrTerrariumNotFound, trId)
+			res := model.Response{Success: false, Code: model.ErrCodeTerrariumNotFound, Message: text}
+			return c.JSON(http.StatusNotFound, res)
+		}
+
+		return next(c)
+	}
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// BundleManifest describes the files in a terrarium bundle (i.e., manifest.json in the tar.gz)
+// to verify them when importing it on another server
+type BundleManifest struct {
+	FormatVersion int          `json:"formatVersion" example:"1"`
+	ToolVersion   string       `json:"toolVersion" example:"v0.1.0"`
+	TerrariumId   string       `json:"terrariumId" example:"tr01"`
+	CreatedAt     time.Time    `json:"createdAt" example:"2024-01-01T00:00:00Z"`
+	Files         []BundleFile `json:"files"`
+	// Credential files excluded from each enrichment, which are copied from the secrets of the importing server
+	Credentials map[string][]string `json:"credentials,omitempty"`
+}
+
+// BundleFile represents a file in a terrarium bundle with its checksum
+type BundleFile struct {
+	Path   string `json:"path" example:"sql-db/terraform.tfstate"`
+	Size   int64  `json:"size" example:"1024"`
+	Sha256 string `json:"sha256" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+}
+
+// ImportResult represents a terrarium imported from a bundle, the requests initializing its enrichments
+// (keyed by the enrichment) and the warnings while importing it
+type ImportResult struct {
+	Terrarium TerrariumInfo     `json:"terrarium"`
+	Requests  map[string]string `json:"requests,omitempty"`
+	Warnings  []string          `json:"warnings,omitempty" example:"the bundle is exported by another version (v0.0.9)"`
+}
@@ -0,0 +1,20 @@
+package model
+
+// EngineVersion represents the IaC engine (e.g., OpenTofu or Terraform) selected to run the commands
+type EngineVersion struct {
+	Engine              string          `json:"engine" example:"opentofu"`
+	Binary              string          `json:"binary" example:"tofu"`
+	Version             SemanticVersion `json:"version"`
+	MinVersion          string          `json:"minVersion,omitempty" example:"1.6.0"`
+	SatisfiesMinVersion bool            `json:"satisfiesMinVersion" example:"true"`
+	Raw                 string          `json:"raw" example:"OpenTofu v1.8.0\non linux_amd64"`
+}
+
+// SemanticVersion represents a parsed semantic version
+type SemanticVersion struct {
+	Version    string `json:"version" example:"1.8.0"`
+	Major      int    `json:"major" example:"1"`
+	Minor      int    `json:"minor" example:"8"`
+	Patch      int    `json:"patch" example:"0"`
+	Prerelease string `json:"prerelease,omitempty" example:""`
+}
@@ -0,0 +1,20 @@
+package model
+
+// EnrichmentKind represents a kind of enrichment supported by mc-terrarium
+type EnrichmentKind struct {
+	Name       string        `json:"name" example:"sql-db"`
+	Title      string        `json:"title" example:"SQL database"`
+	Providers  []string      `json:"providers,omitempty" example:"aws,azure,gcp,ncp"`
+	OutputName string        `json:"outputName" example:"sql_db_info"`
+	TfVars     []TfVarsField `json:"tfVars"`
+}
+
+// TfVarsField represents a variable in the tfVars of an enrichment
+type TfVarsField struct {
+	Name     string `json:"name" example:"csp_region"`
+	Type     string `json:"type" example:"string"`
+	Required bool   `json:"required" example:"true"`
+	Example  string `json:"example,omitempty" example:"ap-northeast-2"`
+	// Rules of the value, which are checked when creating the infracode (e.g., region of the provider)
+	Rules string `json:"rules,omitempty" example:"required,region"`
+}
@@ -0,0 +1,70 @@
+package model
+
+// Codes of the errors in the failed responses, which are stable so that the clients can branch on them.
+// Each code is responded with a single HTTP status.
+const (
+	// 400 Bad Request
+	ErrCodeInvalidRequest  = "INVALID_REQUEST"
+	ErrCodeInvalidProvider = "INVALID_PROVIDER"
+
+	// 401 Unauthorized, 403 Forbidden
+	ErrCodeUnauthorized  = "UNAUTHORIZED"
+	ErrCodeForbidden     = "FORBIDDEN"
+	ErrCodeQuotaExceeded = "QUOTA_EXCEEDED"
+
+	// 404 Not Found
+	ErrCodeNotFound           = "NOT_FOUND"
+	ErrCodeTerrariumNotFound  = "TERRARIUM_NOT_FOUND"
+	ErrCodeEnrichmentNotFound = "ENRICHMENT_NOT_FOUND"
+	ErrCodeUnknownEnrichment  = "UNKNOWN_ENRICHMENT"
+	ErrCodeRequestNotFound    = "REQUEST_NOT_FOUND"
+	ErrCodePlanNotFound       = "PLAN_NOT_FOUND"
+	ErrCodeWebhookNotFound    = "WEBHOOK_NOT_FOUND"
+	ErrCodeDeliveryNotFound   = "DELIVERY_NOT_FOUND"
+
+	// 405 Method Not Allowed
+	ErrCodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+
+	// 409 Conflict
+	ErrCodeTerrariumExists   = "TERRARIUM_EXISTS"
+	ErrCodeTerrariumExpired  = "TERRARIUM_EXPIRED"
+	ErrCodeInvalidTransition = "INVALID_TRANSITION"
+	ErrCodeEnrichmentBusy    = "ENRICHMENT_BUSY"
+	ErrCodeResourcesRemain   = "RESOURCES_REMAIN"
+	ErrCodePlanStale         = "PLAN_STALE"
+	ErrCodeRequestNotRunning = "REQUEST_NOT_RUNNING"
+	ErrCodeRequestCancelled  = "REQUEST_CANCELLED"
+	ErrCodeRevisionConflict  = "REVISION_CONFLICT"
+	ErrCodeTerrariumLocked   = "TERRARIUM_LOCKED"
+	ErrCodeRequestIdInUse    = "REQUEST_ID_IN_USE"
+	// The request with the same Idempotency-Key is still in progress
+	ErrCodeIdempotencyKeyInProgress = "IDEMPOTENCY_KEY_IN_PROGRESS"
+
+	// 412 Precondition Failed
+	ErrCodePreconditionFailed = "PRECONDITION_FAILED"
+
+	// 415 Unsupported Media Type
+	ErrCodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+
+	// 422 Unprocessable Entity
+	ErrCodeInvalidTfVars = "INVALID_TFVARS"
+	ErrCodeInvalidBundle = "INVALID_BUNDLE"
+	// The Idempotency-Key has been used by a different request
+	ErrCodeIdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED"
+
+	// 429 Too Many Requests
+	ErrCodeTooManyRequests = "TOO_MANY_REQUESTS"
+
+	// 500 Internal Server Error
+	ErrCodeTofuInitFailed    = "TOFU_INIT_FAILED"
+	ErrCodeTofuPlanFailed    = "TOFU_PLAN_FAILED"
+	ErrCodeTofuApplyFailed   = "TOFU_APPLY_FAILED"
+	ErrCodeTofuDestroyFailed = "TOFU_DESTROY_FAILED"
+	ErrCodeTofuCommandFailed = "TOFU_COMMAND_FAILED"
+	ErrCodeInternal          = "INTERNAL_ERROR"
+
+	// 503 Service Unavailable
+	ErrCodeQueueFull         = "QUEUE_FULL"
+	ErrCodeNotReady          = "NOT_READY"
+	ErrCodeEngineUnavailable = "ENGINE_UNAVAILABLE"
+)
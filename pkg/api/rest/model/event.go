@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// TerrariumEvent represents a notable event of a terrarium (e.g., it is expiring, an enrichment moved to another state)
+type TerrariumEvent struct {
+	Type        string    `json:"type" example:"terrarium.expiring"`
+	TerrariumId string    `json:"terrariumId" example:"tr01"`
+	Tenant      string    `json:"tenant,omitempty" example:"default"`
+	Enrichments string    `json:"enrichments,omitempty" example:"sql-db"`
+	From        string    `json:"from,omitempty" example:"Applying"`
+	To          string    `json:"to,omitempty" example:"Applied"`
+	Message     string    `json:"message" example:"the terrarium (trId: tr01) expires at 2024-01-04T00:00:00Z"`
+	Time        time.Time `json:"time" example:"2024-01-03T23:00:00Z"`
+}
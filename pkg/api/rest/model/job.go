@@ -0,0 +1,73 @@
+package model
+
+import "time"
+
+// JobInfo represents the record of a tofu command execution requested by a request ID
+type JobInfo struct {
+	Id            string              `json:"id" example:"1712345678901234567"`
+	TerrariumId   string              `json:"terrariumId" example:"tr01"`
+	Enrichments   string              `json:"enrichments" example:"sql-db"`
+	Command       string              `json:"command" example:"tofu"`
+	Subcommand    string              `json:"subcommand" example:"apply"`
+	Args          []string            `json:"args,omitempty" example:"-auto-approve"`
+	Status        string              `json:"status" example:"Running"`
+	Server        string              `json:"server,omitempty" example:"mc-terrarium-7d9f-1a2b3c4d"`
+	QueuePosition int                 `json:"queuePosition,omitempty" example:"1"`
+	QueuedAt      time.Time           `json:"queuedAt" example:"2024-01-01T00:00:00Z"`
+	StartedAt     *time.Time          `json:"startedAt,omitempty" example:"2024-01-01T00:00:00Z"`
+	EndedAt       *time.Time          `json:"endedAt,omitempty" example:"2024-01-01T00:10:00Z"`
+	ExitCode      *int                `json:"exitCode,omitempty" example:"0"`
+	Error         string              `json:"error,omitempty" example:""`
+	Diagnostics   []DiagnosticMessage `json:"diagnostics,omitempty"`
+	LogPath       string              `json:"logPath,omitempty" example:"/app/.terrarium/tr01/sql-db/runningLogs/1712345678901234567.log"`
+	Progress      *JobProgress        `json:"progress,omitempty"`
+	StateLock     *StateLock          `json:"stateLock,omitempty"`
+}
+
+// StateLock represents the lock of a tofu state left by an interrupted job
+type StateLock struct {
+	Id        string `json:"id" example:"2f4e3c2a-1b2c-3d4e-5f60-718293a4b5c6"`
+	Operation string `json:"operation,omitempty" example:"OperationTypeApply"`
+	Who       string `json:"who,omitempty" example:"root@mc-terrarium"`
+	Created   string `json:"created,omitempty" example:"2024-01-01T00:00:00Z"`
+	Path      string `json:"path,omitempty" example:"terraform.tfstate"`
+}
+
+// JobProgress represents the progress of a tofu command parsed from its machine-readable (-json) output
+type JobProgress struct {
+	Operation          string              `json:"operation" example:"apply"`
+	ResourcesTotal     int                 `json:"resourcesTotal" example:"10"`
+	ResourcesCompleted int                 `json:"resourcesCompleted" example:"4"`
+	ResourcesFailed    int                 `json:"resourcesFailed" example:"0"`
+	CurrentResource    string              `json:"currentResource,omitempty" example:"aws_vpn_gateway.vpn_gw"`
+	ChangeSummary      *ChangeSummary      `json:"changeSummary,omitempty"`
+	Resources          []ResourceProgress  `json:"resources,omitempty"`
+	Diagnostics        []DiagnosticMessage `json:"diagnostics,omitempty"`
+}
+
+// ChangeSummary represents the counts of the changes planned or applied by a tofu command
+type ChangeSummary struct {
+	Operation string `json:"operation" example:"apply"`
+	Add       int    `json:"add" example:"10"`
+	Change    int    `json:"change" example:"0"`
+	Remove    int    `json:"remove" example:"0"`
+	Import    int    `json:"import" example:"0"`
+}
+
+// ResourceProgress represents the progress of a resource being changed by a tofu command
+type ResourceProgress struct {
+	Address        string     `json:"address" example:"aws_vpn_gateway.vpn_gw"`
+	Action         string     `json:"action" example:"create"`
+	Status         string     `json:"status" example:"InProgress"`
+	StartedAt      time.Time  `json:"startedAt" example:"2024-01-01T00:00:00Z"`
+	EndedAt        *time.Time `json:"endedAt,omitempty" example:"2024-01-01T00:01:00Z"`
+	ElapsedSeconds float64    `json:"elapsedSeconds" example:"60"`
+}
+
+// DiagnosticMessage represents a diagnostic (i.e., error or warning) reported by a tofu command
+type DiagnosticMessage struct {
+	Severity string `json:"severity" example:"error"`
+	Summary  string `json:"summary" example:"Error creating VPN Gateway"`
+	Detail   string `json:"detail,omitempty" example:""`
+	Address  string `json:"address,omitempty" example:"aws_vpn_gateway.vpn_gw"`
+}
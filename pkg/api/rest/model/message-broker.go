@@ -3,8 +3,8 @@ package model
 // TfVarsMessageBroker represents the configuration structure based on the Terraform variables
 type TfVarsMessageBroker struct {
 	TerrariumID string `json:"terrarium_id" default:"" example:""`
-	CSPRegion   string `json:"csp_region" example:"ap-northeast-2"`
-	CSPVNetID   string `json:"csp_vnet_id,omitempty" example:"vpc-12345678"`
+	CSPRegion   string `json:"csp_region" validate:"required,region" example:"ap-northeast-2"`
+	CSPVNetID   string `json:"csp_vnet_id,omitempty" validate:"omitempty,vpc_id" example:"vpc-12345678"`
 	// CSPResourceGroup string `json:"csp_resource_group,omitempty" example:"koreacentral"`
 }
 
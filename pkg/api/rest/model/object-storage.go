@@ -3,8 +3,8 @@ package model
 // TfVarsObjectStorage represents the configuration structure based on the Terraform variables
 type TfVarsObjectStorage struct {
 	TerrariumID      string `json:"terrarium_id" default:"" example:""`
-	CSPRegion        string `json:"csp_region" example:"ap-northeast-2"`
-	CSPResourceGroup string `json:"csp_resource_group,omitempty" example:"koreacentral"`
+	CSPRegion        string `json:"csp_region" validate:"required,region" example:"ap-northeast-2"`
+	CSPResourceGroup string `json:"csp_resource_group,omitempty" validate:"omitempty,max=90" example:"koreacentral"`
 }
 
 // OutputObjectStorageInfo represents the Object Storage information structure
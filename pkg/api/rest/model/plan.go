@@ -0,0 +1,33 @@
+package model
+
+// PlanSummary represents the structured summary of a saved plan of tofu
+type PlanSummary struct {
+	PlanId          string                  `json:"planId" example:"1712345678901234567"`
+	Add             int                     `json:"add" example:"3"`
+	Change          int                     `json:"change" example:"1"`
+	Destroy         int                     `json:"destroy" example:"0"`
+	HasDestroy      bool                    `json:"hasDestroy" example:"false"`
+	ResourceChanges []PlannedResourceChange `json:"resourceChanges"`
+}
+
+// PlannedResourceChange represents a change of a resource in a plan
+type PlannedResourceChange struct {
+	Address           string            `json:"address" example:"aws_vpn_gateway.vpn_gw"`
+	Mode              string            `json:"mode" example:"managed"`
+	Type              string            `json:"type" example:"aws_vpn_gateway"`
+	Name              string            `json:"name" example:"vpn_gw"`
+	ProviderName      string            `json:"providerName" example:"registry.opentofu.org/hashicorp/aws"`
+	Action            string            `json:"action" example:"create" enums:"create,update,delete,replace,read"`
+	Actions           []string          `json:"actions" example:"create"`
+	ChangedAttributes []AttributeChange `json:"changedAttributes,omitempty"`
+}
+
+// AttributeChange represents a changed attribute of a resource in a plan.
+// Sensitive values are masked and unknown values are shown as "(known after apply)".
+type AttributeChange struct {
+	Name      string      `json:"name" example:"tags"`
+	Before    interface{} `json:"before,omitempty"`
+	After     interface{} `json:"after,omitempty"`
+	Sensitive bool        `json:"sensitive,omitempty" example:"false"`
+	Unknown   bool        `json:"unknown,omitempty" example:"false"`
+}
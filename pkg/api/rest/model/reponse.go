@@ -21,11 +21,16 @@ package model
 // 	Object  map[string]interface{} `json:"object"`
 // }
 
+// Response is the response of a request, where a failed one has the code of the error (see ErrCodeInvalidRequest),
+// the diagnostics reported by the tofu command if it failed, and the invalid fields if the tfVars are invalid.
 type Response struct {
-	Success bool                   `json:"success" example:"true"`
-	Status  int                    `json:"status,omitempty" example:"200"`
-	Message string                 `json:"message" example:"Any message"`
-	Detail  string                 `json:"details,omitempty" example:"Any details"`
-	Object  map[string]interface{} `json:"object,omitempty"`
-	List    []interface{}          `json:"list,omitempty"`
+	Success     bool                   `json:"success" example:"true"`
+	Status      int                    `json:"status,omitempty" example:"200"`
+	Code        string                 `json:"code,omitempty" example:"TERRARIUM_NOT_FOUND"`
+	Message     string                 `json:"message" example:"Any message"`
+	Detail      string                 `json:"details,omitempty" example:"Any details"`
+	Diagnostics []DiagnosticMessage    `json:"diagnostics,omitempty"`
+	Errors      []FieldError           `json:"errors,omitempty"`
+	Object      map[string]interface{} `json:"object,omitempty"`
+	List        []interface{}          `json:"list,omitempty"`
 }
@@ -1,31 +1,13 @@
 package model
 
-// Reqeust bodies for GCP-AWS VPN
-type CreateInfracodeOfGcpAwsVpnRequest struct {
-	TfVars TfVarsGcpAwsVpnTunnel `json:"tfVars"`
-}
+import "encoding/json"
 
-// Reqeust bodies for GCP-Azure VPN
-type CreateInfracodeOfGcpAzureVpnRequest struct {
-	TfVars TfVarsGcpAzureVpnTunnel `json:"tfVars"`
+// Request body for an enrichment, where the tfVars is by the enrichment (see GET /enrichments)
+type CreateInfracodeOfEnrichmentRequest struct {
+	TfVars json.RawMessage `json:"tfVars" swaggertype:"object"`
 }
 
 // Reqeust bodies for test-env
 type CreateInfracodeOfTestEnvRequest struct {
 	TfVars TfVarsTestEnv `json:"tfVars"`
 }
-
-// Request body for sql-db
-type CreateInfracodeOfSqlDbRequest struct {
-	TfVars TfVarsSqlDb `json:"tfVars"`
-}
-
-// Request body for object-storage
-type CreateInfracodeOfObjectStorageRequest struct {
-	TfVars TfVarsMessageBroker `json:"tfVars"`
-}
-
-// Request body for message-broker
-type CreateInfracodeOfMessageBrokerRequest struct {
-	TfVars TfVarsMessageBroker `json:"tfVars"`
-}
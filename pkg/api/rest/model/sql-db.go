@@ -3,18 +3,18 @@ package model
 // TfVarsSqlDb represents the configuration structure based on the Terraform variables
 type TfVarsSqlDb struct {
 	TerrariumID      string `json:"terrarium_id" default:"" example:""`
-	CSPRegion        string `json:"csp_region" example:"ap-northeast-2"`
-	CSPResourceGroup string `json:"csp_resource_group,omitempty" example:"rg-12345678"`
-	CSPVNetID        string `json:"csp_vnet_id,omitempty" example:"vpc-12345678"`
-	CSPSubnet1ID     string `json:"csp_subnet1_id,omitempty" example:"subnet-1234abcd"`
-	CSPSubnet2ID     string `json:"csp_subnet2_id,omitempty" example:"subnet-abcd1234"`
-	DBEnginePort     int    `json:"db_engine_port,omitempty" example:"3306"`
-	IngressCIDRBlock string `json:"ingress_cidr_block,omitempty" example:"0.0.0.0/0"`
-	EgressCIDRBlock  string `json:"egress_cidr_block,omitempty" example:"0.0.0.0/0"`
+	CSPRegion        string `json:"csp_region" validate:"required,region" example:"ap-northeast-2"`
+	CSPResourceGroup string `json:"csp_resource_group,omitempty" validate:"omitempty,max=90" example:"rg-12345678"`
+	CSPVNetID        string `json:"csp_vnet_id,omitempty" validate:"required_for=aws,omitempty,vpc_id" example:"vpc-12345678"`
+	CSPSubnet1ID     string `json:"csp_subnet1_id,omitempty" validate:"required_for=aws ncp,omitempty,subnet_id" example:"subnet-1234abcd"`
+	CSPSubnet2ID     string `json:"csp_subnet2_id,omitempty" validate:"required_for=aws,omitempty,subnet_id" example:"subnet-abcd1234"`
+	DBEnginePort     int    `json:"db_engine_port,omitempty" validate:"omitempty,port" example:"3306"`
+	IngressCIDRBlock string `json:"ingress_cidr_block,omitempty" validate:"omitempty,cidrv4" example:"0.0.0.0/0"`
+	EgressCIDRBlock  string `json:"egress_cidr_block,omitempty" validate:"omitempty,cidrv4" example:"0.0.0.0/0"`
 	DBEngineVersion  string `json:"db_engine_version,omitempty" example:"8.0.39"`
 	DBInstanceSpec   string `json:"db_instance_spec,omitempty" example:"db.t3.micro"`
-	DBAdminUsername  string `json:"db_admin_username" example:"mydbadmin"`
-	DBAdminPassword  string `json:"db_admin_password" example:"Password1234!"`
+	DBAdminUsername  string `json:"db_admin_username" validate:"required,max=63" example:"mydbadmin"`
+	DBAdminPassword  string `json:"db_admin_password" validate:"required,password" example:"Password1234!"`
 	// DBInstanceID     string `json:"db_instance_identifier" example:"mydbinstance"`
 }
 
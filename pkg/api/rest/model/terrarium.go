@@ -1,7 +1,47 @@
 package model
 
+import "time"
+
+// TerrariumInfo represents a terrarium and the enrichments in it, keyed by the name of each enrichment.
+// The ttl (e.g., 72h, 7d) sets expiresAt when issuing, and the expired terrarium is destroyed and erased.
+// The tenant is the one of the caller issuing it, and it is empty for the terrariums issued before the tenants.
 type TerrariumInfo struct {
-	Id          string `json:"id" default:"tr01" example:"tr01" validate:"required"`
-	Description string `json:"description,omitempty" default:"This terrarium enriches ..." example:"This terrarium enriches ..."`
-	Enrichments string `json:"enrichments,omitempty"`
+	Id             string                    `json:"id,omitempty" default:"tr01" example:"tr01"`
+	Tenant         string                    `json:"tenant,omitempty" example:"team-a" readonly:"true"`
+	Description    string                    `json:"description,omitempty" default:"This terrarium enriches ..." example:"This terrarium enriches ..."`
+	Labels         map[string]string         `json:"labels,omitempty"`
+	Enrichments    map[string]EnrichmentInfo `json:"enrichments,omitempty" readonly:"true"`
+	CreatedBy      string                    `json:"createdBy,omitempty" example:"default" readonly:"true"`
+	CreatedAt      time.Time                 `json:"createdAt" example:"2024-01-01T00:00:00Z" readonly:"true"`
+	UpdatedAt      time.Time                 `json:"updatedAt" example:"2024-01-01T00:00:00Z" readonly:"true"`
+	Ttl            string                    `json:"ttl,omitempty" example:"72h"`
+	ExpiresAt      *time.Time                `json:"expiresAt,omitempty" example:"2024-01-04T00:00:00Z"`
+	ExpiryWarnedAt *time.Time                `json:"expiryWarnedAt,omitempty" example:"2024-01-03T23:00:00Z" readonly:"true"`
+}
+
+// ExtendTtlRequest represents a request to extend the time to live of a terrarium
+// by a duration from the current expiration, or to set a new expiration time
+type ExtendTtlRequest struct {
+	Ttl       string     `json:"ttl,omitempty" example:"24h"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" example:"2024-01-05T00:00:00Z"`
+}
+
+// EnrichmentInfo represents an enrichment (e.g., a VPN, a SQL database) in a terrarium.
+// The state is one of the lifecycle: Empty, Initialized, Configured, Planned, Applying, Applied, Destroying, Destroyed and Failed.
+type EnrichmentInfo struct {
+	Name        string    `json:"name" example:"vpn/gcp-aws"`
+	Kind        string    `json:"kind" example:"vpn"`
+	Provider    string    `json:"provider,omitempty" example:"gcp-aws"`
+	WorkingDir  string    `json:"workingDir" example:".terrarium/tr01/vpn/gcp-aws"`
+	State       string    `json:"state" example:"Applied"`
+	StateReason string    `json:"stateReason,omitempty" example:"the request (reqId: 1712345678901234567) is Failed"`
+	CreatedAt   time.Time `json:"createdAt" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt   time.Time `json:"updatedAt" example:"2024-01-01T00:00:00Z"`
+}
+
+// EnrichmentResources represents the resources remaining in the state of an enrichment
+type EnrichmentResources struct {
+	Enrichments string   `json:"enrichments" example:"vpn/gcp-aws"`
+	State       string   `json:"state,omitempty" example:"Applied"`
+	Resources   []string `json:"resources" example:"aws_vpn_gateway.vpn_gw"`
 }
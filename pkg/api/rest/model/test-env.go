@@ -1,8 +1,8 @@
 package model
 
 type TfVarsTestEnv struct {
-	AzureRegion            string `json:"azure-region" default:"koreacentral" example:"koreacentral"`
-	AzureResourceGroupName string `json:"azure-resource-group-name" default:"tr-rg-01" example:"tr-rg-01"`
-	GcpRegion              string `json:"gcp-region" default:"asia-northeast3" example:"asia-northeast3"`
-	AwsRegion              string `json:"aws-region" default:"ap-northeast-2" example:"ap-northeast-2"`
+	AzureRegion            string `json:"azure-region" validate:"required,region=azure" default:"koreacentral" example:"koreacentral"`
+	AzureResourceGroupName string `json:"azure-resource-group-name" validate:"required,max=90" default:"tr-rg-01" example:"tr-rg-01"`
+	GcpRegion              string `json:"gcp-region" validate:"required,region=gcp" default:"asia-northeast3" example:"asia-northeast3"`
+	AwsRegion              string `json:"aws-region" validate:"required,region=aws" default:"ap-northeast-2" example:"ap-northeast-2"`
 }
@@ -0,0 +1,265 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError represents an invalid field of the tfVars with the rule it violates
+type FieldError struct {
+	Field   string `json:"field" example:"csp_region"`
+	Rule    string `json:"rule" example:"region"`
+	Message string `json:"message" example:"must be a region of aws, not 'ap-northeast-9'"`
+}
+
+// ValidationError is returned when the tfVars violate the rules by the validate tags.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+" "+f.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Regions of each provider, which are extended when a provider opens a new region.
+// A region of Azure is also accepted by its display name (e.g., Korea Central).
+var Regions = map[string][]string{
+	"aws": {
+		"af-south-1", "ap-east-1", "ap-east-2", "ap-northeast-1", "ap-northeast-2", "ap-northeast-3",
+		"ap-south-1", "ap-south-2", "ap-southeast-1", "ap-southeast-2", "ap-southeast-3", "ap-southeast-4",
+		"ap-southeast-5", "ap-southeast-7", "ca-central-1", "ca-west-1", "cn-north-1", "cn-northwest-1",
+		"eu-central-1", "eu-central-2", "eu-north-1", "eu-south-1", "eu-south-2", "eu-west-1", "eu-west-2",
+		"eu-west-3", "il-central-1", "me-central-1", "me-south-1", "mx-central-1", "sa-east-1",
+		"us-east-1", "us-east-2", "us-gov-east-1", "us-gov-west-1", "us-west-1", "us-west-2",
+	},
+	"azure": {
+		"australiacentral", "australiacentral2", "australiaeast", "australiasoutheast", "brazilsouth",
+		"brazilsoutheast", "canadacentral", "canadaeast", "centralindia", "centralus", "chilecentral",
+		"eastasia", "eastus", "eastus2", "francecentral", "francesouth", "germanynorth", "germanywestcentral",
+		"indonesiacentral", "israelcentral", "italynorth", "japaneast", "japanwest", "jioindiacentral",
+		"jioindiawest", "koreacentral", "koreasouth", "malaysiawest", "mexicocentral", "newzealandnorth",
+		"northcentralus", "northeurope", "norwayeast", "norwaywest", "polandcentral", "qatarcentral",
+		"southafricanorth", "southafricawest", "southcentralus", "southeastasia", "southindia", "spaincentral",
+		"swedencentral", "switzerlandnorth", "switzerlandwest", "uaecentral", "uaenorth", "uksouth", "ukwest",
+		"westcentralus", "westeurope", "westindia", "westus", "westus2", "westus3",
+	},
+	"gcp": {
+		"africa-south1", "asia-east1", "asia-east2", "asia-northeast1", "asia-northeast2", "asia-northeast3",
+		"asia-south1", "asia-south2", "asia-southeast1", "asia-southeast2", "australia-southeast1",
+		"australia-southeast2", "europe-central2", "europe-north1", "europe-north2", "europe-southwest1",
+		"europe-west1", "europe-west2", "europe-west3", "europe-west4", "europe-west6", "europe-west8",
+		"europe-west9", "europe-west10", "europe-west12", "me-central1", "me-central2", "me-west1",
+		"northamerica-northeast1", "northamerica-northeast2", "northamerica-south1", "southamerica-east1",
+		"southamerica-west1", "us-central1", "us-east1", "us-east4", "us-east5", "us-south1",
+		"us-west1", "us-west2", "us-west3", "us-west4",
+	},
+	"ncp": {"KR", "JPN", "SGN", "USWN", "DEN", "UK"},
+}
+
+// Formats of the IDs of the VPCs and the subnets of each provider, which are not checked for the others
+var (
+	vpcIdPatterns = map[string]*regexp.Regexp{
+		"aws": regexp.MustCompile(`^vpc-([0-9a-f]{8}|[0-9a-f]{17})$`),
+	}
+	subnetIdPatterns = map[string]*regexp.Regexp{
+		"aws": regexp.MustCompile(`^subnet-([0-9a-f]{8}|[0-9a-f]{17})$`),
+		"ncp": regexp.MustCompile(`^[0-9]+$`),
+	}
+	idExamples = map[string]string{
+		"vpc_id/aws":    "vpc-0123456789abcdef0",
+		"subnet_id/aws": "subnet-0123456789abcdef0",
+		"subnet_id/ncp": "12345",
+	}
+)
+
+// Password policy of the database admin, which is the common one of the providers
+// (e.g., Azure requires three of the categories, and AWS disallows '/', '"', '@' and spaces)
+const (
+	minPasswordLength      = 8
+	maxPasswordLength      = 128
+	minPasswordCategories  = 3
+	disallowedPasswordRune = "/\"'@ "
+)
+
+// Key of the provider in the context of a validation
+type providerKey struct{}
+
+var validate = newValidator()
+
+// newValidator returns the validator of the tfVars with the custom rules,
+// where a field is named by its JSON name (e.g., csp_region).
+func newValidator() *validator.Validate {
+
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	// Rules by the provider, which is the param (e.g., region=aws) or the provider of the enrichment
+	_ = v.RegisterValidationCtx("region", isRegion)
+	_ = v.RegisterValidationCtx("vpc_id", isVpcId)
+	_ = v.RegisterValidationCtx("subnet_id", isSubnetId)
+	_ = v.RegisterValidationCtx("required_for", isRequiredFor)
+	_ = v.RegisterValidation("port", isPort)
+	_ = v.RegisterValidation("password", isPassword)
+	return v
+}
+
+// ValidateTfVars checks the tfVars by the rules of their validate tags, and returns a *ValidationError
+// with the invalid fields. The provider (e.g., aws) is of the enrichment, which is empty if it has a single template.
+func ValidateTfVars(tfVars interface{}, provider string) error {
+
+	ctx := context.WithValue(context.Background(), providerKey{}, provider)
+	err := validate.StructCtx(ctx, tfVars)
+	if err == nil {
+		return nil
+	}
+
+	fieldErrs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	fields := make([]FieldError, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		fields = append(fields, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: fieldMessage(fe, provider),
+		})
+	}
+	return &ValidationError{Fields: fields}
+}
+
+// providerOf returns the provider of a rule, which is the param of the rule (e.g., region=aws)
+// or the provider of the enrichment.
+func providerOf(param, provider string) string {
+	if param != "" {
+		return param
+	}
+	return provider
+}
+
+// providerIn returns the provider of the enrichment in the context of a validation.
+func providerIn(ctx context.Context) string {
+	provider, _ := ctx.Value(providerKey{}).(string)
+	return provider
+}
+
+// fieldMessage returns the message of an invalid field of the tfVars of a provider,
+// which does not have the value of a password.
+func fieldMessage(fe validator.FieldError, provider string) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_for":
+		return fmt.Sprintf("is required for %s", provider)
+	case "region":
+		return fmt.Sprintf("must be a region of %s, not '%v'", providerOf(fe.Param(), provider), fe.Value())
+	case "vpc_id", "subnet_id":
+		p := providerOf(fe.Param(), provider)
+		return fmt.Sprintf("must be an ID of %s (e.g., %s), not '%v'", p, idExamples[fe.Tag()+"/"+p], fe.Value())
+	case "port":
+		return fmt.Sprintf("must be a port number (1-65535), not %v", fe.Value())
+	case "password":
+		return fmt.Sprintf("must be %d to %d characters from at least %d of uppercase letters, lowercase letters, numbers and symbols, "+
+			"without spaces, '/', '\"', \"'\" or '@'", minPasswordLength, maxPasswordLength, minPasswordCategories)
+	case "cidrv4":
+		return fmt.Sprintf("must be an IPv4 CIDR block (e.g., 10.0.0.0/16), not '%v'", fe.Value())
+	case "max":
+		return fmt.Sprintf("must be at most %s characters", fe.Param())
+	}
+	return fmt.Sprintf("must satisfy the rule (%s)", fe.Tag())
+}
+
+// isRegion checks if a field is a region of the provider, where any region is valid for an unknown provider.
+func isRegion(ctx context.Context, fl validator.FieldLevel) bool {
+	provider := providerOf(fl.Param(), providerIn(ctx))
+	regions, known := Regions[provider]
+	if !known {
+		return true
+	}
+	region := fl.Field().String()
+	if provider == "azure" {
+		region = strings.ToLower(strings.ReplaceAll(region, " ", ""))
+	}
+	for _, r := range regions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
+
+// isVpcId checks if a field is in the format of a VPC ID of the provider.
+func isVpcId(ctx context.Context, fl validator.FieldLevel) bool {
+	pattern, known := vpcIdPatterns[providerOf(fl.Param(), providerIn(ctx))]
+	return !known || pattern.MatchString(fl.Field().String())
+}
+
+// isSubnetId checks if a field is in the format of a subnet ID of the provider.
+func isSubnetId(ctx context.Context, fl validator.FieldLevel) bool {
+	pattern, known := subnetIdPatterns[providerOf(fl.Param(), providerIn(ctx))]
+	return !known || pattern.MatchString(fl.Field().String())
+}
+
+// isRequiredFor checks if a field is not empty when the provider of the enrichment is one of the params
+// (e.g., required_for=aws ncp).
+func isRequiredFor(ctx context.Context, fl validator.FieldLevel) bool {
+	provider := providerIn(ctx)
+	for _, p := range strings.Fields(fl.Param()) {
+		if p == provider {
+			return !fl.Field().IsZero()
+		}
+	}
+	return true
+}
+
+// isPort checks if a field is a port number.
+func isPort(fl validator.FieldLevel) bool {
+	port := fl.Field().Int()
+	return port >= 1 && port <= 65535
+}
+
+// isPassword checks if a field satisfies the password policy.
+func isPassword(fl validator.FieldLevel) bool {
+
+	password := fl.Field().String()
+	if n := len([]rune(password)); n < minPasswordLength || n > maxPasswordLength {
+		return false
+	}
+	if strings.ContainsAny(password, disallowedPasswordRune) {
+		return false
+	}
+
+	var upper, lower, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		case unicode.IsPrint(r):
+			symbol = 1
+		default:
+			return false
+		}
+	}
+	return upper+lower+digit+symbol >= minPasswordCategories
+}
@@ -0,0 +1,224 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testTfVars has a field of each custom rule, which is validated only if it is not empty.
+type testTfVars struct {
+	Region      string `json:"region,omitempty" validate:"omitempty,region"`
+	AwsRegion   string `json:"aws_region,omitempty" validate:"omitempty,region=aws"`
+	VpcId       string `json:"vpc_id,omitempty" validate:"omitempty,vpc_id"`
+	SubnetId    string `json:"subnet_id,omitempty" validate:"omitempty,subnet_id"`
+	Port        int    `json:"port,omitempty" validate:"omitempty,port"`
+	Cidr        string `json:"cidr,omitempty" validate:"omitempty,cidrv4"`
+	Password    string `json:"password,omitempty" validate:"omitempty,password"`
+	RequiredFor string `json:"required_for,omitempty" validate:"required_for=aws ncp"`
+}
+
+// invalidFields returns the invalid fields of the tfVars as "field/rule".
+func invalidFields(t *testing.T, tfVars testTfVars, provider string) []string {
+	t.Helper()
+
+	if tfVars.RequiredFor == "" {
+		tfVars.RequiredFor = "set"
+	}
+	err := ValidateTfVars(&tfVars, provider)
+	if err == nil {
+		return nil
+	}
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("ValidateTfVars() error = %v, want a *ValidationError", err)
+	}
+	var fields []string
+	for _, f := range validationErr.Fields {
+		fields = append(fields, f.Field+"/"+f.Rule)
+	}
+	return fields
+}
+
+func TestValidateRegion(t *testing.T) {
+	tests := []struct {
+		name     string
+		tfVars   testTfVars
+		provider string
+		want     []string
+	}{
+		{name: "aws", tfVars: testTfVars{Region: "ap-northeast-2"}, provider: "aws"},
+		{name: "aws unknown", tfVars: testTfVars{Region: "ap-northeast-9"}, provider: "aws", want: []string{"region/region"}},
+		{name: "aws region of gcp", tfVars: testTfVars{Region: "asia-northeast3"}, provider: "aws", want: []string{"region/region"}},
+		{name: "azure", tfVars: testTfVars{Region: "koreacentral"}, provider: "azure"},
+		{name: "azure display name", tfVars: testTfVars{Region: "Korea Central"}, provider: "azure"},
+		{name: "azure unknown", tfVars: testTfVars{Region: "koreanorth"}, provider: "azure", want: []string{"region/region"}},
+		{name: "gcp", tfVars: testTfVars{Region: "asia-northeast3"}, provider: "gcp"},
+		{name: "gcp region of aws", tfVars: testTfVars{Region: "ap-northeast-2"}, provider: "gcp", want: []string{"region/region"}},
+		{name: "ncp", tfVars: testTfVars{Region: "KR"}, provider: "ncp"},
+		{name: "ncp lowercase", tfVars: testTfVars{Region: "kr"}, provider: "ncp", want: []string{"region/region"}},
+		{name: "unknown provider", tfVars: testTfVars{Region: "anywhere-1"}, provider: "alibaba"},
+		{name: "param over provider", tfVars: testTfVars{AwsRegion: "us-east-1"}, provider: "azure"},
+		{name: "param over provider unknown", tfVars: testTfVars{AwsRegion: "eastus"}, provider: "azure", want: []string{"aws_region/region"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := invalidFields(t, tt.tfVars, tt.provider); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("invalid fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateIds(t *testing.T) {
+	tests := []struct {
+		name     string
+		tfVars   testTfVars
+		provider string
+		want     []string
+	}{
+		{name: "aws vpc short", tfVars: testTfVars{VpcId: "vpc-12345678"}, provider: "aws"},
+		{name: "aws vpc long", tfVars: testTfVars{VpcId: "vpc-0123456789abcdef0"}, provider: "aws"},
+		{name: "aws vpc length", tfVars: testTfVars{VpcId: "vpc-123456"}, provider: "aws", want: []string{"vpc_id/vpc_id"}},
+		{name: "aws vpc uppercase", tfVars: testTfVars{VpcId: "vpc-1234ABCD"}, provider: "aws", want: []string{"vpc_id/vpc_id"}},
+		{name: "aws vpc prefix", tfVars: testTfVars{VpcId: "subnet-12345678"}, provider: "aws", want: []string{"vpc_id/vpc_id"}},
+		{name: "gcp vpc unchecked", tfVars: testTfVars{VpcId: "my-network"}, provider: "gcp"},
+		{name: "aws subnet short", tfVars: testTfVars{SubnetId: "subnet-1234abcd"}, provider: "aws"},
+		{name: "aws subnet long", tfVars: testTfVars{SubnetId: "subnet-0123456789abcdef0"}, provider: "aws"},
+		{name: "aws subnet prefix", tfVars: testTfVars{SubnetId: "vpc-1234abcd"}, provider: "aws", want: []string{"subnet_id/subnet_id"}},
+		{name: "aws subnet not hex", tfVars: testTfVars{SubnetId: "subnet-1234abcz"}, provider: "aws", want: []string{"subnet_id/subnet_id"}},
+		{name: "ncp subnet", tfVars: testTfVars{SubnetId: "12345"}, provider: "ncp"},
+		{name: "ncp subnet of aws", tfVars: testTfVars{SubnetId: "subnet-1234abcd"}, provider: "ncp", want: []string{"subnet_id/subnet_id"}},
+		{name: "azure subnet unchecked", tfVars: testTfVars{SubnetId: "default"}, provider: "azure"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := invalidFields(t, tt.tfVars, tt.provider); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("invalid fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePortAndCidr(t *testing.T) {
+	tests := []struct {
+		name   string
+		tfVars testTfVars
+		want   []string
+	}{
+		{name: "port min", tfVars: testTfVars{Port: 1}},
+		{name: "port max", tfVars: testTfVars{Port: 65535}},
+		{name: "port negative", tfVars: testTfVars{Port: -1}, want: []string{"port/port"}},
+		{name: "port over max", tfVars: testTfVars{Port: 65536}, want: []string{"port/port"}},
+		{name: "cidr", tfVars: testTfVars{Cidr: "10.0.0.0/16"}},
+		{name: "cidr any", tfVars: testTfVars{Cidr: "0.0.0.0/0"}},
+		{name: "cidr without prefix", tfVars: testTfVars{Cidr: "10.0.0.0"}, want: []string{"cidr/cidrv4"}},
+		{name: "cidr prefix over 32", tfVars: testTfVars{Cidr: "10.0.0.0/33"}, want: []string{"cidr/cidrv4"}},
+		{name: "cidr ipv6", tfVars: testTfVars{Cidr: "::/0"}, want: []string{"cidr/cidrv4"}},
+		{name: "cidr not an address", tfVars: testTfVars{Cidr: "my-network/16"}, want: []string{"cidr/cidrv4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := invalidFields(t, tt.tfVars, ""); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("invalid fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{name: "all categories", password: "Password1234!"},
+		{name: "without symbols", password: "Password1234"},
+		{name: "without lowercase", password: "PASSWORD1234!"},
+		{name: "non-ascii letters", password: "Pässwörd1234"},
+		{name: "min length", password: "Passw0rd"},
+		{name: "max length", password: "Pa1" + strings.Repeat("x", maxPasswordLength-3)},
+		{name: "too short", password: "Pass12!", wantErr: true},
+		{name: "too long", password: "Pa1" + strings.Repeat("x", maxPasswordLength-2), wantErr: true},
+		{name: "two categories", password: "password1234", wantErr: true},
+		{name: "space", password: "Pass word1234", wantErr: true},
+		{name: "slash", password: "Pass/word1234", wantErr: true},
+		{name: "at", password: "Pass@word1234", wantErr: true},
+		{name: "double quote", password: "Pass\"word1234", wantErr: true},
+		{name: "single quote", password: "Pass'word1234", wantErr: true},
+		{name: "control character", password: "Pass\tword1234", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := invalidFields(t, testTfVars{Password: tt.password}, "")
+			if (len(got) > 0) != tt.wantErr {
+				t.Errorf("invalid fields = %v, wantErr %v", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRequiredFor(t *testing.T) {
+	tests := []struct {
+		provider string
+		want     []string
+	}{
+		{provider: "aws", want: []string{"required_for/required_for"}},
+		{provider: "ncp", want: []string{"required_for/required_for"}},
+		{provider: "azure"},
+		{provider: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			var validationErr *ValidationError
+			err := ValidateTfVars(&testTfVars{}, tt.provider)
+			var got []string
+			if errors.As(err, &validationErr) {
+				for _, f := range validationErr.Fields {
+					got = append(got, f.Field+"/"+f.Rule)
+				}
+			} else if err != nil {
+				t.Fatalf("ValidateTfVars() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("invalid fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// The message of an invalid field has the provider and the value, except the password.
+func TestFieldMessage(t *testing.T) {
+	tfVars := testTfVars{Region: "ap-northeast-9", VpcId: "vpc-1", Password: "secret", RequiredFor: "set"}
+	err := ValidateTfVars(&tfVars, "aws")
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("ValidateTfVars() error = %v, want a *ValidationError", err)
+	}
+
+	want := map[string]string{
+		"region": "must be a region of aws, not 'ap-northeast-9'",
+		"vpc_id": "must be an ID of aws (e.g., vpc-0123456789abcdef0), not 'vpc-1'",
+	}
+	for _, f := range validationErr.Fields {
+		if f.Field == "password" {
+			if strings.Contains(f.Message, tfVars.Password) {
+				t.Errorf("message of the password has the value: %s", f.Message)
+			}
+			continue
+		}
+		if f.Message != want[f.Field] {
+			t.Errorf("message of %s = %q, want %q", f.Field, f.Message, want[f.Field])
+		}
+	}
+	if len(validationErr.Fields) != 3 {
+		t.Errorf("invalid fields = %+v, want region, vpc_id and password", validationErr.Fields)
+	}
+}
@@ -2,22 +2,22 @@ package model
 
 type TfVarsGcpAwsVpnTunnel struct {
 	TerrariumId       string `json:"terrarium-id,omitempty" default:"" example:""`
-	AwsRegion         string `json:"aws-region" default:"ap-northeast-2" example:"ap-northeast-2"`
-	AwsVpcId          string `json:"aws-vpc-id" example:"vpc-xxxxx"`
-	AwsSubnetId       string `json:"aws-subnet-id" example:"subnet-xxxxx"`
-	GcpRegion         string `json:"gcp-region" default:"asia-northeast3" example:"asia-northeast3"`
-	GcpVpcNetworkName string `json:"gcp-vpc-network-name" default:"tr-gcp-vpc" example:"tr-gcp-vpc"`
+	AwsRegion         string `json:"aws-region" validate:"required,region=aws" default:"ap-northeast-2" example:"ap-northeast-2"`
+	AwsVpcId          string `json:"aws-vpc-id" validate:"required,vpc_id=aws" example:"vpc-12345678"`
+	AwsSubnetId       string `json:"aws-subnet-id" validate:"required,subnet_id=aws" example:"subnet-1234abcd"`
+	GcpRegion         string `json:"gcp-region" validate:"required,region=gcp" default:"asia-northeast3" example:"asia-northeast3"`
+	GcpVpcNetworkName string `json:"gcp-vpc-network-name" validate:"required,max=63" default:"tr-gcp-vpc" example:"tr-gcp-vpc"`
 	// GcpBgpAsn                   string `json:"gcp-bgp-asn" default:"65530"`
 }
 
 type TfVarsGcpAzureVpnTunnel struct {
 	TerrariumId                 string `json:"terrarium-id,omitempty" default:"" example:""`
-	AzureRegion                 string `json:"azure-region" default:"koreacentral" example:"koreacentral"`
-	AzureResourceGroupName      string `json:"azure-resource-group-name" default:"tr-rg-01" example:"tr-rg-01"`
-	AzureVirtualNetworkName     string `json:"azure-virtual-network-name" default:"tr-azure-vnet" example:"tr-azure-vnet"`
-	AzureGatewaySubnetCidrBlock string `json:"azure-gateway-subnet-cidr-block" default:"192.168.130.0/24" example:"192.168.130.0/24"`
-	GcpRegion                   string `json:"gcp-region" default:"asia-northeast3" example:"asia-northeast3"`
-	GcpVpcNetworkName           string `json:"gcp-vpc-network-name" default:"tr-gcp-vpc" example:"tr-gcp-vpc"`
+	AzureRegion                 string `json:"azure-region" validate:"required,region=azure" default:"koreacentral" example:"koreacentral"`
+	AzureResourceGroupName      string `json:"azure-resource-group-name" validate:"required,max=90" default:"tr-rg-01" example:"tr-rg-01"`
+	AzureVirtualNetworkName     string `json:"azure-virtual-network-name" validate:"required,max=64" default:"tr-azure-vnet" example:"tr-azure-vnet"`
+	AzureGatewaySubnetCidrBlock string `json:"azure-gateway-subnet-cidr-block" validate:"required,cidrv4" default:"192.168.130.0/24" example:"192.168.130.0/24"`
+	GcpRegion                   string `json:"gcp-region" validate:"required,region=gcp" default:"asia-northeast3" example:"asia-northeast3"`
+	GcpVpcNetworkName           string `json:"gcp-vpc-network-name" validate:"required,max=63" default:"tr-gcp-vpc" example:"tr-gcp-vpc"`
 	// AzureBgpAsn				 	string `json:"azure-bgp-asn" default:"65515"`
 	// GcpBgpAsn                   string `json:"gcp-bgp-asn" default:"65534"`
 	// AzureSubnetName             string `json:"azure-subnet-name" default:"tr-azure-subnet-0"`
@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// WebhookRequest represents a request to subscribe to the events by a webhook
+type WebhookRequest struct {
+	Url string `json:"url" example:"https://example.com/hooks/terrarium"`
+	// Types of the events to receive, where a type can end with '*' (e.g., job.*), and all the events if empty
+	Events      []string `json:"events,omitempty" example:"job.succeeded,job.failed,terrarium.expiring"`
+	Description string   `json:"description,omitempty" example:"notify the infra team"`
+	// Secret to sign the deliveries, which is generated if it is empty
+	Secret string `json:"secret,omitempty" example:""`
+}
+
+// Webhook represents a subscription to the events of all the terrariums (global) or a terrarium
+type Webhook struct {
+	Id          string   `json:"id" example:"wh-k3x9q2ab"`
+	Url         string   `json:"url" example:"https://example.com/hooks/terrarium"`
+	TerrariumId string   `json:"terrariumId,omitempty" example:"tr01"`
+	Tenant      string   `json:"tenant,omitempty" example:"default"`
+	Events      []string `json:"events,omitempty" example:"job.succeeded,job.failed,terrarium.expiring"`
+	Description string   `json:"description,omitempty" example:"notify the infra team"`
+	// Secret to sign the deliveries, which is only shown when the webhook is created
+	Secret    string    `json:"secret,omitempty" example:"6f1c2a..."`
+	CreatedAt time.Time `json:"createdAt" example:"2024-01-01T00:00:00Z"`
+}
+
+// WebhookEvent represents an event delivered to the webhooks
+type WebhookEvent struct {
+	Id          string    `json:"id" example:"ev-4f9a1c2e7b3d8a6f0e5c9b2d1a7f3e8c"`
+	Type        string    `json:"type" example:"job.succeeded"`
+	Time        time.Time `json:"time" example:"2024-01-01T00:10:00Z"`
+	TerrariumId string    `json:"terrariumId,omitempty" example:"tr01"`
+	Enrichments string    `json:"enrichments,omitempty" example:"sql-db"`
+	Message     string    `json:"message,omitempty" example:"the request (reqId: 1712345678901234567) to apply is Success"`
+	// Job of a job event (job.*)
+	Job *JobInfo `json:"job,omitempty"`
+	// States of an enrichment.state_changed event
+	From string `json:"from,omitempty" example:"Applying"`
+	To   string `json:"to,omitempty" example:"Applied"`
+}
+
+// WebhookDelivery represents the delivery of an event to a webhook with its attempts
+type WebhookDelivery struct {
+	Id             string       `json:"id" example:"ev-4f9a1c2e7b3d8a6f0e5c9b2d1a7f3e8c-wh-k3x9q2ab"`
+	WebhookId      string       `json:"webhookId" example:"wh-k3x9q2ab"`
+	Url            string       `json:"url" example:"https://example.com/hooks/terrarium"`
+	Event          WebhookEvent `json:"event"`
+	Status         string       `json:"status" example:"Delivered"`
+	Attempts       int          `json:"attempts" example:"1"`
+	LastStatusCode int          `json:"lastStatusCode,omitempty" example:"200"`
+	LastError      string       `json:"lastError,omitempty" example:""`
+	CreatedAt      time.Time    `json:"createdAt" example:"2024-01-01T00:10:00Z"`
+	LastAttemptAt  *time.Time   `json:"lastAttemptAt,omitempty" example:"2024-01-01T00:10:00Z"`
+	NextAttemptAt  *time.Time   `json:"nextAttemptAt,omitempty" example:"2024-01-01T00:10:02Z"`
+	DeliveredAt    *time.Time   `json:"deliveredAt,omitempty" example:"2024-01-01T00:10:00Z"`
+}
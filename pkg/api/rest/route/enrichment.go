@@ -0,0 +1,25 @@
+package route
+
+import (
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/handler"
+	"github.com/labstack/echo/v4"
+)
+
+// /terrarium/tr/:trId/:enrichment/... for the enrichments registered in pkg/enrichment,
+// where the name of an enrichment is one or two path segments (e.g., sql-db, vpn/gcp-aws)
+func RegisterRoutesForEnrichment(g *echo.Group) {
+	g.GET("/enrichments", handler.ListEnrichments)
+
+	for _, prefix := range []string{"/tr/:trId/:enrichment", "/tr/:trId/:enrichment/:variant"} {
+		g.POST(prefix+"/env", handler.InitEnvOfEnrichment)
+		g.DELETE(prefix+"/env", handler.ClearEnvOfEnrichment)
+		g.GET(prefix, handler.GetResourceInfoOfEnrichment)
+		g.POST(prefix+"/infracode", handler.CreateInfracodeOfEnrichment)
+		g.POST(prefix+"/plan", handler.CheckInfracodeOfEnrichment)
+		g.POST(prefix, handler.CreateEnrichment)
+		g.DELETE(prefix, handler.DestroyEnrichment)
+		g.GET(prefix+"/request/:requestId", handler.GetRequestStatusOfEnrichment)
+		g.GET(prefix+"/request/:requestId/logs", handler.GetRequestLogsOfEnrichment)
+		g.DELETE(prefix+"/request/:requestId", handler.CancelRequestOfEnrichment)
+	}
+}
@@ -9,6 +9,10 @@ import (
 func RegisterRoutesForRG(g *echo.Group) {
 	g.POST("/tr", handler.IssueTerrarium)
 	g.GET("/tr", handler.ReadAllTerrarium)
+	g.POST("/tr/import", handler.ImportTerrarium)
 	g.GET("/tr/:trId", handler.ReadTerrarium)
+	g.PATCH("/tr/:trId", handler.PatchTerrarium)
+	g.POST("/tr/:trId/ttl", handler.ExtendTerrariumTtl)
+	g.GET("/tr/:trId/export", handler.ExportTerrarium)
 	g.DELETE("/tr/:trId", handler.EraseTerrarium)
 }
@@ -15,4 +15,6 @@ func RegisterRoutesForTestEnv(g *echo.Group) {
 	g.POST("/test-env", handler.CreateTestEnv)
 	g.DELETE("/test-env", handler.DestroyTestEnv)
 	g.GET("/test-env/request/:requestId/status", handler.GetRequestStatusOfTestEnv)
+	g.GET("/test-env/request/:requestId/logs", handler.GetRequestLogsOfTestEnv)
+	g.DELETE("/test-env/request/:requestId", handler.CancelRequestOfTestEnv)
 }
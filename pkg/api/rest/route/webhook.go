@@ -0,0 +1,22 @@
+package route
+
+import (
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/handler"
+	"github.com/labstack/echo/v4"
+)
+
+// /terrarium/webhooks/... and /terrarium/tr/:trId/webhooks
+func RegisterRoutesForWebhook(g *echo.Group) {
+	g.POST("/webhooks", handler.CreateWebhook)
+	g.GET("/webhooks", handler.ReadAllWebhooks)
+	g.GET("/webhooks/:webhookId", handler.ReadWebhook)
+	g.DELETE("/webhooks/:webhookId", handler.DeleteWebhook)
+	g.POST("/webhooks/:webhookId/ping", handler.PingWebhook)
+	g.GET("/webhooks/:webhookId/deliveries", handler.ReadWebhookDeliveries)
+	g.GET("/webhooks/:webhookId/dead-letters", handler.ReadWebhookDeadLetters)
+	g.POST("/webhooks/:webhookId/dead-letters/:deliveryId/redeliver", handler.RedeliverWebhookDeadLetter)
+	g.DELETE("/webhooks/:webhookId/dead-letters/:deliveryId", handler.DeleteWebhookDeadLetter)
+
+	g.POST("/tr/:trId/webhooks", handler.CreateWebhookOfTerrarium)
+	g.GET("/tr/:trId/webhooks", handler.ReadAllWebhooksOfTerrarium)
+}
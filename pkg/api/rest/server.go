@@ -29,6 +29,9 @@ import (
 
 	// Black import (_) is for running a package's init() function without using its other contents.
 	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/cloud-barista/mc-terrarium/pkg/idempotency"
+	"github.com/cloud-barista/mc-terrarium/pkg/replica"
+	"github.com/cloud-barista/mc-terrarium/pkg/tenant"
 	"github.com/cloud-barista/mc-terrarium/pkg/terrarium"
 	"github.com/rs/zerolog/log"
 
@@ -36,6 +39,7 @@ import (
 	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/middlewares"
 	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/route"
 	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
+	"github.com/cloud-barista/mc-terrarium/pkg/webhook"
 
 	// REST API (echo)
 	"github.com/labstack/echo/v4"
@@ -86,34 +90,101 @@ const (
 // RunServer func start Rest API server
 func RunServer(port string) {
 
-	// Load and set tofu command utility
-	log.Info().Msg("Setting Tofu command utility")
-	if err := tofu.LoadRunningStatusMap(); err != nil {
-		log.Warn().Msg(err.Error())
+	// Open the store of terrarium info
+	log.Info().Msgf("open the store of terrarium info (type: %s)", config.NVL(config.Terrarium.Store.Type, "json"))
+	if err := terrarium.InitStore(); err != nil {
+		log.Fatal().Err(err).Msg("failed to open the store of terrarium info")
 	}
 
 	defer func() {
-		if err := tofu.SaveRunningStatusMap(); err != nil {
-			log.Error().Err(err).Msg("Failed to save running status map")
+		if err := terrarium.CloseStore(); err != nil {
+			log.Error().Err(err).Msg("failed to close the store of terrarium info")
+		}
+	}()
+
+	// Join the servers sharing the store, where the leader runs the background work of all the servers
+	replicaCtx, stopReplica := context.WithCancel(context.Background())
+	defer stopReplica()
+	if err := replica.Start(replicaCtx); err != nil {
+		log.Fatal().Err(err).Msg("failed to join the servers sharing the store")
+	}
+	defer func() {
+		if err := replica.Close(); err != nil {
+			log.Error().Err(err).Msg("failed to close the store of the servers")
 		}
 	}()
 
-	// Load and set terrarium info map
-	log.Info().Msg("load and set terrarium info map")
-	if err := terrarium.LoadTerrariumInfoMap(); err != nil {
-		log.Warn().Msg(err.Error())
+	// Load the tenants sharing this server
+	if err := tenant.Load(); err != nil {
+		log.Fatal().Err(err).Msg("failed to load the tenants")
 	}
+	if path := config.Terrarium.Tenant.File; path != "" {
+		log.Info().Msgf("loaded the tenants from %s", path)
+	}
+
+	// Update the lifecycle state of the enrichments by the result of each job
+	tofu.AddJobListener(terrarium.OnJobFinished)
 
+	// Deliver the events of the jobs and the terrariums to the webhooks until the server stops
+	webhookCtx, stopWebhook := context.WithCancel(context.Background())
+	defer stopWebhook()
+	if err := webhook.Start(webhookCtx); err != nil {
+		log.Fatal().Err(err).Msg("failed to start the webhooks")
+	}
 	defer func() {
-		if err := terrarium.SaveTerrariumInfoMap(); err != nil {
-			log.Error().Err(err).Msg("failed to save terrarium infor map")
+		if err := webhook.Close(); err != nil {
+			log.Error().Err(err).Msg("failed to close the store of the webhooks")
+		}
+	}()
+	tofu.AddJobStartedListener(webhook.OnJobStarted)
+	tofu.AddJobListener(webhook.OnJobFinished)
+	terrarium.AddEventListener(webhook.OnTerrariumEvent)
+
+	// Keep the responses of the requests by the Idempotency-Key header until the server stops
+	idempotencyCtx, stopIdempotency := context.WithCancel(context.Background())
+	defer stopIdempotency()
+	if err := idempotency.Start(idempotencyCtx); err != nil {
+		log.Fatal().Err(err).Msg("failed to keep the idempotency keys")
+	}
+	defer func() {
+		if err := idempotency.Close(); err != nil {
+			log.Error().Err(err).Msg("failed to close the store of the idempotency keys")
 		}
 	}()
 
+	// Load and set tofu command utility, which keeps the jobs until the server stops
+	log.Info().Msg("Setting Tofu command utility")
+	if err := tofu.InitExecutor(); err != nil {
+		log.Fatal().Err(err).Msg("failed to set the executor")
+	}
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if err := tofu.StartJobStore(jobCtx); err != nil {
+		log.Fatal().Err(err).Msg("failed to load the jobs")
+	}
+	defer func() {
+		if err := tofu.CloseJobStore(); err != nil {
+			log.Error().Err(err).Msg("failed to close the store of the jobs")
+		}
+	}()
+
+	// Mark the jobs left queued or running by the previous run (or the stopped servers sharing the store) as interrupted
+	if n := tofu.ReconcileJobs(); n > 0 {
+		log.Warn().Msgf("%d request(s) interrupted by the previous stop of the server", n)
+	}
+
+	// Reap the expired terrariums periodically until the server stops
+	reaperCtx, stopReaper := context.WithCancel(context.Background())
+	defer stopReaper()
+	terrarium.StartReaper(reaperCtx)
+
 	log.Info().Msg("Setting mc-terrarium REST API server")
 
 	e := echo.New()
 
+	// Respond the errors of echo and the middlewares (e.g., no route, unauthorized) with the error codes
+	e.HTTPErrorHandler = handler.HTTPErrorHandler
+
 	// Middleware
 	// e.Use(middleware.Logger()) // default logger middleware in echo
 
@@ -147,8 +218,9 @@ func RunServer(port string) {
 	}
 
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{allowedOrigins},
-		AllowMethods: []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete},
+		AllowOrigins:  []string{allowedOrigins},
+		AllowMethods:  []string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete},
+		ExposeHeaders: []string{echo.HeaderLocation, "X-Next-Cursor", "ETag", idempotency.HeaderReplayed},
 	}))
 
 	// Conditions to prevent abnormal operation due to typos (e.g., ture, falss, etc.)
@@ -173,11 +245,19 @@ func RunServer(port string) {
 					subtle.ConstantTimeCompare([]byte(password), []byte(apiPass)) == 1 {
 					return true, nil
 				}
+				// A tenant is scoped to its own terrariums (see middlewares.TenantScope)
+				if name, ok := tenant.Authenticate(username, password); ok {
+					c.Set(tenant.ContextKey, name)
+					return true, nil
+				}
 				return false, nil
 			},
 		}))
 	}
 
+	// Custom middleware to scope the terrariums to the tenant of the caller
+	e.Use(middlewares.TenantScope)
+
 	fmt.Print("\n ")
 	fmt.Print(banner)
 	fmt.Print("\n\n")
@@ -201,40 +281,17 @@ func RunServer(port string) {
 
 	// A terrarium group has /terrarium as prefix
 	groupTerrarium := e.Group("/terrarium")
+	// Replay the POST and DELETE requests retried with the same Idempotency-Key
+	groupTerrarium.Use(middlewares.Idempotency)
 	// Resource Group APIs
 	route.RegisterRoutesForTestEnv(groupTerrarium)
 	route.RegisterRoutesForRG(groupTerrarium)
-	route.RegisterRoutesForVPN(groupTerrarium)
-
-	// SQL database APIs
-	groupTerrarium.POST("/tr/:trId/sql-db/env", handler.InitEnvForSqlDb)
-	groupTerrarium.DELETE("/tr/:trId/sql-db/env", handler.ClearEnvOfSqlDb)
-	groupTerrarium.POST("/tr/:trId/sql-db/infracode", handler.CreateInfracodeForSqlDb)
-	groupTerrarium.POST("/tr/:trId/sql-db/plan", handler.CheckInfracodeForSqlDb)
-	groupTerrarium.POST("/tr/:trId/sql-db", handler.CreateSqlDb)
-	groupTerrarium.GET("/tr/:trId/sql-db", handler.GetResourceInfoOfSqlDb)
-	groupTerrarium.DELETE("/tr/:trId/sql-db", handler.DestroySqlDb)
-	groupTerrarium.GET("/tr/:trId/sql-db/request/:requestId", handler.GetRequestStatusOfSqlDb)
-
-	// Object Storage APIs
-	groupTerrarium.POST("/tr/:trId/object-storage/env", handler.InitEnvForObjectStorage)
-	groupTerrarium.DELETE("/tr/:trId/object-storage/env", handler.ClearEnvOfObjectStorage)
-	groupTerrarium.POST("/tr/:trId/object-storage/infracode", handler.CreateInfracodeForObjectStorage)
-	groupTerrarium.POST("/tr/:trId/object-storage/plan", handler.CheckInfracodeForObjectStorage)
-	groupTerrarium.POST("/tr/:trId/object-storage", handler.CreateObjectStorage)
-	groupTerrarium.GET("/tr/:trId/object-storage", handler.GetResourceInfoOfObjectStorage)
-	groupTerrarium.DELETE("/tr/:trId/object-storage", handler.DestroyObjectStorage)
-	groupTerrarium.GET("/tr/:trId/object-storage/request/:requestId", handler.GetRequestStatusOfObjectStorage)
-
-	// Message Broker APIs
-	groupTerrarium.POST("/tr/:trId/message-broker/env", handler.InitEnvForMessageBroker)
-	groupTerrarium.DELETE("/tr/:trId/message-broker/env", handler.ClearEnvOfMessageBroker)
-	groupTerrarium.POST("/tr/:trId/message-broker/infracode", handler.CreateInfracodeForMessageBroker)
-	groupTerrarium.POST("/tr/:trId/message-broker/plan", handler.CheckInfracodeForMessageBroker)
-	groupTerrarium.POST("/tr/:trId/message-broker", handler.CreateMessageBroker)
-	groupTerrarium.GET("/tr/:trId/message-broker", handler.GetResourceInfoOfMessageBroker)
-	groupTerrarium.DELETE("/tr/:trId/message-broker", handler.DestroyMessageBroker)
-	groupTerrarium.GET("/tr/:trId/message-broker/request/:requestId", handler.GetRequestStatusOfMessageBroker)
+
+	// Enrichment APIs (e.g., sql-db, vpn/gcp-aws) by the descriptors in pkg/enrichment
+	route.RegisterRoutesForEnrichment(groupTerrarium)
+
+	// Webhook APIs to receive the events of the jobs and the terrariums
+	route.RegisterRoutesForWebhook(groupTerrarium)
 
 	// Sample API group (for developers to add new API)
 	groupSample := groupTerrarium.Group("/sample")
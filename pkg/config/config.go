@@ -29,7 +29,12 @@ type TerrariumConfig struct {
 	LogWriter   string            `mapstructure:"logwriter"`
 	Node        NodeConfig        `mapstructure:"node"`
 	AutoControl AutoControlConfig `mapstructure:"autocontrol"`
+	Scheduler   SchedulerConfig   `mapstructure:"scheduler"`
+	Executor    ExecutorConfig    `mapstructure:"executor"`
+	Store       StoreConfig       `mapstructure:"store"`
 	Tumblebug   TumblebugConfig   `mapstructure:"tumblebug"`
+	Tenant      TenantConfig      `mapstructure:"tenant"`
+	Webhook     WebhookConfig     `mapstructure:"webhook"`
 	// LKVStore    LkvStoreConfig    `mapstructure:"lkvstore"`
 }
 
@@ -68,7 +73,43 @@ type NodeConfig struct {
 }
 
 type AutoControlConfig struct {
-	DurationMilliSec int `mapstructure:"duration_ms"`
+	DurationMilliSec    int `mapstructure:"duration_ms"`
+	ExpiryWarningMinute int `mapstructure:"expirywarning_min"`
+}
+
+type SchedulerConfig struct {
+	MaxWorkers   int `mapstructure:"maxworkers"`
+	MaxQueueSize int `mapstructure:"maxqueuesize"`
+}
+
+type ExecutorConfig struct {
+	Engine      string   `mapstructure:"engine"`
+	BinaryPath  string   `mapstructure:"binarypath"`
+	MinVersion  string   `mapstructure:"minversion"`
+	GlobalFlags []string `mapstructure:"globalflags"`
+	Env         []string `mapstructure:"env"`
+}
+
+type StoreConfig struct {
+	Type      string   `mapstructure:"type"`
+	Path      string   `mapstructure:"path"`
+	Endpoints []string `mapstructure:"endpoints"`
+	Username  string   `mapstructure:"username"`
+	Password  string   `mapstructure:"password"`
+	Prefix    string   `mapstructure:"prefix"`
+}
+
+type TenantConfig struct {
+	File           string `mapstructure:"file"`
+	MaxTerrariums  int    `mapstructure:"maxterrariums"`
+	MaxEnrichments int    `mapstructure:"maxenrichments"`
+}
+
+type WebhookConfig struct {
+	MaxAttempts     int  `mapstructure:"maxattempts"`
+	BackoffMilliSec int  `mapstructure:"backoff_ms"`
+	TimeoutMilliSec int  `mapstructure:"timeout_ms"`
+	AllowPrivate    bool `mapstructure:"allowprivate"`
 }
 
 type TumblebugConfig struct {
@@ -191,6 +232,27 @@ func bindEnvironmentVariables() {
 	viper.BindEnv("terrarium.logwriter", "TERRARIUM_LOGWRITER")
 	viper.BindEnv("terrarium.node.env", "TERRARIUM_NODE_ENV")
 	viper.BindEnv("terrarium.autocontrol.duration_ms", "TERRARIUM_AUTOCONTROL_DURATION_MS")
+	viper.BindEnv("terrarium.autocontrol.expirywarning_min", "TERRARIUM_AUTOCONTROL_EXPIRYWARNING_MIN")
+	viper.BindEnv("terrarium.scheduler.maxworkers", "TERRARIUM_SCHEDULER_MAXWORKERS")
+	viper.BindEnv("terrarium.scheduler.maxqueuesize", "TERRARIUM_SCHEDULER_MAXQUEUESIZE")
+	viper.BindEnv("terrarium.executor.engine", "TERRARIUM_EXECUTOR_ENGINE")
+	viper.BindEnv("terrarium.executor.binarypath", "TERRARIUM_EXECUTOR_BINARYPATH")
+	viper.BindEnv("terrarium.executor.minversion", "TERRARIUM_EXECUTOR_MINVERSION")
+	viper.BindEnv("terrarium.executor.globalflags", "TERRARIUM_EXECUTOR_GLOBALFLAGS")
+	viper.BindEnv("terrarium.executor.env", "TERRARIUM_EXECUTOR_ENV")
+	viper.BindEnv("terrarium.store.type", "TERRARIUM_STORE_TYPE")
+	viper.BindEnv("terrarium.store.path", "TERRARIUM_STORE_PATH")
+	viper.BindEnv("terrarium.store.endpoints", "TERRARIUM_STORE_ENDPOINTS")
+	viper.BindEnv("terrarium.store.username", "TERRARIUM_STORE_USERNAME")
+	viper.BindEnv("terrarium.store.password", "TERRARIUM_STORE_PASSWORD")
+	viper.BindEnv("terrarium.store.prefix", "TERRARIUM_STORE_PREFIX")
+	viper.BindEnv("terrarium.tenant.file", "TERRARIUM_TENANT_FILE")
+	viper.BindEnv("terrarium.tenant.maxterrariums", "TERRARIUM_TENANT_MAXTERRARIUMS")
+	viper.BindEnv("terrarium.tenant.maxenrichments", "TERRARIUM_TENANT_MAXENRICHMENTS")
+	viper.BindEnv("terrarium.webhook.maxattempts", "TERRARIUM_WEBHOOK_MAXATTEMPTS")
+	viper.BindEnv("terrarium.webhook.backoff_ms", "TERRARIUM_WEBHOOK_BACKOFF_MS")
+	viper.BindEnv("terrarium.webhook.timeout_ms", "TERRARIUM_WEBHOOK_TIMEOUT_MS")
+	viper.BindEnv("terrarium.webhook.allowprivate", "TERRARIUM_WEBHOOK_ALLOWPRIVATE")
 	viper.BindEnv("terrarium.tumblebug.endpoint", "TERRARIUM_TUMBLEBUG_ENDPOINT")
 	viper.BindEnv("terrarium.tumblebug.api.username", "TERRARIUM_TUMBLEBUG_API_USERNAME")
 	viper.BindEnv("terrarium.tumblebug.api.password", "TERRARIUM_TUMBLEBUG_API_PASSWORD")
@@ -0,0 +1,5 @@
+package config
+
+// Version of mc-terrarium, which can be set at build time
+// (e.g., -ldflags "-X github.com/cloud-barista/mc-terrarium/pkg/config.Version=v0.1.0").
+var Version = "latest"
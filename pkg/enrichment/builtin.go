@@ -0,0 +1,124 @@
+package enrichment
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
+)
+
+// Built-in enrichments with the templates in the templates directory
+func init() {
+
+	MustRegister(Descriptor{
+		Name:           "sql-db",
+		Title:          "SQL database",
+		Providers:      []string{"aws", "azure", "gcp", "ncp"},
+		OutputName:     "sql_db_info",
+		NewTfVars:      func() interface{} { return new(model.TfVarsSqlDb) },
+		TerrariumIdVar: "terrarium_id",
+		AfterInit:      onProvider("gcp", copyGcpCredentials),
+	})
+
+	MustRegister(Descriptor{
+		Name:           "object-storage",
+		Title:          "Object Storage",
+		Providers:      []string{"aws", "azure"},
+		OutputName:     "object_storage_info",
+		NewTfVars:      func() interface{} { return new(model.TfVarsObjectStorage) },
+		TerrariumIdVar: "terrarium_id",
+	})
+
+	MustRegister(Descriptor{
+		Name:           "message-broker",
+		Title:          "Message Broker",
+		Providers:      []string{"aws"},
+		OutputName:     "message_broker_info",
+		NewTfVars:      func() interface{} { return new(model.TfVarsMessageBroker) },
+		TerrariumIdVar: "terrarium_id",
+	})
+
+	MustRegister(Descriptor{
+		Name:           "vpn/gcp-aws",
+		Title:          "GCP to AWS VPN tunnels",
+		OutputName:     "vpn_info",
+		NewTfVars:      func() interface{} { return new(model.TfVarsGcpAwsVpnTunnel) },
+		TerrariumIdVar: "terrarium-id",
+		AfterInit:      copyGcpCredentials,
+		BeforeDestroy:  removeImportedRouteTable,
+	})
+
+	MustRegister(Descriptor{
+		Name:           "vpn/gcp-azure",
+		Title:          "GCP to Azure VPN tunnels",
+		OutputName:     "vpn_info",
+		NewTfVars:      func() interface{} { return new(model.TfVarsGcpAzureVpnTunnel) },
+		TerrariumIdVar: "terrarium-id",
+		AfterInit:      chain(copyGcpCredentials, copyAzureCredentials),
+	})
+}
+
+// onProvider returns a hook running only for a provider.
+func onProvider(provider string, hook Hook) Hook {
+	return func(t Target) error {
+		if t.Provider != provider {
+			return nil
+		}
+		return hook(t)
+	}
+}
+
+// chain returns a hook running the hooks in order until an error.
+func chain(hooks ...Hook) Hook {
+	return func(t Target) error {
+		for _, hook := range hooks {
+			if err := hook(t); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// copyGcpCredentials copies the GCP credentials to the working directory (always overwrite).
+func copyGcpCredentials(t Target) error {
+	if err := tofu.CopyGCPCredentials(filepath.Join(t.WorkingDir, "credential-gcp.json")); err != nil {
+		return fmt.Errorf("failed to copy gcp credentials: %w", err)
+	}
+	return nil
+}
+
+// copyAzureCredentials copies the Azure credentials to the working directory (always overwrite).
+func copyAzureCredentials(t Target) error {
+	if err := tofu.CopyAzureCredentials(filepath.Join(t.WorkingDir, "credential-azure.env")); err != nil {
+		return fmt.Errorf("failed to copy azure credentials: %w", err)
+	}
+	return nil
+}
+
+// removeImportedRouteTable removes the imported AWS route table from the state and imports.tf
+// not to destroy it, since it is the existing one of the VPC. It is skipped for the state
+// if the route table has been removed already (e.g., by a failed destroy before).
+func removeImportedRouteTable(t Target) error {
+	const address = "aws_route_table.imported_route_table"
+
+	resources, err := tofu.StateResources(t.WorkingDir)
+	if err != nil {
+		return err
+	}
+	for _, r := range resources {
+		if r != address {
+			continue
+		}
+		ret, err := tofu.ExecuteTofuCommand(t.TrId, t.Name, t.ReqId, "-chdir="+t.WorkingDir, "state", "rm", address)
+		if err != nil {
+			return fmt.Errorf("failed to remove the state of the imported resources (returned: %s): %w", ret, err)
+		}
+		break
+	}
+	if err := tofu.TruncateFile(filepath.Join(t.WorkingDir, "imports.tf")); err != nil {
+		return fmt.Errorf("failed to truncate imports.tf: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+// Package enrichment provides the registry of the enrichments (e.g., sql-db, vpn/gcp-aws) of a terrarium.
+// Each kind of enrichment is declared by a descriptor, which has the templates, the tfvars and the hooks,
+// and served by the generic handlers on /tr/:trId/:enrichment/... (see handler.InitEnvOfEnrichment).
+// So, adding a new enrichment is adding its templates and registering its descriptor.
+package enrichment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+)
+
+var (
+	// ErrUnknownEnrichment is returned when an enrichment is not registered.
+	ErrUnknownEnrichment = errors.New("not supported enrichment")
+	// ErrInvalidProvider is returned when a provider is not valid for an enrichment.
+	ErrInvalidProvider = errors.New("invalid provider")
+)
+
+// An enrichment name is one or two path segments (e.g., sql-db, vpn/gcp-aws)
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*(/[a-z][a-z0-9-]*)?$`)
+
+// Segments of the routes of a terrarium and an enrichment, which cannot be a segment of an enrichment name
+var reservedSegments = map[string]bool{
+	"env":       true,
+	"infracode": true,
+	"plan":      true,
+	"request":   true,
+	"ttl":       true,
+	"export":    true,
+	"import":    true,
+	"webhooks":  true,
+}
+
+// Target is an enrichment of a terrarium on which a hook runs.
+type Target struct {
+	TrId       string
+	Name       string
+	Provider   string
+	ReqId      string
+	WorkingDir string
+}
+
+// Hook runs on an enrichment at a step of its lifecycle, where an error stops the step.
+type Hook func(t Target) error
+
+// Descriptor declares a kind of enrichment.
+type Descriptor struct {
+	// Name in the path and the terrarium info (e.g., sql-db, vpn/gcp-aws)
+	Name string
+	// Title in the messages (e.g., SQL database)
+	Title string
+	// Valid providers by the provider query, each of which has its own templates in a subdirectory.
+	// The enrichment has a single template if it is empty (e.g., vpn/gcp-aws).
+	Providers []string
+	// Template directory relative to the templates directory (default: Name)
+	Template string
+	// Name of the output having the resource info (e.g., sql_db_info)
+	OutputName string
+	// NewTfVars returns a pointer to a new tfvars, which is decoded from the request and saved as terraform.tfvars.json
+	NewTfVars func() interface{}
+	// Variable (i.e., JSON name) of the terrarium ID in the tfvars, which is set by the path param if it is empty
+	TerrariumIdVar string
+	// AfterInit runs after copying the templates and before initializing (e.g., copy the credentials)
+	AfterInit Hook
+	// BeforeDestroy runs before destroying (e.g., remove the imported resources from the state)
+	BeforeDestroy Hook
+}
+
+// Registered descriptors, keyed by the name
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Descriptor)
+)
+
+// Register registers the descriptor of an enrichment.
+func Register(d Descriptor) error {
+
+	if !namePattern.MatchString(d.Name) {
+		return fmt.Errorf("invalid enrichment name (%s): one or two path segments of lowercase alphanumeric characters or '-'", d.Name)
+	}
+	for _, segment := range strings.Split(d.Name, "/") {
+		if reservedSegments[segment] {
+			return fmt.Errorf("invalid enrichment name (%s): '%s' is reserved for the routes", d.Name, segment)
+		}
+	}
+	if d.NewTfVars == nil {
+		return fmt.Errorf("invalid enrichment (%s): tfvars is required", d.Name)
+	}
+	if d.OutputName == "" {
+		return fmt.Errorf("invalid enrichment (%s): output name is required", d.Name)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[d.Name]; exists {
+		return fmt.Errorf("invalid enrichment (%s): already registered", d.Name)
+	}
+	registry[d.Name] = d
+	return nil
+}
+
+// MustRegister registers the descriptor of an enrichment, and panics if it is invalid.
+func MustRegister(d Descriptor) {
+	if err := Register(d); err != nil {
+		panic(err)
+	}
+}
+
+// Get returns the descriptor of an enrichment.
+func Get(name string) (Descriptor, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	d, exists := registry[name]
+	if !exists {
+		return Descriptor{}, fmt.Errorf("%w (enrichments: %s)", ErrUnknownEnrichment, name)
+	}
+	return d, nil
+}
+
+// List returns the descriptors of all enrichments sorted by the name.
+func List() []Descriptor {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	list := make([]Descriptor, 0, len(registry))
+	for _, d := range registry {
+		list = append(list, d)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// ValidateProvider checks the provider to initialize an enrichment, which must be empty if it has a single template.
+func (d Descriptor) ValidateProvider(provider string) error {
+	if len(d.Providers) == 0 {
+		if provider != "" {
+			return fmt.Errorf("%w (provider: %s): the enrichment (%s) has no providers", ErrInvalidProvider, provider, d.Name)
+		}
+		return nil
+	}
+	for _, p := range d.Providers {
+		if p == provider {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w (provider: %s): it must be one of [%s]", ErrInvalidProvider, provider, strings.Join(d.Providers, ", "))
+}
+
+// TemplatePath returns the directory of the templates of an enrichment by the provider.
+func (d Descriptor) TemplatePath(provider string) string {
+	return filepath.Join(config.Terrarium.Root, "templates", config.NVL(d.Template, d.Name), provider)
+}
+
+// DecodeTfVars decodes the tfvars of an enrichment, sets the terrarium ID if it is empty,
+// and validates them by their validate tags for the provider (see model.ValidateTfVars).
+func (d Descriptor) DecodeTfVars(data json.RawMessage, trId, provider string) (interface{}, error) {
+
+	tfVars := d.NewTfVars()
+	if len(data) > 0 && string(data) != "null" {
+		if err := json.Unmarshal(data, tfVars); err != nil {
+			return nil, err
+		}
+	}
+
+	if d.TerrariumIdVar != "" {
+		v := reflect.Indirect(reflect.ValueOf(tfVars))
+		for i := 0; i < v.NumField(); i++ {
+			if jsonName(v.Type().Field(i)) != d.TerrariumIdVar {
+				continue
+			}
+			if f := v.Field(i); f.Kind() == reflect.String && f.String() == "" {
+				f.SetString(trId)
+			}
+		}
+	}
+
+	if err := model.ValidateTfVars(tfVars, provider); err != nil {
+		return nil, err
+	}
+	return tfVars, nil
+}
+
+// Schema returns the variables of the tfvars of an enrichment with their validate rules,
+// where a variable is required if it is not omitted when empty.
+func (d Descriptor) Schema() []model.TfVarsField {
+
+	t := reflect.TypeOf(d.NewTfVars())
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	fields := make([]model.TfVarsField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := jsonName(f)
+		if name == "" {
+			continue
+		}
+		fields = append(fields, model.TfVarsField{
+			Name:     name,
+			Type:     jsonType(f.Type),
+			Required: name != d.TerrariumIdVar && !strings.Contains(f.Tag.Get("json"), ",omitempty"),
+			Example:  f.Tag.Get("example"),
+			Rules:    f.Tag.Get("validate"),
+		})
+	}
+	return fields
+}
+
+// Info returns the information of an enrichment to show.
+func (d Descriptor) Info() model.EnrichmentKind {
+	return model.EnrichmentKind{
+		Name:       d.Name,
+		Title:      d.Title,
+		Providers:  d.Providers,
+		OutputName: d.OutputName,
+		TfVars:     d.Schema(),
+	}
+}
+
+// jsonName returns the JSON name of a field, which is empty if it is not encoded.
+func jsonName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return config.NVL(name, f.Name)
+}
+
+// jsonType returns the JSON type of a field.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonType(t.Elem())
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
@@ -0,0 +1,306 @@
+// Package idempotency provides the records of the requests by the Idempotency-Key header,
+// so that a retried request (e.g., after a network failure) returns the original response instead of running again.
+// A record is scoped to the tenant of the caller and kept with the fingerprint of the request and its response.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/cloud-barista/mc-terrarium/pkg/replica"
+	"github.com/cloud-barista/mc-terrarium/pkg/store"
+	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Header of the idempotency key of a request, and the header of a replayed response
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	// Max length of an idempotency key
+	maxKeyLength = 255
+
+	// Name of the store of the records (i.e., .terrarium/idempotency.bolt or the keys under <prefix>idempotency/ in etcd)
+	recordStoreName = "idempotency"
+	// Prefix of the keys of the records in the store
+	recordKeyPrefix = "idempotency-keys/"
+	// Scope of the records of the API user not acting as a tenant, which cannot be a tenant name
+	adminScope = "_"
+
+	// Time to keep a record, after which the key can be used again
+	recordTtl = 24 * time.Hour
+	// Time after which a request in progress without a running job is regarded as stopped (e.g., by a crash)
+	staleAfter = 10 * time.Minute
+	// Interval to purge the expired records
+	purgeInterval = time.Hour
+
+	// Timeout of an operation on the store
+	storeTimeout = 10 * time.Second
+)
+
+// Status of a record
+const (
+	StatusInProgress = "InProgress"
+	StatusCompleted  = "Completed"
+)
+
+var (
+	// ErrInvalidKey is returned when an idempotency key is not allowed.
+	ErrInvalidKey = errors.New("invalid idempotency key")
+	// ErrKeyReused is returned when an idempotency key comes with a different request.
+	ErrKeyReused = errors.New("the idempotency key is already used by a different request")
+	// ErrInProgress is returned when the request of an idempotency key is still in progress.
+	ErrInProgress = errors.New("the request of the idempotency key is still in progress")
+)
+
+// Record is a request by an idempotency key with its response.
+type Record struct {
+	Fingerprint string      `json:"fingerprint"`
+	Status      string      `json:"status"`
+	RequestId   string      `json:"requestId"`
+	StatusCode  int         `json:"statusCode,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	ExpiresAt   time.Time   `json:"expiresAt"`
+}
+
+// Store of the records, which is apart from the store of the terrarium info (see store.Open)
+// not to rewrite the terrarium info (e.g., of the JSON store) with the responses and to list it past them.
+var idStore store.Store
+
+// Start opens the store of the records, and purges the expired ones on start and periodically
+// until the context is done. With the store shared by the servers, the leader purges them.
+func Start(ctx context.Context) error {
+
+	s, err := store.Open(config.Terrarium.Store, recordStoreName)
+	if err != nil {
+		return fmt.Errorf("failed to open the store of the idempotency keys: %w", err)
+	}
+	idStore = s
+
+	if replica.IsLeader() {
+		if err := purgeExpired(time.Now()); err != nil {
+			log.Error().Err(err).Msg("failed to purge the expired idempotency keys")
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if !replica.IsLeader() {
+					continue
+				}
+				if err := purgeExpired(now); err != nil {
+					log.Error().Err(err).Msg("failed to purge the expired idempotency keys")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Close closes the store of the records.
+func Close() error {
+	if idStore == nil {
+		return nil
+	}
+	return idStore.Close()
+}
+
+// SetStore replaces the store of the records (e.g., with a store.MemoryStore in tests) without purging them,
+// where nil disables the records.
+func SetStore(s store.Store) {
+	idStore = s
+}
+
+// Enabled returns whether the records can be kept (i.e., Start has been called).
+func Enabled() bool {
+	return idStore != nil
+}
+
+// ValidateKey checks if an idempotency key is allowed, which is up to 255 printable ASCII characters.
+func ValidateKey(key string) error {
+	if key == "" || len(key) > maxKeyLength {
+		return fmt.Errorf("%w (%s): it must be 1 to %d characters", ErrInvalidKey, key, maxKeyLength)
+	}
+	for _, r := range key {
+		if r < 0x20 || r > 0x7e {
+			return fmt.Errorf("%w (%s): it must consist of printable ASCII characters", ErrInvalidKey, key)
+		}
+	}
+	return nil
+}
+
+// Fingerprint returns the fingerprint of a request by its method, path, query and body,
+// which is read to the end as a stream (e.g., a bundle to import).
+func Fingerprint(method, path, query string, body io.Reader) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", method, path, query)
+	if _, err := io.Copy(h, body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// recordKey returns the key of the record of an idempotency key of a tenant (empty for the API user) in the store.
+func recordKey(tenantName, key string) string {
+	if tenantName == "" {
+		tenantName = adminScope
+	}
+	sum := sha256.Sum256([]byte(key))
+	return recordKeyPrefix + tenantName + "/" + hex.EncodeToString(sum[:])
+}
+
+// Begin records a request of an idempotency key as in progress, and returns nil if the request should run.
+// If the key has been used, it returns the record to replay its response, or with ErrInProgress if the request
+// is still in progress. It returns ErrKeyReused if the key has been used by a request of another fingerprint.
+// An expired record and a stopped request (e.g., by a crash) are replaced by the new request.
+func Begin(tenantName, key, fingerprint, reqId string) (*Record, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	now := time.Now()
+	value, err := json.Marshal(Record{
+		Fingerprint: fingerprint,
+		Status:      StatusInProgress,
+		RequestId:   reqId,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(recordTtl),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the idempotency record: %w", err)
+	}
+
+	k := recordKey(tenantName, key)
+	_, err = idStore.Create(ctx, k, value)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, store.ErrAlreadyExists) {
+		return nil, fmt.Errorf("failed to save the idempotency record: %w", err)
+	}
+
+	kv, err := idStore.Get(ctx, k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the idempotency record: %w", err)
+	}
+	var rec Record
+	if err := json.Unmarshal(kv.Value, &rec); err != nil {
+		return nil, fmt.Errorf("failed to decode the idempotency record: %w", err)
+	}
+
+	if now.After(rec.ExpiresAt) || (rec.Status == StatusInProgress && stopped(rec, now)) {
+		if _, err := idStore.CompareAndSwap(ctx, k, kv.Revision, value); err != nil {
+			if errors.Is(err, store.ErrConflict) {
+				// Another request has taken the key in the meantime
+				return nil, fmt.Errorf("%w (%s)", ErrInProgress, key)
+			}
+			return nil, fmt.Errorf("failed to save the idempotency record: %w", err)
+		}
+		return nil, nil
+	}
+
+	if rec.Fingerprint != fingerprint {
+		return nil, fmt.Errorf("%w (%s)", ErrKeyReused, key)
+	}
+	if rec.Status == StatusInProgress {
+		return &rec, fmt.Errorf("%w (%s, reqId: %s)", ErrInProgress, key, rec.RequestId)
+	}
+	return &rec, nil
+}
+
+// stopped returns whether the request of a record in progress has stopped without completing it,
+// which is when its job was interrupted, or it has no running job for a while.
+func stopped(rec Record, now time.Time) bool {
+	job, err := tofu.GetJob(rec.RequestId)
+	if err == nil {
+		switch job.Status {
+		case tofu.JobStatusInterrupted:
+			return true
+		case tofu.JobStatusQueued, tofu.JobStatusRunning:
+			return false
+		}
+	}
+	return now.Sub(rec.CreatedAt) > staleAfter
+}
+
+// Complete records the response of the request of an idempotency key to replay it.
+func Complete(tenantName, key string, statusCode int, header http.Header, body []byte) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	k := recordKey(tenantName, key)
+	kv, err := idStore.Get(ctx, k)
+	if err != nil {
+		return fmt.Errorf("failed to read the idempotency record: %w", err)
+	}
+	var rec Record
+	if err := json.Unmarshal(kv.Value, &rec); err != nil {
+		return fmt.Errorf("failed to decode the idempotency record: %w", err)
+	}
+
+	rec.Status = StatusCompleted
+	rec.StatusCode = statusCode
+	rec.Header = header
+	rec.Body = body
+	value, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode the idempotency record: %w", err)
+	}
+	if _, err := idStore.CompareAndSwap(ctx, k, kv.Revision, value); err != nil {
+		return fmt.Errorf("failed to save the idempotency record: %w", err)
+	}
+	return nil
+}
+
+// Release deletes the record of an idempotency key so that the request can be retried with the key
+// (e.g., when it was rejected without any change).
+func Release(tenantName, key string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if err := idStore.Delete(ctx, recordKey(tenantName, key)); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("failed to delete the idempotency record: %w", err)
+	}
+	return nil
+}
+
+// purgeExpired deletes the expired records.
+func purgeExpired(now time.Time) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	kvs, err := idStore.List(ctx, recordKeyPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, kv := range kvs {
+		var rec Record
+		if err := json.Unmarshal(kv.Value, &rec); err != nil || now.After(rec.ExpiresAt) {
+			if err := idStore.Delete(ctx, kv.Key); err != nil && !errors.Is(err, store.ErrNotFound) {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+// Package replica coordinates the servers (i.e., replicas) of mc-terrarium sharing the store (i.e., etcd):
+// the heartbeat of each server, the leader which runs the background work of all the servers
+// (e.g., the reaper of the expired terrariums), and the locks of the terrariums across the servers.
+// With a store of a server (i.e., json or bbolt), this server is the only one and always the leader.
+package replica
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/cloud-barista/mc-terrarium/pkg/store"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Name of the store of the heartbeats, the leader and the locks (see store.Open)
+	storeName = "replicas"
+	// Keys of the leases in the store
+	heartbeatKeyPrefix = "heartbeats/"
+	leaderKey          = "leader"
+	lockKeyPrefix      = "locks/"
+
+	// Time to keep a lease without renewing it, after which a stopped server is regarded as dead
+	leaseTtl = 15 * time.Second
+	// Interval to renew the leases, which is shorter than the TTL to survive a failed renewal
+	renewInterval = 5 * time.Second
+	// Interval to try to acquire a lock held by another server
+	lockPollInterval = time.Second
+
+	// Timeout of an operation on the store
+	storeTimeout = 5 * time.Second
+)
+
+var (
+	// ID of this server, which is unique across the restarts of the server
+	id = newId()
+	// Store of the leases, which is nil unless the store is shared (see Start)
+	rStore store.Store
+	// Whether this server is the leader
+	leader atomic.Bool
+)
+
+// newId returns an ID of this server by the host name and a random suffix.
+func newId() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "mc-terrarium"
+	}
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	return host + "-" + hex.EncodeToString(b)
+}
+
+// Id returns the ID of this server, which is recorded in the jobs run by it.
+func Id() string {
+	return id
+}
+
+// Shared returns whether the other servers may share the store with this server.
+func Shared() bool {
+	return rStore != nil
+}
+
+// IsLeader returns whether this server runs the background work of all the servers.
+func IsLeader() bool {
+	return !Shared() || leader.Load()
+}
+
+// Start joins the servers sharing the store, which keeps the heartbeat of this server
+// and campaigns for the leader until the context is done. It does nothing with a store of a server.
+func Start(ctx context.Context) error {
+
+	if !store.Shared(config.Terrarium.Store) {
+		log.Info().Msgf("running as a single server (id: %s)", id)
+		return nil
+	}
+
+	s, err := store.Open(config.Terrarium.Store, storeName)
+	if err != nil {
+		return fmt.Errorf("failed to open the store of the servers: %w", err)
+	}
+	rStore = s
+	log.Info().Msgf("joining the servers sharing the store (id: %s)", id)
+
+	renew()
+	go func() {
+		ticker := time.NewTicker(renewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				renew()
+			}
+		}
+	}()
+	return nil
+}
+
+// Close releases the heartbeat and the leader of this server so that the other servers take over immediately,
+// and closes the store.
+func Close() error {
+	if rStore == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	leader.Store(false)
+	if err := store.Unlock(ctx, rStore, leaderKey, id); err != nil {
+		log.Warn().Err(err).Msg("failed to resign the leader")
+	}
+	if err := store.Unlock(ctx, rStore, heartbeatKeyPrefix+id, id); err != nil {
+		log.Warn().Err(err).Msg("failed to release the heartbeat")
+	}
+	return rStore.Close()
+}
+
+// renew renews the heartbeat of this server, and acquires or renews the leader.
+func renew() {
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if _, err := store.TryLock(ctx, rStore, heartbeatKeyPrefix+id, id, leaseTtl); err != nil {
+		log.Error().Err(err).Msg("failed to renew the heartbeat")
+	}
+
+	// Not the leader on an error, since the lease may expire before the next renewal
+	ok, err := store.TryLock(ctx, rStore, leaderKey, id, leaseTtl)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to campaign for the leader")
+	}
+	isLeader := ok && err == nil
+	if was := leader.Swap(isLeader); was != isLeader {
+		if was {
+			log.Warn().Msgf("this server (id: %s) is no longer the leader", id)
+		} else {
+			log.Info().Msgf("this server (id: %s) is the leader", id)
+		}
+	}
+}
+
+// Alive returns whether a server is running (i.e., its heartbeat has not expired).
+// With a store of a server, the other servers (e.g., this server before a restart) are not running.
+// It returns true on a failure of the store not to take over the work of a running server.
+func Alive(serverId string) bool {
+	if serverId == id {
+		return true
+	}
+	if !Shared() || serverId == "" {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	lease, _, err := store.GetLease(ctx, rStore, heartbeatKeyPrefix+serverId)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return false
+		}
+		log.Warn().Err(err).Msgf("failed to read the heartbeat of the server (id: %s)", serverId)
+		return true
+	}
+	return !lease.Expired(time.Now())
+}
+
+// Lock acquires the lock of a name (e.g., a terrarium) across the servers, which waits until the other server
+// releases it or the context is done. The lock is renewed until the returned function releases it.
+// It does nothing with a store of a server, where the callers lock in memory.
+func Lock(ctx context.Context, name string) (func(), error) {
+
+	if !Shared() {
+		return func() {}, nil
+	}
+
+	key := lockKeyPrefix + name
+	for {
+		storeCtx, cancel := context.WithTimeout(ctx, storeTimeout)
+		ok, err := store.TryLock(storeCtx, rStore, key, id, leaseTtl)
+		cancel()
+		if ok {
+			break
+		}
+		if err != nil {
+			log.Warn().Err(err).Msgf("failed to acquire the lock (%s), retrying", name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(renewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				storeCtx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+				ok, err := store.TryLock(storeCtx, rStore, key, id, leaseTtl)
+				cancel()
+				if err != nil || !ok {
+					log.Error().Err(err).Msgf("failed to renew the lock (%s), which another server may take", name)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+
+		storeCtx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+		defer cancel()
+		if err := store.Unlock(storeCtx, rStore, key, id); err != nil {
+			log.Warn().Err(err).Msgf("failed to release the lock (%s)", name)
+		}
+	}, nil
+}
+
+// Locked returns whether the lock of a name is held by any server sharing the store.
+// It returns false with a store of a server, where the callers lock in memory.
+func Locked(name string) bool {
+	if !Shared() {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	lease, _, err := store.GetLease(ctx, rStore, lockKeyPrefix+name)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Warn().Err(err).Msgf("failed to read the lock (%s)", name)
+		}
+		return false
+	}
+	return !lease.Expired(time.Now())
+}
@@ -0,0 +1,107 @@
+package replica
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/store"
+)
+
+// setUpSharedStore sets the store of the leases as if the store is shared, and restores it after the test.
+func setUpSharedStore(t *testing.T) store.Store {
+	t.Helper()
+
+	s, err := store.NewBoltStore(filepath.Join(t.TempDir(), storeName+".bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rStore = s
+	t.Cleanup(func() {
+		rStore = nil
+		s.Close()
+	})
+	return s
+}
+
+func TestAlive(t *testing.T) {
+	s := setUpSharedStore(t)
+	ctx := context.Background()
+
+	if _, err := store.TryLock(ctx, s, heartbeatKeyPrefix+"server-alive", "server-alive", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.TryLock(ctx, s, heartbeatKeyPrefix+"server-dead", "server-dead", -time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		serverId string
+		want     bool
+	}{
+		{serverId: Id(), want: true},
+		{serverId: "server-alive", want: true},
+		{serverId: "server-dead", want: false},
+		{serverId: "server-unknown", want: false},
+		{serverId: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := Alive(tt.serverId); got != tt.want {
+			t.Errorf("Alive(%q) = %v, want %v", tt.serverId, got, tt.want)
+		}
+	}
+}
+
+// A lock held by another server is acquired after it is released.
+func TestLock(t *testing.T) {
+	s := setUpSharedStore(t)
+	ctx := context.Background()
+
+	key := lockKeyPrefix + "terrariums/tr01"
+	if ok, err := store.TryLock(ctx, s, key, "server-other", time.Minute); err != nil || !ok {
+		t.Fatalf("TryLock() = (%v, %v), want (true, nil)", ok, err)
+	}
+
+	// Not acquired while the other server holds it
+	waitCtx, cancel := context.WithTimeout(ctx, 1500*time.Millisecond)
+	defer cancel()
+	if _, err := Lock(waitCtx, "terrariums/tr01"); err == nil {
+		t.Fatal("Lock() of a lock held by another server succeeded, want an error by the context")
+	}
+
+	if err := store.Unlock(ctx, s, key, "server-other"); err != nil {
+		t.Fatal(err)
+	}
+	release, err := Lock(ctx, "terrariums/tr01")
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	if lease, _, _ := store.GetLease(ctx, s, key); lease.Holder != Id() {
+		t.Errorf("holder of the lock = %s, want %s", lease.Holder, Id())
+	}
+
+	if !Locked("terrariums/tr01") {
+		t.Errorf("Locked() while holding the lock = false, want true")
+	}
+
+	release()
+	if Locked("terrariums/tr01") {
+		t.Errorf("Locked() after the release = true, want false")
+	}
+	if ok, err := store.TryLock(ctx, s, key, "server-other", time.Minute); err != nil || !ok {
+		t.Errorf("TryLock() after the release = (%v, %v), want (true, nil)", ok, err)
+	}
+}
+
+// Without a shared store, this server is the leader and the locks are in memory.
+func TestSingleServer(t *testing.T) {
+	if Shared() || !IsLeader() {
+		t.Fatalf("(Shared(), IsLeader()) = (%v, %v), want (false, true)", Shared(), IsLeader())
+	}
+	release, err := Lock(context.Background(), "terrariums/tr01")
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	release()
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bucket of the keys in the bolt store
+var boltBucket = []byte("mc-terrarium")
+
+// BoltStore keeps the keys in an embedded transactional key-value database (bbolt).
+// The database file is locked by a process, so it is for a single server of mc-terrarium.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens the bolt store of a file, which is created if it does not exist.
+func NewBoltStore(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt store: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create bucket: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) Get(ctx context.Context, key string) (KeyValue, error) {
+	var kv KeyValue
+	err := s.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(boltBucket).Get([]byte(key))
+		if raw == nil {
+			return fmt.Errorf("%w (key: %s)", ErrNotFound, key)
+		}
+		kv = decodeBoltValue(key, raw)
+		return nil
+	})
+	return kv, err
+}
+
+func (s *BoltStore) Create(ctx context.Context, key string, value []byte) (int64, error) {
+	var revision int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucket)
+		if b.Get([]byte(key)) != nil {
+			return fmt.Errorf("%w (key: %s)", ErrAlreadyExists, key)
+		}
+		var err error
+		revision, err = putBoltValue(b, key, value)
+		return err
+	})
+	return revision, err
+}
+
+func (s *BoltStore) Put(ctx context.Context, key string, value []byte) (int64, error) {
+	var revision int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		revision, err = putBoltValue(tx.Bucket(boltBucket), key, value)
+		return err
+	})
+	return revision, err
+}
+
+func (s *BoltStore) Delete(ctx context.Context, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucket)
+		if b.Get([]byte(key)) == nil {
+			return fmt.Errorf("%w (key: %s)", ErrNotFound, key)
+		}
+		return b.Delete([]byte(key))
+	})
+}
+
+func (s *BoltStore) List(ctx context.Context, prefix string) ([]KeyValue, error) {
+	var list []KeyValue
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBucket).Cursor()
+		p := []byte(prefix)
+		for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+			list = append(list, decodeBoltValue(string(k), v))
+		}
+		return nil
+	})
+	return list, err
+}
+
+func (s *BoltStore) CompareAndSwap(ctx context.Context, key string, revision int64, value []byte) (int64, error) {
+	var newRevision int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucket)
+		raw := b.Get([]byte(key))
+		if raw == nil {
+			return fmt.Errorf("%w (key: %s)", ErrNotFound, key)
+		}
+		if current := decodeBoltValue(key, raw).Revision; current != revision {
+			return fmt.Errorf("%w (key: %s, revision: %d, current: %d)", ErrConflict, key, revision, current)
+		}
+		var err error
+		newRevision, err = putBoltValue(b, key, value)
+		return err
+	})
+	return newRevision, err
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// putBoltValue puts a value with a new revision, which is the next sequence of the bucket.
+// The value is stored with the revision (8 bytes, big endian) in front.
+func putBoltValue(b *bolt.Bucket, key string, value []byte) (int64, error) {
+	seq, err := b.NextSequence()
+	if err != nil {
+		return 0, err
+	}
+	raw := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(raw, seq)
+	copy(raw[8:], value)
+	if err := b.Put([]byte(key), raw); err != nil {
+		return 0, err
+	}
+	return int64(seq), nil
+}
+
+// decodeBoltValue decodes a value stored by putBoltValue.
+// It copies the value since it is valid only in the transaction.
+func decodeBoltValue(key string, raw []byte) KeyValue {
+	if len(raw) < 8 {
+		return KeyValue{Key: key}
+	}
+	return KeyValue{
+		Key:      key,
+		Value:    append([]byte(nil), raw[8:]...),
+		Revision: int64(binary.BigEndian.Uint64(raw[:8])),
+	}
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdStore keeps the keys in etcd (or a server compatible with the etcd v3 API).
+// The keys outlive the server of mc-terrarium, and the revisions are the modification revisions of etcd.
+// It is shared by more than one server of mc-terrarium, which coordinate by the leases (see TryLock).
+type EtcdStore struct {
+	client *clientv3.Client
+	prefix string
+	// Close the client when the store is closed if the store opened it
+	ownClient bool
+}
+
+// OpenEtcdStore connects to etcd and opens the store with the keys under the prefix.
+func OpenEtcdStore(endpoints []string, username, password, prefix string) (*EtcdStore, error) {
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no etcd endpoints")
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		Username:    username,
+		Password:    password,
+		DialTimeout: defaultEtcdDialTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to etcd: %w", err)
+	}
+
+	s := NewEtcdStore(client, prefix)
+	s.ownClient = true
+	return s, nil
+}
+
+// NewEtcdStore creates the store with the keys under the prefix by an etcd client (e.g., of an embedded server).
+func NewEtcdStore(client *clientv3.Client, prefix string) *EtcdStore {
+	return &EtcdStore{client: client, prefix: prefix}
+}
+
+func (s *EtcdStore) Get(ctx context.Context, key string) (KeyValue, error) {
+	resp, err := s.client.Get(ctx, s.prefix+key)
+	if err != nil {
+		return KeyValue{}, fmt.Errorf("failed to get from etcd: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return KeyValue{}, fmt.Errorf("%w (key: %s)", ErrNotFound, key)
+	}
+	kv := resp.Kvs[0]
+	return KeyValue{Key: key, Value: kv.Value, Revision: kv.ModRevision}, nil
+}
+
+func (s *EtcdStore) Create(ctx context.Context, key string, value []byte) (int64, error) {
+	k := s.prefix + key
+	resp, err := s.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(k), "=", 0)).
+		Then(clientv3.OpPut(k, string(value))).
+		Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create in etcd: %w", err)
+	}
+	if !resp.Succeeded {
+		return 0, fmt.Errorf("%w (key: %s)", ErrAlreadyExists, key)
+	}
+	return resp.Header.Revision, nil
+}
+
+func (s *EtcdStore) Put(ctx context.Context, key string, value []byte) (int64, error) {
+	resp, err := s.client.Put(ctx, s.prefix+key, string(value))
+	if err != nil {
+		return 0, fmt.Errorf("failed to put to etcd: %w", err)
+	}
+	return resp.Header.Revision, nil
+}
+
+func (s *EtcdStore) Delete(ctx context.Context, key string) error {
+	resp, err := s.client.Delete(ctx, s.prefix+key)
+	if err != nil {
+		return fmt.Errorf("failed to delete from etcd: %w", err)
+	}
+	if resp.Deleted == 0 {
+		return fmt.Errorf("%w (key: %s)", ErrNotFound, key)
+	}
+	return nil
+}
+
+func (s *EtcdStore) List(ctx context.Context, prefix string) ([]KeyValue, error) {
+	resp, err := s.client.Get(ctx, s.prefix+prefix, clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list from etcd: %w", err)
+	}
+
+	list := make([]KeyValue, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		list = append(list, KeyValue{
+			Key:      strings.TrimPrefix(string(kv.Key), s.prefix),
+			Value:    kv.Value,
+			Revision: kv.ModRevision,
+		})
+	}
+	return list, nil
+}
+
+func (s *EtcdStore) CompareAndSwap(ctx context.Context, key string, revision int64, value []byte) (int64, error) {
+	k := s.prefix + key
+	resp, err := s.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(k), "=", revision)).
+		Then(clientv3.OpPut(k, string(value))).
+		Else(clientv3.OpGet(k)).
+		Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to compare and swap in etcd: %w", err)
+	}
+	if !resp.Succeeded {
+		kvs := resp.Responses[0].GetResponseRange().Kvs
+		if len(kvs) == 0 {
+			return 0, fmt.Errorf("%w (key: %s)", ErrNotFound, key)
+		}
+		return 0, fmt.Errorf("%w (key: %s, revision: %d, current: %d)", ErrConflict, key, revision, kvs[0].ModRevision)
+	}
+	return resp.Header.Revision, nil
+}
+
+func (s *EtcdStore) Close() error {
+	if s.ownClient {
+		return s.client.Close()
+	}
+	return nil
+}
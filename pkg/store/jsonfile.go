@@ -0,0 +1,180 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/fileutil"
+)
+
+// JSONStore keeps the keys in memory and writes them to a JSON file atomically on every modification.
+// It is for a single server of mc-terrarium.
+type JSONStore struct {
+	mu   sync.Mutex
+	path string
+	data jsonStoreData
+}
+
+type jsonStoreData struct {
+	Revision int64                     `json:"revision"`
+	Entries  map[string]jsonStoreEntry `json:"entries"`
+}
+
+type jsonStoreEntry struct {
+	Value    json.RawMessage `json:"value"`
+	Revision int64           `json:"revision"`
+}
+
+// NewJSONStore opens the JSON store of a file, which is created on the first modification.
+func NewJSONStore(path string) (*JSONStore, error) {
+	s := &JSONStore{
+		path: path,
+		data: jsonStoreData{Entries: make(map[string]jsonStoreEntry)},
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read store file: %w", err)
+	}
+	if err := json.Unmarshal(raw, &s.data); err != nil {
+		return nil, fmt.Errorf("failed to decode store file: %w", err)
+	}
+	if s.data.Entries == nil {
+		s.data.Entries = make(map[string]jsonStoreEntry)
+	}
+	return s, nil
+}
+
+func (s *JSONStore) Get(ctx context.Context, key string) (KeyValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.data.Entries[key]
+	if !ok {
+		return KeyValue{}, fmt.Errorf("%w (key: %s)", ErrNotFound, key)
+	}
+	return entry.keyValue(key), nil
+}
+
+func (s *JSONStore) Create(ctx context.Context, key string, value []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.Entries[key]; ok {
+		return 0, fmt.Errorf("%w (key: %s)", ErrAlreadyExists, key)
+	}
+	return s.set(key, value)
+}
+
+func (s *JSONStore) Put(ctx context.Context, key string, value []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.set(key, value)
+}
+
+func (s *JSONStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.data.Entries[key]
+	if !ok {
+		return fmt.Errorf("%w (key: %s)", ErrNotFound, key)
+	}
+
+	delete(s.data.Entries, key)
+	s.data.Revision++
+	if err := s.save(); err != nil {
+		// Roll back not to diverge from the file
+		s.data.Entries[key] = entry
+		s.data.Revision--
+		return err
+	}
+	return nil
+}
+
+func (s *JSONStore) List(ctx context.Context, prefix string) ([]KeyValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list []KeyValue
+	for key, entry := range s.data.Entries {
+		if strings.HasPrefix(key, prefix) {
+			list = append(list, entry.keyValue(key))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list, nil
+}
+
+func (s *JSONStore) CompareAndSwap(ctx context.Context, key string, revision int64, value []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.data.Entries[key]
+	if !ok {
+		return 0, fmt.Errorf("%w (key: %s)", ErrNotFound, key)
+	}
+	if entry.Revision != revision {
+		return 0, fmt.Errorf("%w (key: %s, revision: %d, current: %d)", ErrConflict, key, revision, entry.Revision)
+	}
+	return s.set(key, value)
+}
+
+func (s *JSONStore) Close() error {
+	return nil
+}
+
+// set sets the value of a key and saves the file. It should be called with the lock.
+func (s *JSONStore) set(key string, value []byte) (int64, error) {
+	if !json.Valid(value) {
+		return 0, fmt.Errorf("the value of a JSON store should be JSON (key: %s)", key)
+	}
+
+	prev, existed := s.data.Entries[key]
+
+	s.data.Revision++
+	s.data.Entries[key] = jsonStoreEntry{
+		Value:    append(json.RawMessage(nil), value...),
+		Revision: s.data.Revision,
+	}
+	if err := s.save(); err != nil {
+		// Roll back not to diverge from the file
+		if existed {
+			s.data.Entries[key] = prev
+		} else {
+			delete(s.data.Entries, key)
+		}
+		s.data.Revision--
+		return 0, err
+	}
+	return s.data.Revision, nil
+}
+
+// save writes the data to the file atomically. It should be called with the lock.
+func (s *JSONStore) save() error {
+	raw, err := json.Marshal(s.data)
+	if err != nil {
+		return fmt.Errorf("failed to encode store: %w", err)
+	}
+	if err := fileutil.WriteFileAtomic(s.path, raw, 0644); err != nil {
+		return fmt.Errorf("failed to save store file: %w", err)
+	}
+	return nil
+}
+
+func (e jsonStoreEntry) keyValue(key string) KeyValue {
+	return KeyValue{
+		Key:      key,
+		Value:    append([]byte(nil), e.Value...),
+		Revision: e.Revision,
+	}
+}
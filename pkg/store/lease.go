@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Lease is a lock of a key held by a holder (e.g., a server) until it expires unless it is renewed.
+// The expiration time is of the clock of the holder, so the clocks of the holders should be synchronized (e.g., by NTP).
+type Lease struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Expired returns whether the lease has expired at a time.
+func (l Lease) Expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// TryLock acquires or renews the lease of a key for a holder for the TTL. It returns false without waiting
+// if another holder has the lease which has not expired.
+func TryLock(ctx context.Context, s Store, key, holder string, ttl time.Duration) (bool, error) {
+
+	now := time.Now()
+	value, err := json.Marshal(Lease{Holder: holder, ExpiresAt: now.Add(ttl)})
+	if err != nil {
+		return false, fmt.Errorf("failed to encode the lease (key: %s): %w", key, err)
+	}
+
+	_, err = s.Create(ctx, key, value)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, ErrAlreadyExists) {
+		return false, err
+	}
+
+	lease, revision, err := GetLease(ctx, s, key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			// Released in the meantime, which is taken next time
+			return false, nil
+		}
+		return false, err
+	}
+	if lease.Holder != holder && !lease.Expired(now) {
+		return false, nil
+	}
+
+	if _, err := s.CompareAndSwap(ctx, key, revision, value); err != nil {
+		if errors.Is(err, ErrConflict) || errors.Is(err, ErrNotFound) {
+			// Taken or released by another holder in the meantime
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Unlock releases the lease of a key if the holder has it, which expires it instead of deleting the key
+// not to release the lease taken by another holder in the meantime.
+func Unlock(ctx context.Context, s Store, key, holder string) error {
+
+	lease, revision, err := GetLease(ctx, s, key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if lease.Holder != holder {
+		return nil
+	}
+
+	value, err := json.Marshal(Lease{Holder: holder})
+	if err != nil {
+		return fmt.Errorf("failed to encode the lease (key: %s): %w", key, err)
+	}
+	if _, err := s.CompareAndSwap(ctx, key, revision, value); err != nil && !errors.Is(err, ErrConflict) && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// GetLease returns the lease of a key with its revision. It returns ErrNotFound if the key does not exist.
+func GetLease(ctx context.Context, s Store, key string) (Lease, int64, error) {
+	kv, err := s.Get(ctx, key)
+	if err != nil {
+		return Lease{}, 0, err
+	}
+	var lease Lease
+	if err := json.Unmarshal(kv.Value, &lease); err != nil {
+		return Lease{}, 0, fmt.Errorf("failed to decode the lease (key: %s): %w", key, err)
+	}
+	return lease, kv.Revision, nil
+}
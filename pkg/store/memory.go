@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryStore keeps the keys only in memory, for tests.
+type MemoryStore struct {
+	mu       sync.Mutex
+	revision int64
+	entries  map[string]KeyValue
+}
+
+// NewMemoryStore creates an empty memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]KeyValue)}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (KeyValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kv, exists := s.entries[key]
+	if !exists {
+		return KeyValue{}, fmt.Errorf("%w (key: %s)", ErrNotFound, key)
+	}
+	return copyKeyValue(kv), nil
+}
+
+func (s *MemoryStore) Create(ctx context.Context, key string, value []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[key]; exists {
+		return 0, fmt.Errorf("%w (key: %s)", ErrAlreadyExists, key)
+	}
+	return s.put(key, value), nil
+}
+
+func (s *MemoryStore) Put(ctx context.Context, key string, value []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.put(key, value), nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[key]; !exists {
+		return fmt.Errorf("%w (key: %s)", ErrNotFound, key)
+	}
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *MemoryStore) List(ctx context.Context, prefix string) ([]KeyValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list []KeyValue
+	for key, kv := range s.entries {
+		if strings.HasPrefix(key, prefix) {
+			list = append(list, copyKeyValue(kv))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list, nil
+}
+
+func (s *MemoryStore) CompareAndSwap(ctx context.Context, key string, revision int64, value []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kv, exists := s.entries[key]
+	if !exists {
+		return 0, fmt.Errorf("%w (key: %s)", ErrNotFound, key)
+	}
+	if kv.Revision != revision {
+		return 0, fmt.Errorf("%w (key: %s, revision: %d, current: %d)", ErrConflict, key, revision, kv.Revision)
+	}
+	return s.put(key, value), nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// put sets a copy of the value with the next revision. It should be called with mu held.
+func (s *MemoryStore) put(key string, value []byte) int64 {
+	s.revision++
+	s.entries[key] = KeyValue{Key: key, Value: append([]byte(nil), value...), Revision: s.revision}
+	return s.revision
+}
+
+// copyKeyValue copies the value not to be modified by the caller.
+func copyKeyValue(kv KeyValue) KeyValue {
+	kv.Value = append([]byte(nil), kv.Value...)
+	return kv
+}
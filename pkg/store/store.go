@@ -0,0 +1,100 @@
+// Package store provides key-value stores to persist the data of mc-terrarium.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+)
+
+// Types of the stores
+const (
+	TypeJSON = "json"
+	TypeBolt = "bbolt"
+	TypeEtcd = "etcd"
+)
+
+var (
+	// ErrNotFound is returned when a key does not exist.
+	ErrNotFound = errors.New("key not found")
+	// ErrAlreadyExists is returned when creating a key which already exists.
+	ErrAlreadyExists = errors.New("key already exists")
+	// ErrConflict is returned when the revision of a key does not match in compare-and-swap.
+	ErrConflict = errors.New("revision conflict")
+)
+
+// KeyValue is a key and its value with the revision of the last modification.
+type KeyValue struct {
+	Key      string
+	Value    []byte
+	Revision int64
+}
+
+// Store is a key-value store with revisions.
+// A revision is increased on every modification, so it can be used for compare-and-swap.
+type Store interface {
+	// Get returns the value of a key. It returns ErrNotFound if the key does not exist.
+	Get(ctx context.Context, key string) (KeyValue, error)
+	// Create sets the value of a new key. It returns ErrAlreadyExists if the key exists.
+	Create(ctx context.Context, key string, value []byte) (int64, error)
+	// Put sets the value of a key whether it exists or not, and returns the new revision.
+	Put(ctx context.Context, key string, value []byte) (int64, error)
+	// Delete deletes a key. It returns ErrNotFound if the key does not exist.
+	Delete(ctx context.Context, key string) error
+	// List returns the keys with a prefix in the order of the keys.
+	List(ctx context.Context, prefix string) ([]KeyValue, error)
+	// CompareAndSwap sets the value of a key only if its revision is the given one,
+	// and returns the new revision. It returns ErrConflict if the revision does not match.
+	CompareAndSwap(ctx context.Context, key string, revision int64, value []byte) (int64, error)
+	// Close closes the store.
+	Close() error
+}
+
+// Default settings of the stores
+const (
+	defaultJSONFileName    = "store.json"
+	defaultBoltFileName    = "store.bolt"
+	defaultEtcdPrefix      = "/mc-terrarium/"
+	defaultEtcdDialTimeout = 5 * time.Second
+)
+
+// New opens the store by the configuration.
+func New(cfg config.StoreConfig) (Store, error) {
+	dataDir := filepath.Join(config.Terrarium.Root, ".terrarium")
+
+	switch strings.ToLower(cfg.Type) {
+	case "", TypeJSON:
+		return NewJSONStore(config.NVL(cfg.Path, filepath.Join(dataDir, defaultJSONFileName)))
+	case TypeBolt, "bolt":
+		return NewBoltStore(config.NVL(cfg.Path, filepath.Join(dataDir, defaultBoltFileName)))
+	case TypeEtcd:
+		return OpenEtcdStore(cfg.Endpoints, cfg.Username, cfg.Password, config.NVL(cfg.Prefix, defaultEtcdPrefix))
+	default:
+		return nil, fmt.Errorf("unsupported store type: %s", cfg.Type)
+	}
+}
+
+// Open opens the store of a kind of data (e.g., jobs) apart from the terrarium registry by the configuration,
+// which is a bolt file in the terrarium directory (i.e., .terrarium/<name>.bolt) for json and bbolt,
+// or the keys under <prefix><name>/ in etcd to be shared by the servers.
+func Open(cfg config.StoreConfig, name string) (Store, error) {
+	switch strings.ToLower(cfg.Type) {
+	case "", TypeJSON, TypeBolt, "bolt":
+		return NewBoltStore(filepath.Join(config.Terrarium.Root, ".terrarium", name+".bolt"))
+	case TypeEtcd:
+		return OpenEtcdStore(cfg.Endpoints, cfg.Username, cfg.Password, config.NVL(cfg.Prefix, defaultEtcdPrefix)+name+"/")
+	default:
+		return nil, fmt.Errorf("unsupported store type: %s", cfg.Type)
+	}
+}
+
+// Shared returns whether the store of the configuration is shared by the servers (i.e., etcd),
+// where more than one server can run at a time.
+func Shared(cfg config.StoreConfig) bool {
+	return strings.ToLower(cfg.Type) == TypeEtcd
+}
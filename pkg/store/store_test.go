@@ -0,0 +1,343 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// openEtcdStore starts an embedded etcd server in a temporary directory, and opens the store by its client.
+func openEtcdStore(t *testing.T) Store {
+	t.Helper()
+
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	cfg.ListenClientUrls = []url.URL{freeURL(t)}
+	cfg.AdvertiseClientUrls = cfg.ListenClientUrls
+	cfg.ListenPeerUrls = []url.URL{freeURL(t)}
+	cfg.AdvertisePeerUrls = cfg.ListenPeerUrls
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatalf("failed to start etcd: %v", err)
+	}
+	t.Cleanup(e.Close)
+
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for etcd to be ready")
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{cfg.ListenClientUrls[0].String()},
+		DialTimeout: defaultEtcdDialTimeout,
+	})
+	if err != nil {
+		t.Fatalf("failed to connect to etcd: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return NewEtcdStore(client, defaultEtcdPrefix)
+}
+
+// freeURL returns a URL of the loopback address with a free port.
+func freeURL(t *testing.T) url.URL {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return url.URL{Scheme: "http", Host: l.Addr().String()}
+}
+
+// stores are the backends under test, each of which is opened in a temporary directory or in memory.
+var stores = []struct {
+	name string
+	open func(t *testing.T) Store
+}{
+	{name: TypeJSON, open: func(t *testing.T) Store {
+		s, err := NewJSONStore(filepath.Join(t.TempDir(), defaultJSONFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}},
+	{name: TypeBolt, open: func(t *testing.T) Store {
+		s, err := NewBoltStore(filepath.Join(t.TempDir(), defaultBoltFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}},
+	{name: TypeEtcd, open: openEtcdStore},
+	{name: "memory", open: func(t *testing.T) Store { return NewMemoryStore() }},
+}
+
+// TestStore runs the same cases against every backend, where the values are JSON for the JSON store.
+func TestStore(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, ctx context.Context, s Store)
+	}{
+		{name: "get a missing key", run: testGetMissing},
+		{name: "create", run: testCreate},
+		{name: "create an existing key", run: testCreateExisting},
+		{name: "put", run: testPut},
+		{name: "delete", run: testDelete},
+		{name: "list by prefix", run: testListByPrefix},
+		{name: "compare and swap", run: testCompareAndSwap},
+		{name: "compare and swap a missing key", run: testCompareAndSwapMissing},
+		{name: "lock", run: testLock},
+		{name: "lock an expired lease", run: testLockExpired},
+		{name: "unlock", run: testUnlock},
+	}
+
+	for _, backend := range stores {
+		t.Run(backend.name, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					s := backend.open(t)
+					t.Cleanup(func() { s.Close() })
+
+					ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+					defer cancel()
+					tt.run(t, ctx, s)
+				})
+			}
+		})
+	}
+}
+
+func testGetMissing(t *testing.T, ctx context.Context, s Store) {
+	if _, err := s.Get(ctx, "tr/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testCreate(t *testing.T, ctx context.Context, s Store) {
+	rev, err := s.Create(ctx, "tr/tr01", []byte(`"v1"`))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	kv, err := s.Get(ctx, "tr/tr01")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	want := KeyValue{Key: "tr/tr01", Value: []byte(`"v1"`), Revision: rev}
+	if !reflect.DeepEqual(kv, want) {
+		t.Errorf("Get() = %+v, want %+v", kv, want)
+	}
+}
+
+func testCreateExisting(t *testing.T, ctx context.Context, s Store) {
+	if _, err := s.Create(ctx, "tr/tr01", []byte(`"v1"`)); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := s.Create(ctx, "tr/tr01", []byte(`"v2"`)); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("Create() of an existing key error = %v, want %v", err, ErrAlreadyExists)
+	}
+
+	// The value is not overwritten
+	kv, err := s.Get(ctx, "tr/tr01")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(kv.Value) != `"v1"` {
+		t.Errorf("Get() value = %s, want v1", kv.Value)
+	}
+}
+
+func testPut(t *testing.T, ctx context.Context, s Store) {
+	rev1, err := s.Put(ctx, "tr/tr01", []byte(`"v1"`))
+	if err != nil {
+		t.Fatalf("Put() of a new key error = %v", err)
+	}
+	rev2, err := s.Put(ctx, "tr/tr01", []byte(`"v2"`))
+	if err != nil {
+		t.Fatalf("Put() of an existing key error = %v", err)
+	}
+	if rev2 <= rev1 {
+		t.Errorf("revision after Put() = %d, want greater than %d", rev2, rev1)
+	}
+
+	kv, err := s.Get(ctx, "tr/tr01")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(kv.Value) != `"v2"` || kv.Revision != rev2 {
+		t.Errorf("Get() = (%s, %d), want (v2, %d)", kv.Value, kv.Revision, rev2)
+	}
+}
+
+func testDelete(t *testing.T, ctx context.Context, s Store) {
+	if _, err := s.Put(ctx, "tr/tr01", []byte(`"v1"`)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := s.Delete(ctx, "tr/tr01"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Get(ctx, "tr/tr01"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a deleted key error = %v, want %v", err, ErrNotFound)
+	}
+	if err := s.Delete(ctx, "tr/tr01"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() of a missing key error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testListByPrefix(t *testing.T, ctx context.Context, s Store) {
+	// Keys of the other prefixes, including one which shares the leading characters (tr vs. tr-x)
+	for _, key := range []string{"tr/tr02", "idem/key01", "tr/tr01", "tr-x/tr01", "tr/tr03"} {
+		if _, err := s.Put(ctx, key, []byte(`"value of `+key+`"`)); err != nil {
+			t.Fatalf("Put(%s) error = %v", key, err)
+		}
+	}
+
+	kvs, err := s.List(ctx, "tr/")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var keys []string
+	for _, kv := range kvs {
+		keys = append(keys, kv.Key)
+		if want := `"value of ` + kv.Key + `"`; string(kv.Value) != want {
+			t.Errorf("value of %s = %s, want %s", kv.Key, kv.Value, want)
+		}
+	}
+	if want := []string{"tr/tr01", "tr/tr02", "tr/tr03"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("List() keys = %v, want %v", keys, want)
+	}
+
+	kvs, err = s.List(ctx, "none/")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(kvs) != 0 {
+		t.Errorf("List() of a prefix without keys = %v, want none", kvs)
+	}
+}
+
+func testCompareAndSwap(t *testing.T, ctx context.Context, s Store) {
+	rev, err := s.Create(ctx, "tr/tr01", []byte(`"v1"`))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	newRev, err := s.CompareAndSwap(ctx, "tr/tr01", rev, []byte(`"v2"`))
+	if err != nil {
+		t.Fatalf("CompareAndSwap() error = %v", err)
+	}
+	if newRev <= rev {
+		t.Errorf("revision after CompareAndSwap() = %d, want greater than %d", newRev, rev)
+	}
+
+	// The revision is stale after the swap
+	if _, err := s.CompareAndSwap(ctx, "tr/tr01", rev, []byte(`"v3"`)); !errors.Is(err, ErrConflict) {
+		t.Errorf("CompareAndSwap() with a stale revision error = %v, want %v", err, ErrConflict)
+	}
+
+	kv, err := s.Get(ctx, "tr/tr01")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(kv.Value) != `"v2"` || kv.Revision != newRev {
+		t.Errorf("Get() = (%s, %d), want (v2, %d)", kv.Value, kv.Revision, newRev)
+	}
+}
+
+func testCompareAndSwapMissing(t *testing.T, ctx context.Context, s Store) {
+	_, err := s.CompareAndSwap(ctx, "tr/missing", 1, []byte(`"v1"`))
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("CompareAndSwap() of a missing key error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := s.Get(ctx, "tr/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after CompareAndSwap() of a missing key error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testLock(t *testing.T, ctx context.Context, s Store) {
+	tests := []struct {
+		holder string
+		want   bool
+	}{
+		{holder: "server-a", want: true},
+		// Renewed by the holder
+		{holder: "server-a", want: true},
+		// Held by another holder
+		{holder: "server-b", want: false},
+	}
+	for _, tt := range tests {
+		got, err := TryLock(ctx, s, "leases/leader", tt.holder, time.Minute)
+		if err != nil {
+			t.Fatalf("TryLock(%s) error = %v", tt.holder, err)
+		}
+		if got != tt.want {
+			t.Errorf("TryLock(%s) = %v, want %v", tt.holder, got, tt.want)
+		}
+	}
+
+	lease, _, err := GetLease(ctx, s, "leases/leader")
+	if err != nil {
+		t.Fatalf("GetLease() error = %v", err)
+	}
+	if lease.Holder != "server-a" || lease.Expired(time.Now()) {
+		t.Errorf("GetLease() = %+v, want held by server-a", lease)
+	}
+}
+
+func testLockExpired(t *testing.T, ctx context.Context, s Store) {
+	if ok, err := TryLock(ctx, s, "leases/leader", "server-a", -time.Second); err != nil || !ok {
+		t.Fatalf("TryLock(server-a) = (%v, %v), want (true, nil)", ok, err)
+	}
+
+	// Taken over by another holder since it has expired (e.g., the holder has stopped)
+	if ok, err := TryLock(ctx, s, "leases/leader", "server-b", time.Minute); err != nil || !ok {
+		t.Errorf("TryLock(server-b) of an expired lease = (%v, %v), want (true, nil)", ok, err)
+	}
+}
+
+func testUnlock(t *testing.T, ctx context.Context, s Store) {
+	if ok, err := TryLock(ctx, s, "leases/tr01", "server-a", time.Minute); err != nil || !ok {
+		t.Fatalf("TryLock(server-a) = (%v, %v), want (true, nil)", ok, err)
+	}
+
+	// Not released by another holder
+	if err := Unlock(ctx, s, "leases/tr01", "server-b"); err != nil {
+		t.Fatalf("Unlock(server-b) error = %v", err)
+	}
+	if ok, _ := TryLock(ctx, s, "leases/tr01", "server-b", time.Minute); ok {
+		t.Errorf("TryLock(server-b) after Unlock(server-b) = true, want false")
+	}
+
+	if err := Unlock(ctx, s, "leases/tr01", "server-a"); err != nil {
+		t.Fatalf("Unlock(server-a) error = %v", err)
+	}
+	if ok, err := TryLock(ctx, s, "leases/tr01", "server-b", time.Minute); err != nil || !ok {
+		t.Errorf("TryLock(server-b) after Unlock(server-a) = (%v, %v), want (true, nil)", ok, err)
+	}
+
+	// A missing lease is released already
+	if err := Unlock(ctx, s, "leases/missing", "server-a"); err != nil {
+		t.Errorf("Unlock() of a missing lease error = %v", err)
+	}
+}
+
+// Ensure the backends implement the store
+var (
+	_ Store = (*JSONStore)(nil)
+	_ Store = (*BoltStore)(nil)
+	_ Store = (*EtcdStore)(nil)
+)
@@ -0,0 +1,174 @@
+// Package tenant provides the tenants (e.g., teams) sharing mc-terrarium, which are isolated from each other.
+// Each terrarium belongs to a tenant, and a tenant can only see and manage its own terrariums.
+// The API user is the administrator in the default tenant, which can access the terrariums of all tenants.
+package tenant
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Default is the tenant of the API user and the terrariums issued before the tenants
+	Default = "default"
+
+	// Keys of the tenant of the caller and whether the caller is the administrator in the request context
+	ContextKey      = "tenant"
+	ContextKeyAdmin = "tenantAdmin"
+
+	// Header for the administrator to act as a tenant
+	HeaderTenant = "X-Tenant"
+
+	// Max length of a tenant name
+	maxNameLength = 63
+)
+
+var (
+	// ErrInvalidTenant is returned when a tenant is not allowed or does not exist.
+	ErrInvalidTenant = errors.New("invalid tenant")
+	// ErrQuotaExceeded is returned when a tenant has reached its quota.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// A tenant name is a DNS label, so that it is safe as a directory name
+var namePattern = regexp.MustCompile(`^[a-z]([a-z0-9-]*[a-z0-9])?$`)
+
+// Quota is the limits of a tenant, where 0 means no limit.
+type Quota struct {
+	// Max number of terrariums
+	MaxTerrariums int `mapstructure:"maxterrariums"`
+	// Max number of enrichments in all the terrariums
+	MaxEnrichments int `mapstructure:"maxenrichments"`
+}
+
+// Tenant is a tenant with its basic auth credentials and quota.
+type Tenant struct {
+	Name     string `mapstructure:"name"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	Quota    Quota  `mapstructure:"quota"`
+}
+
+// Tenants loaded from the tenants file, keyed by the name
+var (
+	tenantsMu sync.RWMutex
+	tenants   = make(map[string]Tenant)
+)
+
+// ValidateName checks if a tenant name is allowed.
+func ValidateName(name string) error {
+	if len(name) > maxNameLength || !namePattern.MatchString(name) {
+		return fmt.Errorf("%w (tenant: %s): it must be a DNS label up to %d characters", ErrInvalidTenant, name, maxNameLength)
+	}
+	return nil
+}
+
+// Load loads the tenants from the tenants file in the configuration (see conf/template-tenants.yaml).
+// There are no tenants but the default one if the file is not set.
+func Load() error {
+
+	loaded := make(map[string]Tenant)
+
+	if path := config.Terrarium.Tenant.File; path != "" {
+		v := viper.New()
+		v.SetConfigFile(path)
+		v.SetConfigType("yaml")
+		if err := v.ReadInConfig(); err != nil {
+			return fmt.Errorf("failed to read the tenants file (%s): %w", path, err)
+		}
+
+		var file struct {
+			Tenants []Tenant `mapstructure:"tenants"`
+		}
+		if err := v.Unmarshal(&file); err != nil {
+			return fmt.Errorf("failed to decode the tenants file (%s): %w", path, err)
+		}
+
+		usernames := map[string]bool{config.Terrarium.API.Username: true}
+		for _, t := range file.Tenants {
+			if err := ValidateName(t.Name); err != nil {
+				return err
+			}
+			if t.Name == Default {
+				return fmt.Errorf("%w (tenant: %s): it is reserved for the API user", ErrInvalidTenant, t.Name)
+			}
+			if _, exists := loaded[t.Name]; exists {
+				return fmt.Errorf("%w (tenant: %s): duplicated", ErrInvalidTenant, t.Name)
+			}
+			if t.Username == "" || t.Password == "" {
+				return fmt.Errorf("%w (tenant: %s): username and password are required", ErrInvalidTenant, t.Name)
+			}
+			if usernames[t.Username] {
+				return fmt.Errorf("%w (tenant: %s): the username is used by the API user or another tenant", ErrInvalidTenant, t.Name)
+			}
+			usernames[t.Username] = true
+			loaded[t.Name] = t
+		}
+	}
+
+	tenantsMu.Lock()
+	tenants = loaded
+	tenantsMu.Unlock()
+
+	return nil
+}
+
+// Authenticate returns the tenant of the basic auth credentials.
+func Authenticate(username, password string) (string, bool) {
+
+	tenantsMu.RLock()
+	defer tenantsMu.RUnlock()
+
+	// Be careful to use constant time comparison to prevent timing attacks
+	for _, t := range tenants {
+		if subtle.ConstantTimeCompare([]byte(username), []byte(t.Username)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(password), []byte(t.Password)) == 1 {
+			return t.Name, true
+		}
+	}
+	return "", false
+}
+
+// Exists returns whether a tenant exists, where the default tenant always exists.
+func Exists(name string) bool {
+	if name == Default {
+		return true
+	}
+
+	tenantsMu.RLock()
+	defer tenantsMu.RUnlock()
+	_, exists := tenants[name]
+	return exists
+}
+
+// QuotaOf returns the quota of a tenant, which is the default quota in the configuration if it is not set.
+// The default tenant of the API user has no limit.
+func QuotaOf(name string) Quota {
+	if name == Default {
+		return Quota{}
+	}
+
+	tenantsMu.RLock()
+	t := tenants[name]
+	tenantsMu.RUnlock()
+
+	quota := t.Quota
+	if quota.MaxTerrariums == 0 {
+		quota.MaxTerrariums = config.Terrarium.Tenant.MaxTerrariums
+	}
+	if quota.MaxEnrichments == 0 {
+		quota.MaxEnrichments = config.Terrarium.Tenant.MaxEnrichments
+	}
+	return quota
+}
+
+// Normalize returns the tenant of a terrarium, which is the default one if it is empty (i.e., issued before the tenants).
+func Normalize(name string) string {
+	return config.NVL(name, Default)
+}
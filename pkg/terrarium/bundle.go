@@ -0,0 +1,562 @@
+package terrarium
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/cloud-barista/mc-terrarium/pkg/fileutil"
+	"github.com/cloud-barista/mc-terrarium/pkg/store"
+	"github.com/cloud-barista/mc-terrarium/pkg/tenant"
+	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Version of the bundle format, which is increased on incompatible changes
+	bundleFormatVersion = 1
+
+	// Files in a bundle, where the files of the enrichments are in the files directory (e.g., files/sql-db/terraform.tfstate)
+	bundleManifestName  = "manifest.json"
+	bundleTerrariumName = "terrarium.json"
+	bundleFilesDir      = "files"
+
+	// Max total size of the (uncompressed) files in a bundle, which also limits the request body to fingerprint by an idempotency key
+	MaxBundleSize = 256 << 20
+
+	// Prefix of the credential files copied to the working directories, which are not exported
+	credentialFilePrefix = "credential-"
+	// Prefix of the staging directory (in the terrarium directory) to extract a bundle
+	importStagingPrefix = ".import-"
+)
+
+// ErrInvalidBundle is returned when a bundle is malformed or does not match its manifest.
+var ErrInvalidBundle = errors.New("invalid bundle")
+
+// Credential files copied from the secrets (see tofu.CopyGCPCredentials and tofu.CopyAzureCredentials)
+var credentialCopiers = map[string]func(des string) error{
+	"credential-gcp.json":  tofu.CopyGCPCredentials,
+	"credential-azure.env": tofu.CopyAzureCredentials,
+}
+
+// isBundleFile returns whether a file in a working directory is exported,
+// which are the infracode (i.e., templates and tfvars), the lock file and the state files.
+func isBundleFile(name string) bool {
+	switch name {
+	case ".terraform.lock.hcl", stateFileName, stateBackupFileName:
+		return true
+	}
+	for _, ext := range []string{".tf", ".tf.json", ".tfvars", ".tfvars.json"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bundle is a terrarium to export, which has the manifest and the contents of the files.
+type Bundle struct {
+	Manifest model.BundleManifest
+	contents map[string][]byte
+}
+
+// NewBundle collects the metadata of a terrarium and the files of each enrichment to export,
+// except the credentials. It is rejected while any enrichment is applying or destroying.
+func NewBundle(trId string) (*Bundle, error) {
+
+	trInfo, err := ReadTerrariumInfo(trId)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckErase(trId); err != nil {
+		return nil, err
+	}
+
+	root, err := TerrariumPath(trId)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{
+		Manifest: model.BundleManifest{
+			FormatVersion: bundleFormatVersion,
+			ToolVersion:   config.Version,
+			TerrariumId:   trId,
+			CreatedAt:     time.Now(),
+		},
+		contents: make(map[string][]byte),
+	}
+
+	trInfo.ExpiryWarnedAt = nil
+	data, err := json.MarshalIndent(trInfo, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode terrarium info: %w", err)
+	}
+	b.add(bundleTerrariumName, data)
+
+	var total int64
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Skip the provider plugins (.terraform) and so on
+			if strings.HasPrefix(d.Name(), ".") && p != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if strings.HasPrefix(d.Name(), credentialFilePrefix) {
+			if b.Manifest.Credentials == nil {
+				b.Manifest.Credentials = make(map[string][]string)
+			}
+			name := path.Dir(rel)
+			b.Manifest.Credentials[name] = append(b.Manifest.Credentials[name], d.Name())
+			return nil
+		}
+		if !isBundleFile(d.Name()) {
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		total += int64(len(data))
+		if total > MaxBundleSize {
+			return fmt.Errorf("the files are larger than %d bytes", MaxBundleSize)
+		}
+		b.add(path.Join(bundleFilesDir, rel), data)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect the files of the terrarium (trId: %s): %w", trId, err)
+	}
+
+	sort.Slice(b.Manifest.Files, func(i, j int) bool {
+		return b.Manifest.Files[i].Path < b.Manifest.Files[j].Path
+	})
+
+	return b, nil
+}
+
+// add adds a file to the bundle with its checksum.
+func (b *Bundle) add(name string, data []byte) {
+	sum := sha256.Sum256(data)
+	b.Manifest.Files = append(b.Manifest.Files, model.BundleFile{
+		Path:   name,
+		Size:   int64(len(data)),
+		Sha256: hex.EncodeToString(sum[:]),
+	})
+	b.contents[name] = data
+}
+
+// Write writes the bundle as a tar.gz, where the manifest is the first entry.
+func (b *Bundle) Write(w io.Writer) error {
+
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the manifest: %w", err)
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	writeEntry := func(name string, data []byte) error {
+		header := &tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: b.Manifest.CreatedAt,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	if err := writeEntry(bundleManifestName, manifest); err != nil {
+		return fmt.Errorf("failed to write the manifest: %w", err)
+	}
+	for _, file := range b.Manifest.Files {
+		if err := writeEntry(file.Path, b.contents[file.Path]); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// ImportOptions are the options to import a bundle.
+type ImportOptions struct {
+	// ID to import the terrarium as, instead of the ID in the bundle
+	TrId string
+	// If true, import it with a generated ID when the ID is in use, otherwise it is rejected
+	Rename bool
+	// Request ID to initialize the enrichments, which is suffixed by each enrichment
+	ReqId string
+	// If true, initialize the enrichments in the background after importing the files
+	Async bool
+	// Tenant to import the terrarium into, instead of the tenant in the bundle
+	Tenant string
+}
+
+// readBundle reads the entries of a tar.gz bundle and verifies them by the manifest.
+func readBundle(r io.Reader) (model.BundleManifest, map[string][]byte, error) {
+
+	var manifest model.BundleManifest
+
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return manifest, nil, fmt.Errorf("%w: not a tar.gz: %s", ErrInvalidBundle, err)
+	}
+	defer gr.Close()
+
+	entries := make(map[string][]byte)
+	var total int64
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return manifest, nil, fmt.Errorf("%w: %s", ErrInvalidBundle, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			return manifest, nil, fmt.Errorf("%w: %s is not a regular file", ErrInvalidBundle, header.Name)
+		}
+		if !filepath.IsLocal(header.Name) || path.Clean(header.Name) != header.Name {
+			return manifest, nil, fmt.Errorf("%w: invalid path (%s)", ErrInvalidBundle, header.Name)
+		}
+		if _, exists := entries[header.Name]; exists {
+			return manifest, nil, fmt.Errorf("%w: duplicated file (%s)", ErrInvalidBundle, header.Name)
+		}
+
+		total += header.Size
+		if header.Size < 0 || total > MaxBundleSize {
+			return manifest, nil, fmt.Errorf("%w: the files are larger than %d bytes", ErrInvalidBundle, MaxBundleSize)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, header.Size))
+		if err != nil {
+			return manifest, nil, fmt.Errorf("%w: failed to read %s: %s", ErrInvalidBundle, header.Name, err)
+		}
+		entries[header.Name] = data
+	}
+
+	data, exists := entries[bundleManifestName]
+	if !exists {
+		return manifest, nil, fmt.Errorf("%w: no %s", ErrInvalidBundle, bundleManifestName)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, nil, fmt.Errorf("%w: failed to decode %s: %s", ErrInvalidBundle, bundleManifestName, err)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > bundleFormatVersion {
+		return manifest, nil, fmt.Errorf("%w: unsupported format version (%d), which must be up to %d",
+			ErrInvalidBundle, manifest.FormatVersion, bundleFormatVersion)
+	}
+	delete(entries, bundleManifestName)
+
+	// Verify the files by the checksums, and reject the files not in the manifest
+	if len(manifest.Files) != len(entries) {
+		return manifest, nil, fmt.Errorf("%w: %d file(s) in the bundle, but %d in the manifest", ErrInvalidBundle, len(entries), len(manifest.Files))
+	}
+	for _, file := range manifest.Files {
+		data, exists := entries[file.Path]
+		if !exists {
+			return manifest, nil, fmt.Errorf("%w: no %s in the manifest", ErrInvalidBundle, file.Path)
+		}
+		sum := sha256.Sum256(data)
+		if int64(len(data)) != file.Size || hex.EncodeToString(sum[:]) != file.Sha256 {
+			return manifest, nil, fmt.Errorf("%w: checksum mismatch (%s)", ErrInvalidBundle, file.Path)
+		}
+	}
+	if _, exists := entries[bundleTerrariumName]; !exists {
+		return manifest, nil, fmt.Errorf("%w: no %s", ErrInvalidBundle, bundleTerrariumName)
+	}
+
+	return manifest, entries, nil
+}
+
+// ImportTerrarium recreates a terrarium from a bundle exported by NewBundle.
+// The credentials are copied from the secrets of this server, and each enrichment is initialized
+// (i.e., tofu init) to install the providers while keeping the lifecycle state in the bundle.
+// The failures after the terrarium is recreated are returned as the warnings.
+func ImportTerrarium(r io.Reader, opts ImportOptions) (model.ImportResult, error) {
+
+	var result model.ImportResult
+
+	manifest, entries, err := readBundle(r)
+	if err != nil {
+		return result, err
+	}
+	if manifest.ToolVersion != config.Version {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("the bundle is exported by another version (%s), not %s",
+			manifest.ToolVersion, config.Version))
+	}
+
+	trInfo, err := decodeTerrariumInfo(entries[bundleTerrariumName])
+	if err != nil {
+		return result, fmt.Errorf("%w: failed to decode %s: %s", ErrInvalidBundle, bundleTerrariumName, err)
+	}
+	delete(entries, bundleTerrariumName)
+
+	trInfo.Id = config.NVL(opts.TrId, config.NVL(trInfo.Id, manifest.TerrariumId))
+	if err := ValidateId(trInfo.Id); err != nil {
+		return result, err
+	}
+	if err := ValidateLabels(trInfo.Labels); err != nil {
+		return result, err
+	}
+	trInfo.Tenant = tenant.Normalize(opts.Tenant)
+	if !tenant.Exists(trInfo.Tenant) {
+		return result, fmt.Errorf("%w (tenant: %s): not existed the tenant", tenant.ErrInvalidTenant, trInfo.Tenant)
+	}
+
+	now := time.Now()
+	trInfo.UpdatedAt = now
+	trInfo.Ttl = ""
+	trInfo.ExpiryWarnedAt = nil
+	if trInfo.ExpiresAt != nil && !trInfo.ExpiresAt.After(now) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("the terrarium expired at %s, so it does not expire anymore (see TTL extension)",
+			trInfo.ExpiresAt.Format(time.RFC3339)))
+		trInfo.ExpiresAt = nil
+	}
+
+	// Extract the files to a staging directory, which is moved to the terrarium directory at last
+	parent := filepath.Join(config.Terrarium.Root, terrariumDir)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return result, fmt.Errorf("failed to create the terrarium directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(parent, importStagingPrefix)
+	if err != nil {
+		return result, fmt.Errorf("failed to create a staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	for name, data := range entries {
+		rel, ok := strings.CutPrefix(name, bundleFilesDir+"/")
+		if !ok || !filepath.IsLocal(rel) {
+			return result, fmt.Errorf("%w: unexpected file (%s)", ErrInvalidBundle, name)
+		}
+		dest := filepath.Join(staging, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return result, fmt.Errorf("failed to create a directory to extract %s: %w", name, err)
+		}
+		if err := fileutil.WriteFileAtomic(dest, data, 0644); err != nil {
+			return result, fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+	}
+
+	// Keep the lifecycle state of each enrichment except applying and destroying, which cannot be resumed
+	states := make(map[string]model.EnrichmentInfo, len(trInfo.Enrichments))
+	for name, enrichment := range trInfo.Enrichments {
+		if enrichment.State == StateApplying || enrichment.State == StateDestroying {
+			enrichment.StateReason = "the bundle is exported while " + strings.ToLower(enrichment.State)
+			enrichment.State = StateFailed
+		}
+		states[name] = enrichment
+		trInfo.Enrichments[name] = enrichment
+	}
+
+	// Claim the ID in the store within the quota of the tenant, and generate another one if renaming is allowed
+	if err := claimImportedId(&trInfo, opts.Rename, &result); err != nil {
+		return result, err
+	}
+
+	dir, err := TerrariumPath(trInfo.Id)
+	if err == nil {
+		if _, statErr := os.Stat(dir); statErr == nil {
+			err = fmt.Errorf("%w: the directory of the terrarium (trId: %s) remains", ErrTerrariumExists, trInfo.Id)
+		} else if err = os.MkdirAll(filepath.Dir(dir), 0755); err == nil {
+			err = os.Rename(staging, dir)
+		}
+	}
+	if err != nil {
+		if err2 := DeleteTerrariumInfo(trInfo.Id); err2 != nil {
+			log.Warn().Err(err2).Msgf("failed to delete the terrarium info (trId: %s)", trInfo.Id)
+		}
+		return result, fmt.Errorf("failed to import the terrarium (trId: %s): %w", trInfo.Id, err)
+	}
+	log.Info().Msgf("imported the terrarium (trId: %s) from the bundle of %s exported at %s", trInfo.Id, manifest.TerrariumId,
+		manifest.CreatedAt.Format(time.RFC3339))
+
+	// Initialize the enrichments having the infracode by the request ID suffixed by each enrichment
+	baseReqId := config.NVL(opts.ReqId, fmt.Sprintf("%d", time.Now().UnixNano()))
+	result.Requests = make(map[string]string)
+	for _, name := range infracodeEnrichments(manifest) {
+		result.Requests[name] = baseReqId + "-" + strings.ReplaceAll(name, "/", "-")
+	}
+	if opts.Async {
+		trId, requests := trInfo.Id, result.Requests
+		go func() {
+			for _, warning := range initImportedEnrichments(trId, requests, manifest, states) {
+				log.Warn().Msgf("importing the terrarium (trId: %s): %s", trId, warning)
+			}
+		}()
+	} else {
+		result.Warnings = append(result.Warnings, initImportedEnrichments(trInfo.Id, result.Requests, manifest, states)...)
+	}
+
+	result.Terrarium, err = ReadTerrariumInfo(trInfo.Id)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// claimImportedId creates the info of an imported terrarium in the store within the quota of its tenant.
+// If the ID is in use, it generates another one if renaming is allowed, which is noticed as a warning.
+func claimImportedId(trInfo *model.TerrariumInfo, rename bool, result *model.ImportResult) error {
+
+	unlock, err := lockQuota(trInfo.Tenant)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := checkTerrariumQuota(trInfo.Tenant); err != nil {
+		return err
+	}
+	quota := tenant.QuotaOf(trInfo.Tenant)
+	if quota.MaxEnrichments > 0 {
+		_, enrichments, err := countUsage(trInfo.Tenant)
+		if err != nil {
+			return err
+		}
+		if enrichments+len(trInfo.Enrichments) > quota.MaxEnrichments {
+			return fmt.Errorf("%w (tenant: %s): up to %d enrichments", tenant.ErrQuotaExceeded, trInfo.Tenant, quota.MaxEnrichments)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	for i := 0; ; i++ {
+		for name, enrichment := range trInfo.Enrichments {
+			enrichment.WorkingDir = enrichmentWorkingDir(trInfo.Tenant, trInfo.Id, name)
+			trInfo.Enrichments[name] = enrichment
+		}
+		value, err := json.Marshal(trInfo)
+		if err != nil {
+			return fmt.Errorf("failed to encode terrarium info: %w", err)
+		}
+		_, err = trStore.Create(ctx, terrariumKeyPrefix+trInfo.Id, value)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, store.ErrAlreadyExists) {
+			return err
+		}
+		if !rename || i >= maxUpdateRetries {
+			return fmt.Errorf("%w (trId: %s)", ErrTerrariumExists, trInfo.Id)
+		}
+		previous := trInfo.Id
+		if trInfo.Id, err = generateId(); err != nil {
+			return err
+		}
+		result.Warnings = append(result.Warnings, fmt.Sprintf("the terrarium (trId: %s) already exists, so it is imported as %s", previous, trInfo.Id))
+	}
+}
+
+// infracodeEnrichments returns the enrichments having the infracode in a bundle, sorted by the name.
+func infracodeEnrichments(manifest model.BundleManifest) []string {
+
+	hasInfracode := make(map[string]bool)
+	for _, file := range manifest.Files {
+		rel, ok := strings.CutPrefix(file.Path, bundleFilesDir+"/")
+		if ok && strings.HasSuffix(rel, ".tf") {
+			hasInfracode[path.Dir(rel)] = true
+		}
+	}
+
+	names := make([]string, 0, len(hasInfracode))
+	for name := range hasInfracode {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// initImportedEnrichments copies the credentials and initializes the imported enrichments by the requests of each,
+// and restores the lifecycle states changed by initializing them. It returns the failures as warnings.
+func initImportedEnrichments(trId string, requests map[string]string, manifest model.BundleManifest, states map[string]model.EnrichmentInfo) []string {
+
+	var warnings []string
+
+	names := make([]string, 0, len(requests))
+	for name := range requests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		workingDir, err := TerrariumPath(trId, name)
+		if err != nil {
+			warnings = append(warnings, err.Error())
+			continue
+		}
+
+		for _, credential := range manifest.Credentials[name] {
+			copyCredential, exists := credentialCopiers[credential]
+			if !exists {
+				warnings = append(warnings, fmt.Sprintf("unknown credential (%s) of the enrichment (%s), copy it manually", credential, name))
+				continue
+			}
+			if err := copyCredential(filepath.Join(workingDir, credential)); err != nil {
+				warnings = append(warnings, fmt.Sprintf("failed to copy the credential (%s) of the enrichment (%s): %s", credential, name, err))
+			}
+		}
+
+		jobId := requests[name]
+		if _, err := tofu.ExecuteTofuCommand(trId, name, jobId, "-chdir="+workingDir, "init"); err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to initialize the enrichment (%s), check the request (reqId: %s): %s", name, jobId, err))
+		}
+	}
+
+	// Restore the lifecycle states, which are the states of the resources regardless of the providers installed
+	err := updateTerrariumInfo(trId, func(trInfo *model.TerrariumInfo) error {
+		for name, imported := range states {
+			enrichment, exists := trInfo.Enrichments[name]
+			if !exists {
+				continue
+			}
+			enrichment.State = imported.State
+			enrichment.StateReason = imported.StateReason
+			trInfo.Enrichments[name] = enrichment
+		}
+		return nil
+	})
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("failed to restore the states of the enrichments: %s", err))
+	}
+
+	return warnings
+}
@@ -0,0 +1,190 @@
+package terrarium
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+)
+
+// bundleEntry is an entry of a tar.gz bundle to write as it is.
+type bundleEntry struct {
+	header tar.Header
+	data   []byte
+}
+
+func fileEntry(name, data string) bundleEntry {
+	return bundleEntry{
+		header: tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), Typeflag: tar.TypeReg},
+		data:   []byte(data),
+	}
+}
+
+func manifestEntry(t *testing.T, manifest model.BundleManifest) bundleEntry {
+	t.Helper()
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fileEntry(bundleManifestName, string(data))
+}
+
+// writeTarGz writes the entries as a tar.gz.
+func writeTarGz(t *testing.T, entries ...bundleEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, e := range entries {
+		header := e.header
+		if err := tw.WriteHeader(&header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(e.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testBundle returns a bundle of a terrarium with the files of an enrichment.
+func testBundle() *Bundle {
+	b := &Bundle{
+		Manifest: model.BundleManifest{
+			FormatVersion: bundleFormatVersion,
+			TerrariumId:   "tr-bundle",
+			CreatedAt:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		contents: make(map[string][]byte),
+	}
+	b.add(bundleTerrariumName, []byte(`{"id":"tr-bundle"}`))
+	b.add(bundleFilesDir+"/sql-db/main.tf", []byte(`resource "null_resource" "test" {}`))
+	b.add(bundleFilesDir+"/sql-db/"+stateFileName, []byte(`{"version":4,"resources":[]}`))
+	return b
+}
+
+func TestReadBundle(t *testing.T) {
+	b := testBundle()
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, entries, err := readBundle(&buf)
+	if err != nil {
+		t.Fatalf("readBundle() error = %v", err)
+	}
+	if !reflect.DeepEqual(manifest.Files, b.Manifest.Files) {
+		t.Errorf("files of the manifest = %+v, want %+v", manifest.Files, b.Manifest.Files)
+	}
+	if !reflect.DeepEqual(entries, b.contents) {
+		t.Errorf("entries = %v, want %v", entries, b.contents)
+	}
+}
+
+func TestReadBundleRejects(t *testing.T) {
+	b := testBundle()
+	manifest := manifestEntry(t, b.Manifest)
+	terrariumEntry := fileEntry(bundleTerrariumName, string(b.contents[bundleTerrariumName]))
+	mainTf := fileEntry(bundleFilesDir+"/sql-db/main.tf", string(b.contents[bundleFilesDir+"/sql-db/main.tf"]))
+	state := fileEntry(bundleFilesDir+"/sql-db/"+stateFileName, string(b.contents[bundleFilesDir+"/sql-db/"+stateFileName]))
+
+	// A manifest with a file renamed, which has the same checksum as the entry
+	renamed := b.Manifest
+	renamed.Files = append([]model.BundleFile(nil), b.Manifest.Files...)
+	for i := range renamed.Files {
+		if renamed.Files[i].Path == mainTf.header.Name {
+			renamed.Files[i].Path = bundleFilesDir + "/sql-db/other.tf"
+		}
+	}
+
+	unsupported := b.Manifest
+	unsupported.FormatVersion = bundleFormatVersion + 1
+
+	tamperedTf := fileEntry(mainTf.header.Name, `resource "null_resource" "evil" {}`)
+
+	tests := []struct {
+		name    string
+		entries []bundleEntry
+	}{
+		{name: "parent directory", entries: []bundleEntry{manifest, terrariumEntry, mainTf, fileEntry("../evil.tf", "evil")}},
+		{name: "parent directory inside", entries: []bundleEntry{manifest, terrariumEntry, mainTf, fileEntry("files/../../evil.tf", "evil")}},
+		{name: "absolute path", entries: []bundleEntry{manifest, terrariumEntry, mainTf, fileEntry("/etc/evil.tf", "evil")}},
+		{name: "unclean path", entries: []bundleEntry{manifest, terrariumEntry, fileEntry("files/./sql-db/main.tf", string(mainTf.data)), state}},
+		{
+			name: "symlink",
+			entries: []bundleEntry{manifest, terrariumEntry, mainTf,
+				{header: tar.Header{Name: state.header.Name, Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd", Mode: 0777}}},
+		},
+		{
+			name: "hard link",
+			entries: []bundleEntry{manifest, terrariumEntry, mainTf,
+				{header: tar.Header{Name: state.header.Name, Typeflag: tar.TypeLink, Linkname: "/etc/passwd", Mode: 0600}}},
+		},
+		{
+			name: "directory",
+			entries: []bundleEntry{manifest, terrariumEntry, mainTf, state,
+				{header: tar.Header{Name: "files/sql-db/", Typeflag: tar.TypeDir, Mode: 0755}}},
+		},
+		{name: "duplicated entry", entries: []bundleEntry{manifest, terrariumEntry, mainTf, state, mainTf}},
+		{name: "duplicated manifest", entries: []bundleEntry{manifest, terrariumEntry, mainTf, state, manifest}},
+		{name: "checksum mismatch", entries: []bundleEntry{manifest, terrariumEntry, tamperedTf, state}},
+		{name: "size mismatch", entries: []bundleEntry{manifest, terrariumEntry, fileEntry(mainTf.header.Name, string(mainTf.data)+"\n"), state}},
+		{name: "entry not in manifest", entries: []bundleEntry{manifest, terrariumEntry, mainTf, state, fileEntry("files/sql-db/extra.tf", "extra")}},
+		{name: "manifest file not in entries", entries: []bundleEntry{manifest, terrariumEntry, state}},
+		{name: "renamed file", entries: []bundleEntry{manifestEntry(t, renamed), terrariumEntry, mainTf, state}},
+		{name: "no manifest", entries: []bundleEntry{terrariumEntry, mainTf, state}},
+		{name: "invalid manifest", entries: []bundleEntry{fileEntry(bundleManifestName, "{"), terrariumEntry, mainTf, state}},
+		{name: "unsupported format version", entries: []bundleEntry{manifestEntry(t, unsupported), terrariumEntry, mainTf, state}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := writeTarGz(t, tt.entries...)
+			if _, _, err := readBundle(bytes.NewReader(data)); !errors.Is(err, ErrInvalidBundle) {
+				t.Errorf("readBundle() error = %v, want %v", err, ErrInvalidBundle)
+			}
+		})
+	}
+}
+
+func TestReadBundleRejectsNonTarGz(t *testing.T) {
+	if _, _, err := readBundle(bytes.NewReader([]byte("not a bundle"))); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("readBundle() error = %v, want %v", err, ErrInvalidBundle)
+	}
+}
+
+// An entry larger than the max size is rejected by its header without reading it.
+func TestReadBundleRejectsLargeEntry(t *testing.T) {
+	var header bytes.Buffer
+	tw := tar.NewWriter(&header)
+	if err := tw.WriteHeader(&tar.Header{Name: "files/sql-db/large.tf", Mode: 0600, Size: MaxBundleSize + 1, Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(header.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := readBundle(&buf); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("readBundle() error = %v, want %v", err, ErrInvalidBundle)
+	}
+}
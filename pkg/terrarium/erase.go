@@ -0,0 +1,336 @@
+package terrarium
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/cloud-barista/mc-terrarium/pkg/enrichment"
+	"github.com/cloud-barista/mc-terrarium/pkg/fileutil"
+	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Directory (in the terrarium directory) to archive the states of the force-erased terrariums
+	archiveDirName = ".archive"
+
+	// State files to archive in a working directory
+	stateFileName       = "terraform.tfstate"
+	stateBackupFileName = "terraform.tfstate.backup"
+)
+
+// Order to destroy the kinds of enrichments, where the others may depend on the later ones (e.g., the network by VPN)
+var destroyOrder = map[string]int{
+	"vpn": 1,
+}
+
+// remainingResources returns the resources remaining in the state of a working directory,
+// except the imported ones which existed before applying and are not destroyed.
+func remainingResources(workingDir string) ([]string, error) {
+
+	resources, err := tofu.StateResources(workingDir)
+	if err != nil || len(resources) == 0 {
+		return resources, err
+	}
+
+	imported, err := tofu.ImportedResources(workingDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(imported) == 0 {
+		return resources, nil
+	}
+
+	isImported := make(map[string]bool, len(imported))
+	for _, address := range imported {
+		isImported[address] = true
+	}
+	var owned []string
+	for _, address := range resources {
+		if !isImported[address] {
+			owned = append(owned, address)
+		}
+	}
+	return owned, nil
+}
+
+// stateDirs returns the names of the enrichments (i.e., the working directories relative to the terrarium directory)
+// which have a state file, including the ones not recorded in the terrarium info (e.g., created by an old version).
+func stateDirs(trId string) ([]string, error) {
+
+	root, err := TerrariumPath(trId)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() && strings.HasPrefix(d.Name(), ".") && path != root {
+			// Skip the provider plugins (.terraform) and so on
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && d.Name() == stateFileName {
+			name, err := filepath.Rel(root, filepath.Dir(path))
+			if err != nil {
+				return err
+			}
+			names = append(names, filepath.ToSlash(name))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the state files of the terrarium (trId: %s): %w", trId, err)
+	}
+
+	return names, nil
+}
+
+// ListRemainingResources returns the resources remaining in the state of each enrichment of a terrarium
+// in the order to destroy them. The enrichments without any resources are omitted.
+func ListRemainingResources(trId string) ([]model.EnrichmentResources, error) {
+
+	trInfo, err := ReadTerrariumInfo(trId)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := stateDirs(trId)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []model.EnrichmentResources
+	for _, name := range names {
+		workingDir, err := TerrariumPath(trId, name)
+		if err != nil {
+			return nil, err
+		}
+		resources, err := remainingResources(workingDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the state of the enrichment (trId: %s, enrichments: %s): %w", trId, name, err)
+		}
+		if len(resources) == 0 {
+			continue
+		}
+		list = append(list, model.EnrichmentResources{
+			Enrichments: name,
+			State:       trInfo.Enrichments[name].State,
+			Resources:   resources,
+		})
+	}
+
+	sortForDestroy(list, trInfo.Enrichments)
+
+	return list, nil
+}
+
+// sortForDestroy sorts the enrichments in the order to destroy them: the kinds which the others may depend on later,
+// and the recently created ones earlier in the same kind.
+func sortForDestroy(list []model.EnrichmentResources, enrichments map[string]model.EnrichmentInfo) {
+	sort.SliceStable(list, func(i, j int) bool {
+		ki, _ := splitEnrichment(list[i].Enrichments)
+		kj, _ := splitEnrichment(list[j].Enrichments)
+		if destroyOrder[ki] != destroyOrder[kj] {
+			return destroyOrder[ki] < destroyOrder[kj]
+		}
+		ci := enrichments[list[i].Enrichments].CreatedAt
+		cj := enrichments[list[j].Enrichments].CreatedAt
+		if !ci.Equal(cj) {
+			return ci.After(cj)
+		}
+		return list[i].Enrichments < list[j].Enrichments
+	})
+}
+
+// CheckErase checks if a terrarium can be erased, which is rejected while any enrichment is applying or destroying.
+func CheckErase(trId string) error {
+
+	trInfo, err := ReadTerrariumInfo(trId)
+	if err != nil {
+		return err
+	}
+
+	for _, enrichment := range trInfo.Enrichments {
+		if enrichment.State == StateApplying || enrichment.State == StateDestroying {
+			return &TransitionError{TrId: trId, Enrichments: enrichment.Name, From: enrichment.State, To: StateEmpty}
+		}
+	}
+
+	return nil
+}
+
+// DestroyEnrichments destroys the resources of the enrichments in the given order (see ListRemainingResources).
+// It stops at the first failure. Each enrichment is destroyed by a job of the request holding the lock of the terrarium
+// (see tofu.TerrariumLock.JobId), which is the only one running in the terrarium while it is erased.
+func DestroyEnrichments(trId string, lock *tofu.TerrariumLock, list []model.EnrichmentResources) error {
+
+	for _, r := range list {
+		jobId, err := lock.JobId(r.Enrichments)
+		if err != nil {
+			return err
+		}
+		if err := destroyEnrichment(trId, r.Enrichments, jobId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// destroyEnrichment destroys the resources of an enrichment, running the BeforeDestroy hook of its descriptor
+// not to destroy the imported ones.
+func destroyEnrichment(trId, name, reqId string) error {
+
+	workingDir, err := TerrariumPath(trId, name)
+	if err != nil {
+		return err
+	}
+
+	// Move the enrichment to the Destroying state if it is recorded
+	prevState, err := transition(trId, name, StateDestroying, "", true)
+	recorded := err == nil
+	if err != nil && !errors.Is(err, ErrEnrichmentNotFound) {
+		return err
+	}
+	rollback := func() {
+		if !recorded {
+			return
+		}
+		if err := RollbackTransition(trId, name, StateDestroying, prevState); err != nil {
+			log.Warn().Err(err).Msgf("failed to roll back the state of the enrichment (trId: %s, enrichments: %s)", trId, name)
+		}
+	}
+
+	// Run the hook of the enrichment (e.g., remove the imported resources from the state),
+	// which is skipped for an unknown one (e.g., created by an old version)
+	if d, err := enrichment.Get(name); err == nil && d.BeforeDestroy != nil {
+		provider := ""
+		if trInfo, err := ReadTerrariumInfo(trId); err == nil {
+			provider = trInfo.Enrichments[name].Provider
+		}
+		target := enrichment.Target{TrId: trId, Name: name, Provider: provider, ReqId: reqId, WorkingDir: workingDir}
+		if err := d.BeforeDestroy(target); err != nil {
+			rollback()
+			return fmt.Errorf("failed to prepare destroying the enrichment (trId: %s, enrichments: %s): %w", trId, name, err)
+		}
+	}
+
+	log.Info().Msgf("destroying the enrichment (trId: %s, enrichments: %s) to erase the terrarium", trId, name)
+	if _, err := tofu.ExecuteTofuCommand(trId, name, reqId, "-chdir="+workingDir, "destroy", "-auto-approve", "-json"); err != nil {
+		rollback()
+		return fmt.Errorf("failed to destroy the enrichment (trId: %s, enrichments: %s): %w", trId, name, err)
+	}
+
+	return nil
+}
+
+// RemoveTerrarium removes the directory and the info of a terrarium.
+// It should be checked by CheckErase, and the remaining resources should be destroyed or archived before.
+func RemoveTerrarium(trId string) error {
+
+	tenantName, err := removeTerrarium(trId)
+	if err != nil {
+		return err
+	}
+
+	emitEvent(model.TerrariumEvent{
+		Type:        EventErased,
+		TerrariumId: trId,
+		Tenant:      tenantName,
+		Message:     fmt.Sprintf("the terrarium (trId: %s) is erased", trId),
+	})
+	return nil
+}
+
+// removeTerrarium removes the directory and the info of a terrarium without the event,
+// and returns the tenant of it, which cannot be read after the info is deleted.
+func removeTerrarium(trId string) (string, error) {
+
+	tenantName, _ := TenantOf(trId)
+
+	dir, err := TerrariumPath(trId)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return "", fmt.Errorf("failed to remove the directory of the terrarium (trId: %s): %w", trId, err)
+	}
+
+	if err := DeleteTerrariumInfo(trId); err != nil {
+		return "", err
+	}
+	return tenantName, nil
+}
+
+// ArchiveStates copies the state files of a terrarium with the terrarium info to an archive directory
+// (i.e., .terrarium/.archive/{trId}-{timestamp}) before erasing it forcibly, and returns the archive directory.
+// The other files (e.g., credentials) are not archived.
+func ArchiveStates(trId string) (string, error) {
+
+	trInfo, err := ReadTerrariumInfo(trId)
+	if err != nil {
+		return "", err
+	}
+
+	names, err := stateDirs(trId)
+	if err != nil {
+		return "", err
+	}
+
+	archiveDir := filepath.Join(config.Terrarium.Root, terrariumDir, archiveDirName,
+		fmt.Sprintf("%s-%s", trId, time.Now().UTC().Format("20060102T150405Z")))
+
+	for _, name := range names {
+		for _, fileName := range []string{stateFileName, stateBackupFileName} {
+			path, err := TerrariumPath(trId, name, fileName)
+			if err != nil {
+				return "", err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return "", fmt.Errorf("failed to read the state file (trId: %s, enrichments: %s): %w", trId, name, err)
+			}
+			dest := filepath.Join(archiveDir, name, fileName)
+			if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+				return "", fmt.Errorf("failed to create the archive directory: %w", err)
+			}
+			if err := fileutil.WriteFileAtomic(dest, data, 0600); err != nil {
+				return "", fmt.Errorf("failed to archive the state file (trId: %s, enrichments: %s): %w", trId, name, err)
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(trInfo, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode terrarium info: %w", err)
+	}
+	if err := os.MkdirAll(archiveDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create the archive directory: %w", err)
+	}
+	if err := fileutil.WriteFileAtomic(filepath.Join(archiveDir, "terrarium.json"), data, 0600); err != nil {
+		return "", fmt.Errorf("failed to archive terrarium info: %w", err)
+	}
+
+	log.Warn().Msgf("archived the states of the terrarium (trId: %s) to %s", trId, archiveDir)
+
+	return archiveDir, nil
+}
@@ -0,0 +1,61 @@
+package terrarium
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/rs/zerolog/log"
+)
+
+// Types of the terrarium events
+const (
+	EventExpiring   = "terrarium.expiring"
+	EventExpired    = "terrarium.expired"
+	EventReaped     = "terrarium.reaped"
+	EventReapFailed = "terrarium.reap_failed"
+	EventErased     = "terrarium.erased"
+	// An enrichment moved from a lifecycle state to another (e.g., Applying to Applied)
+	EventStateChanged = "enrichment.state_changed"
+)
+
+var (
+	eventListenersMu sync.RWMutex
+	eventListeners   []func(event model.TerrariumEvent)
+)
+
+// AddEventListener adds a function to be called on each terrarium event (e.g., to notify it is expiring).
+// The function is called synchronously by the reaper and the requests, so it should not block long.
+func AddEventListener(listener func(event model.TerrariumEvent)) {
+	eventListenersMu.Lock()
+	defer eventListenersMu.Unlock()
+	eventListeners = append(eventListeners, listener)
+}
+
+// notifyEvent logs an event and calls the listeners with it.
+func notifyEvent(eventType, trId, message string) {
+	log.Warn().Str("event", eventType).Msg(message)
+	emitEvent(model.TerrariumEvent{
+		Type:        eventType,
+		TerrariumId: trId,
+		Message:     message,
+	})
+}
+
+// emitEvent calls the listeners with an event, setting the tenant of the terrarium if it is not set.
+func emitEvent(event model.TerrariumEvent) {
+	if event.Tenant == "" {
+		event.Tenant, _ = TenantOf(event.TerrariumId)
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	eventListenersMu.RLock()
+	listeners := eventListeners
+	eventListenersMu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
@@ -0,0 +1,116 @@
+package terrarium
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+)
+
+const (
+	// Max length of a terrarium ID, which is the prefix of the cloud resource names
+	// (e.g., {trId}-svc of a SQL database in NCP, which is up to 30 characters)
+	maxIdLength = 26
+
+	// Prefix and length of the random part of a generated terrarium ID (e.g., tr-k3x9q2ab)
+	generatedIdPrefix = "tr-"
+	generatedIdLength = 8
+	generatedIdChars  = "abcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// ErrInvalidId is returned when a terrarium ID is not allowed.
+var ErrInvalidId = errors.New("invalid terrarium ID")
+
+// A terrarium ID is a DNS label (RFC 1035): lowercase alphanumerics and '-', starting with a letter
+// and ending with an alphanumeric, so that it is safe as a directory name and a cloud resource name.
+var idPattern = regexp.MustCompile(`^[a-z]([a-z0-9-]*[a-z0-9])?$`)
+
+// IDs reserved for the other directories in the terrarium directory
+var reservedIds = map[string]bool{
+	"test-env": true,
+	tenantsDir: true,
+}
+
+// ValidateId checks if a terrarium ID is allowed.
+func ValidateId(trId string) error {
+	if trId == "" {
+		return fmt.Errorf("%w: the ID is required", ErrInvalidId)
+	}
+	if len(trId) > maxIdLength {
+		return fmt.Errorf("%w (trId: %s): it must be up to %d characters", ErrInvalidId, trId, maxIdLength)
+	}
+	if !idPattern.MatchString(trId) {
+		return fmt.Errorf("%w (trId: %s): it must consist of lowercase alphanumerics and '-', start with a letter and end with an alphanumeric", ErrInvalidId, trId)
+	}
+	if reservedIds[trId] {
+		return fmt.Errorf("%w (trId: %s): it is reserved", ErrInvalidId, trId)
+	}
+	return nil
+}
+
+// Max length of an existing terrarium ID as a directory name
+const maxPathIdLength = 255
+
+// checkPathId checks if the ID of an existing terrarium is safe as a directory name, which may have been
+// issued before ValidateId (e.g., with uppercase letters or '_'). It rejects the IDs which are not a single
+// path element (e.g., ../tr02, a/b), the hidden and the reserved directories (e.g., .archive, tenants).
+func checkPathId(trId string) error {
+	if trId == "" {
+		return fmt.Errorf("%w: the ID is required", ErrInvalidId)
+	}
+	if len(trId) > maxPathIdLength {
+		return fmt.Errorf("%w (trId: %s): it must be up to %d characters", ErrInvalidId, trId, maxPathIdLength)
+	}
+	if strings.ContainsAny(trId, "/\\\x00") || strings.HasPrefix(trId, ".") {
+		return fmt.Errorf("%w (trId: %s): it must be a single path element not starting with '.'", ErrInvalidId, trId)
+	}
+	if reservedIds[trId] {
+		return fmt.Errorf("%w (trId: %s): it is reserved", ErrInvalidId, trId)
+	}
+	return nil
+}
+
+// generateId returns a random terrarium ID (e.g., tr-k3x9q2ab).
+func generateId() (string, error) {
+	var sb strings.Builder
+	sb.WriteString(generatedIdPrefix)
+	for i := 0; i < generatedIdLength; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(generatedIdChars))))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate a terrarium ID: %w", err)
+		}
+		sb.WriteByte(generatedIdChars[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// TerrariumPath returns the directory of a terrarium in the directory of its tenant
+// (i.e., {root}/.terrarium/{trId} or {root}/.terrarium/tenants/{tenant}/{trId}),
+// or the path of the given elements in it (e.g., an enrichment, vpn/gcp-aws).
+// It rejects a terrarium ID unsafe as a directory name (see checkPathId), not by ValidateId
+// to keep the terrariums issued before it accessible, and a path escaping the terrarium directory (e.g., ../tr02).
+func TerrariumPath(trId string, elem ...string) (string, error) {
+
+	if err := checkPathId(trId); err != nil {
+		return "", err
+	}
+	tenantName, err := tenantPathOf(trId)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(config.Terrarium.Root, filepath.FromSlash(terrariumRelDir(tenantName, trId)))
+	path := filepath.Join(append([]string{dir}, elem...)...)
+
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path (trId: %s, path: %s): it must be in the terrarium directory", trId, filepath.Join(elem...))
+	}
+
+	return path, nil
+}
@@ -0,0 +1,63 @@
+package terrarium
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateId(t *testing.T) {
+	tests := []struct {
+		trId    string
+		wantErr bool
+	}{
+		{trId: "tr01"},
+		{trId: "my-terrarium-2"},
+		{trId: "", wantErr: true},
+		{trId: "Tr01", wantErr: true},
+		{trId: "tr_01", wantErr: true},
+		{trId: "1tr", wantErr: true},
+		{trId: "tr01-", wantErr: true},
+		{trId: "a-terrarium-id-longer-than-26", wantErr: true},
+		{trId: "../tr02", wantErr: true},
+		{trId: "test-env", wantErr: true},
+		{trId: "tenants", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateId(tt.trId)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateId(%q) error = %v, wantErr %v", tt.trId, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidId) {
+			t.Errorf("ValidateId(%q) error = %v, want %v", tt.trId, err, ErrInvalidId)
+		}
+	}
+}
+
+// The IDs issued before ValidateId are accessible unless they are unsafe as a directory name.
+func TestCheckPathId(t *testing.T) {
+	tests := []struct {
+		trId    string
+		wantErr bool
+	}{
+		{trId: "tr01"},
+		{trId: "Tr01"},
+		{trId: "tr_01"},
+		{trId: "a-terrarium-id-longer-than-26"},
+		{trId: "", wantErr: true},
+		{trId: "..", wantErr: true},
+		{trId: "../tr02", wantErr: true},
+		{trId: "tr01/vpn", wantErr: true},
+		{trId: `tr01\vpn`, wantErr: true},
+		{trId: ".archive", wantErr: true},
+		{trId: "tenants", wantErr: true},
+		{trId: "tr\x0001", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := checkPathId(tt.trId)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkPathId(%q) error = %v, wantErr %v", tt.trId, err, tt.wantErr)
+		}
+	}
+}
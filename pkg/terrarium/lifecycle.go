@@ -0,0 +1,282 @@
+package terrarium
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
+	"github.com/rs/zerolog/log"
+)
+
+// Lifecycle states of an enrichment
+const (
+	StateEmpty       = "Empty"
+	StateInitialized = "Initialized"
+	StateConfigured  = "Configured"
+	StatePlanned     = "Planned"
+	StateApplying    = "Applying"
+	StateApplied     = "Applied"
+	StateDestroying  = "Destroying"
+	StateDestroyed   = "Destroyed"
+	StateFailed      = "Failed"
+)
+
+// ErrInvalidTransition is returned when an enrichment cannot move from the current state to another.
+var ErrInvalidTransition = errors.New("invalid transition")
+
+// transitions are the states to which an enrichment can move from each state.
+// Moving to Empty means clearing the working directory of the enrichment.
+var transitions = map[string][]string{
+	StateEmpty:       {StateEmpty, StateInitialized, StateFailed},
+	StateInitialized: {StateEmpty, StateInitialized, StateConfigured, StateFailed},
+	StateConfigured:  {StateEmpty, StateInitialized, StateConfigured, StatePlanned, StateApplying, StateFailed},
+	StatePlanned:     {StateEmpty, StateInitialized, StateConfigured, StatePlanned, StateApplying, StateFailed},
+	StateApplying:    {StateApplied, StateFailed},
+	StateApplied:     {StateConfigured, StatePlanned, StateApplying, StateDestroying},
+	StateDestroying:  {StateDestroyed, StateFailed},
+	StateDestroyed:   {StateEmpty, StateInitialized, StateConfigured, StatePlanned, StateApplying, StateDestroying},
+	StateFailed:      {StateEmpty, StateInitialized, StateConfigured, StatePlanned, StateApplying, StateDestroying},
+}
+
+// TransitionError explains why an enrichment cannot move to a state.
+type TransitionError struct {
+	TrId        string
+	Enrichments string
+	From        string
+	To          string
+	// Resources remaining in the state, which prevent clearing the enrichment
+	Resources []string
+}
+
+func (e *TransitionError) Error() string {
+	if len(e.Resources) > 0 {
+		return fmt.Sprintf("cannot clear the enrichment (trId: %s, enrichments: %s) since %d resource(s) remain in the state (%s), destroy them first",
+			e.TrId, e.Enrichments, len(e.Resources), strings.Join(e.Resources, ", "))
+	}
+
+	text := fmt.Sprintf("cannot move the enrichment (trId: %s, enrichments: %s) from %s to %s", e.TrId, e.Enrichments, e.From, e.To)
+	if hint := transitionHint(e.From, e.To); hint != "" {
+		text += ", " + hint
+	}
+	return text
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
+// canTransition returns whether an enrichment can move from a state to another.
+func canTransition(from, to string) bool {
+	for _, state := range transitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionHint returns what to do before moving from a state to another.
+func transitionHint(from, to string) string {
+	switch from {
+	case StateEmpty:
+		return "initialize it first"
+	case StateInitialized:
+		return "create the infracode first"
+	case StateApplying:
+		return "wait until applying is completed"
+	case StateDestroying:
+		return "wait until destroying is completed"
+	case StateApplied:
+		return "destroy the resources first"
+	}
+	return ""
+}
+
+// checkTransition returns a TransitionError if an enrichment cannot move to a state.
+func checkTransition(trId string, enrichment model.EnrichmentInfo, to string) error {
+	from := config.NVL(enrichment.State, StateEmpty)
+	if !canTransition(from, to) {
+		return &TransitionError{TrId: trId, Enrichments: enrichment.Name, From: from, To: to}
+	}
+	return nil
+}
+
+// CheckTransition checks if an enrichment can move to a state without changing it.
+func CheckTransition(trId, name, to string) error {
+
+	enrichment, err := ReadEnrichmentInfo(trId, name)
+	if err != nil {
+		return err
+	}
+
+	return checkTransition(trId, enrichment, to)
+}
+
+// TransitionEnrichment moves an enrichment to a state if it is allowed, and returns the previous state.
+func TransitionEnrichment(trId, name, to string) (string, error) {
+	return transition(trId, name, to, "", false)
+}
+
+// transition moves an enrichment to a state with the reason (e.g., why it failed) if it is allowed.
+// If force is true, it is allowed from any state except applying and destroying (e.g., to destroy the
+// resources remaining in the state of an enrichment in any state when erasing the terrarium).
+func transition(trId, name, to, reason string, force bool) (string, error) {
+
+	var from string
+	err := updateTerrariumInfo(trId, func(trInfo *model.TerrariumInfo) error {
+		enrichment, exists := trInfo.Enrichments[name]
+		if !exists {
+			return fmt.Errorf("%w (trId: %s, enrichments: %s)", ErrEnrichmentNotFound, trId, name)
+		}
+		busy := enrichment.State == StateApplying || enrichment.State == StateDestroying
+		if !force || busy {
+			if err := checkTransition(trId, enrichment, to); err != nil {
+				return err
+			}
+		}
+
+		from = config.NVL(enrichment.State, StateEmpty)
+		enrichment.State = to
+		enrichment.StateReason = reason
+		enrichment.UpdatedAt = time.Now()
+		trInfo.Enrichments[name] = enrichment
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if from != to {
+		log.Info().Msgf("the enrichment (trId: %s, enrichments: %s) moved from %s to %s", trId, name, from, to)
+		notifyStateChanged(trId, name, from, to, reason)
+	}
+	return from, nil
+}
+
+// notifyStateChanged calls the event listeners with an enrichment moved from a state to another.
+func notifyStateChanged(trId, name, from, to, reason string) {
+	message := fmt.Sprintf("the enrichment (trId: %s, enrichments: %s) moved from %s to %s", trId, name, from, to)
+	if reason != "" {
+		message += ", " + reason
+	}
+	emitEvent(model.TerrariumEvent{
+		Type:        EventStateChanged,
+		TerrariumId: trId,
+		Enrichments: name,
+		From:        from,
+		To:          to,
+		Message:     message,
+	})
+}
+
+// RollbackTransition moves an enrichment back to the previous state if it is still in the state,
+// e.g., when the command to apply could not be requested since the queue is full.
+func RollbackTransition(trId, name, state, previous string) error {
+
+	rolledBack := false
+	err := updateTerrariumInfo(trId, func(trInfo *model.TerrariumInfo) error {
+		enrichment, exists := trInfo.Enrichments[name]
+		rolledBack = exists && enrichment.State == state
+		if !rolledBack {
+			return nil
+		}
+		enrichment.State = previous
+		enrichment.UpdatedAt = time.Now()
+		trInfo.Enrichments[name] = enrichment
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if rolledBack && state != previous {
+		notifyStateChanged(trId, name, state, previous, "rolled back")
+	}
+	return nil
+}
+
+// CheckClear checks if the working directory of an enrichment can be cleared,
+// which is allowed only when no resources remain in the state so that no cloud resources are orphaned.
+func CheckClear(trId, name string) error {
+
+	enrichment, err := ReadEnrichmentInfo(trId, name)
+	if err != nil && !errors.Is(err, ErrEnrichmentNotFound) {
+		return err
+	}
+	if err == nil {
+		if err := checkTransition(trId, enrichment, StateEmpty); err != nil {
+			return err
+		}
+	}
+
+	workingDir, err := TerrariumPath(trId, name)
+	if err != nil {
+		return err
+	}
+	resources, err := remainingResources(workingDir)
+	if err != nil {
+		return err
+	}
+	if len(resources) > 0 {
+		return &TransitionError{
+			TrId:        trId,
+			Enrichments: name,
+			From:        config.NVL(enrichment.State, StateEmpty),
+			To:          StateEmpty,
+			Resources:   resources,
+		}
+	}
+
+	return nil
+}
+
+// OnJobFinished moves an enrichment by the result of a job (i.e., init, plan, apply and destroy).
+// It should be added as a listener of the jobs.
+func OnJobFinished(job model.JobInfo) {
+
+	enrichment, err := ReadEnrichmentInfo(job.TerrariumId, job.Enrichments)
+	if err != nil {
+		log.Debug().Err(err).Msgf("no enrichment to update by the request (reqId: %s)", job.Id)
+		return
+	}
+
+	succeeded := job.Status == tofu.JobStatusSuccess
+
+	var to string
+	switch job.Subcommand {
+	case "init":
+		to = StateInitialized
+	case "plan":
+		if !succeeded {
+			// Nothing has been changed by the failed plan
+			return
+		}
+		to = StatePlanned
+	case "apply":
+		if enrichment.State != StateApplying {
+			return
+		}
+		to = StateApplied
+	case "destroy":
+		if enrichment.State != StateDestroying {
+			return
+		}
+		to = StateDestroyed
+	default:
+		return
+	}
+
+	reason := ""
+	if !succeeded {
+		to = StateFailed
+		reason = fmt.Sprintf("the request (reqId: %s) to %s is %s", job.Id, job.Subcommand, job.Status)
+	}
+
+	if _, err := transition(job.TerrariumId, job.Enrichments, to, reason, false); err != nil {
+		log.Warn().Err(err).Msgf("failed to update the state of the enrichment by the request (reqId: %s)", job.Id)
+	}
+}
@@ -0,0 +1,272 @@
+package terrarium
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/tenant"
+)
+
+const (
+	// Max length of a label key and value
+	maxLabelLength = 63
+
+	// Fields to sort the terrariums
+	SortById        = "id"
+	SortByCreatedAt = "createdAt"
+	SortByUpdatedAt = "updatedAt"
+)
+
+var (
+	// ErrInvalidLabel is returned when a label key or value is invalid.
+	ErrInvalidLabel = errors.New("invalid label")
+	// ErrInvalidListOptions is returned when an option to list the terrariums is invalid.
+	ErrInvalidListOptions = errors.New("invalid list options")
+)
+
+// Label keys (e.g., team, app.kubernetes.io/name) and values consist of alphanumerics, '-', '_', '.' and '/' for keys
+var (
+	labelKeyPattern   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]*[A-Za-z0-9])?$`)
+	labelValuePattern = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?)?$`)
+)
+
+// ValidateLabels checks the keys and values of labels.
+func ValidateLabels(labels map[string]string) error {
+	for key, value := range labels {
+		if len(key) > maxLabelLength || !labelKeyPattern.MatchString(key) {
+			return fmt.Errorf("%w key: %q (alphanumerics, '-', '_', '.' and '/' up to %d characters)", ErrInvalidLabel, key, maxLabelLength)
+		}
+		if len(value) > maxLabelLength || !labelValuePattern.MatchString(value) {
+			return fmt.Errorf("%w value: %q of key %q (alphanumerics, '-', '_' and '.' up to %d characters)", ErrInvalidLabel, value, key, maxLabelLength)
+		}
+	}
+	return nil
+}
+
+// ListOptions are the options to filter, sort and paginate the terrariums.
+type ListOptions struct {
+	// Tenant of the terrariums, where empty means all the tenants
+	Tenant string
+	// Label selector (e.g., "team=infra,env!=dev,owner,!temporary")
+	LabelSelector string
+	// Name (e.g., vpn/gcp-aws) or kind (e.g., vpn) of an enrichment in the terrarium
+	Enrichments string
+	// Provider of an enrichment in the terrarium (e.g., aws)
+	Provider string
+	// Lifecycle state of an enrichment in the terrarium (e.g., Applied)
+	State string
+	// Field to sort by (id, createdAt or updatedAt), which is prefixed by '-' for the descending order
+	Sort string
+	// Max number of terrariums to return, where 0 means no limit
+	Limit int
+	// Cursor returned by the previous page
+	Cursor string
+}
+
+// labelRequirement is a requirement of a label selector.
+type labelRequirement struct {
+	key      string
+	operator string // "=", "!=", "exists" or "!exists"
+	value    string
+}
+
+// parseLabelSelector parses an equality-based label selector (e.g., "team=infra,env!=dev,owner,!temporary").
+func parseLabelSelector(selector string) ([]labelRequirement, error) {
+
+	var requirements []labelRequirement
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		var r labelRequirement
+		switch {
+		case strings.Contains(term, "!="):
+			key, value, _ := strings.Cut(term, "!=")
+			r = labelRequirement{key: strings.TrimSpace(key), operator: "!=", value: strings.TrimSpace(value)}
+		case strings.Contains(term, "=="):
+			key, value, _ := strings.Cut(term, "==")
+			r = labelRequirement{key: strings.TrimSpace(key), operator: "=", value: strings.TrimSpace(value)}
+		case strings.Contains(term, "="):
+			key, value, _ := strings.Cut(term, "=")
+			r = labelRequirement{key: strings.TrimSpace(key), operator: "=", value: strings.TrimSpace(value)}
+		case strings.HasPrefix(term, "!"):
+			r = labelRequirement{key: strings.TrimSpace(term[1:]), operator: "!exists"}
+		default:
+			r = labelRequirement{key: term, operator: "exists"}
+		}
+
+		if !labelKeyPattern.MatchString(r.key) {
+			return nil, fmt.Errorf("%w: label selector %q", ErrInvalidListOptions, term)
+		}
+		requirements = append(requirements, r)
+	}
+
+	return requirements, nil
+}
+
+// matches returns whether the labels satisfy the requirement.
+func (r labelRequirement) matches(labels map[string]string) bool {
+	value, exists := labels[r.key]
+	switch r.operator {
+	case "=":
+		return exists && value == r.value
+	case "!=":
+		return !exists || value != r.value
+	case "exists":
+		return exists
+	case "!exists":
+		return !exists
+	}
+	return false
+}
+
+// matchesEnrichments returns whether any enrichment of the terrarium matches the filters.
+func matchesEnrichments(trInfo model.TerrariumInfo, opts ListOptions) bool {
+	if opts.Enrichments == "" && opts.Provider == "" && opts.State == "" {
+		return true
+	}
+
+	for _, enrichment := range trInfo.Enrichments {
+		if opts.Enrichments != "" && enrichment.Name != opts.Enrichments && enrichment.Kind != opts.Enrichments {
+			continue
+		}
+		if opts.Provider != "" && !strings.EqualFold(enrichment.Provider, opts.Provider) {
+			continue
+		}
+		if opts.State != "" && !strings.EqualFold(enrichment.State, opts.State) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// listCursor is the position of the last terrarium of a page, which is encoded as an opaque string.
+type listCursor struct {
+	Sort string    `json:"s"`
+	Time time.Time `json:"t,omitempty"`
+	Id   string    `json:"i"`
+}
+
+func encodeCursor(cursor listCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (listCursor, error) {
+	var cursor listCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err != nil {
+		return listCursor{}, fmt.Errorf("%w: cursor %q", ErrInvalidListOptions, s)
+	}
+	return cursor, nil
+}
+
+// sortTime returns the time of a terrarium to sort by.
+func sortTime(trInfo model.TerrariumInfo, field string) time.Time {
+	if field == SortByUpdatedAt {
+		return trInfo.UpdatedAt
+	}
+	return trInfo.CreatedAt
+}
+
+// ListTerrariumInfo returns a page of the terrariums filtered and sorted by the options,
+// and the cursor of the next page, which is empty if it is the last page.
+// The cursor keeps the position even if terrariums are issued or erased between the pages.
+func ListTerrariumInfo(opts ListOptions) ([]model.TerrariumInfo, string, error) {
+
+	requirements, err := parseLabelSelector(opts.LabelSelector)
+	if err != nil {
+		return nil, "", err
+	}
+
+	descending := strings.HasPrefix(opts.Sort, "-")
+	field := strings.TrimPrefix(opts.Sort, "-")
+	switch field {
+	case "":
+		field = SortById
+	case SortById, SortByCreatedAt, SortByUpdatedAt:
+	default:
+		return nil, "", fmt.Errorf("%w: sort %q (id, createdAt or updatedAt)", ErrInvalidListOptions, opts.Sort)
+	}
+	if opts.Limit < 0 {
+		return nil, "", fmt.Errorf("%w: limit %d", ErrInvalidListOptions, opts.Limit)
+	}
+
+	var after *listCursor
+	if opts.Cursor != "" {
+		cursor, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if cursor.Sort != opts.Sort {
+			return nil, "", fmt.Errorf("%w: the cursor is for another sort (%q)", ErrInvalidListOptions, cursor.Sort)
+		}
+		after = &cursor
+	}
+
+	all, err := ReadAllTerrariumInfo()
+	if err != nil {
+		return nil, "", err
+	}
+
+	// less returns whether a terrarium comes before the position in the order
+	less := func(a model.TerrariumInfo, t time.Time, id string) bool {
+		if field != SortById {
+			if ta := sortTime(a, field); !ta.Equal(t) {
+				return ta.Before(t) != descending
+			}
+		}
+		return (a.Id < id) != descending
+	}
+
+	var list []model.TerrariumInfo
+	for _, trInfo := range all {
+		if opts.Tenant != "" && tenant.Normalize(trInfo.Tenant) != opts.Tenant {
+			continue
+		}
+		matched := true
+		for _, r := range requirements {
+			if !r.matches(trInfo.Labels) {
+				matched = false
+				break
+			}
+		}
+		if !matched || !matchesEnrichments(trInfo, opts) {
+			continue
+		}
+		if after != nil && (trInfo.Id == after.Id || less(trInfo, after.Time, after.Id)) {
+			continue
+		}
+		list = append(list, trInfo)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return less(list[i], sortTime(list[j], field), list[j].Id)
+	})
+
+	next := ""
+	if opts.Limit > 0 && len(list) > opts.Limit {
+		list = list[:opts.Limit]
+		last := list[len(list)-1]
+		cursor := listCursor{Sort: opts.Sort, Id: last.Id}
+		if field != SortById {
+			cursor.Time = sortTime(last, field)
+		}
+		next = encodeCursor(cursor)
+	}
+
+	return list, next, nil
+}
@@ -0,0 +1,164 @@
+package terrarium
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+)
+
+// setUpStore opens the store in a temporary project root, and issues the terrariums in order.
+func setUpStore(t *testing.T, trIds ...string) {
+	t.Helper()
+
+	config.Terrarium.Root = t.TempDir()
+	if err := InitStore(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { CloseStore() })
+
+	for _, trId := range trIds {
+		if _, err := IssueTerrarium(model.TerrariumInfo{Id: trId}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// listIds returns the IDs of the terrariums.
+func listIds(list []model.TerrariumInfo) []string {
+	ids := []string{}
+	for _, trInfo := range list {
+		ids = append(ids, trInfo.Id)
+	}
+	return ids
+}
+
+// listAllPages lists the terrariums page by page by the cursors, and returns the IDs of each page.
+func listAllPages(t *testing.T, opts ListOptions) [][]string {
+	t.Helper()
+
+	var pages [][]string
+	for {
+		list, next, err := ListTerrariumInfo(opts)
+		if err != nil {
+			t.Fatalf("ListTerrariumInfo(%+v) error = %v", opts, err)
+		}
+		pages = append(pages, listIds(list))
+		if next == "" {
+			return pages
+		}
+		if len(pages) > 10 {
+			t.Fatalf("ListTerrariumInfo(%+v) returns a cursor for too many pages: %v", opts, pages)
+		}
+		opts.Cursor = next
+	}
+}
+
+func TestListTerrariumInfoPages(t *testing.T) {
+	// Issued in the order different from the IDs to sort by the creation time
+	setUpStore(t, "tr-c", "tr-a", "tr-e", "tr-b", "tr-d")
+
+	tests := []struct {
+		name  string
+		sort  string
+		limit int
+		want  [][]string
+	}{
+		{name: "no limit", want: [][]string{{"tr-a", "tr-b", "tr-c", "tr-d", "tr-e"}}},
+		{name: "limit 1", limit: 1, want: [][]string{{"tr-a"}, {"tr-b"}, {"tr-c"}, {"tr-d"}, {"tr-e"}}},
+		{name: "limit 2", limit: 2, want: [][]string{{"tr-a", "tr-b"}, {"tr-c", "tr-d"}, {"tr-e"}}},
+		{name: "limit dividing all", limit: 4, want: [][]string{{"tr-a", "tr-b", "tr-c", "tr-d"}, {"tr-e"}}},
+		{name: "limit of all", limit: 5, want: [][]string{{"tr-a", "tr-b", "tr-c", "tr-d", "tr-e"}}},
+		{name: "limit over all", limit: 6, want: [][]string{{"tr-a", "tr-b", "tr-c", "tr-d", "tr-e"}}},
+		{name: "descending id", sort: "-id", limit: 2, want: [][]string{{"tr-e", "tr-d"}, {"tr-c", "tr-b"}, {"tr-a"}}},
+		{name: "createdAt", sort: SortByCreatedAt, limit: 2, want: [][]string{{"tr-c", "tr-a"}, {"tr-e", "tr-b"}, {"tr-d"}}},
+		{name: "descending createdAt", sort: "-" + SortByCreatedAt, limit: 3, want: [][]string{{"tr-d", "tr-b", "tr-e"}, {"tr-a", "tr-c"}}},
+		{name: "updatedAt", sort: SortByUpdatedAt, limit: 4, want: [][]string{{"tr-c", "tr-a", "tr-e", "tr-b"}, {"tr-d"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := listAllPages(t, ListOptions{Sort: tt.sort, Limit: tt.limit})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// The cursor keeps the position of the last terrarium even if it is erased,
+// and the terrariums issued before the position are not listed in the next pages.
+func TestListTerrariumInfoCursor(t *testing.T) {
+	setUpStore(t, "tr-b", "tr-d", "tr-f", "tr-h")
+
+	opts := ListOptions{Limit: 2}
+	list, next, err := ListTerrariumInfo(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := listIds(list), []string{"tr-b", "tr-d"}; !reflect.DeepEqual(got, want) || next == "" {
+		t.Fatalf("first page = (%v, %q), want (%v, a cursor)", got, next, want)
+	}
+
+	if err := DeleteTerrariumInfo("tr-d"); err != nil {
+		t.Fatal(err)
+	}
+	for _, trId := range []string{"tr-a", "tr-e"} {
+		if _, err := IssueTerrarium(model.TerrariumInfo{Id: trId}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts.Cursor = next
+	got := listAllPages(t, opts)
+	if want := [][]string{{"tr-e", "tr-f"}, {"tr-h"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("next pages = %v, want %v", got, want)
+	}
+}
+
+// The cursor of the last terrarium of the filtered ones is not returned if no more match.
+func TestListTerrariumInfoFilteredLastPage(t *testing.T) {
+	setUpStore(t, "tr-a", "tr-b", "tr-c")
+	if _, err := IssueTerrarium(model.TerrariumInfo{Id: "tr-d", Labels: map[string]string{"team": "infra"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := IssueTerrarium(model.TerrariumInfo{Id: "tr-e", Labels: map[string]string{"team": "infra"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := listAllPages(t, ListOptions{LabelSelector: "team=infra", Limit: 2})
+	if want := [][]string{{"tr-d", "tr-e"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pages = %v, want %v", got, want)
+	}
+}
+
+func TestListTerrariumInfoInvalidOptions(t *testing.T) {
+	setUpStore(t, "tr-a", "tr-b", "tr-c")
+
+	_, idCursor, err := ListTerrariumInfo(ListOptions{Limit: 1})
+	if err != nil || idCursor == "" {
+		t.Fatalf("ListTerrariumInfo() = (%q, %v), want a cursor", idCursor, err)
+	}
+
+	tests := []struct {
+		name string
+		opts ListOptions
+	}{
+		{name: "negative limit", opts: ListOptions{Limit: -1}},
+		{name: "unknown sort", opts: ListOptions{Sort: "name"}},
+		{name: "cursor not base64", opts: ListOptions{Limit: 1, Cursor: "not a cursor!"}},
+		{name: "cursor not json", opts: ListOptions{Limit: 1, Cursor: "bm90IGpzb24"}},
+		{name: "cursor of another sort", opts: ListOptions{Sort: "-id", Limit: 1, Cursor: idCursor}},
+		{name: "invalid label selector", opts: ListOptions{LabelSelector: "team=infra,-env"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ListTerrariumInfo(tt.opts); !errors.Is(err, ErrInvalidListOptions) {
+				t.Errorf("ListTerrariumInfo(%+v) error = %v, want %v", tt.opts, err, ErrInvalidListOptions)
+			}
+		})
+	}
+}
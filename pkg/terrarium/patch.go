@@ -0,0 +1,152 @@
+package terrarium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/store"
+)
+
+var (
+	// ErrInvalidPatch is returned when a merge patch is not a JSON object or changes a read-only field.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrPreconditionFailed is returned when the terrarium has been updated since the given revision.
+	ErrPreconditionFailed = errors.New("the terrarium has been modified")
+)
+
+// Fields of the terrarium info which can be changed by a merge patch
+var patchableFields = map[string]bool{
+	"description": true,
+	"labels":      true,
+}
+
+// ReadTerrariumInfoWithRevision returns the terrarium info and its revision (i.e., resource version),
+// which can be used to update it conditionally by PatchTerrariumInfo.
+func ReadTerrariumInfoWithRevision(trId string) (model.TerrariumInfo, int64, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	trInfo, revision, err := getTerrariumInfo(ctx, trId)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return model.TerrariumInfo{}, 0, fmt.Errorf("%w (trId: %s)", ErrTerrariumNotFound, trId)
+		}
+		return model.TerrariumInfo{}, 0, err
+	}
+
+	return trInfo, revision, nil
+}
+
+// PatchTerrariumInfo applies a JSON merge patch (RFC 7396) to the description and the labels of the terrarium info,
+// and returns the patched one and its new revision.
+// If revision is not 0, it is applied only if the terrarium has not been updated since the revision,
+// otherwise it returns ErrPreconditionFailed. If revision is 0, it is retried on concurrent updates.
+func PatchTerrariumInfo(trId string, patch []byte, revision int64) (model.TerrariumInfo, int64, error) {
+
+	var patchDoc map[string]interface{}
+	if err := json.Unmarshal(patch, &patchDoc); err != nil || patchDoc == nil {
+		return model.TerrariumInfo{}, 0, fmt.Errorf("%w: the merge patch must be a JSON object", ErrInvalidPatch)
+	}
+	for field := range patchDoc {
+		if !patchableFields[field] {
+			return model.TerrariumInfo{}, 0, fmt.Errorf("%w: the field (%s) cannot be patched, only description and labels", ErrInvalidPatch, field)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	for i := 0; ; i++ {
+		current, currentRevision, err := getTerrariumInfo(ctx, trId)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return model.TerrariumInfo{}, 0, fmt.Errorf("%w (trId: %s)", ErrTerrariumNotFound, trId)
+			}
+			return model.TerrariumInfo{}, 0, err
+		}
+		if revision != 0 && currentRevision != revision {
+			return model.TerrariumInfo{}, 0, fmt.Errorf("%w (trId: %s, revision: %d, current: %d)", ErrPreconditionFailed, trId, revision, currentRevision)
+		}
+
+		patched, err := applyMergePatch(current, patchDoc)
+		if err != nil {
+			return model.TerrariumInfo{}, 0, err
+		}
+		if err := ValidateLabels(patched.Labels); err != nil {
+			return model.TerrariumInfo{}, 0, err
+		}
+
+		current.Description = patched.Description
+		current.Labels = patched.Labels
+		current.UpdatedAt = time.Now()
+
+		value, err := json.Marshal(current)
+		if err != nil {
+			return model.TerrariumInfo{}, 0, fmt.Errorf("failed to encode terrarium info: %w", err)
+		}
+
+		newRevision, err := trStore.CompareAndSwap(ctx, terrariumKeyPrefix+trId, currentRevision, value)
+		if err == nil {
+			return current, newRevision, nil
+		}
+		if errors.Is(err, store.ErrConflict) && revision != 0 {
+			return model.TerrariumInfo{}, 0, fmt.Errorf("%w (trId: %s, revision: %d)", ErrPreconditionFailed, trId, revision)
+		}
+		if !errors.Is(err, store.ErrConflict) || i >= maxUpdateRetries {
+			return model.TerrariumInfo{}, 0, fmt.Errorf("failed to update the terrarium (trId: %s): %w", trId, err)
+		}
+	}
+}
+
+// applyMergePatch applies a merge patch to the terrarium info through its JSON document.
+func applyMergePatch(trInfo model.TerrariumInfo, patchDoc map[string]interface{}) (model.TerrariumInfo, error) {
+
+	data, err := json.Marshal(trInfo)
+	if err != nil {
+		return model.TerrariumInfo{}, fmt.Errorf("failed to encode terrarium info: %w", err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return model.TerrariumInfo{}, fmt.Errorf("failed to decode terrarium info: %w", err)
+	}
+
+	data, err = json.Marshal(mergePatch(doc, patchDoc))
+	if err != nil {
+		return model.TerrariumInfo{}, fmt.Errorf("failed to encode the patched terrarium info: %w", err)
+	}
+	var patched model.TerrariumInfo
+	if err := json.Unmarshal(data, &patched); err != nil {
+		return model.TerrariumInfo{}, fmt.Errorf("%w: %s", ErrInvalidPatch, err.Error())
+	}
+
+	return patched, nil
+}
+
+// mergePatch returns the target patched by the algorithm of RFC 7396,
+// where null removes a member and an object is merged recursively.
+func mergePatch(target, patch interface{}) interface{} {
+
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+
+	return targetObj
+}
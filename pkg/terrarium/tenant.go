@@ -0,0 +1,111 @@
+package terrarium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/replica"
+	"github.com/cloud-barista/mc-terrarium/pkg/tenant"
+)
+
+// quotaMu serializes counting and adding the terrariums and the enrichments in this server,
+// which is held with the lock of the tenant across the servers sharing the store (see lockQuota).
+var quotaMu sync.Mutex
+
+// lockQuota holds the quota of a tenant in this server and across the servers sharing the store
+// until the returned function releases it, so that concurrent requests of the tenant cannot exceed its quota together.
+func lockQuota(tenantName string) (func(), error) {
+	quotaMu.Lock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	release, err := replica.Lock(ctx, "tenants/"+tenant.Normalize(tenantName))
+	if err != nil {
+		quotaMu.Unlock()
+		return nil, fmt.Errorf("failed to lock the quota of the tenant (tenant: %s): %w", tenantName, err)
+	}
+
+	return func() {
+		release()
+		quotaMu.Unlock()
+	}, nil
+}
+
+// TenantOf returns the tenant of a terrarium.
+func TenantOf(trId string) (string, error) {
+	trInfo, err := ReadTerrariumInfo(trId)
+	if err != nil {
+		return "", err
+	}
+	return tenant.Normalize(trInfo.Tenant), nil
+}
+
+// tenantPathOf returns the tenant to locate the directory of a terrarium,
+// which is the default one if the terrarium does not exist (e.g., to check the directory before issuing it).
+func tenantPathOf(trId string) (string, error) {
+	tenantName, err := TenantOf(trId)
+	if errors.Is(err, ErrTerrariumNotFound) {
+		return tenant.Default, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := tenant.ValidateName(tenantName); err != nil {
+		return "", err
+	}
+	return tenantName, nil
+}
+
+// countUsage returns the number of terrariums and the number of enrichments in them of a tenant.
+func countUsage(tenantName string) (int, int, error) {
+	trInfoList, err := ReadAllTerrariumInfo()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	terrariums, enrichments := 0, 0
+	for _, trInfo := range trInfoList {
+		if tenant.Normalize(trInfo.Tenant) != tenantName {
+			continue
+		}
+		terrariums++
+		enrichments += len(trInfo.Enrichments)
+	}
+	return terrariums, enrichments, nil
+}
+
+// checkTerrariumQuota checks if a tenant can have another terrarium. It should be called with lockQuota held.
+func checkTerrariumQuota(tenantName string) error {
+	quota := tenant.QuotaOf(tenantName)
+	if quota.MaxTerrariums <= 0 {
+		return nil
+	}
+	terrariums, _, err := countUsage(tenantName)
+	if err != nil {
+		return err
+	}
+	if terrariums >= quota.MaxTerrariums {
+		return fmt.Errorf("%w (tenant: %s): up to %d terrariums", tenant.ErrQuotaExceeded, tenantName, quota.MaxTerrariums)
+	}
+	return nil
+}
+
+// checkEnrichmentQuota checks if a tenant can have another enrichment. It should be called with lockQuota held.
+func checkEnrichmentQuota(tenantName string) error {
+	tenantName = tenant.Normalize(tenantName)
+	quota := tenant.QuotaOf(tenantName)
+	if quota.MaxEnrichments <= 0 {
+		return nil
+	}
+	_, enrichments, err := countUsage(tenantName)
+	if err != nil {
+		return err
+	}
+	if enrichments >= quota.MaxEnrichments {
+		return fmt.Errorf("%w (tenant: %s): up to %d enrichments", tenant.ErrQuotaExceeded, tenantName, quota.MaxEnrichments)
+	}
+	return nil
+}
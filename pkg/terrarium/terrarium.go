@@ -1,119 +1,301 @@
 package terrarium
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"sync"
+	"strings"
+	"time"
 
 	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
 	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/cloud-barista/mc-terrarium/pkg/store"
+	"github.com/cloud-barista/mc-terrarium/pkg/tenant"
+	"github.com/rs/zerolog/log"
 )
 
 const (
+	// Legacy file of the terrarium info map, which is migrated to the store
 	terrariumDbFileName = "terrarium.db"
 	terrariumDir        = ".terrarium"
+	// Directory of the terrariums of each tenant except the default one (i.e., .terrarium/tenants/{tenant}/{trId})
+	tenantsDir = "tenants"
+
+	// Prefix of the keys of the terrarium info in the store
+	terrariumKeyPrefix = "terrariums/"
+
+	// Timeout of a store operation
+	storeTimeout = 10 * time.Second
+
+	// Max number of retries of an update by compare-and-swap
+	maxUpdateRetries = 5
 )
 
-// Manage the running status of tofu commands.
-var terrariumInfoMap sync.Map
+var (
+	// ErrTerrariumNotFound is returned when a terrarium does not exist.
+	ErrTerrariumNotFound = errors.New("not existed the terrarium")
+	// ErrTerrariumExists is returned when issuing a terrarium with the ID of an existing one.
+	ErrTerrariumExists = errors.New("already existed the terrarium")
+	// ErrEnrichmentNotFound is returned when an enrichment does not exist in a terrarium.
+	ErrEnrichmentNotFound = errors.New("not existed the enrichment")
+)
 
-// Save the terrarium info to file
-func SaveTerrariumInfoMap() error {
-	projectRoot := config.Terrarium.Root
-	terrariumDbFilePath := fmt.Sprintf("%s/%s/%s", projectRoot, terrariumDir, terrariumDbFileName)
+// Store of the terrarium info
+var trStore store.Store
 
-	// Create the file to store the terrarium info map
-	file, err := os.Create(terrariumDbFilePath)
+// InitStore opens the store of the terrarium info by the configuration
+// and migrates the legacy terrarium info map file if it exists.
+func InitStore() error {
+	s, err := store.New(config.Terrarium.Store)
 	if err != nil {
-		return fmt.Errorf("failed to create terrarium info db file: %w", err)
+		return fmt.Errorf("failed to open the store of terrarium info: %w", err)
 	}
-	defer file.Close()
+	trStore = s
 
-	// Encode sync.Map to a JSON file
-	tempMap := make(map[string]model.TerrariumInfo)
-	terrariumInfoMap.Range(func(key, value interface{}) bool {
-		tempMap[key.(string)] = value.(model.TerrariumInfo)
-		return true
-	})
-
-	encoder := json.NewEncoder(file)
-	if err := encoder.Encode(tempMap); err != nil {
-		return fmt.Errorf("failed to encode terrarium info map: %w", err)
+	if err := migrateTerrariumInfoMap(); err != nil {
+		log.Warn().Err(err).Msg("failed to migrate the terrarium info map file")
 	}
 
 	return nil
 }
 
-// Load the terrarium info from file
-func LoadTerrariumInfoMap() error {
+// CloseStore closes the store of the terrarium info.
+func CloseStore() error {
+	if trStore == nil {
+		return nil
+	}
+	return trStore.Close()
+}
+
+// migrateTerrariumInfoMap moves the terrarium info in the legacy file (.terrarium/terrarium.db) to the store.
+func migrateTerrariumInfoMap() error {
 	projectRoot := config.Terrarium.Root
 	terrariumDbFilePath := fmt.Sprintf("%s/%s/%s", projectRoot, terrariumDir, terrariumDbFileName)
 
-	// Check and open the status file
-	if _, err := os.Stat(terrariumDbFilePath); os.IsNotExist(err) {
-		return fmt.Errorf("terrarium info db file does not exist: %w", err)
-	}
-
-	file, err := os.Open(terrariumDbFilePath)
+	data, err := os.ReadFile(terrariumDbFilePath)
 	if err != nil {
-		return fmt.Errorf("failed to open terrarium info db file: %w", err)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read terrarium info db file: %w", err)
 	}
-	defer file.Close()
 
-	// Decode JSON file to sync.Map
-	var tempMap map[string]model.TerrariumInfo
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&tempMap); err != nil {
+	var tempMap map[string]json.RawMessage
+	if err := json.Unmarshal(data, &tempMap); err != nil {
 		return fmt.Errorf("failed to decode terrarium info map: %w", err)
 	}
 
-	for key, value := range tempMap {
-		terrariumInfoMap.Store(key, value)
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	for trId, raw := range tempMap {
+		trInfo, err := decodeTerrariumInfo(raw)
+		if err != nil {
+			return fmt.Errorf("failed to decode terrarium info (trId: %s): %w", trId, err)
+		}
+		value, err := json.Marshal(trInfo)
+		if err != nil {
+			return fmt.Errorf("failed to encode terrarium info (trId: %s): %w", trId, err)
+		}
+		if _, err := trStore.Create(ctx, terrariumKeyPrefix+trId, value); err != nil && !errors.Is(err, store.ErrAlreadyExists) {
+			return fmt.Errorf("failed to migrate terrarium info (trId: %s): %w", trId, err)
+		}
 	}
 
+	if err := os.Rename(terrariumDbFilePath, terrariumDbFilePath+".migrated"); err != nil {
+		return fmt.Errorf("failed to rename terrarium info db file: %w", err)
+	}
+	log.Info().Msgf("migrated %d terrarium(s) from %s to the store", len(tempMap), terrariumDbFilePath)
+
 	return nil
 }
 
-// Set the terrarium info for a given trId.
-func setTerrariumInfo(trInfo model.TerrariumInfo) {
-	terrariumInfoMap.Store(trInfo.Id, trInfo)
+// Get the terrarium info and its revision for a given trId.
+func getTerrariumInfo(ctx context.Context, trId string) (model.TerrariumInfo, int64, error) {
+	kv, err := trStore.Get(ctx, terrariumKeyPrefix+trId)
+	if err != nil {
+		return model.TerrariumInfo{}, 0, err
+	}
+
+	trInfo, err := decodeTerrariumInfo(kv.Value)
+	if err != nil {
+		return model.TerrariumInfo{}, 0, fmt.Errorf("failed to decode terrarium info (trId: %s): %w", trId, err)
+	}
+	return trInfo, kv.Revision, nil
 }
 
-// Get the terrarium info for a given trId.
-func getTerrariumInfo(trId string) (model.TerrariumInfo, bool) {
-	value, exists := terrariumInfoMap.Load(trId)
-	if !exists {
-		return model.TerrariumInfo{}, false
+// decodeTerrariumInfo decodes the terrarium info, including the legacy one
+// which has a single enrichment as a string (e.g., "enrichments": "vpn/gcp-aws").
+func decodeTerrariumInfo(data []byte) (model.TerrariumInfo, error) {
+	var trInfo model.TerrariumInfo
+	err := json.Unmarshal(data, &trInfo)
+	if err == nil {
+		return trInfo, nil
 	}
-	return value.(model.TerrariumInfo), true
+
+	var legacy struct {
+		Id          string `json:"id"`
+		Description string `json:"description"`
+		Enrichments string `json:"enrichments"`
+	}
+	if json.Unmarshal(data, &legacy) != nil {
+		return model.TerrariumInfo{}, err
+	}
+
+	trInfo = model.TerrariumInfo{
+		Id:          legacy.Id,
+		Description: legacy.Description,
+	}
+	if legacy.Enrichments != "" {
+		kind, provider := splitEnrichment(legacy.Enrichments)
+		trInfo.Enrichments = map[string]model.EnrichmentInfo{
+			legacy.Enrichments: {
+				Name:       legacy.Enrichments,
+				Kind:       kind,
+				Provider:   provider,
+				WorkingDir: enrichmentWorkingDir(tenant.Default, legacy.Id, legacy.Enrichments),
+				State:      StateInitialized,
+			},
+		}
+	}
+	return trInfo, nil
 }
 
-// Get all terrarium info.
-func getAllTerrariumInfo() []model.TerrariumInfo {
-	var terrariumInfoList []model.TerrariumInfo
-	terrariumInfoMap.Range(func(key, value interface{}) bool {
-		terrariumInfoList = append(terrariumInfoList, value.(model.TerrariumInfo))
-		return true
-	})
-	return terrariumInfoList
+// splitEnrichment splits the name of an enrichment into the kind and the provider (e.g., vpn/gcp-aws).
+func splitEnrichment(name string) (string, string) {
+	kind, provider, _ := strings.Cut(name, "/")
+	return kind, provider
+}
+
+// terrariumRelDir returns the directory of a terrarium relative to the project root,
+// which is .terrarium/{trId} for the default tenant and .terrarium/tenants/{tenant}/{trId} for the others.
+func terrariumRelDir(tenantName, trId string) string {
+	if tenant.Normalize(tenantName) == tenant.Default {
+		return terrariumDir + "/" + trId
+	}
+	return terrariumDir + "/" + tenantsDir + "/" + tenantName + "/" + trId
 }
 
-func IssueTerrarium(trInfo model.TerrariumInfo) error {
+// enrichmentWorkingDir returns the working directory of an enrichment relative to the project root.
+func enrichmentWorkingDir(tenantName, trId, name string) string {
+	return terrariumRelDir(tenantName, trId) + "/" + name
+}
 
-	if _, exists := getTerrariumInfo(trInfo.Id); exists {
-		return fmt.Errorf("the terrarium (trId: %s) already exist", trInfo.Id)
+// updateTerrariumInfo updates the terrarium info by a function with compare-and-swap,
+// and retries if it has been updated by another request in the meantime.
+func updateTerrariumInfo(trId string, update func(trInfo *model.TerrariumInfo) error) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	for i := 0; ; i++ {
+		trInfo, revision, err := getTerrariumInfo(ctx, trId)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return fmt.Errorf("%w (trId: %s)", ErrTerrariumNotFound, trId)
+			}
+			return err
+		}
+
+		if err := update(&trInfo); err != nil {
+			return err
+		}
+		trInfo.UpdatedAt = time.Now()
+
+		value, err := json.Marshal(trInfo)
+		if err != nil {
+			return fmt.Errorf("failed to encode terrarium info: %w", err)
+		}
+
+		_, err = trStore.CompareAndSwap(ctx, terrariumKeyPrefix+trId, revision, value)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, store.ErrConflict) || i >= maxUpdateRetries {
+			return fmt.Errorf("failed to update the terrarium (trId: %s): %w", trId, err)
+		}
 	}
-	setTerrariumInfo(trInfo)
+}
 
-	return nil
+// IssueTerrarium creates a terrarium with a valid ID (see ValidateId), or a generated one if the ID is omitted,
+// and returns the issued terrarium info.
+func IssueTerrarium(trInfo model.TerrariumInfo) (model.TerrariumInfo, error) {
+
+	generated := trInfo.Id == ""
+	if !generated {
+		if err := ValidateId(trInfo.Id); err != nil {
+			return model.TerrariumInfo{}, err
+		}
+	}
+	if err := ValidateLabels(trInfo.Labels); err != nil {
+		return model.TerrariumInfo{}, err
+	}
+	trInfo.Tenant = tenant.Normalize(trInfo.Tenant)
+	if !tenant.Exists(trInfo.Tenant) {
+		return model.TerrariumInfo{}, fmt.Errorf("%w (tenant: %s): not existed the tenant", tenant.ErrInvalidTenant, trInfo.Tenant)
+	}
+
+	now := time.Now()
+	trInfo.CreatedAt = now
+	trInfo.UpdatedAt = now
+	if err := setExpiry(&trInfo, now); err != nil {
+		return model.TerrariumInfo{}, err
+	}
+
+	// Hold the quota of the tenant until the terrarium is created
+	unlock, err := lockQuota(trInfo.Tenant)
+	if err != nil {
+		return model.TerrariumInfo{}, err
+	}
+	defer unlock()
+	if err := checkTerrariumQuota(trInfo.Tenant); err != nil {
+		return model.TerrariumInfo{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	for i := 0; ; i++ {
+		if generated {
+			trId, err := generateId()
+			if err != nil {
+				return model.TerrariumInfo{}, err
+			}
+			trInfo.Id = trId
+		}
+
+		value, err := json.Marshal(trInfo)
+		if err != nil {
+			return model.TerrariumInfo{}, fmt.Errorf("failed to encode terrarium info: %w", err)
+		}
+
+		_, err = trStore.Create(ctx, terrariumKeyPrefix+trInfo.Id, value)
+		if err == nil {
+			return trInfo, nil
+		}
+		if !errors.Is(err, store.ErrAlreadyExists) {
+			return model.TerrariumInfo{}, err
+		}
+		// Retry with another ID if the generated one collides
+		if !generated || i >= maxUpdateRetries {
+			return model.TerrariumInfo{}, fmt.Errorf("%w (trId: %s)", ErrTerrariumExists, trInfo.Id)
+		}
+	}
 }
 
 func ReadTerrariumInfo(trId string) (model.TerrariumInfo, error) {
 
-	trInfo, exists := getTerrariumInfo(trId)
-	if !exists {
-		return model.TerrariumInfo{}, fmt.Errorf("not existed the terrarium (trId: %s)", trId)
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	trInfo, _, err := getTerrariumInfo(ctx, trId)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return model.TerrariumInfo{}, fmt.Errorf("%w (trId: %s)", ErrTerrariumNotFound, trId)
+		}
+		return model.TerrariumInfo{}, err
 	}
 
 	return trInfo, nil
@@ -121,29 +303,132 @@ func ReadTerrariumInfo(trId string) (model.TerrariumInfo, error) {
 
 func ReadAllTerrariumInfo() ([]model.TerrariumInfo, error) {
 
-	trInfoList := getAllTerrariumInfo()
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	kvs, err := trStore.List(ctx, terrariumKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var trInfoList []model.TerrariumInfo
+	for _, kv := range kvs {
+		trInfo, err := decodeTerrariumInfo(kv.Value)
+		if err != nil {
+			log.Warn().Err(err).Msgf("failed to decode terrarium info (key: %s)", kv.Key)
+			continue
+		}
+		trInfoList = append(trInfoList, trInfo)
+	}
 
 	return trInfoList, nil
 }
 
+// UpdateTerrariumInfo updates the description and the labels of the terrarium info.
+// The enrichments are managed by AddEnrichment, TransitionEnrichment and RemoveEnrichment.
 func UpdateTerrariumInfo(trInfo model.TerrariumInfo) error {
 
-	_, exists := getTerrariumInfo(trInfo.Id)
+	if err := ValidateLabels(trInfo.Labels); err != nil {
+		return err
+	}
+
+	return updateTerrariumInfo(trInfo.Id, func(current *model.TerrariumInfo) error {
+		current.Description = trInfo.Description
+		current.Labels = trInfo.Labels
+		return nil
+	})
+}
+
+// AddEnrichment adds an enrichment to the terrarium in the Empty state if it does not exist,
+// unless the tenant of the terrarium has reached the quota of the enrichments.
+// If it exists, the lifecycle state is kept, which is changed by each operation on the enrichment,
+// and the provider is updated only if the enrichment can be initialized (e.g., not while applying).
+func AddEnrichment(trId, name, provider string) error {
+
+	trInfo, err := ReadTerrariumInfo(trId)
+	if err != nil {
+		return err
+	}
+
+	// Hold the quota of the tenant until the enrichment is added
+	unlock, err := lockQuota(trInfo.Tenant)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, exists := trInfo.Enrichments[name]; !exists {
+		if err := checkEnrichmentQuota(trInfo.Tenant); err != nil {
+			return err
+		}
+	}
+
+	return updateTerrariumInfo(trId, func(trInfo *model.TerrariumInfo) error {
+		now := time.Now()
+		kind, p := splitEnrichment(name)
+
+		enrichment, exists := trInfo.Enrichments[name]
+		if !exists {
+			enrichment = model.EnrichmentInfo{
+				Name:       name,
+				Kind:       kind,
+				WorkingDir: enrichmentWorkingDir(trInfo.Tenant, trId, name),
+				State:      StateEmpty,
+				CreatedAt:  now,
+			}
+		}
+		if err := checkTransition(trId, enrichment, StateInitialized); err != nil {
+			return err
+		}
+		enrichment.Provider = config.NVL(provider, p)
+		enrichment.UpdatedAt = now
+
+		if trInfo.Enrichments == nil {
+			trInfo.Enrichments = make(map[string]model.EnrichmentInfo)
+		}
+		trInfo.Enrichments[name] = enrichment
+		return nil
+	})
+}
+
+// ReadEnrichmentInfo returns an enrichment of the terrarium.
+func ReadEnrichmentInfo(trId, name string) (model.EnrichmentInfo, error) {
+
+	trInfo, err := ReadTerrariumInfo(trId)
+	if err != nil {
+		return model.EnrichmentInfo{}, err
+	}
+
+	enrichment, exists := trInfo.Enrichments[name]
 	if !exists {
-		return fmt.Errorf("not existed the terrarium (trId: %s)", trInfo.Id)
+		return model.EnrichmentInfo{}, fmt.Errorf("%w (trId: %s, enrichments: %s)", ErrEnrichmentNotFound, trId, name)
 	}
-	setTerrariumInfo(trInfo)
 
-	return nil
+	return enrichment, nil
+}
+
+// RemoveEnrichment removes an enrichment from the terrarium.
+func RemoveEnrichment(trId, name string) error {
+
+	return updateTerrariumInfo(trId, func(trInfo *model.TerrariumInfo) error {
+		if _, exists := trInfo.Enrichments[name]; !exists {
+			return fmt.Errorf("%w (trId: %s, enrichments: %s)", ErrEnrichmentNotFound, trId, name)
+		}
+		delete(trInfo.Enrichments, name)
+		return nil
+	})
 }
 
 func DeleteTerrariumInfo(trId string) error {
 
-	_, exists := getTerrariumInfo(trId)
-	if !exists {
-		return fmt.Errorf("not existed the terrarium (trId: %s)", trId)
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if err := trStore.Delete(ctx, terrariumKeyPrefix+trId); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("%w (trId: %s)", ErrTerrariumNotFound, trId)
+		}
+		return err
 	}
-	terrariumInfoMap.Delete(trId)
 
 	return nil
 }
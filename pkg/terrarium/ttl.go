@@ -0,0 +1,321 @@
+package terrarium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/cloud-barista/mc-terrarium/pkg/replica"
+	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Default interval to scan the expired terrariums
+	defaultReapInterval = 10 * time.Second
+	// Default window before the expiration to notify a terrarium is expiring
+	defaultExpiryWarning = 60 * time.Minute
+	// Interval to retry reaping a terrarium after a failure (e.g., failed to destroy)
+	reapRetryInterval = 30 * time.Minute
+)
+
+var (
+	// ErrInvalidTtl is returned when a time to live or an expiration time is invalid.
+	ErrInvalidTtl = errors.New("invalid TTL")
+	// ErrExpired is returned when extending the TTL of a terrarium which is being reaped.
+	ErrExpired = errors.New("the terrarium has expired")
+)
+
+// reaper keeps the terrariums being reaped and the ones to retry later in this server,
+// which is the leader of the servers sharing the store (see StartReaper).
+var reaper = struct {
+	mu         sync.Mutex
+	reaping    map[string]bool
+	retryAfter map[string]time.Time
+}{
+	reaping:    make(map[string]bool),
+	retryAfter: make(map[string]time.Time),
+}
+
+// ParseTtl parses a time to live, which is a duration (e.g., 90m, 72h) or a number of days (e.g., 7d).
+func ParseTtl(ttl string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(ttl, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(ttl)
+	}
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%w (ttl: %s): use a positive duration such as 90m, 72h or 7d", ErrInvalidTtl, ttl)
+	}
+	return d, nil
+}
+
+// setExpiry sets the expiration time of a new terrarium by the TTL or the given expiration time.
+func setExpiry(trInfo *model.TerrariumInfo, now time.Time) error {
+
+	trInfo.ExpiryWarnedAt = nil
+	if trInfo.Ttl == "" {
+		if trInfo.ExpiresAt != nil && !trInfo.ExpiresAt.After(now) {
+			return fmt.Errorf("%w: expiresAt (%s) must be in the future", ErrInvalidTtl, trInfo.ExpiresAt.Format(time.RFC3339))
+		}
+		return nil
+	}
+	if trInfo.ExpiresAt != nil {
+		return fmt.Errorf("%w: ttl and expiresAt cannot be used together", ErrInvalidTtl)
+	}
+
+	d, err := ParseTtl(trInfo.Ttl)
+	if err != nil {
+		return err
+	}
+	expiresAt := now.Add(d)
+	trInfo.ExpiresAt = &expiresAt
+	trInfo.Ttl = ""
+	return nil
+}
+
+// ExtendTtl extends the expiration time of a terrarium by the TTL from the current expiration time
+// (or from now if it does not expire), or sets the given expiration time. The expiring notice is reset.
+func ExtendTtl(trId string, req model.ExtendTtlRequest) (model.TerrariumInfo, error) {
+
+	if (req.Ttl == "") == (req.ExpiresAt == nil) {
+		return model.TerrariumInfo{}, fmt.Errorf("%w: either ttl or expiresAt is required", ErrInvalidTtl)
+	}
+	var d time.Duration
+	if req.Ttl != "" {
+		var err error
+		if d, err = ParseTtl(req.Ttl); err != nil {
+			return model.TerrariumInfo{}, err
+		}
+	}
+
+	if isReaping(trId) {
+		return model.TerrariumInfo{}, fmt.Errorf("%w (trId: %s): it is being destroyed and erased", ErrExpired, trId)
+	}
+
+	err := updateTerrariumInfo(trId, func(trInfo *model.TerrariumInfo) error {
+		now := time.Now()
+		expiresAt := now.Add(d)
+		if req.ExpiresAt != nil {
+			expiresAt = *req.ExpiresAt
+		} else if trInfo.ExpiresAt != nil && trInfo.ExpiresAt.After(now) {
+			expiresAt = trInfo.ExpiresAt.Add(d)
+		}
+		if !expiresAt.After(now) {
+			return fmt.Errorf("%w: expiresAt (%s) must be in the future", ErrInvalidTtl, expiresAt.Format(time.RFC3339))
+		}
+		trInfo.ExpiresAt = &expiresAt
+		trInfo.ExpiryWarnedAt = nil
+		return nil
+	})
+	if err != nil {
+		return model.TerrariumInfo{}, err
+	}
+
+	return ReadTerrariumInfo(trId)
+}
+
+// StartReaper starts to reap the expired terrariums periodically by the auto control interval
+// until the context is done. It notifies the terrariums expiring within the warning window once.
+// With the store shared by the servers, only the leader reaps them.
+func StartReaper(ctx context.Context) {
+
+	interval := time.Duration(config.Terrarium.AutoControl.DurationMilliSec) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultReapInterval
+	}
+	log.Info().Msgf("starting the reaper of the expired terrariums (interval: %s)", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if replica.IsLeader() {
+					ReapExpired(now)
+				}
+			}
+		}
+	}()
+}
+
+// ReapExpired notifies the terrariums expiring soon and starts to reap the expired ones,
+// which destroys the enrichments through the job pipeline and erases the terrarium.
+func ReapExpired(now time.Time) {
+
+	warning := time.Duration(config.Terrarium.AutoControl.ExpiryWarningMinute) * time.Minute
+	if warning <= 0 {
+		warning = defaultExpiryWarning
+	}
+
+	trInfoList, err := ReadAllTerrariumInfo()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to read the terrariums to reap")
+		return
+	}
+
+	for _, trInfo := range trInfoList {
+		if trInfo.ExpiresAt == nil {
+			continue
+		}
+		expiresAt := *trInfo.ExpiresAt
+
+		if now.Before(expiresAt) {
+			if trInfo.ExpiryWarnedAt == nil && !now.Before(expiresAt.Add(-warning)) {
+				warnExpiring(trInfo.Id, expiresAt, now)
+			}
+			continue
+		}
+
+		if claimReaping(trInfo.Id, now) {
+			go reapTerrarium(trInfo.Id)
+		}
+	}
+}
+
+// warnExpiring records the expiring notice of a terrarium and notifies it once.
+func warnExpiring(trId string, expiresAt, now time.Time) {
+
+	warned := false
+	err := updateTerrariumInfo(trId, func(trInfo *model.TerrariumInfo) error {
+		// Skip if it has been extended or notified by another server in the meantime
+		warned = trInfo.ExpiryWarnedAt == nil && trInfo.ExpiresAt != nil && trInfo.ExpiresAt.Equal(expiresAt)
+		if warned {
+			trInfo.ExpiryWarnedAt = &now
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warn().Err(err).Msgf("failed to record the expiring notice of the terrarium (trId: %s)", trId)
+		return
+	}
+
+	if warned {
+		notifyEvent(EventExpiring, trId, fmt.Sprintf("the terrarium (trId: %s) expires at %s, extend the TTL to keep it",
+			trId, expiresAt.Format(time.RFC3339)))
+	}
+}
+
+// isReaping returns whether a terrarium is being reaped in this server or another server sharing the store.
+func isReaping(trId string) bool {
+	reaper.mu.Lock()
+	reaping := reaper.reaping[trId]
+	reaper.mu.Unlock()
+	return reaping || replica.Locked(reapingLockName(trId))
+}
+
+// reapingLockName returns the name of the lock of a terrarium being reaped across the servers.
+func reapingLockName(trId string) string {
+	return "reaping/" + trId
+}
+
+// claimReaping marks a terrarium as being reaped unless it is already or it should be retried later.
+func claimReaping(trId string, now time.Time) bool {
+	reaper.mu.Lock()
+	defer reaper.mu.Unlock()
+	if reaper.reaping[trId] || now.Before(reaper.retryAfter[trId]) {
+		return false
+	}
+	reaper.reaping[trId] = true
+	return true
+}
+
+// releaseReaping unmarks a terrarium, which is retried after the interval if it failed.
+func releaseReaping(trId string, failed bool) {
+	reaper.mu.Lock()
+	defer reaper.mu.Unlock()
+	delete(reaper.reaping, trId)
+	if failed {
+		reaper.retryAfter[trId] = time.Now().Add(reapRetryInterval)
+	} else {
+		delete(reaper.retryAfter, trId)
+	}
+}
+
+// reapTerrarium destroys the remaining resources of an expired terrarium and erases it.
+func reapTerrarium(trId string) {
+
+	failed := false
+	defer func() { releaseReaping(trId, failed) }()
+
+	// Lock it across the servers, where the previous leader may be still reaping it
+	release, err := replica.Lock(context.Background(), reapingLockName(trId))
+	if err != nil {
+		return
+	}
+	defer release()
+
+	// Check again if it has been extended in the meantime
+	trInfo, err := ReadTerrariumInfo(trId)
+	if err != nil || trInfo.ExpiresAt == nil || time.Now().Before(*trInfo.ExpiresAt) {
+		return
+	}
+
+	// Lock the terrarium until it is erased, which waits for the queued requests of it and rejects the new ones
+	reqId := fmt.Sprintf("%d", time.Now().UnixNano())
+	lock, err := tofu.LockTerrarium(context.Background(), trId, reqId)
+	if err != nil {
+		log.Debug().Err(err).Msgf("postpone reaping the terrarium (trId: %s)", trId)
+		return
+	}
+	defer lock.Unlock()
+
+	// Wait until applying or destroying is completed
+	if err := CheckErase(trId); err != nil {
+		var te *TransitionError
+		if errors.Is(err, ErrTerrariumNotFound) {
+			return
+		}
+		if errors.As(err, &te) {
+			log.Debug().Err(err).Msgf("postpone reaping the terrarium (trId: %s)", trId)
+			return
+		}
+		failed = true
+		notifyEvent(EventReapFailed, trId, fmt.Sprintf("failed to reap the terrarium (trId: %s): %s", trId, err))
+		return
+	}
+
+	notifyEvent(EventExpired, trId, fmt.Sprintf("the terrarium (trId: %s) expired at %s, destroying and erasing it",
+		trId, trInfo.ExpiresAt.Format(time.RFC3339)))
+
+	fail := func(err error) {
+		failed = true
+		notifyEvent(EventReapFailed, trId, fmt.Sprintf("failed to reap the terrarium (trId: %s), retry after %s: %s",
+			trId, reapRetryInterval, err))
+	}
+
+	remaining, err := ListRemainingResources(trId)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	if err := DestroyEnrichments(trId, lock, remaining); err != nil {
+		fail(err)
+		return
+	}
+
+	tenantName, err := removeTerrarium(trId)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	// Reaped is the erased event of an expired terrarium, which has the tenant read before erasing it
+	message := fmt.Sprintf("the expired terrarium (trId: %s) is destroyed and erased", trId)
+	log.Warn().Str("event", EventReaped).Msg(message)
+	emitEvent(model.TerrariumEvent{Type: EventReaped, TerrariumId: trId, Tenant: tenantName, Message: message})
+}
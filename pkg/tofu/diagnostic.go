@@ -0,0 +1,86 @@
+package tofu
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+)
+
+// CommandError is returned when a tofu command fails,
+// which has the output and the diagnostics (i.e., errors and warnings) of the command.
+type CommandError struct {
+	Subcommand  string
+	Output      string
+	Diagnostics []model.DiagnosticMessage
+	Err         error
+}
+
+func (e *CommandError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// ansiPattern matches the escape sequences of the colors in the human-readable output
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// ParseDiagnostics parses the diagnostics from the human-readable output of a tofu command,
+// where each is a box of lines (i.e., ╷ │ ╵) starting with "Error: " or "Warning: ",
+// or a line starting with it if the output has no boxes.
+func ParseDiagnostics(output string) []model.DiagnosticMessage {
+
+	var diagnostics []model.DiagnosticMessage
+	var box []string
+	inBox := false
+
+	for _, line := range strings.Split(ansiPattern.ReplaceAllString(output, ""), "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "╷"):
+			inBox, box = true, nil
+		case strings.HasPrefix(line, "╵"):
+			if d, ok := newDiagnostic(box); ok {
+				diagnostics = append(diagnostics, d)
+			}
+			inBox = false
+		case inBox:
+			line = strings.TrimPrefix(line, "│")
+			box = append(box, strings.TrimPrefix(line, " "))
+		default:
+			if d, ok := newDiagnostic([]string{line}); ok {
+				diagnostics = append(diagnostics, d)
+			}
+		}
+	}
+	return diagnostics
+}
+
+// newDiagnostic returns a diagnostic of the lines, whose first line has the severity and the summary.
+func newDiagnostic(lines []string) (model.DiagnosticMessage, bool) {
+	if len(lines) == 0 {
+		return model.DiagnosticMessage{}, false
+	}
+
+	var d model.DiagnosticMessage
+	switch first := strings.TrimSpace(lines[0]); {
+	case strings.HasPrefix(first, "Error: "):
+		d = model.DiagnosticMessage{Severity: "error", Summary: strings.TrimPrefix(first, "Error: ")}
+	case strings.HasPrefix(first, "Warning: "):
+		d = model.DiagnosticMessage{Severity: "warning", Summary: strings.TrimPrefix(first, "Warning: ")}
+	default:
+		return model.DiagnosticMessage{}, false
+	}
+	d.Detail = strings.TrimSpace(strings.Join(lines[1:], "\n"))
+
+	// e.g., "with aws_vpn_gateway.vpn_gw," in the detail
+	for _, line := range lines[1:] {
+		if addr, ok := strings.CutPrefix(strings.TrimSpace(line), "with "); ok {
+			d.Address = strings.TrimSuffix(addr, ",")
+			break
+		}
+	}
+	return d, true
+}
@@ -0,0 +1,378 @@
+package tofu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/rs/zerolog/log"
+)
+
+// IaC engines supported by the executors
+const (
+	EngineOpenTofu  = "opentofu"
+	EngineTerraform = "terraform"
+	// Engine of the FakeExecutor, which is set only by SetExecutor in tests (not by the configuration)
+	EngineFake = "fake"
+)
+
+// Executor runs the commands of an IaC engine (e.g., OpenTofu or Terraform).
+type Executor interface {
+	// Engine returns the name of the IaC engine.
+	Engine() string
+	// Binary returns the binary of the IaC engine.
+	Binary() string
+	// Version returns the version of the IaC engine.
+	Version(ctx context.Context) (string, error)
+	// Run runs a command with the arguments and writes the output to stdout and stderr.
+	// When the context is cancelled, the command should be interrupted gracefully.
+	Run(ctx context.Context, args []string, stdout, stderr io.Writer) error
+}
+
+var (
+	executor     Executor
+	executorOnce sync.Once
+	executorMu   sync.RWMutex
+)
+
+// InitExecutor creates the executor by the configuration, which should be called on start
+// to fail fast if the engine is not supported.
+func InitExecutor() error {
+	e, err := NewExecutor(config.Terrarium.Executor)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("Setting executor (engine: %s, binary: %s)", e.Engine(), e.Binary())
+	SetExecutor(e)
+	return nil
+}
+
+// getExecutor returns the executor, which is created by the configuration on the first call unless InitExecutor has been called.
+// If the engine is not supported, the commands fail by the error instead of running another engine.
+func getExecutor() Executor {
+	executorOnce.Do(func() {
+		e, err := NewExecutor(config.Terrarium.Executor)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to create the executor")
+			e = &invalidExecutor{engine: config.Terrarium.Executor.Engine, err: err}
+		} else {
+			log.Info().Msgf("Setting executor (engine: %s, binary: %s)", e.Engine(), e.Binary())
+		}
+
+		executorMu.Lock()
+		if executor == nil {
+			executor = e
+		}
+		executorMu.Unlock()
+	})
+
+	executorMu.RLock()
+	defer executorMu.RUnlock()
+	return executor
+}
+
+// SetExecutor replaces the executor (e.g., with a FakeExecutor in tests).
+func SetExecutor(e Executor) {
+	executorOnce.Do(func() {})
+
+	executorMu.Lock()
+	executor = e
+	executorMu.Unlock()
+
+	resetVersionCheck()
+}
+
+// NewExecutor creates an executor of the engine in the configuration.
+func NewExecutor(cfg config.ExecutorConfig) (Executor, error) {
+	switch strings.ToLower(cfg.Engine) {
+	case "", EngineOpenTofu, "tofu":
+		return NewOpenTofuExecutor(cfg), nil
+	case EngineTerraform:
+		return NewTerraformExecutor(cfg), nil
+	default:
+		return nil, fmt.Errorf("unsupported engine: %s", cfg.Engine)
+	}
+}
+
+// invalidExecutor fails the commands by the error of creating the executor (e.g., an unsupported engine).
+type invalidExecutor struct {
+	engine string
+	err    error
+}
+
+func (e *invalidExecutor) Engine() string {
+	return e.engine
+}
+
+func (e *invalidExecutor) Binary() string {
+	return e.engine
+}
+
+func (e *invalidExecutor) Version(ctx context.Context) (string, error) {
+	return "", e.err
+}
+
+func (e *invalidExecutor) Run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	return e.err
+}
+
+// cliExecutor runs the commands by the CLI of an IaC engine.
+type cliExecutor struct {
+	engine      string
+	binary      string
+	globalFlags []string
+	env         []string
+}
+
+// NewOpenTofuExecutor creates an executor running the commands by the OpenTofu CLI.
+func NewOpenTofuExecutor(cfg config.ExecutorConfig) Executor {
+	return &cliExecutor{
+		engine:      EngineOpenTofu,
+		binary:      config.NVL(cfg.BinaryPath, "tofu"),
+		globalFlags: cfg.GlobalFlags,
+		env:         cfg.Env,
+	}
+}
+
+// NewTerraformExecutor creates an executor running the commands by the Terraform CLI,
+// which is compatible with the OpenTofu CLI in the commands used by mc-terrarium.
+func NewTerraformExecutor(cfg config.ExecutorConfig) Executor {
+	return &cliExecutor{
+		engine:      EngineTerraform,
+		binary:      config.NVL(cfg.BinaryPath, "terraform"),
+		globalFlags: cfg.GlobalFlags,
+		// Note - Terraform prints the hints for the manual operation unless it runs in automation
+		env: append([]string{"TF_IN_AUTOMATION=1"}, cfg.Env...),
+	}
+}
+
+func (e *cliExecutor) Engine() string {
+	return e.engine
+}
+
+func (e *cliExecutor) Binary() string {
+	return e.binary
+}
+
+func (e *cliExecutor) Version(ctx context.Context) (string, error) {
+	var outputBuffer bytes.Buffer
+
+	if err := e.Run(ctx, []string{"version"}, &outputBuffer, &outputBuffer); err != nil {
+		return "", fmt.Errorf("failed to execute command: %s version. Error: %w", e.binary, err)
+	}
+	return outputBuffer.String(), nil
+}
+
+// Run sends SIGINT to the process when the context is cancelled so that
+// the state lock can be released, and sends SIGKILL after the grace period.
+func (e *cliExecutor) Run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	cmd := exec.CommandContext(ctx, e.binary, append(append([]string{}, e.globalFlags...), args...)...)
+	cmd.Cancel = func() error {
+		log.Info().Msgf("Interrupting command: %s %s", e.binary, strings.Join(args, " "))
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = cancelGracePeriod
+	if len(e.env) > 0 {
+		cmd.Env = append(os.Environ(), e.env...)
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	return cmd.Run()
+}
+
+// FakeCall is a command recorded by the FakeExecutor.
+type FakeCall struct {
+	Args []string
+}
+
+// FakeResult is the result of a command run by the FakeExecutor.
+// If Wait is set, the command runs until it is closed or the command is cancelled.
+type FakeResult struct {
+	Output string
+	Err    error
+	Wait   <-chan struct{}
+}
+
+// FakeExecutor records the commands instead of running them, for tests.
+// The results can be set per subcommand (e.g., "apply", "output").
+type FakeExecutor struct {
+	mu            sync.Mutex
+	calls         []FakeCall
+	results       map[string]FakeResult
+	VersionOutput string
+}
+
+// NewFakeExecutor creates a FakeExecutor, which succeeds in every command without output.
+func NewFakeExecutor() *FakeExecutor {
+	return &FakeExecutor{
+		results:       make(map[string]FakeResult),
+		VersionOutput: "OpenTofu v1.8.0\non linux_amd64\n",
+	}
+}
+
+// SetResult sets the result of a subcommand.
+func (e *FakeExecutor) SetResult(subcommand string, result FakeResult) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.results[subcommand] = result
+}
+
+// Calls returns the commands recorded so far.
+func (e *FakeExecutor) Calls() []FakeCall {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]FakeCall(nil), e.calls...)
+}
+
+func (e *FakeExecutor) Engine() string {
+	return EngineFake
+}
+
+func (e *FakeExecutor) Binary() string {
+	return "fake"
+}
+
+func (e *FakeExecutor) Version(ctx context.Context) (string, error) {
+	return e.VersionOutput, nil
+}
+
+func (e *FakeExecutor) Run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	e.mu.Lock()
+	e.calls = append(e.calls, FakeCall{Args: append([]string(nil), args...)})
+	subcommand, _ := splitSubcommand(args)
+	result := e.results[subcommand]
+	e.mu.Unlock()
+
+	if result.Wait != nil {
+		select {
+		case <-result.Wait:
+		case <-ctx.Done():
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(stdout, result.Output); err != nil {
+		return err
+	}
+	return result.Err
+}
+
+// semverPattern matches a semantic version (e.g., v1.8.0, 1.9.0-beta1)
+var semverPattern = regexp.MustCompile(`v?(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?`)
+
+// ParseVersion parses the semantic version from the output of the version command.
+func ParseVersion(output string) (model.SemanticVersion, error) {
+
+	// The JSON output (i.e., version -json) has the version as "terraform_version" in both engines
+	var versionJson struct {
+		Version string `json:"terraform_version"`
+	}
+	if err := json.Unmarshal([]byte(output), &versionJson); err == nil && versionJson.Version != "" {
+		output = versionJson.Version
+	}
+
+	m := semverPattern.FindStringSubmatch(output)
+	if m == nil {
+		return model.SemanticVersion{}, fmt.Errorf("no version found in: %s", strings.TrimSpace(output))
+	}
+
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	patch, _ := strconv.Atoi(m[3])
+
+	return model.SemanticVersion{
+		Version:    strings.TrimPrefix(m[0], "v"),
+		Major:      major,
+		Minor:      minor,
+		Patch:      patch,
+		Prerelease: m[4],
+	}, nil
+}
+
+// compareVersion compares two semantic versions and returns -1, 0 or 1.
+// A pre-release version has lower precedence than the normal version.
+func compareVersion(a, b model.SemanticVersion) int {
+	for _, d := range []int{a.Major - b.Major, a.Minor - b.Minor, a.Patch - b.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case a.Prerelease == b.Prerelease:
+		return 0
+	case a.Prerelease == "":
+		return 1
+	case b.Prerelease == "":
+		return -1
+	case a.Prerelease < b.Prerelease:
+		return -1
+	default:
+		return 1
+	}
+}
+
+var (
+	versionCheckMu  sync.Mutex
+	versionCheckErr error
+	versionChecked  bool
+)
+
+// checkMinVersion checks once if the version of the engine satisfies the minimum version in the configuration.
+func checkMinVersion(ctx context.Context) error {
+	versionCheckMu.Lock()
+	defer versionCheckMu.Unlock()
+
+	if versionChecked {
+		return versionCheckErr
+	}
+
+	minVersion := config.Terrarium.Executor.MinVersion
+	if minVersion == "" {
+		versionChecked = true
+		return nil
+	}
+
+	required, err := ParseVersion(minVersion)
+	if err != nil {
+		// Not cached, it is a configuration error
+		return fmt.Errorf("invalid minimum version: %w", err)
+	}
+
+	e := getExecutor()
+	output, err := e.Version(ctx)
+	if err != nil {
+		// Not cached, the engine may be installed later
+		return err
+	}
+	current, err := ParseVersion(output)
+	if err == nil && compareVersion(current, required) < 0 {
+		err = fmt.Errorf("%s v%s does not satisfy the minimum version v%s", e.Engine(), current.Version, required.Version)
+	}
+
+	versionChecked = true
+	versionCheckErr = err
+	return err
+}
+
+func resetVersionCheck() {
+	versionCheckMu.Lock()
+	defer versionCheckMu.Unlock()
+
+	versionChecked = false
+	versionCheckErr = nil
+}
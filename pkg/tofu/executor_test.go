@@ -0,0 +1,202 @@
+package tofu
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+)
+
+// TestMain sets the project root to a temporary directory, where the jobs are saved during the tests.
+func TestMain(m *testing.M) {
+	root, err := os.MkdirTemp("", "mc-terrarium-tofu-")
+	if err != nil {
+		panic(err)
+	}
+	config.Terrarium.Root = root
+	if err := os.MkdirAll(filepath.Join(root, terrariumDir), 0755); err != nil {
+		panic(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := StartJobStore(ctx); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+
+	cancel()
+	CloseJobStore()
+	os.RemoveAll(root)
+	os.Exit(code)
+}
+
+// setUpFakeExecutor replaces the executor with a FakeExecutor for a test, and restores it after the test.
+func setUpFakeExecutor(t *testing.T) *FakeExecutor {
+	t.Helper()
+
+	prev := getExecutor()
+	fe := NewFakeExecutor()
+	SetExecutor(fe)
+
+	t.Cleanup(func() { SetExecutor(prev) })
+	return fe
+}
+
+func TestNewExecutor(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        config.ExecutorConfig
+		wantEngine string
+		wantBinary string
+		wantErr    bool
+	}{
+		{name: "default", cfg: config.ExecutorConfig{}, wantEngine: EngineOpenTofu, wantBinary: "tofu"},
+		{name: "opentofu", cfg: config.ExecutorConfig{Engine: "opentofu"}, wantEngine: EngineOpenTofu, wantBinary: "tofu"},
+		{name: "terraform", cfg: config.ExecutorConfig{Engine: "terraform"}, wantEngine: EngineTerraform, wantBinary: "terraform"},
+		{name: "binary path", cfg: config.ExecutorConfig{Engine: "terraform", BinaryPath: "/opt/bin/terraform"}, wantEngine: EngineTerraform, wantBinary: "/opt/bin/terraform"},
+		{name: "fake", cfg: config.ExecutorConfig{Engine: "fake"}, wantErr: true},
+		{name: "unsupported", cfg: config.ExecutorConfig{Engine: "pulumi"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := NewExecutor(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewExecutor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if e.Engine() != tt.wantEngine || e.Binary() != tt.wantBinary {
+				t.Errorf("NewExecutor() = (%s, %s), want (%s, %s)", e.Engine(), e.Binary(), tt.wantEngine, tt.wantBinary)
+			}
+		})
+	}
+}
+
+func TestInitExecutorUnsupported(t *testing.T) {
+	fe := setUpFakeExecutor(t)
+
+	prev := config.Terrarium.Executor
+	config.Terrarium.Executor = config.ExecutorConfig{Engine: EngineFake}
+	t.Cleanup(func() { config.Terrarium.Executor = prev })
+
+	if err := InitExecutor(); err == nil {
+		t.Fatal("InitExecutor() error = nil, want an unsupported engine")
+	}
+	if getExecutor() != Executor(fe) {
+		t.Errorf("getExecutor() = %v, want the executor unchanged", getExecutor())
+	}
+}
+
+func TestInvalidExecutor(t *testing.T) {
+	prev := getExecutor()
+	unsupported := errors.New("unsupported engine: pulumi")
+	SetExecutor(&invalidExecutor{engine: "pulumi", err: unsupported})
+	t.Cleanup(func() { SetExecutor(prev) })
+
+	if _, err := ExecuteTofuCommand("tr-invalid-executor", "sql-db", "req-invalid-executor", "validate"); !errors.Is(err, unsupported) {
+		t.Errorf("ExecuteTofuCommand() error = %v, want %v", err, unsupported)
+	}
+}
+
+func TestFakeExecutorRecordsCalls(t *testing.T) {
+	fe := setUpFakeExecutor(t)
+	fe.SetResult("output", FakeResult{Output: `{"id":"tr01"}`})
+
+	output, err := ExecuteTofuCommand("tr-fake-calls", "sql-db", "req-fake-calls-1", "output", "-json")
+	if err != nil {
+		t.Fatalf("ExecuteTofuCommand() error = %v", err)
+	}
+	if output != `{"id":"tr01"}` {
+		t.Errorf("ExecuteTofuCommand() output = %q", output)
+	}
+
+	if _, err := ExecuteTofuCommand("tr-fake-calls", "sql-db", "req-fake-calls-2", "validate"); err != nil {
+		t.Fatalf("ExecuteTofuCommand() error = %v", err)
+	}
+
+	want := []FakeCall{{Args: []string{"output", "-json"}}, {Args: []string{"validate"}}}
+	if got := fe.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Calls() = %v, want %v", got, want)
+	}
+}
+
+func TestFakeExecutorFailure(t *testing.T) {
+	fe := setUpFakeExecutor(t)
+	fe.SetResult("apply", FakeResult{Output: "Error: invalid value\n", Err: errors.New("exit status 1")})
+
+	output, err := ExecuteTofuCommand("tr-fake-failure", "sql-db", "req-fake-failure", "apply", "-auto-approve")
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("ExecuteTofuCommand() error = %v, want a CommandError", err)
+	}
+	if cmdErr.Subcommand != "apply" || cmdErr.Output != output || output != "Error: invalid value\n" {
+		t.Errorf("CommandError = (%s, %q), output = %q", cmdErr.Subcommand, cmdErr.Output, output)
+	}
+}
+
+func TestFakeExecutorCancelled(t *testing.T) {
+	fe := NewFakeExecutor()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := fe.Run(ctx, []string{"apply"}, nil, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{name: "opentofu", output: "OpenTofu v1.8.0\non linux_amd64\n", want: "1.8.0"},
+		{name: "terraform", output: "Terraform v1.9.5\non linux_amd64\n", want: "1.9.5"},
+		{name: "json", output: `{"terraform_version":"1.7.2","platform":"linux_amd64"}`, want: "1.7.2"},
+		{name: "prerelease", output: "OpenTofu v1.9.0-beta1", want: "1.9.0-beta1"},
+		{name: "no version", output: "command not found", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseVersion(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Version != tt.want {
+				t.Errorf("ParseVersion() = %s, want %s", got.Version, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareVersion(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.8.0", b: "1.6.0", want: 1},
+		{a: "1.6.0", b: "1.6.0", want: 0},
+		{a: "1.5.7", b: "1.6.0", want: -1},
+		{a: "1.6.0-rc1", b: "1.6.0", want: -1},
+		{a: "1.6.0", b: "1.6.0-rc1", want: 1},
+	}
+
+	for _, tt := range tests {
+		a, _ := ParseVersion(tt.a)
+		b, _ := ParseVersion(tt.b)
+		if got := compareVersion(a, b); got != tt.want {
+			t.Errorf("compareVersion(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package tofu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/replica"
+)
+
+// Status of a job (i.e., a tofu command execution)
+const (
+	JobStatusQueued    = "Queued"
+	JobStatusRunning   = "Running"
+	JobStatusSuccess   = "Success"
+	JobStatusFailed    = "Failed"
+	JobStatusCancelled = "Cancelled"
+	// The job was queued or running when the server stopped (e.g., crash or restart)
+	JobStatusInterrupted = "Interrupted"
+)
+
+var (
+	// ErrJobNotFound is returned when no job is recorded for a request ID.
+	ErrJobNotFound = errors.New("no request found")
+	// ErrJobNotRunning is returned when cancelling a job which is not queued or running.
+	ErrJobNotRunning = errors.New("the request is not running")
+	// ErrRequestIdInUse is returned when a request ID is used by a job of another terrarium or enrichment,
+	// or by a job still queued or running.
+	ErrRequestIdInUse = errors.New("the request ID is already used by another request")
+)
+
+// Manage the job records of tofu commands by request ID, each of which is saved to the store on its change.
+var jobMap sync.Map
+
+// Manage the cancel functions of running tofu commands by request ID.
+var cancelFuncMap sync.Map
+
+// Min interval to save a job by its progress, which is kept in memory in between.
+// The changes of the status (e.g., finished) are saved immediately with the latest progress.
+const progressSaveInterval = time.Second
+
+// Manage the last time a running job was saved by its progress by request ID.
+var progressSavedAt sync.Map
+
+// Listeners to be notified when a job is started and finished.
+var (
+	jobListenersMu      sync.RWMutex
+	jobListeners        []func(job model.JobInfo)
+	jobStartedListeners []func(job model.JobInfo)
+)
+
+// AddJobListener adds a function to be called when a job is finished (i.e., succeeded, failed, cancelled or interrupted).
+// The function is called synchronously before the result is returned to the requester, so it should not block long.
+func AddJobListener(listener func(job model.JobInfo)) {
+	jobListenersMu.Lock()
+	defer jobListenersMu.Unlock()
+	jobListeners = append(jobListeners, listener)
+}
+
+// AddJobStartedListener adds a function to be called when a queued job starts running.
+// The function is called synchronously before the tofu command runs, so it should not block long.
+func AddJobStartedListener(listener func(job model.JobInfo)) {
+	jobListenersMu.Lock()
+	defer jobListenersMu.Unlock()
+	jobStartedListeners = append(jobStartedListeners, listener)
+}
+
+// notifyJobStarted calls the listeners with a started job.
+func notifyJobStarted(job model.JobInfo) {
+	jobListenersMu.RLock()
+	listeners := jobStartedListeners
+	jobListenersMu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(job)
+	}
+}
+
+// notifyJobFinished calls the listeners with a finished job.
+func notifyJobFinished(job model.JobInfo) {
+	jobListenersMu.RLock()
+	listeners := jobListeners
+	jobListenersMu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(job)
+	}
+}
+
+// Set the job record for a given reqId, which is saved on every change.
+func setJob(job model.JobInfo) {
+	jobMap.Store(job.Id, job)
+	persistJob(job.Id)
+}
+
+// Get the job record for a given reqId.
+func getJob(reqId string) (model.JobInfo, bool) {
+	value, exists := jobMap.Load(reqId)
+	if !exists {
+		return model.JobInfo{}, false
+	}
+	return value.(model.JobInfo), true
+}
+
+// checkReuse returns ErrRequestIdInUse unless the job recorded for a request ID can track the next command
+// of the request, which is in the same terrarium and enrichment after the previous command is done.
+// The request ID is given by the client (i.e., X-Request-Id), so it cannot take over the job of another request.
+func checkReuse(job model.JobInfo, trId, enrichments string) error {
+	if job.TerrariumId != trId || job.Enrichments != enrichments {
+		return fmt.Errorf("%w (reqId: %s) of the terrarium (trId: %s, enrichments: %s)", ErrRequestIdInUse, job.Id, job.TerrariumId, job.Enrichments)
+	}
+	if job.Status == JobStatusQueued || job.Status == JobStatusRunning {
+		return fmt.Errorf("%w (reqId: %s), which is %s", ErrRequestIdInUse, job.Id, strings.ToLower(job.Status))
+	}
+	return nil
+}
+
+// checkRequestId checks if a request ID can queue a command of an enrichment (see checkReuse),
+// including the job recorded before a restart or by another server sharing the store.
+func checkRequestId(trId, enrichments, reqId string) error {
+	job, err := GetJob(reqId)
+	if errors.Is(err, ErrJobNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return checkReuse(job, trId, enrichments)
+}
+
+// queueJob records a tofu command queued for a given reqId in memory, which the caller saves by persistJob
+// (i.e., after releasing the scheduler lock not to block the other requests by the I/O).
+// If the request runs several commands in sequence (e.g., apply and then output),
+// the record keeps the first start time and tracks the latest command.
+// It returns ErrRequestIdInUse if the request ID is used by another request (see checkReuse).
+func queueJob(trId, enrichments, reqId string, args []string) error {
+
+	job, exists := getJob(reqId)
+	if exists {
+		if err := checkReuse(job, trId, enrichments); err != nil {
+			return err
+		}
+	} else {
+		job = model.JobInfo{
+			Id:          reqId,
+			TerrariumId: trId,
+			Enrichments: enrichments,
+			Command:     getExecutor().Binary(),
+		}
+	}
+
+	job.Subcommand, job.Args = splitSubcommand(args)
+	job.Status = JobStatusQueued
+	job.Server = replica.Id()
+	job.QueuedAt = time.Now()
+	job.EndedAt = nil
+	job.ExitCode = nil
+	job.Error = ""
+	job.LogPath = runningLogFilePath(reqId, args)
+	job.Progress = nil
+
+	jobMap.Store(job.Id, job)
+	return nil
+}
+
+// startJob records the start of the queued tofu command for a given reqId.
+func startJob(reqId string) {
+
+	job, exists := getJob(reqId)
+	if !exists {
+		return
+	}
+
+	if job.StartedAt == nil {
+		startedAt := time.Now()
+		job.StartedAt = &startedAt
+	}
+	job.Status = JobStatusRunning
+
+	setJob(job)
+	notifyJobStarted(job)
+}
+
+// setJobProgress records the progress of the running tofu command for a given reqId,
+// which is saved to the file on a throttle (see progressSaveInterval).
+func setJobProgress(reqId string, progress *model.JobProgress) {
+
+	job, exists := getJob(reqId)
+	if !exists {
+		return
+	}
+
+	job.Progress = progress
+	jobMap.Store(job.Id, job)
+
+	// Save the progress at most once per interval not to save the job by every event
+	now := time.Now()
+	if last, ok := progressSavedAt.Load(reqId); ok && now.Sub(last.(time.Time)) < progressSaveInterval {
+		return
+	}
+	progressSavedAt.Store(reqId, now)
+	persistJob(reqId)
+}
+
+// finishJob records the result of a tofu command for a given reqId.
+func finishJob(reqId string, err error) {
+
+	job, exists := getJob(reqId)
+	if !exists {
+		return
+	}
+
+	endedAt := time.Now()
+	job.EndedAt = &endedAt
+
+	exitCode := 0
+	if err != nil {
+		exitCode = -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		job.Status = JobStatusFailed
+		if errors.Is(err, context.Canceled) {
+			job.Status = JobStatusCancelled
+		}
+		job.Error = err.Error()
+		var cmdErr *CommandError
+		if errors.As(err, &cmdErr) {
+			job.Diagnostics = cmdErr.Diagnostics
+		}
+	} else {
+		job.Status = JobStatusSuccess
+	}
+	job.ExitCode = &exitCode
+
+	progressSavedAt.Delete(reqId)
+	setJob(job)
+	notifyJobFinished(job)
+}
+
+// Set the cancel function for a given reqId.
+func setCancelFunc(reqId string, cancel context.CancelFunc) {
+	cancelFuncMap.Store(reqId, cancel)
+}
+
+// Delete the cancel function for a given reqId.
+func deleteCancelFunc(reqId string) {
+	cancelFuncMap.Delete(reqId)
+}
+
+// splitSubcommand extracts the subcommand and its arguments from the tofu arguments,
+// skipping the global options (e.g., -chdir=...).
+func splitSubcommand(args []string) (string, []string) {
+	for i, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return arg, args[i+1:]
+		}
+	}
+	return "", nil
+}
+
+// GetJob returns the job record of a given reqId, which may be run by another server sharing the store.
+func GetJob(reqId string) (model.JobInfo, error) {
+
+	job, exists := getJob(reqId)
+	if !exists {
+		var err error
+		if job, err = readJob(reqId); err != nil {
+			return model.JobInfo{}, err
+		}
+	}
+
+	if job.Status == JobStatusQueued {
+		job.QueuePosition = getScheduler().position(job.TerrariumId, reqId)
+	}
+
+	// Set the elapsed time of the resources in progress
+	if job.Progress != nil {
+		progress := *job.Progress
+		progress.Resources = append([]model.ResourceProgress(nil), job.Progress.Resources...)
+		for i, r := range progress.Resources {
+			if r.Status == ResourceStatusInProgress {
+				progress.Resources[i].ElapsedSeconds = time.Since(r.StartedAt).Seconds()
+			}
+		}
+		job.Progress = &progress
+	}
+
+	return job, nil
+}
+
+// CancelJob cancels the queued or running tofu command of a given reqId.
+// A queued command is removed from the queue. A running tofu process is
+// interrupted (SIGINT) to release the state lock cleanly and killed (SIGKILL)
+// if it does not exit within the grace period.
+// The job is marked as "Cancelled" when the process exits.
+// The job run by another server sharing the store is cancelled by the server (see requestCancel).
+func CancelJob(reqId string) error {
+
+	job, exists := getJob(reqId)
+	if !exists {
+		job, err := readJob(reqId)
+		if err != nil {
+			return err
+		}
+		if job.Status != JobStatusQueued && job.Status != JobStatusRunning {
+			return fmt.Errorf("%w (reqId: %s, status: %s)", ErrJobNotRunning, reqId, job.Status)
+		}
+		return requestCancel(reqId)
+	}
+
+	if job.Status == JobStatusQueued && getScheduler().remove(job.TerrariumId, reqId) {
+		return nil
+	}
+
+	value, exists := cancelFuncMap.Load(reqId)
+	if !exists || (job.Status != JobStatusQueued && job.Status != JobStatusRunning) {
+		return fmt.Errorf("%w (reqId: %s, status: %s)", ErrJobNotRunning, reqId, job.Status)
+	}
+
+	cancel := value.(context.CancelFunc)
+	cancel()
+
+	return nil
+}
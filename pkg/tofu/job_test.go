@@ -0,0 +1,522 @@
+package tofu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/replica"
+)
+
+// waitForJob waits until the job of a request has one of the statuses, and returns it.
+func waitForJob(t *testing.T, reqId string, statuses ...string) model.JobInfo {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := GetJob(reqId)
+		if err == nil {
+			for _, status := range statuses {
+				if job.Status == status {
+					return job
+				}
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the job (reqId: %s) to be %v, got %+v (err: %v)", reqId, statuses, job, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSchedulerRunsInFifoOrder(t *testing.T) {
+	fe := setUpFakeExecutor(t)
+
+	// Hold the first command running while the others are queued
+	release := make(chan struct{})
+	fe.SetResult("init", FakeResult{Wait: release})
+
+	trId := "tr-fifo"
+	if _, err := ExecuteTofuCommandAsync(trId, "sql-db", "req-fifo-0", "init"); err != nil {
+		t.Fatal(err)
+	}
+	waitForJob(t, "req-fifo-0", JobStatusRunning)
+
+	for i := 1; i <= 3; i++ {
+		reqId := fmt.Sprintf("req-fifo-%d", i)
+		if _, err := ExecuteTofuCommandAsync(trId, "sql-db", reqId, "output", reqId); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 1; i <= 3; i++ {
+		job := waitForJob(t, fmt.Sprintf("req-fifo-%d", i), JobStatusQueued)
+		if job.QueuePosition != i {
+			t.Errorf("queue position of req-fifo-%d = %d, want %d", i, job.QueuePosition, i)
+		}
+	}
+
+	close(release)
+	for i := 0; i <= 3; i++ {
+		waitForJob(t, fmt.Sprintf("req-fifo-%d", i), JobStatusSuccess)
+	}
+
+	want := []FakeCall{
+		{Args: []string{"init"}},
+		{Args: []string{"output", "req-fifo-1"}},
+		{Args: []string{"output", "req-fifo-2"}},
+		{Args: []string{"output", "req-fifo-3"}},
+	}
+	if got := fe.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Calls() = %v, want %v", got, want)
+	}
+}
+
+func TestCancelQueuedJob(t *testing.T) {
+	fe := setUpFakeExecutor(t)
+
+	release := make(chan struct{})
+	fe.SetResult("init", FakeResult{Wait: release})
+
+	trId := "tr-cancel-queued"
+	if _, err := ExecuteTofuCommandAsync(trId, "sql-db", "req-cancel-queued-0", "init"); err != nil {
+		t.Fatal(err)
+	}
+	waitForJob(t, "req-cancel-queued-0", JobStatusRunning)
+	if _, err := ExecuteTofuCommandAsync(trId, "sql-db", "req-cancel-queued-1", "apply"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CancelJob("req-cancel-queued-1"); err != nil {
+		t.Fatalf("CancelJob() error = %v", err)
+	}
+	waitForJob(t, "req-cancel-queued-1", JobStatusCancelled)
+
+	close(release)
+	waitForJob(t, "req-cancel-queued-0", JobStatusSuccess)
+
+	// The cancelled command is never run
+	for _, call := range fe.Calls() {
+		if subcommand, _ := splitSubcommand(call.Args); subcommand == "apply" {
+			t.Errorf("the cancelled command is run: %v", call.Args)
+		}
+	}
+
+	if err := CancelJob("req-cancel-queued-1"); !errors.Is(err, ErrJobNotRunning) {
+		t.Errorf("CancelJob() of a cancelled job error = %v, want %v", err, ErrJobNotRunning)
+	}
+}
+
+func TestCancelJobWaitingForWorker(t *testing.T) {
+	fe := setUpFakeExecutor(t)
+
+	// Hold all the workers so that the task waits for one
+	s := getScheduler()
+	for i := 0; i < cap(s.workers); i++ {
+		s.workers <- struct{}{}
+	}
+	defer func() {
+		for i := 0; i < cap(s.workers); i++ {
+			<-s.workers
+		}
+	}()
+
+	task, err := s.submit("tr-cancel-waiting", "sql-db", "req-cancel-waiting", []string{"apply"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForJob(t, "req-cancel-waiting", JobStatusQueued)
+
+	// Cancel the task waiting in the dispatcher without removing it from the queue
+	task.cancel()
+	waitForJob(t, "req-cancel-waiting", JobStatusCancelled)
+
+	if got := len(s.workers); got != cap(s.workers) {
+		t.Errorf("len(workers) = %d, want %d", got, cap(s.workers))
+	}
+	if calls := fe.Calls(); len(calls) != 0 {
+		t.Errorf("the cancelled command is run: %v", calls)
+	}
+}
+
+func TestLockTerrarium(t *testing.T) {
+	fe := setUpFakeExecutor(t)
+
+	release := make(chan struct{})
+	fe.SetResult("init", FakeResult{Wait: release})
+
+	trId := "tr-lock"
+	if _, err := ExecuteTofuCommandAsync(trId, "sql-db", "req-running", "init"); err != nil {
+		t.Fatal(err)
+	}
+	waitForJob(t, "req-running", JobStatusRunning)
+
+	// Wait for the running command to lock the terrarium
+	locked := make(chan *TerrariumLock)
+	go func() {
+		lock, err := LockTerrarium(context.Background(), trId, "req-lock")
+		if err != nil {
+			t.Errorf("LockTerrarium() error = %v", err)
+		}
+		locked <- lock
+	}()
+	select {
+	case <-locked:
+		t.Fatal("LockTerrarium() returned while a command of the terrarium is running")
+	case <-time.After(3 * idlePollInterval):
+	}
+
+	close(release)
+	lock := <-locked
+	waitForJob(t, "req-running", JobStatusSuccess)
+
+	// Only the commands of the jobs of the request holding the lock are accepted,
+	// not the request IDs given by the other requests like the ones of the holder
+	for _, reqId := range []string{"req-other", "req-lock", "req-lock-sql-db"} {
+		if _, err := ExecuteTofuCommandAsync(trId, "sql-db", reqId, "apply"); !errors.Is(err, ErrTerrariumLocked) {
+			t.Errorf("ExecuteTofuCommandAsync() of %s error = %v, want %v", reqId, err, ErrTerrariumLocked)
+		}
+	}
+	if _, err := LockTerrarium(context.Background(), trId, "req-other"); !errors.Is(err, ErrTerrariumLocked) {
+		t.Errorf("LockTerrarium() of another request error = %v, want %v", err, ErrTerrariumLocked)
+	}
+	jobId, err := lock.JobId("sql-db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(jobId, "req-lock-sql-db-") {
+		t.Errorf("JobId() = %s, want req-lock-sql-db-{nonce}", jobId)
+	}
+	for _, subcommand := range []string{"state", "destroy"} {
+		if _, err := ExecuteTofuCommand(trId, "sql-db", jobId, subcommand); err != nil {
+			t.Errorf("ExecuteTofuCommand() of the job of the request holding the lock error = %v", err)
+		}
+	}
+	if other, err := lock.JobId("sql-db"); err != nil || other == jobId {
+		t.Errorf("JobId() = (%s, %v), want another job ID than %s", other, err, jobId)
+	}
+
+	lock.Unlock()
+	if _, err := ExecuteTofuCommand(trId, "sql-db", "req-after-lock", "init"); err != nil {
+		t.Errorf("ExecuteTofuCommand() after unlocking error = %v", err)
+	}
+}
+
+// A request ID runs the commands of its enrichment in sequence, but cannot take over the job of another request.
+func TestRequestIdInUse(t *testing.T) {
+	fe := setUpFakeExecutor(t)
+
+	reqId := "req-in-use"
+	if _, err := ExecuteTofuCommand("tr-in-use", "sql-db", reqId, "apply"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExecuteTofuCommand("tr-in-use", "sql-db", reqId, "output"); err != nil {
+		t.Errorf("ExecuteTofuCommand() of the next command error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		trId        string
+		enrichments string
+	}{
+		{name: "another terrarium", trId: "tr-in-use-other", enrichments: "sql-db"},
+		{name: "another enrichment", trId: "tr-in-use", enrichments: "vpn/gcp-aws"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ExecuteTofuCommand(tt.trId, tt.enrichments, reqId, "destroy"); !errors.Is(err, ErrRequestIdInUse) {
+				t.Errorf("ExecuteTofuCommand() error = %v, want %v", err, ErrRequestIdInUse)
+			}
+		})
+	}
+
+	job, err := GetJob(reqId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.TerrariumId != "tr-in-use" || job.Enrichments != "sql-db" || job.Subcommand != "output" || job.Status != JobStatusSuccess {
+		t.Errorf("job = %+v, want the output of sql-db in tr-in-use succeeded", job)
+	}
+
+	// The same request cannot queue another command while running
+	release := make(chan struct{})
+	fe.SetResult("apply", FakeResult{Wait: release})
+	if _, err := ExecuteTofuCommandAsync("tr-in-use", "sql-db", "req-in-use-running", "apply"); err != nil {
+		t.Fatal(err)
+	}
+	waitForJob(t, "req-in-use-running", JobStatusRunning)
+	if _, err := ExecuteTofuCommandAsync("tr-in-use", "sql-db", "req-in-use-running", "apply"); !errors.Is(err, ErrRequestIdInUse) {
+		t.Errorf("ExecuteTofuCommandAsync() while running error = %v, want %v", err, ErrRequestIdInUse)
+	}
+	close(release)
+	waitForJob(t, "req-in-use-running", JobStatusSuccess)
+}
+
+func TestCancelRunningJob(t *testing.T) {
+	fe := setUpFakeExecutor(t)
+
+	// The command runs until it is cancelled
+	fe.SetResult("apply", FakeResult{Wait: make(chan struct{})})
+
+	reqId := "req-cancel-running"
+	done := make(chan error, 1)
+	go func() {
+		_, err := ExecuteTofuCommand("tr-cancel-running", "sql-db", reqId, "apply", "-auto-approve")
+		done <- err
+	}()
+	waitForJob(t, reqId, JobStatusRunning)
+
+	if err := CancelJob(reqId); err != nil {
+		t.Fatalf("CancelJob() error = %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("ExecuteTofuCommand() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the cancelled command")
+	}
+	waitForJob(t, reqId, JobStatusCancelled)
+}
+
+func TestCancelUnknownJob(t *testing.T) {
+	setUpFakeExecutor(t)
+
+	if err := CancelJob("req-unknown"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("CancelJob() error = %v, want %v", err, ErrJobNotFound)
+	}
+}
+
+func TestFinishJob(t *testing.T) {
+	setUpFakeExecutor(t)
+
+	exitErr := exec.Command("sh", "-c", "exit 3").Run()
+	diagnostics := []model.DiagnosticMessage{{Severity: "error", Summary: "Invalid value"}}
+
+	tests := []struct {
+		name            string
+		err             error
+		wantStatus      string
+		wantExitCode    int
+		wantDiagnostics []model.DiagnosticMessage
+	}{
+		{name: "success", err: nil, wantStatus: JobStatusSuccess, wantExitCode: 0},
+		{name: "failure", err: errors.New("failed to start"), wantStatus: JobStatusFailed, wantExitCode: -1},
+		{name: "exit code", err: fmt.Errorf("failed to execute: %w", exitErr), wantStatus: JobStatusFailed, wantExitCode: 3},
+		{name: "cancelled", err: fmt.Errorf("cancelled while queued: %w", context.Canceled), wantStatus: JobStatusCancelled, wantExitCode: -1},
+		{
+			name:            "diagnostics",
+			err:             &CommandError{Subcommand: "plan", Diagnostics: diagnostics, Err: errors.New("exit status 1")},
+			wantStatus:      JobStatusFailed,
+			wantExitCode:    -1,
+			wantDiagnostics: diagnostics,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqId := "req-finish-" + tt.name
+			if err := queueJob("tr-finish", "sql-db", reqId, []string{"plan"}); err != nil {
+				t.Fatal(err)
+			}
+			startJob(reqId)
+			finishJob(reqId, tt.err)
+
+			job, err := GetJob(reqId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", job.Status, tt.wantStatus)
+			}
+			if job.ExitCode == nil || *job.ExitCode != tt.wantExitCode {
+				t.Errorf("exit code = %v, want %d", job.ExitCode, tt.wantExitCode)
+			}
+			if job.StartedAt == nil || job.EndedAt == nil {
+				t.Errorf("start and end times = (%v, %v), want both", job.StartedAt, job.EndedAt)
+			}
+			if !reflect.DeepEqual(job.Diagnostics, tt.wantDiagnostics) {
+				t.Errorf("diagnostics = %v, want %v", job.Diagnostics, tt.wantDiagnostics)
+			}
+		})
+	}
+}
+
+// finishedJob returns the record of a job which ended at a time.
+func finishedJob(reqId string, endedAt time.Time) model.JobInfo {
+	exitCode := 0
+	return model.JobInfo{Id: reqId, TerrariumId: "tr-prune", Status: JobStatusSuccess, EndedAt: &endedAt, ExitCode: &exitCode}
+}
+
+func TestPruneJobs(t *testing.T) {
+	now := time.Now()
+	maxJobs := 3
+
+	// Keep the jobs of the other tests out of the count, and restore them after the test
+	others := make(map[string]model.JobInfo)
+	jobMap.Range(func(key, value interface{}) bool {
+		others[key.(string)] = value.(model.JobInfo)
+		jobMap.Delete(key)
+		jobStore.Delete(context.Background(), jobKeyPrefix+key.(string))
+		return true
+	})
+
+	// The finished jobs over the max number, the newest first
+	var jobs []model.JobInfo
+	for i := 0; i < maxJobs+2; i++ {
+		jobs = append(jobs, finishedJob(fmt.Sprintf("req-prune-%d", i), now.Add(-time.Duration(i)*time.Second)))
+	}
+	jobs = append(jobs,
+		finishedJob("req-prune-expired", now.Add(-jobRetention-time.Second)),
+		model.JobInfo{Id: "req-prune-running", TerrariumId: "tr-prune", Status: JobStatusRunning},
+	)
+	for _, job := range jobs {
+		setJob(job)
+	}
+	t.Cleanup(func() {
+		for _, job := range jobs {
+			jobMap.Delete(job.Id)
+			jobStore.Delete(context.Background(), jobKeyPrefix+job.Id)
+		}
+		for _, job := range others {
+			setJob(job)
+		}
+	})
+
+	if err := pruneJobs(now, maxJobs); err != nil {
+		t.Fatalf("pruneJobs() error = %v", err)
+	}
+
+	tests := []struct {
+		reqId      string
+		wantPruned bool
+	}{
+		{reqId: "req-prune-0"},
+		{reqId: fmt.Sprintf("req-prune-%d", maxJobs-1)},
+		{reqId: fmt.Sprintf("req-prune-%d", maxJobs), wantPruned: true},
+		{reqId: fmt.Sprintf("req-prune-%d", maxJobs+1), wantPruned: true},
+		{reqId: "req-prune-expired", wantPruned: true},
+		{reqId: "req-prune-running"},
+	}
+	for _, tt := range tests {
+		_, inMemory := getJob(tt.reqId)
+		_, err := jobStore.Get(context.Background(), jobKeyPrefix+tt.reqId)
+		inStore := err == nil
+		if inMemory == tt.wantPruned || inStore == tt.wantPruned {
+			t.Errorf("job %s kept (in memory: %v, in store: %v), want pruned %v", tt.reqId, inMemory, inStore, tt.wantPruned)
+		}
+	}
+}
+
+func TestMigrateJobMap(t *testing.T) {
+	job := finishedJob("req-migrate", time.Now())
+	data, err := json.Marshal(map[string]model.JobInfo{job.Id: job})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), statusFileName)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { jobStore.Delete(context.Background(), jobKeyPrefix+job.Id) })
+
+	if err := migrateJobMap(path); err != nil {
+		t.Fatalf("migrateJobMap() error = %v", err)
+	}
+
+	kv, err := jobStore.Get(context.Background(), jobKeyPrefix+job.Id)
+	if err != nil {
+		t.Fatalf("the migrated job is not in the store: %v", err)
+	}
+	var got model.JobInfo
+	if err := json.Unmarshal(kv.Value, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Id != job.Id || got.Status != job.Status || !got.EndedAt.Equal(*job.EndedAt) {
+		t.Errorf("migrated job = %+v, want %+v", got, job)
+	}
+
+	// The file is renamed not to be migrated again
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the job map file is left after the migration (err: %v)", err)
+	}
+}
+
+// The jobs of the stopped servers are interrupted, and the jobs of this server are kept.
+func TestReconcileJobs(t *testing.T) {
+	setUpFakeExecutor(t)
+
+	jobs := []model.JobInfo{
+		{Id: "req-reconcile-stopped", TerrariumId: "tr-reconcile", Status: JobStatusRunning, Server: "server-stopped"},
+		{Id: "req-reconcile-legacy", TerrariumId: "tr-reconcile", Status: JobStatusQueued},
+		{Id: "req-reconcile-running", TerrariumId: "tr-reconcile", Status: JobStatusRunning, Server: replica.Id()},
+	}
+	for _, job := range jobs {
+		setJob(job)
+	}
+	t.Cleanup(func() {
+		for _, job := range jobs {
+			jobMap.Delete(job.Id)
+			jobStore.Delete(context.Background(), jobKeyPrefix+job.Id)
+		}
+	})
+
+	if n := ReconcileJobs(); n != 2 {
+		t.Errorf("ReconcileJobs() = %d, want 2", n)
+	}
+
+	tests := []struct {
+		reqId      string
+		wantStatus string
+	}{
+		{reqId: "req-reconcile-stopped", wantStatus: JobStatusInterrupted},
+		{reqId: "req-reconcile-legacy", wantStatus: JobStatusInterrupted},
+		{reqId: "req-reconcile-running", wantStatus: JobStatusRunning},
+	}
+	for _, tt := range tests {
+		job, err := readJob(tt.reqId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != tt.wantStatus {
+			t.Errorf("status of %s in the store = %s, want %s", tt.reqId, job.Status, tt.wantStatus)
+		}
+		if job, _ := getJob(tt.reqId); job.Status != tt.wantStatus {
+			t.Errorf("status of %s in memory = %s, want %s", tt.reqId, job.Status, tt.wantStatus)
+		}
+	}
+}
+
+// The job of another server is read from the store.
+func TestGetJobOfAnotherServer(t *testing.T) {
+	setUpFakeExecutor(t)
+
+	job := finishedJob("req-another-server", time.Now())
+	job.Server = "server-another"
+	if err := putJob(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { jobStore.Delete(context.Background(), jobKeyPrefix+job.Id) })
+
+	got, err := GetJob(job.Id)
+	if err != nil {
+		t.Fatalf("GetJob() error = %v", err)
+	}
+	if got.Server != job.Server || got.Status != job.Status {
+		t.Errorf("GetJob() = %+v, want %+v", got, job)
+	}
+
+	if err := CancelJob(job.Id); !errors.Is(err, ErrJobNotRunning) {
+		t.Errorf("CancelJob() of a finished job error = %v, want %v", err, ErrJobNotRunning)
+	}
+}
@@ -0,0 +1,101 @@
+package tofu
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+)
+
+// Interval to check new lines in the log file of a running job
+const logPollInterval = 500 * time.Millisecond
+
+// IsJobCompleted checks if the job is no longer queued or running.
+func IsJobCompleted(job model.JobInfo) bool {
+	return job.Status != JobStatusQueued && job.Status != JobStatusRunning
+}
+
+// FollowJobLog reads the log of a request from the given offset (in bytes) and
+// passes each line with the offset right after the line to the handle function.
+// If follow is true, it keeps reading new lines until the job is completed or
+// the context is done. It returns the latest job record.
+func FollowJobLog(ctx context.Context, reqId string, offset int64, follow bool, handle func(line string, offset int64) error) (model.JobInfo, error) {
+
+	var file *os.File
+	var reader *bufio.Reader
+	var partial string
+
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+
+	for {
+		job, err := GetJob(reqId)
+		if err != nil {
+			return model.JobInfo{}, err
+		}
+		if job.LogPath == "" {
+			return job, fmt.Errorf("no log file of the request (reqId: %s)", reqId)
+		}
+		// Check the completion before reading to get all lines written by the job
+		completed := IsJobCompleted(job)
+
+		// Open the log file if it has been created (i.e., the job has started)
+		if file == nil {
+			file, err = os.Open(job.LogPath)
+			if err != nil && !os.IsNotExist(err) {
+				return job, fmt.Errorf("failed to open log file: %w", err)
+			}
+			if file != nil {
+				if _, err := file.Seek(offset, io.SeekStart); err != nil {
+					return job, fmt.Errorf("failed to seek log file: %w", err)
+				}
+				reader = bufio.NewReader(file)
+			}
+		}
+
+		// Read the lines appended to the log file
+		if reader != nil {
+			for {
+				chunk, err := reader.ReadString('\n')
+				if err != nil && !errors.Is(err, io.EOF) {
+					return job, fmt.Errorf("failed to read log file: %w", err)
+				}
+				partial += chunk
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				offset += int64(len(partial))
+				if err := handle(strings.TrimRight(partial, "\r\n"), offset); err != nil {
+					return job, err
+				}
+				partial = ""
+			}
+		}
+
+		if completed || !follow {
+			// Pass the last line without a line break
+			if partial != "" {
+				offset += int64(len(partial))
+				if err := handle(strings.TrimRight(partial, "\r\n"), offset); err != nil {
+					return job, err
+				}
+			}
+			return job, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-time.After(logPollInterval):
+		}
+	}
+}
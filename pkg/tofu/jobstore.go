@@ -0,0 +1,384 @@
+package tofu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/cloud-barista/mc-terrarium/pkg/replica"
+	"github.com/cloud-barista/mc-terrarium/pkg/store"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Name of the store of the job records (i.e., .terrarium/jobs.bolt or the keys under <prefix>jobs/ in etcd)
+	jobStoreName = "jobs"
+	// Prefixes of the keys of the job records and the requests to cancel them in the store
+	jobKeyPrefix    = "jobs/"
+	cancelKeyPrefix = "job-cancels/"
+
+	// Time to keep the record of a finished job, after which it is pruned
+	jobRetention = 7 * 24 * time.Hour
+	// Max number of the finished jobs to keep, over which the oldest ones are pruned
+	maxFinishedJobs = 10000
+	// Interval to prune the finished jobs
+	pruneInterval = time.Hour
+	// Interval to check the requests to cancel the jobs of this server from the other servers sharing the store
+	cancelPollInterval = 2 * time.Second
+	// Interval to interrupt the jobs of the stopped servers sharing the store (see ReconcileJobs)
+	reconcileInterval = time.Minute
+
+	// Timeout of an operation on the store
+	jobStoreTimeout = 10 * time.Second
+)
+
+// Store of the job records, where each job is kept by its own key
+// not to rewrite all the jobs on every change of a job.
+var jobStore store.Store
+
+// Serialize saving the jobs so that the latest record of a job is saved last.
+var saveMu sync.Mutex
+
+// StartJobStore opens the store of the job records and loads them, which migrates the job map
+// of the previous versions (i.e., .terrarium/runningStatusMap.db) if it exists.
+// It prunes the finished jobs on start and periodically by the leader until the context is done.
+// With the store shared by the servers, the jobs of the other servers are read from the store when requested,
+// and the requests to cancel the jobs of this server are checked periodically.
+func StartJobStore(ctx context.Context) error {
+
+	s, err := store.Open(config.Terrarium.Store, jobStoreName)
+	if err != nil {
+		return fmt.Errorf("failed to open the store of the jobs: %w", err)
+	}
+	jobStore = s
+
+	if err := migrateJobMap(filepath.Join(config.Terrarium.Root, terrariumDir, statusFileName)); err != nil {
+		log.Error().Err(err).Msg("failed to migrate the job map")
+	}
+	if !replica.Shared() {
+		if err := loadJobs(); err != nil {
+			return err
+		}
+	}
+
+	if replica.IsLeader() {
+		if err := pruneJobs(time.Now(), maxFinishedJobs); err != nil {
+			log.Error().Err(err).Msg("failed to prune the finished jobs")
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(pruneInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if !replica.IsLeader() {
+					continue
+				}
+				if err := pruneJobs(now, maxFinishedJobs); err != nil {
+					log.Error().Err(err).Msg("failed to prune the finished jobs")
+				}
+			}
+		}
+	}()
+
+	if replica.Shared() {
+		go pollCancels(ctx)
+		go func() {
+			ticker := time.NewTicker(reconcileInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if !replica.IsLeader() {
+						continue
+					}
+					if n := ReconcileJobs(); n > 0 {
+						log.Warn().Msgf("%d request(s) interrupted by the stop of their servers", n)
+					}
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+// CloseJobStore closes the store of the job records.
+func CloseJobStore() error {
+	if jobStore == nil {
+		return nil
+	}
+	return jobStore.Close()
+}
+
+// loadJobs loads the job records from the store to the job map.
+func loadJobs() error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobStoreTimeout)
+	defer cancel()
+
+	kvs, err := jobStore.List(ctx, jobKeyPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to load the jobs: %w", err)
+	}
+
+	for _, kv := range kvs {
+		var job model.JobInfo
+		if err := json.Unmarshal(kv.Value, &job); err != nil {
+			log.Warn().Err(err).Msgf("failed to decode the job (%s), skipping", kv.Key)
+			continue
+		}
+		jobMap.Store(job.Id, job)
+	}
+	return nil
+}
+
+// migrateJobMap saves the jobs of the job map file of the previous versions to the store,
+// and renames the file not to migrate it again.
+func migrateJobMap(path string) error {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read the job map: %w", err)
+	}
+
+	var jobs map[string]model.JobInfo
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return fmt.Errorf("failed to decode the job map: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobStoreTimeout)
+	defer cancel()
+
+	for _, job := range jobs {
+		if err := putJob(ctx, job); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(path, path+".migrated"); err != nil {
+		return fmt.Errorf("failed to rename the migrated job map: %w", err)
+	}
+	log.Info().Msgf("migrated %d job(s) from %s", len(jobs), path)
+	return nil
+}
+
+// persistJob saves the latest record of a job in the job map to the store.
+// It does nothing until the store is opened (see StartJobStore).
+func persistJob(reqId string) {
+	if jobStore == nil {
+		return
+	}
+
+	saveMu.Lock()
+	defer saveMu.Unlock()
+
+	job, exists := getJob(reqId)
+	if !exists {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobStoreTimeout)
+	defer cancel()
+
+	if err := putJob(ctx, job); err != nil {
+		log.Error().Err(err).Msgf("failed to save the job (reqId: %s)", reqId)
+	}
+}
+
+// putJob saves the record of a job to the store.
+func putJob(ctx context.Context, job model.JobInfo) error {
+	value, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode the job (reqId: %s): %w", job.Id, err)
+	}
+	if _, err := jobStore.Put(ctx, jobKeyPrefix+job.Id, value); err != nil {
+		return fmt.Errorf("failed to save the job (reqId: %s): %w", job.Id, err)
+	}
+	return nil
+}
+
+// readJob reads the record of a job from the store, which is run by another server sharing the store.
+func readJob(reqId string) (model.JobInfo, error) {
+	if jobStore == nil {
+		return model.JobInfo{}, fmt.Errorf("%w (reqId: %s)", ErrJobNotFound, reqId)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobStoreTimeout)
+	defer cancel()
+
+	kv, err := jobStore.Get(ctx, jobKeyPrefix+reqId)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return model.JobInfo{}, fmt.Errorf("%w (reqId: %s)", ErrJobNotFound, reqId)
+		}
+		return model.JobInfo{}, fmt.Errorf("failed to read the job (reqId: %s): %w", reqId, err)
+	}
+
+	var job model.JobInfo
+	if err := json.Unmarshal(kv.Value, &job); err != nil {
+		return model.JobInfo{}, fmt.Errorf("failed to decode the job (reqId: %s): %w", reqId, err)
+	}
+	return job, nil
+}
+
+// storedJob is the record of a job in the store with its revision.
+type storedJob struct {
+	job      model.JobInfo
+	revision int64
+}
+
+// listJobs lists the records of all the jobs in the store, which skips the records failed to decode.
+func listJobs(ctx context.Context) ([]storedJob, error) {
+
+	kvs, err := jobStore.List(ctx, jobKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the jobs: %w", err)
+	}
+
+	jobs := make([]storedJob, 0, len(kvs))
+	for _, kv := range kvs {
+		var job model.JobInfo
+		if err := json.Unmarshal(kv.Value, &job); err != nil {
+			log.Warn().Err(err).Msgf("failed to decode the job (%s), skipping", kv.Key)
+			continue
+		}
+		jobs = append(jobs, storedJob{job: job, revision: kv.Revision})
+	}
+	return jobs, nil
+}
+
+// requestCancel requests the server running a job to cancel it (see pollCancels).
+func requestCancel(reqId string) error {
+	if !replica.Shared() {
+		return fmt.Errorf("%w (reqId: %s): the server running it has stopped", ErrJobNotRunning, reqId)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobStoreTimeout)
+	defer cancel()
+
+	value, err := json.Marshal(struct {
+		RequestedAt time.Time `json:"requestedAt"`
+		RequestedBy string    `json:"requestedBy"`
+	}{RequestedAt: time.Now(), RequestedBy: replica.Id()})
+	if err != nil {
+		return fmt.Errorf("failed to encode the request to cancel the job (reqId: %s): %w", reqId, err)
+	}
+	if _, err := jobStore.Put(ctx, cancelKeyPrefix+reqId, value); err != nil {
+		return fmt.Errorf("failed to request to cancel the job (reqId: %s): %w", reqId, err)
+	}
+	return nil
+}
+
+// pollCancels cancels the jobs of this server requested by the other servers sharing the store
+// until the context is done. The requests of the finished jobs are deleted by any server.
+func pollCancels(ctx context.Context) {
+
+	ticker := time.NewTicker(cancelPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		storeCtx, cancel := context.WithTimeout(ctx, jobStoreTimeout)
+		kvs, err := jobStore.List(storeCtx, cancelKeyPrefix)
+		cancel()
+		if err != nil {
+			log.Error().Err(err).Msg("failed to read the requests to cancel the jobs")
+			continue
+		}
+
+		for _, kv := range kvs {
+			reqId := strings.TrimPrefix(kv.Key, cancelKeyPrefix)
+
+			if _, running := cancelFuncMap.Load(reqId); running {
+				log.Info().Msgf("cancelling the request (reqId: %s) by another server", reqId)
+				if err := CancelJob(reqId); err != nil {
+					log.Warn().Err(err).Msgf("failed to cancel the request (reqId: %s)", reqId)
+				}
+			} else if job, err := readJob(reqId); err == nil && (job.Status == JobStatusQueued || job.Status == JobStatusRunning) {
+				// Of another server
+				continue
+			}
+
+			storeCtx, cancel := context.WithTimeout(ctx, jobStoreTimeout)
+			if err := jobStore.Delete(storeCtx, kv.Key); err != nil && !errors.Is(err, store.ErrNotFound) {
+				log.Warn().Err(err).Msgf("failed to delete the request to cancel the job (reqId: %s)", reqId)
+			}
+			cancel()
+		}
+	}
+}
+
+// pruneJobs deletes the finished jobs which ended before the retention (see jobRetention),
+// and the oldest ones over the max number of the finished jobs (see maxFinishedJobs).
+// The queued and running jobs are never pruned.
+func pruneJobs(now time.Time, maxJobs int) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobStoreTimeout)
+	jobs, err := listJobs(ctx)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	var finished []storedJob
+	for _, j := range jobs {
+		if j.job.EndedAt != nil && j.job.Status != JobStatusQueued && j.job.Status != JobStatusRunning {
+			finished = append(finished, j)
+		}
+	}
+
+	// The newest first
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].job.EndedAt.After(*finished[j].job.EndedAt)
+	})
+
+	saveMu.Lock()
+	defer saveMu.Unlock()
+
+	pruned := 0
+	for i, j := range finished {
+		job := j.job
+		if i < maxJobs && now.Sub(*job.EndedAt) < jobRetention {
+			continue
+		}
+		// Skip the job if it has been queued again by the same request in the meantime
+		if latest, exists := getJob(job.Id); exists && (latest.EndedAt == nil || !latest.EndedAt.Equal(*job.EndedAt)) {
+			continue
+		}
+		jobMap.Delete(job.Id)
+		ctx, cancel := context.WithTimeout(context.Background(), jobStoreTimeout)
+		err := jobStore.Delete(ctx, jobKeyPrefix+job.Id)
+		cancel()
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("failed to delete the job (reqId: %s): %w", job.Id, err)
+		}
+		pruned++
+	}
+
+	if pruned > 0 {
+		log.Info().Msgf("pruned %d finished job(s)", pruned)
+	}
+	return nil
+}
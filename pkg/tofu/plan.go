@@ -0,0 +1,502 @@
+package tofu
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Directory (in the working directory) to save plan files
+	planDirName = "plans"
+
+	// State file of the local backend
+	stateFileName = "terraform.tfstate"
+
+	// Max number of the saved plans in a working directory, over which the oldest ones are removed
+	maxSavedPlans = 10
+
+	sensitiveValue = "(sensitive value)"
+	unknownValue   = "(known after apply)"
+)
+
+var (
+	// ErrInvalidPlanId is returned when a plan ID is not a valid file name.
+	ErrInvalidPlanId = errors.New("invalid plan ID")
+	// ErrPlanNotFound is returned when no saved plan exists for a plan ID.
+	ErrPlanNotFound = errors.New("plan not found")
+	// ErrPlanStale is returned when the state or tfvars changed after a plan was made.
+	ErrPlanStale = errors.New("plan is stale")
+)
+
+// planRecord is the record of a saved plan to check if the plan is still valid.
+type planRecord struct {
+	PlanId         string    `json:"planId"`
+	CreatedAt      time.Time `json:"createdAt"`
+	StateChecksum  string    `json:"stateChecksum"`
+	TfVarsChecksum string    `json:"tfVarsChecksum"`
+}
+
+// planJson is a part of the JSON representation of a plan (i.e., `tofu show -json <plan file>`).
+// See https://opentofu.org/docs/internals/json-format/
+type planJson struct {
+	ResourceChanges []struct {
+		Address      string `json:"address"`
+		Mode         string `json:"mode"`
+		Type         string `json:"type"`
+		Name         string `json:"name"`
+		ProviderName string `json:"provider_name"`
+		Change       struct {
+			Actions         []string    `json:"actions"`
+			Before          interface{} `json:"before"`
+			After           interface{} `json:"after"`
+			AfterUnknown    interface{} `json:"after_unknown"`
+			BeforeSensitive interface{} `json:"before_sensitive"`
+			AfterSensitive  interface{} `json:"after_sensitive"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// PlanFilePath returns the path of the plan file of a plan ID in a working directory.
+func PlanFilePath(workingDir, planId string) string {
+	return filepath.Join(workingDir, PlanFileArg(planId))
+}
+
+// PlanFileArg returns the path of the plan file relative to the working directory,
+// which is passed to a tofu command with the global option -chdir.
+func PlanFileArg(planId string) string {
+	return filepath.Join(planDirName, planId+".tfplan")
+}
+
+func planRecordPath(workingDir, planId string) string {
+	return filepath.Join(workingDir, planDirName, planId+".json")
+}
+
+// ExecuteTofuPlan saves a plan by `plan -out=<plan file>` and summarizes it by `show -json <plan file>`.
+// The request ID is used as the plan ID. It returns the summary and the output of the plan command.
+func ExecuteTofuPlan(trId, enrichments, reqId, workingDir string) (model.PlanSummary, string, error) {
+
+	if err := checkPlanId(reqId); err != nil {
+		return model.PlanSummary{}, "", err
+	}
+
+	planFile := PlanFilePath(workingDir, reqId)
+	if err := os.MkdirAll(filepath.Dir(planFile), 0755); err != nil {
+		return model.PlanSummary{}, "", fmt.Errorf("failed to create plan directory: %w", err)
+	}
+
+	// Note - the relative path of the plan file is resolved in the working directory (-chdir)
+	relPlanFile := PlanFileArg(reqId)
+
+	ret, err := ExecuteTofuCommand(trId, enrichments, reqId, "-chdir="+workingDir, "plan", "-out="+relPlanFile)
+	if err != nil {
+		return model.PlanSummary{}, ret, err
+	}
+
+	// Record the checksums of the state and tfvars which the plan is made from
+	if err := savePlanRecord(workingDir, reqId); err != nil {
+		return model.PlanSummary{}, ret, err
+	}
+
+	show, err := ExecuteTofuCommand(trId, enrichments, reqId, "-chdir="+workingDir, "show", "-json", relPlanFile)
+	if err != nil {
+		return model.PlanSummary{}, ret, fmt.Errorf("failed to show the plan (planId: %s): %w", reqId, err)
+	}
+
+	summary, err := SummarizePlan([]byte(show))
+	if err != nil {
+		return model.PlanSummary{}, ret, err
+	}
+	summary.PlanId = reqId
+
+	// Remove the stale plans and the oldest ones over the limit
+	prunePlans(workingDir)
+
+	return summary, ret, nil
+}
+
+// VerifyPlan checks if the saved plan of a plan ID can be applied as it was reviewed.
+// It returns ErrPlanStale if the state or tfvars changed after the plan was made.
+func VerifyPlan(workingDir, planId string) error {
+
+	if err := checkPlanId(planId); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(PlanFilePath(workingDir, planId)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w (planId: %s)", ErrPlanNotFound, planId)
+		}
+		return fmt.Errorf("failed to check the plan file: %w", err)
+	}
+
+	data, err := os.ReadFile(planRecordPath(workingDir, planId))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w (planId: %s)", ErrPlanNotFound, planId)
+		}
+		return fmt.Errorf("failed to read the plan record: %w", err)
+	}
+	var record planRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return fmt.Errorf("failed to unmarshal the plan record: %w", err)
+	}
+
+	stateChecksum, err := checksumFiles(workingDir, []string{stateFileName})
+	if err != nil {
+		return err
+	}
+	if stateChecksum != record.StateChecksum {
+		removePlan(workingDir, planId)
+		return fmt.Errorf("%w, the state changed after the plan was made (planId: %s)", ErrPlanStale, planId)
+	}
+
+	tfVarsChecksum, err := checksumFiles(workingDir, tfVarsFiles(workingDir))
+	if err != nil {
+		return err
+	}
+	if tfVarsChecksum != record.TfVarsChecksum {
+		removePlan(workingDir, planId)
+		return fmt.Errorf("%w, the tfvars changed after the plan was made (planId: %s)", ErrPlanStale, planId)
+	}
+
+	return nil
+}
+
+// removePlan removes the plan file and the record of a plan ID.
+func removePlan(workingDir, planId string) {
+	for _, path := range []string{PlanFilePath(workingDir, planId), planRecordPath(workingDir, planId)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Warn().Err(err).Msgf("failed to remove the plan (planId: %s)", planId)
+		}
+	}
+}
+
+// prunePlans removes the stale plans in a working directory, which cannot be applied any more,
+// and the oldest ones over the limit (see maxSavedPlans).
+func prunePlans(workingDir string) {
+
+	recordPaths, err := filepath.Glob(filepath.Join(workingDir, planDirName, "*.json"))
+	if err != nil {
+		return
+	}
+
+	var records []planRecord
+	for _, path := range recordPaths {
+		planId := strings.TrimSuffix(filepath.Base(path), ".json")
+		if err := VerifyPlan(workingDir, planId); err != nil {
+			// A stale plan is removed by the verification
+			if errors.Is(err, ErrPlanNotFound) {
+				removePlan(workingDir, planId)
+			}
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var record planRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+
+	if len(records) <= maxSavedPlans {
+		return
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].CreatedAt.After(records[j].CreatedAt) })
+	for _, record := range records[maxSavedPlans:] {
+		removePlan(workingDir, record.PlanId)
+	}
+}
+
+// cleanUpPlans removes the plan applied by a successful command (i.e., apply <plan file>)
+// and the plans made stale by it (e.g., apply, destroy) in the working directory of the command.
+func cleanUpPlans(args []string) {
+
+	workingDir := chdirOf(args)
+	subcommand, subArgs := splitSubcommand(args)
+	if workingDir == "" || (subcommand != "apply" && subcommand != "destroy") {
+		return
+	}
+
+	for _, arg := range subArgs {
+		if filepath.Dir(arg) == planDirName && strings.HasSuffix(arg, ".tfplan") {
+			removePlan(workingDir, strings.TrimSuffix(filepath.Base(arg), ".tfplan"))
+		}
+	}
+	prunePlans(workingDir)
+}
+
+// checkPlanId checks if a plan ID can be used as a file name in the plan directory.
+func checkPlanId(planId string) error {
+	if planId == "" || planId != filepath.Base(planId) || strings.HasPrefix(planId, ".") {
+		return fmt.Errorf("%w (planId: %s)", ErrInvalidPlanId, planId)
+	}
+	return nil
+}
+
+// savePlanRecord saves the record of a plan with the current checksums of the state and tfvars.
+func savePlanRecord(workingDir, planId string) error {
+
+	stateChecksum, err := checksumFiles(workingDir, []string{stateFileName})
+	if err != nil {
+		return err
+	}
+	tfVarsChecksum, err := checksumFiles(workingDir, tfVarsFiles(workingDir))
+	if err != nil {
+		return err
+	}
+
+	record := planRecord{
+		PlanId:         planId,
+		CreatedAt:      time.Now(),
+		StateChecksum:  stateChecksum,
+		TfVarsChecksum: tfVarsChecksum,
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the plan record: %w", err)
+	}
+	if err := os.WriteFile(planRecordPath(workingDir, planId), data, 0644); err != nil {
+		return fmt.Errorf("failed to save the plan record: %w", err)
+	}
+	return nil
+}
+
+// tfVarsFiles returns the names of the variable definition files loaded automatically by tofu:
+// - Files named exactly terraform.tfvars or terraform.tfvars.json.
+// - Any files with names ending in .auto.tfvars or .auto.tfvars.json.
+func tfVarsFiles(workingDir string) []string {
+	names := []string{"terraform.tfvars", "terraform.tfvars.json"}
+	for _, pattern := range []string{"*.auto.tfvars", "*.auto.tfvars.json"} {
+		matches, _ := filepath.Glob(filepath.Join(workingDir, pattern))
+		for _, match := range matches {
+			names = append(names, filepath.Base(match))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checksumFiles returns the SHA-256 checksum of the names and contents of files in a directory.
+// A file which does not exist is skipped.
+func checksumFiles(dir string, names []string) (string, error) {
+	hash := sha256.New()
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00", name, len(data))
+		hash.Write(data)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// SummarizePlan summarizes the JSON representation of a plan.
+// Sensitive values are masked in the changed attributes.
+func SummarizePlan(data []byte) (model.PlanSummary, error) {
+
+	var plan planJson
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return model.PlanSummary{}, fmt.Errorf("failed to unmarshal the plan: %w", err)
+	}
+
+	summary := model.PlanSummary{
+		ResourceChanges: []model.PlannedResourceChange{},
+	}
+
+	for _, rc := range plan.ResourceChanges {
+		action := planAction(rc.Change.Actions)
+		if action == "no-op" {
+			continue
+		}
+
+		// Count the changes of managed resources as tofu does in the plan output
+		if rc.Mode == "managed" {
+			switch action {
+			case "create":
+				summary.Add++
+			case "update":
+				summary.Change++
+			case "delete":
+				summary.Destroy++
+			case "replace":
+				summary.Add++
+				summary.Destroy++
+			}
+		}
+
+		after := markUnknown(rc.Change.After, rc.Change.AfterUnknown)
+
+		summary.ResourceChanges = append(summary.ResourceChanges, model.PlannedResourceChange{
+			Address:      rc.Address,
+			Mode:         rc.Mode,
+			Type:         rc.Type,
+			Name:         rc.Name,
+			ProviderName: rc.ProviderName,
+			Action:       action,
+			Actions:      rc.Change.Actions,
+			ChangedAttributes: changedAttributes(
+				rc.Change.Before, after,
+				rc.Change.BeforeSensitive, rc.Change.AfterSensitive, rc.Change.AfterUnknown,
+			),
+		})
+	}
+	summary.HasDestroy = summary.Destroy > 0
+
+	return summary, nil
+}
+
+// planAction returns a single action from the actions of a resource change.
+func planAction(actions []string) string {
+	switch {
+	case len(actions) == 2:
+		// ["delete", "create"] or ["create", "delete"]
+		return "replace"
+	case len(actions) == 1:
+		return actions[0]
+	default:
+		return "no-op"
+	}
+}
+
+// changedAttributes returns the top-level attributes which differ between before and after.
+// The values are compared before masking so that a change of a sensitive value is not missed.
+func changedAttributes(before, after, beforeSensitive, afterSensitive, afterUnknown interface{}) []model.AttributeChange {
+
+	beforeMap, _ := before.(map[string]interface{})
+	afterMap, _ := after.(map[string]interface{})
+
+	names := map[string]bool{}
+	for name := range beforeMap {
+		names[name] = true
+	}
+	for name := range afterMap {
+		names[name] = true
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var changes []model.AttributeChange
+	for _, name := range sorted {
+		b, a := beforeMap[name], afterMap[name]
+		if reflect.DeepEqual(b, a) {
+			continue
+		}
+		changes = append(changes, model.AttributeChange{
+			Name:      name,
+			Before:    maskValue(b, attributeOf(beforeSensitive, name)),
+			After:     maskValue(a, attributeOf(afterSensitive, name)),
+			Sensitive: hasMark(attributeOf(beforeSensitive, name)) || hasMark(attributeOf(afterSensitive, name)),
+			Unknown:   hasMark(attributeOf(afterUnknown, name)),
+		})
+	}
+	return changes
+}
+
+// maskValue replaces the values marked as sensitive (i.e., true in the marks) with a placeholder.
+func maskValue(value, marks interface{}) interface{} {
+	return replaceMarked(value, marks, sensitiveValue)
+}
+
+// markUnknown replaces the values marked as unknown (i.e., true in the marks) with a placeholder.
+func markUnknown(value, marks interface{}) interface{} {
+	return replaceMarked(value, marks, unknownValue)
+}
+
+// replaceMarked replaces the values marked by the marks, which have the same structure
+// as the value and true at the marked values, with the placeholder.
+func replaceMarked(value, marks interface{}, placeholder string) interface{} {
+	switch m := marks.(type) {
+	case bool:
+		if m {
+			return placeholder
+		}
+		return value
+
+	case map[string]interface{}:
+		v, ok := value.(map[string]interface{})
+		if !ok {
+			if value != nil || len(m) == 0 {
+				return value
+			}
+			v = map[string]interface{}{}
+		}
+		replaced := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			replaced[key] = elem
+		}
+		for key, mark := range m {
+			if !hasMark(mark) {
+				continue
+			}
+			replaced[key] = replaceMarked(replaced[key], mark, placeholder)
+		}
+		return replaced
+
+	case []interface{}:
+		v, ok := value.([]interface{})
+		if !ok {
+			return value
+		}
+		replaced := append([]interface{}(nil), v...)
+		for i, mark := range m {
+			if i < len(replaced) && hasMark(mark) {
+				replaced[i] = replaceMarked(replaced[i], mark, placeholder)
+			}
+		}
+		return replaced
+	}
+
+	return value
+}
+
+// hasMark checks if the marks have any true value.
+func hasMark(marks interface{}) bool {
+	switch m := marks.(type) {
+	case bool:
+		return m
+	case map[string]interface{}:
+		for _, mark := range m {
+			if hasMark(mark) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, mark := range m {
+			if hasMark(mark) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// attributeOf returns the marks of an attribute.
+func attributeOf(marks interface{}, name string) interface{} {
+	if m, ok := marks.(map[string]interface{}); ok {
+		return m[name]
+	}
+	return marks
+}
@@ -0,0 +1,149 @@
+package tofu
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+)
+
+// planWithSensitiveValues is a part of `tofu show -json` of a plan, which has sensitive values
+// in the created, updated and replaced resources.
+const planWithSensitiveValues = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {
+      "address": "aws_db_instance.db",
+      "mode": "managed",
+      "type": "aws_db_instance",
+      "name": "db",
+      "provider_name": "registry.opentofu.org/hashicorp/aws",
+      "change": {
+        "actions": ["create"],
+        "before": null,
+        "after": {
+          "identifier": "mydb",
+          "password": "S3cret-Passw0rd",
+          "port": 3306,
+          "tags": {"env": "dev", "token": "tok-12345"}
+        },
+        "after_unknown": {"id": true, "endpoint": true},
+        "before_sensitive": false,
+        "after_sensitive": {"password": true, "tags": {"token": true}}
+      }
+    },
+    {
+      "address": "aws_secretsmanager_secret_version.secret",
+      "mode": "managed",
+      "type": "aws_secretsmanager_secret_version",
+      "name": "secret",
+      "provider_name": "registry.opentofu.org/hashicorp/aws",
+      "change": {
+        "actions": ["update"],
+        "before": {"secret_id": "my-secret", "secret_string": "old-secret-value"},
+        "after": {"secret_id": "my-secret", "secret_string": "new-secret-value"},
+        "after_unknown": {},
+        "before_sensitive": {"secret_string": true},
+        "after_sensitive": {"secret_string": true}
+      }
+    },
+    {
+      "address": "random_password.admin",
+      "mode": "managed",
+      "type": "random_password",
+      "name": "admin",
+      "provider_name": "registry.opentofu.org/hashicorp/random",
+      "change": {
+        "actions": ["delete", "create"],
+        "before": {"length": 16, "result": "old-random-password"},
+        "after": {"length": 20},
+        "after_unknown": {"result": true},
+        "before_sensitive": {"result": true},
+        "after_sensitive": {"result": true}
+      }
+    },
+    {
+      "address": "aws_vpc.unchanged",
+      "mode": "managed",
+      "type": "aws_vpc",
+      "name": "unchanged",
+      "provider_name": "registry.opentofu.org/hashicorp/aws",
+      "change": {
+        "actions": ["no-op"],
+        "before": {"cidr_block": "10.0.0.0/16"},
+        "after": {"cidr_block": "10.0.0.0/16"},
+        "after_unknown": {},
+        "before_sensitive": {},
+        "after_sensitive": {}
+      }
+    }
+  ]
+}`
+
+func TestSummarizePlanMasksSensitiveValues(t *testing.T) {
+	summary, err := SummarizePlan([]byte(planWithSensitiveValues))
+	if err != nil {
+		t.Fatalf("SummarizePlan() error = %v", err)
+	}
+
+	// No sensitive value is in the summary
+	data, err := json.Marshal(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"S3cret-Passw0rd", "tok-12345", "old-secret-value", "new-secret-value", "old-random-password"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("the summary has the sensitive value %q: %s", secret, data)
+		}
+	}
+
+	if summary.Add != 2 || summary.Change != 1 || summary.Destroy != 1 || !summary.HasDestroy {
+		t.Errorf("(add, change, destroy, hasDestroy) = (%d, %d, %d, %v), want (2, 1, 1, true)",
+			summary.Add, summary.Change, summary.Destroy, summary.HasDestroy)
+	}
+	if len(summary.ResourceChanges) != 3 {
+		t.Fatalf("resource changes = %d, want 3 without the no-op", len(summary.ResourceChanges))
+	}
+
+	attributes := map[string]model.AttributeChange{}
+	for _, rc := range summary.ResourceChanges {
+		for _, a := range rc.ChangedAttributes {
+			attributes[rc.Address+"."+a.Name] = a
+		}
+	}
+
+	tests := []struct {
+		name          string
+		wantBefore    interface{}
+		wantAfter     interface{}
+		wantSensitive bool
+	}{
+		{name: "aws_db_instance.db.identifier", wantAfter: "mydb"},
+		{name: "aws_db_instance.db.password", wantAfter: sensitiveValue, wantSensitive: true},
+		{name: "aws_db_instance.db.tags", wantAfter: map[string]interface{}{"env": "dev", "token": sensitiveValue}, wantSensitive: true},
+		{name: "aws_secretsmanager_secret_version.secret.secret_string", wantBefore: sensitiveValue, wantAfter: sensitiveValue, wantSensitive: true},
+		{name: "random_password.admin.result", wantBefore: sensitiveValue, wantAfter: sensitiveValue, wantSensitive: true},
+		{name: "random_password.admin.length", wantBefore: float64(16), wantAfter: float64(20)},
+	}
+	for _, tt := range tests {
+		a, exists := attributes[tt.name]
+		if !exists {
+			t.Errorf("no changed attribute %s", tt.name)
+			continue
+		}
+		if !reflect.DeepEqual(a.Before, tt.wantBefore) || !reflect.DeepEqual(a.After, tt.wantAfter) || a.Sensitive != tt.wantSensitive {
+			t.Errorf("%s = (%v, %v, sensitive: %v), want (%v, %v, sensitive: %v)",
+				tt.name, a.Before, a.After, a.Sensitive, tt.wantBefore, tt.wantAfter, tt.wantSensitive)
+		}
+	}
+
+	// The unchanged secret ID is omitted, and the unknown values are marked
+	if _, exists := attributes["aws_secretsmanager_secret_version.secret.secret_id"]; exists {
+		t.Errorf("the unchanged attribute is in the changed attributes")
+	}
+	if a := attributes["aws_db_instance.db.id"]; !a.Unknown || a.After != unknownValue {
+		t.Errorf("aws_db_instance.db.id = (%v, unknown: %v), want (%s, unknown: true)", a.After, a.Unknown, unknownValue)
+	}
+}
@@ -0,0 +1,244 @@
+package tofu
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+)
+
+// Status of a resource being changed by a tofu command
+const (
+	ResourceStatusInProgress = "InProgress"
+	ResourceStatusComplete   = "Complete"
+	ResourceStatusErrored    = "Errored"
+)
+
+// uiEvent is a message of the machine-readable UI (i.e., -json) of tofu.
+// See https://opentofu.org/docs/internals/machine-readable-ui/
+type uiEvent struct {
+	Level      string        `json:"@level"`
+	Message    string        `json:"@message"`
+	Timestamp  time.Time     `json:"@timestamp"`
+	Type       string        `json:"type"`
+	Hook       *uiHook       `json:"hook,omitempty"`
+	Change     *uiHook       `json:"change,omitempty"`
+	Changes    *uiChanges    `json:"changes,omitempty"`
+	Diagnostic *uiDiagnostic `json:"diagnostic,omitempty"`
+}
+
+type uiHook struct {
+	Resource struct {
+		Addr string `json:"addr"`
+	} `json:"resource"`
+	Action         string  `json:"action"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+}
+
+type uiChanges struct {
+	Add       int    `json:"add"`
+	Change    int    `json:"change"`
+	Remove    int    `json:"remove"`
+	Import    int    `json:"import"`
+	Operation string `json:"operation"`
+}
+
+type uiDiagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail"`
+	Address  string `json:"address"`
+}
+
+// Actions which change a resource (i.e., counted in the progress)
+var countedActions = map[string]bool{
+	"create":  true,
+	"update":  true,
+	"replace": true,
+	"delete":  true,
+}
+
+// progressWriter parses the machine-readable UI output of tofu line by line,
+// records the progress to the job of the request, and writes
+// the human-readable message of each event to the next writer.
+type progressWriter struct {
+	reqId    string
+	next     io.Writer
+	buf      []byte
+	progress model.JobProgress
+}
+
+func newProgressWriter(reqId, operation string, next io.Writer) *progressWriter {
+	w := &progressWriter{
+		reqId:    reqId,
+		next:     next,
+		progress: model.JobProgress{Operation: operation},
+	}
+	setJobProgress(reqId, w.snapshot())
+	return w
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := w.handleLine(w.buf[:i]); err != nil {
+			return len(p), err
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush handles the last line without a line break.
+func (w *progressWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	err := w.handleLine(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *progressWriter) handleLine(line []byte) error {
+	var event uiEvent
+	if err := json.Unmarshal(line, &event); err != nil || event.Type == "" {
+		// Pass through a line that is not an event
+		_, err := w.next.Write(append(line, '\n'))
+		return err
+	}
+
+	if w.apply(event) {
+		setJobProgress(w.reqId, w.snapshot())
+	}
+
+	_, err := w.next.Write([]byte(event.Message + "\n"))
+	return err
+}
+
+// apply updates the progress by an event and returns true if the progress is changed.
+func (w *progressWriter) apply(event uiEvent) bool {
+	p := &w.progress
+
+	switch event.Type {
+	case "planned_change":
+		if event.Change != nil && countedActions[event.Change.Action] {
+			p.ResourcesTotal++
+			return true
+		}
+
+	case "apply_start":
+		if event.Hook == nil || !countedActions[event.Hook.Action] {
+			return false
+		}
+		p.Resources = append(p.Resources, model.ResourceProgress{
+			Address:   event.Hook.Resource.Addr,
+			Action:    event.Hook.Action,
+			Status:    ResourceStatusInProgress,
+			StartedAt: eventTime(event),
+		})
+		p.CurrentResource = event.Hook.Resource.Addr
+		return true
+
+	case "apply_complete", "apply_errored":
+		if event.Hook == nil || !countedActions[event.Hook.Action] {
+			return false
+		}
+		status := ResourceStatusComplete
+		if event.Type == "apply_errored" {
+			status = ResourceStatusErrored
+		}
+		for i := range p.Resources {
+			r := &p.Resources[i]
+			if r.Address != event.Hook.Resource.Addr || r.Status != ResourceStatusInProgress {
+				continue
+			}
+			endedAt := eventTime(event)
+			r.Status = status
+			r.EndedAt = &endedAt
+			r.ElapsedSeconds = event.Hook.ElapsedSeconds
+			break
+		}
+		if status == ResourceStatusComplete {
+			p.ResourcesCompleted++
+		} else {
+			p.ResourcesFailed++
+		}
+		p.CurrentResource = currentResource(p.Resources)
+		return true
+
+	case "change_summary":
+		if event.Changes == nil {
+			return false
+		}
+		p.ChangeSummary = &model.ChangeSummary{
+			Operation: event.Changes.Operation,
+			Add:       event.Changes.Add,
+			Change:    event.Changes.Change,
+			Remove:    event.Changes.Remove,
+			Import:    event.Changes.Import,
+		}
+		return true
+
+	case "diagnostic":
+		if event.Diagnostic == nil {
+			return false
+		}
+		p.Diagnostics = append(p.Diagnostics, model.DiagnosticMessage{
+			Severity: event.Diagnostic.Severity,
+			Summary:  event.Diagnostic.Summary,
+			Detail:   event.Diagnostic.Detail,
+			Address:  event.Diagnostic.Address,
+		})
+		return true
+	}
+
+	return false
+}
+
+// snapshot returns a copy of the progress, which can be shared safely.
+func (w *progressWriter) snapshot() *model.JobProgress {
+	p := w.progress
+	if p.ChangeSummary != nil {
+		summary := *p.ChangeSummary
+		p.ChangeSummary = &summary
+	}
+	p.Resources = append([]model.ResourceProgress(nil), w.progress.Resources...)
+	p.Diagnostics = append([]model.DiagnosticMessage(nil), w.progress.Diagnostics...)
+	return &p
+}
+
+// currentResource returns the address of the latest resource in progress.
+func currentResource(resources []model.ResourceProgress) string {
+	for i := len(resources) - 1; i >= 0; i-- {
+		if resources[i].Status == ResourceStatusInProgress {
+			return resources[i].Address
+		}
+	}
+	return ""
+}
+
+func eventTime(event uiEvent) time.Time {
+	if event.Timestamp.IsZero() {
+		return time.Now()
+	}
+	return event.Timestamp
+}
+
+// syncWriter serializes writes to a writer shared by stdout and stderr of a command.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
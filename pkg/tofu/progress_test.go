@@ -0,0 +1,190 @@
+package tofu
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+)
+
+// Events of the machine-readable UI of tofu (i.e., apply -json)
+const (
+	eventPlannedVpc   = `{"@level":"info","@message":"aws_vpc.main: Plan to create","@timestamp":"2024-01-01T00:00:00Z","type":"planned_change","change":{"resource":{"addr":"aws_vpc.main"},"action":"create"}}`
+	eventPlannedRead  = `{"@level":"info","@message":"data.aws_ami.ubuntu: Plan to read","@timestamp":"2024-01-01T00:00:00Z","type":"planned_change","change":{"resource":{"addr":"data.aws_ami.ubuntu"},"action":"read"}}`
+	eventStartVpc     = `{"@level":"info","@message":"aws_vpc.main: Creating...","@timestamp":"2024-01-01T00:00:01Z","type":"apply_start","hook":{"resource":{"addr":"aws_vpc.main"},"action":"create"}}`
+	eventStartSubnet  = `{"@level":"info","@message":"aws_subnet.main: Creating...","@timestamp":"2024-01-01T00:00:02Z","type":"apply_start","hook":{"resource":{"addr":"aws_subnet.main"},"action":"create"}}`
+	eventCompleteVpc  = `{"@level":"info","@message":"aws_vpc.main: Creation complete after 3s","@timestamp":"2024-01-01T00:00:04Z","type":"apply_complete","hook":{"resource":{"addr":"aws_vpc.main"},"action":"create","elapsed_seconds":3}}`
+	eventErroredVpc   = `{"@level":"error","@message":"aws_vpc.main: Creation errored after 3s","@timestamp":"2024-01-01T00:00:04Z","type":"apply_errored","hook":{"resource":{"addr":"aws_vpc.main"},"action":"create","elapsed_seconds":3}}`
+	eventStartRead    = `{"@level":"info","@message":"data.aws_ami.ubuntu: Reading...","@timestamp":"2024-01-01T00:00:01Z","type":"apply_start","hook":{"resource":{"addr":"data.aws_ami.ubuntu"},"action":"read"}}`
+	eventCompleteRead = `{"@level":"info","@message":"data.aws_ami.ubuntu: Read complete after 1s","@timestamp":"2024-01-01T00:00:02Z","type":"apply_complete","hook":{"resource":{"addr":"data.aws_ami.ubuntu"},"action":"read","elapsed_seconds":1}}`
+	eventDiagnostic   = `{"@level":"error","@message":"Error: creating EC2 VPC","@timestamp":"2024-01-01T00:00:04Z","type":"diagnostic","diagnostic":{"severity":"error","summary":"creating EC2 VPC","detail":"VpcLimitExceeded","address":"aws_vpc.main"}}`
+	eventSummary      = `{"@level":"info","@message":"Apply complete! Resources: 1 added, 0 changed, 0 destroyed.","@timestamp":"2024-01-01T00:00:05Z","type":"change_summary","changes":{"add":1,"change":0,"remove":0,"import":0,"operation":"apply"}}`
+)
+
+func progressTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
+
+func progressTimeRef(s string) *time.Time {
+	t := progressTime(s)
+	return &t
+}
+
+func TestProgressWriter(t *testing.T) {
+	tests := []struct {
+		name       string
+		chunks     []string
+		want       model.JobProgress
+		wantOutput string
+	}{
+		{
+			name:   "apply start and complete",
+			chunks: []string{eventPlannedVpc + "\n" + eventPlannedRead + "\n", eventStartVpc + "\n", eventCompleteVpc + "\n", eventSummary + "\n"},
+			want: model.JobProgress{
+				Operation:          "apply",
+				ResourcesTotal:     1,
+				ResourcesCompleted: 1,
+				ChangeSummary:      &model.ChangeSummary{Operation: "apply", Add: 1},
+				Resources: []model.ResourceProgress{{
+					Address:        "aws_vpc.main",
+					Action:         "create",
+					Status:         ResourceStatusComplete,
+					StartedAt:      progressTime("2024-01-01T00:00:01Z"),
+					EndedAt:        progressTimeRef("2024-01-01T00:00:04Z"),
+					ElapsedSeconds: 3,
+				}},
+			},
+			wantOutput: "aws_vpc.main: Plan to create\ndata.aws_ami.ubuntu: Plan to read\naws_vpc.main: Creating...\n" +
+				"aws_vpc.main: Creation complete after 3s\nApply complete! Resources: 1 added, 0 changed, 0 destroyed.\n",
+		},
+		{
+			name:   "current resource in progress",
+			chunks: []string{eventStartVpc + "\n" + eventStartSubnet + "\n" + eventCompleteVpc + "\n"},
+			want: model.JobProgress{
+				Operation:          "apply",
+				ResourcesCompleted: 1,
+				CurrentResource:    "aws_subnet.main",
+				Resources: []model.ResourceProgress{
+					{
+						Address:        "aws_vpc.main",
+						Action:         "create",
+						Status:         ResourceStatusComplete,
+						StartedAt:      progressTime("2024-01-01T00:00:01Z"),
+						EndedAt:        progressTimeRef("2024-01-01T00:00:04Z"),
+						ElapsedSeconds: 3,
+					},
+					{
+						Address:   "aws_subnet.main",
+						Action:    "create",
+						Status:    ResourceStatusInProgress,
+						StartedAt: progressTime("2024-01-01T00:00:02Z"),
+					},
+				},
+			},
+			wantOutput: "aws_vpc.main: Creating...\naws_subnet.main: Creating...\naws_vpc.main: Creation complete after 3s\n",
+		},
+		{
+			name:   "apply errored with diagnostic",
+			chunks: []string{eventStartVpc + "\n", eventErroredVpc + "\n", eventDiagnostic + "\n"},
+			want: model.JobProgress{
+				Operation:       "apply",
+				ResourcesFailed: 1,
+				Resources: []model.ResourceProgress{{
+					Address:        "aws_vpc.main",
+					Action:         "create",
+					Status:         ResourceStatusErrored,
+					StartedAt:      progressTime("2024-01-01T00:00:01Z"),
+					EndedAt:        progressTimeRef("2024-01-01T00:00:04Z"),
+					ElapsedSeconds: 3,
+				}},
+				Diagnostics: []model.DiagnosticMessage{
+					{Severity: "error", Summary: "creating EC2 VPC", Detail: "VpcLimitExceeded", Address: "aws_vpc.main"},
+				},
+			},
+			wantOutput: "aws_vpc.main: Creating...\naws_vpc.main: Creation errored after 3s\nError: creating EC2 VPC\n",
+		},
+		{
+			name:       "reading data source is not counted",
+			chunks:     []string{eventStartRead + "\n" + eventCompleteRead + "\n"},
+			want:       model.JobProgress{Operation: "apply"},
+			wantOutput: "data.aws_ami.ubuntu: Reading...\ndata.aws_ami.ubuntu: Read complete after 1s\n",
+		},
+		{
+			name:       "malformed lines are passed through",
+			chunks:     []string{"not an event\n", `{"@message":"no type"}` + "\n", `{"type":"apply_start","hook":` + "\n"},
+			want:       model.JobProgress{Operation: "apply"},
+			wantOutput: "not an event\n" + `{"@message":"no type"}` + "\n" + `{"type":"apply_start","hook":` + "\n",
+		},
+		{
+			name:   "event split across writes",
+			chunks: []string{eventStartVpc[:40], eventStartVpc[40:100], eventStartVpc[100:] + "\n"},
+			want: model.JobProgress{
+				Operation:       "apply",
+				CurrentResource: "aws_vpc.main",
+				Resources: []model.ResourceProgress{{
+					Address:   "aws_vpc.main",
+					Action:    "create",
+					Status:    ResourceStatusInProgress,
+					StartedAt: progressTime("2024-01-01T00:00:01Z"),
+				}},
+			},
+			wantOutput: "aws_vpc.main: Creating...\n",
+		},
+		{
+			name:   "last event without line break",
+			chunks: []string{eventDiagnostic},
+			want: model.JobProgress{
+				Operation: "apply",
+				Diagnostics: []model.DiagnosticMessage{
+					{Severity: "error", Summary: "creating EC2 VPC", Detail: "VpcLimitExceeded", Address: "aws_vpc.main"},
+				},
+			},
+			wantOutput: "Error: creating EC2 VPC\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			w := newProgressWriter("req-progress", "apply", &out)
+			for _, chunk := range tt.chunks {
+				if n, err := w.Write([]byte(chunk)); err != nil || n != len(chunk) {
+					t.Fatalf("Write() = (%d, %v), want (%d, nil)", n, err, len(chunk))
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			if got := *w.snapshot(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("progress = %+v, want %+v", got, tt.want)
+			}
+			if got := out.String(); got != tt.wantOutput {
+				t.Errorf("output = %q, want %q", got, tt.wantOutput)
+			}
+		})
+	}
+}
+
+// A partial line is not handled until its line break is written.
+func TestProgressWriterPartialLine(t *testing.T) {
+	var out bytes.Buffer
+	w := newProgressWriter("req-progress-partial", "apply", &out)
+
+	if _, err := w.Write([]byte(eventStartVpc[:50])); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 || len(w.snapshot().Resources) != 0 {
+		t.Errorf("a partial line is handled: output %q, progress %+v", out.String(), w.snapshot())
+	}
+
+	if _, err := w.Write([]byte(eventStartVpc[50:] + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.snapshot().CurrentResource; got != "aws_vpc.main" {
+		t.Errorf("current resource = %q, want aws_vpc.main", got)
+	}
+}
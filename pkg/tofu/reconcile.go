@@ -0,0 +1,140 @@
+package tofu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/replica"
+	"github.com/cloud-barista/mc-terrarium/pkg/store"
+	"github.com/rs/zerolog/log"
+)
+
+// Lock info file of the state in the local backend
+const stateLockInfoFileName = ".terraform.tfstate.lock.info"
+
+// ReconcileJobs marks the jobs, which were queued or running when their servers stopped, as "Interrupted".
+// For the jobs which were running, it checks the state lock left in the working directory.
+// It should be called once on startup after loading the jobs (see StartJobStore), and periodically by the leader
+// with the store shared by the servers, where any server can interrupt a job once by compare-and-swap.
+// It returns the number of interrupted jobs.
+func ReconcileJobs() int {
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobStoreTimeout)
+	jobs, err := listJobs(ctx)
+	cancel()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to read the jobs to reconcile")
+		return 0
+	}
+
+	// Whether the server of a job is running, which is checked once per server
+	alive := make(map[string]bool)
+
+	var interrupted []model.JobInfo
+	exitCode := -1
+	for _, j := range jobs {
+		job := j.job
+		if job.Status != JobStatusQueued && job.Status != JobStatusRunning {
+			continue
+		}
+		if _, checked := alive[job.Server]; !checked {
+			alive[job.Server] = replica.Alive(job.Server)
+		}
+		if alive[job.Server] {
+			continue
+		}
+
+		log.Warn().Msgf("the request (reqId: %s, trId: %s, enrichments: %s) was %s when its server stopped",
+			job.Id, job.TerrariumId, job.Enrichments, job.Status)
+
+		if job.Status == JobStatusRunning {
+			if lock := readStateLock(jobWorkingDir(job)); lock != nil {
+				log.Warn().Msgf("the state of the terrarium (trId: %s, enrichments: %s) is locked (lock ID: %s), "+
+					"please unlock it by 'force-unlock' after checking no tofu process is running",
+					job.TerrariumId, job.Enrichments, lock.Id)
+				job.StateLock = lock
+			}
+		}
+
+		now := time.Now()
+		job.Error = "interrupted as the server stopped while the request was " + job.Status
+		job.Status = JobStatusInterrupted
+		job.EndedAt = &now
+		job.ExitCode = &exitCode
+
+		value, err := json.Marshal(job)
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to encode the job (reqId: %s)", job.Id)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), jobStoreTimeout)
+		_, err = jobStore.CompareAndSwap(ctx, jobKeyPrefix+job.Id, j.revision, value)
+		cancel()
+		if err != nil {
+			if !errors.Is(err, store.ErrConflict) && !errors.Is(err, store.ErrNotFound) {
+				log.Error().Err(err).Msgf("failed to save the interrupted job (reqId: %s)", job.Id)
+			}
+			// Updated by another server in the meantime
+			continue
+		}
+		if _, exists := getJob(job.Id); exists {
+			jobMap.Store(job.Id, job)
+		}
+		interrupted = append(interrupted, job)
+	}
+
+	for _, job := range interrupted {
+		notifyJobFinished(job)
+	}
+
+	return len(interrupted)
+}
+
+// jobWorkingDir returns the working directory of a job from its log path (i.e., <workingDir>/runningLogs/<reqId>.log).
+func jobWorkingDir(job model.JobInfo) string {
+	if job.LogPath == "" {
+		return ""
+	}
+	return filepath.Dir(filepath.Dir(job.LogPath))
+}
+
+// readStateLock reads the lock info of the state in a working directory.
+// It returns nil if the state is not locked.
+func readStateLock(workingDir string) *model.StateLock {
+	if workingDir == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(workingDir, stateLockInfoFileName))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn().Err(err).Msgf("failed to read the state lock info in %s", workingDir)
+		}
+		return nil
+	}
+
+	var info struct {
+		ID        string `json:"ID"`
+		Operation string `json:"Operation"`
+		Who       string `json:"Who"`
+		Created   string `json:"Created"`
+		Path      string `json:"Path"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		log.Warn().Err(err).Msgf("failed to parse the state lock info in %s", workingDir)
+		return &model.StateLock{}
+	}
+
+	return &model.StateLock{
+		Id:        info.ID,
+		Operation: info.Operation,
+		Who:       info.Who,
+		Created:   info.Created,
+		Path:      info.Path,
+	}
+}
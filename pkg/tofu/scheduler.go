@@ -0,0 +1,410 @@
+package tofu
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/cloud-barista/mc-terrarium/pkg/replica"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultMaxWorkers   = 4
+	defaultMaxQueueSize = 100
+
+	// Interval to check if the commands of a terrarium are done to lock it
+	idlePollInterval = 100 * time.Millisecond
+	// Length of the random nonce of a job ID of the request holding the lock of a terrarium (in bytes)
+	jobNonceLength = 4
+)
+
+var (
+	// ErrQueueFull is returned when no more requests can be queued.
+	ErrQueueFull = errors.New("the job queue is full, please try again later")
+	// ErrTerrariumLocked is returned when requesting a command of a terrarium locked by another request (e.g., to erase it).
+	ErrTerrariumLocked = errors.New("the terrarium is locked by another request (e.g., being erased)")
+)
+
+// task is a tofu command requested by a request ID.
+type task struct {
+	trId   string
+	reqId  string
+	args   []string
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan taskResult
+	// Whether the task is of a job of the request holding the lock of the terrarium (see TerrariumLock.JobId)
+	locked bool
+}
+
+// taskResult is the result of a task.
+type taskResult struct {
+	output string
+	err    error
+}
+
+// scheduler queues tasks per terrarium in FIFO order and runs them one at a time
+// in each terrarium, while limiting the number of tasks running at the same time.
+type scheduler struct {
+	mu           sync.Mutex
+	queues       map[string][]*task        // pending tasks by trId
+	dispatching  map[string]bool           // whether a dispatcher is running for trId
+	locks        map[string]*TerrariumLock // lock of trId held by a request (see LockTerrarium)
+	pending      int                       // number of pending tasks in all queues
+	maxQueueSize int
+	workers      chan struct{} // semaphore for the global worker limit
+}
+
+var (
+	jobScheduler  *scheduler
+	schedulerOnce sync.Once
+)
+
+// getScheduler returns the scheduler, which is created by the configuration on the first call.
+func getScheduler() *scheduler {
+	schedulerOnce.Do(func() {
+		maxWorkers := config.Terrarium.Scheduler.MaxWorkers
+		if maxWorkers <= 0 {
+			maxWorkers = defaultMaxWorkers
+		}
+		maxQueueSize := config.Terrarium.Scheduler.MaxQueueSize
+		if maxQueueSize <= 0 {
+			maxQueueSize = defaultMaxQueueSize
+		}
+		log.Info().Msgf("Setting job scheduler (max workers: %d, max queue size: %d)", maxWorkers, maxQueueSize)
+
+		jobScheduler = &scheduler{
+			queues:       make(map[string][]*task),
+			dispatching:  make(map[string]bool),
+			locks:        make(map[string]*TerrariumLock),
+			maxQueueSize: maxQueueSize,
+			workers:      make(chan struct{}, maxWorkers),
+		}
+	})
+	return jobScheduler
+}
+
+// submit queues a tofu command of a request and records the job as "Queued".
+// The job is saved after releasing the lock not to block the scheduler by the I/O.
+func (s *scheduler) submit(trId, enrichments, reqId string, args []string) (*task, error) {
+	if s.lockedOut(trId, reqId) {
+		return nil, fmt.Errorf("%w (trId: %s)", ErrTerrariumLocked, trId)
+	}
+	if err := checkRequestId(trId, enrichments, reqId); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+
+	if s.pending >= s.maxQueueSize {
+		s.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+
+	// Record the job before the dispatcher can start it, which checks again in memory
+	// for the same request ID submitted in the meantime
+	if err := queueJob(trId, enrichments, reqId, args); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &task{
+		trId:   trId,
+		reqId:  reqId,
+		args:   args,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan taskResult, 1),
+	}
+	if lock, locked := s.locks[trId]; locked {
+		t.locked = lock.jobs[reqId]
+	}
+
+	setCancelFunc(reqId, cancel)
+
+	s.queues[trId] = append(s.queues[trId], t)
+	s.pending++
+
+	if !s.dispatching[trId] {
+		s.dispatching[trId] = true
+		go s.dispatch(trId)
+	}
+	s.mu.Unlock()
+
+	persistJob(reqId)
+	return t, nil
+}
+
+// dispatch runs the queued tasks of a terrarium one at a time until the queue is empty.
+func (s *scheduler) dispatch(trId string) {
+	for {
+		s.mu.Lock()
+		queue := s.queues[trId]
+		if len(queue) == 0 {
+			delete(s.queues, trId)
+			delete(s.dispatching, trId)
+			s.mu.Unlock()
+			return
+		}
+		t := queue[0]
+		s.mu.Unlock()
+
+		// Wait for the other servers sharing the store to finish with the terrarium, and then for an available worker.
+		// The request holding the lock of the terrarium has already waited for them.
+		release := func() {}
+		var err error
+		if !t.locked {
+			release, err = replica.Lock(t.ctx, terrariumLockName(trId))
+		}
+		acquired := false
+		if err == nil {
+			select {
+			case s.workers <- struct{}{}:
+				acquired = true
+			case <-t.ctx.Done():
+				err = t.ctx.Err()
+			}
+		}
+
+		s.mu.Lock()
+		queue = s.queues[trId]
+		if len(queue) == 0 || queue[0] != t {
+			// The task has been cancelled and removed while waiting
+			s.mu.Unlock()
+			if acquired {
+				<-s.workers
+			}
+			if release != nil {
+				release()
+			}
+			continue
+		}
+		s.queues[trId] = queue[1:]
+		s.pending--
+		s.mu.Unlock()
+
+		if !acquired {
+			// The task is done (e.g., cancelled) while waiting, so complete it without running
+			if release != nil {
+				release()
+			}
+			s.complete(t, fmt.Errorf("cancelled while waiting for the terrarium (trId: %s): %w", trId, err))
+			continue
+		}
+
+		// Do not run in the working directory removed in the meantime (e.g., erased by another server)
+		if dir := chdirOf(t.args); dir != "" {
+			if _, err := os.Stat(dir); os.IsNotExist(err) {
+				<-s.workers
+				release()
+				s.complete(t, fmt.Errorf("the working directory (%s) does not exist, the terrarium (trId: %s) may be erased", dir, trId))
+				continue
+			}
+		}
+
+		s.run(t)
+		<-s.workers
+		release()
+	}
+}
+
+// run executes the tofu command of a task and records the result.
+func (s *scheduler) run(t *task) {
+	var result taskResult
+
+	defer func() {
+		if r := recover(); r != nil {
+			result = taskResult{err: fmt.Errorf("panic: %v", r)}
+		}
+		finishJob(t.reqId, result.err)
+		deleteCancelFunc(t.reqId)
+		t.cancel()
+		t.done <- result
+	}()
+
+	startJob(t.reqId)
+
+	output, err := executeCommand(t.ctx, t.reqId, t.args)
+	if err != nil {
+		log.Error().Msgf("Command execution failed: %v", err)
+	}
+	result = taskResult{output: output, err: err}
+}
+
+// remove removes a pending task of a request from the queue and completes it as cancelled.
+// It returns false if the task is not in the queue (e.g., already running).
+// The job is finished (i.e., saved and notified to the listeners) after releasing the lock.
+func (s *scheduler) remove(trId, reqId string) bool {
+	s.mu.Lock()
+
+	var removed *task
+	queue := s.queues[trId]
+	for i, t := range queue {
+		if t.reqId != reqId {
+			continue
+		}
+		s.queues[trId] = append(queue[:i:i], queue[i+1:]...)
+		s.pending--
+		removed = t
+		break
+	}
+	s.mu.Unlock()
+
+	if removed == nil {
+		return false
+	}
+
+	s.complete(removed, fmt.Errorf("cancelled while queued: %w", context.Canceled))
+	return true
+}
+
+// complete finishes a task which has not been run with the error.
+func (s *scheduler) complete(t *task, err error) {
+	t.cancel()
+	finishJob(t.reqId, err)
+	deleteCancelFunc(t.reqId)
+	t.done <- taskResult{err: err}
+}
+
+// position returns the position (1-based) of a pending request in the queue of a terrarium.
+// It returns 0 if the request is not in the queue.
+func (s *scheduler) position(trId, reqId string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, t := range s.queues[trId] {
+		if t.reqId == reqId {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// terrariumLockName returns the name of the lock of a terrarium across the servers, which is held to run a command of it.
+func terrariumLockName(trId string) string {
+	return "terrariums/" + trId
+}
+
+// exclusiveLockName returns the name of the lock of a terrarium held by a request across the servers (see LockTerrarium).
+func exclusiveLockName(trId string) string {
+	return "exclusive/" + trId
+}
+
+// lockedOut returns whether a request cannot run a command of a terrarium
+// since another request holds the lock of it in this server or another server sharing the store.
+func (s *scheduler) lockedOut(trId, reqId string) bool {
+	s.mu.Lock()
+	lock, locked := s.locks[trId]
+	allowed := locked && lock.jobs[reqId]
+	s.mu.Unlock()
+	if locked {
+		return !allowed
+	}
+	return replica.Locked(exclusiveLockName(trId))
+}
+
+// TerrariumLock is the lock of a terrarium held by a request (e.g., to erase it) until Unlock is called.
+type TerrariumLock struct {
+	reqId   string
+	jobs    map[string]bool // request IDs of the jobs of the holder, guarded by the scheduler lock
+	release func()
+}
+
+// JobId returns a new request ID of a job of the holder (e.g., to destroy an enrichment when erasing the terrarium),
+// which is the request ID of the holder suffixed by the name and a random nonce (e.g., {reqId}-sql-db-1a2b3c4d).
+// Only the commands of the request IDs returned by it run in the terrarium while it is locked,
+// and the nonce keeps the other requests from running by the request ID given by the client.
+func (l *TerrariumLock) JobId(name string) (string, error) {
+	b := make([]byte, jobNonceLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate a job ID: %w", err)
+	}
+	jobId := l.reqId + "-" + strings.ReplaceAll(name, "/", "-") + "-" + hex.EncodeToString(b)
+
+	s := getScheduler()
+	s.mu.Lock()
+	l.jobs[jobId] = true
+	s.mu.Unlock()
+
+	return jobId, nil
+}
+
+// Unlock releases the lock of the terrarium.
+func (l *TerrariumLock) Unlock() {
+	l.release()
+}
+
+// LockTerrarium locks a terrarium for a request (e.g., to erase it), which rejects the commands of the other requests
+// with ErrTerrariumLocked, and waits until the commands queued or running in this server and the other servers are done.
+// Only the commands of the jobs of the holder (see TerrariumLock.JobId) run until the lock is released.
+func LockTerrarium(ctx context.Context, trId, reqId string) (*TerrariumLock, error) {
+
+	s := getScheduler()
+	lock := &TerrariumLock{reqId: reqId, jobs: make(map[string]bool)}
+	s.mu.Lock()
+	if _, locked := s.locks[trId]; locked {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w (trId: %s)", ErrTerrariumLocked, trId)
+	}
+	s.locks[trId] = lock
+	s.mu.Unlock()
+
+	unlock := func() {
+		s.mu.Lock()
+		delete(s.locks, trId)
+		s.mu.Unlock()
+	}
+
+	// Reject the new commands in the other servers sharing the store
+	releaseExclusive, err := replica.Lock(ctx, exclusiveLockName(trId))
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+
+	// Wait for the commands queued before in this server
+	ticker := time.NewTicker(idlePollInterval)
+	defer ticker.Stop()
+	for {
+		s.mu.Lock()
+		dispatching := s.dispatching[trId]
+		s.mu.Unlock()
+		if !dispatching {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			releaseExclusive()
+			unlock()
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	// Wait for the commands running in the other servers
+	release, err := replica.Lock(ctx, terrariumLockName(trId))
+	if err != nil {
+		releaseExclusive()
+		unlock()
+		return nil, err
+	}
+
+	var once sync.Once
+	lock.release = func() {
+		once.Do(func() {
+			release()
+			releaseExclusive()
+			unlock()
+		})
+	}
+	return lock, nil
+}
@@ -0,0 +1,86 @@
+package tofu
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// tfState is the part of a state file (format version 4) to list the resources.
+type tfState struct {
+	Resources []struct {
+		Module    string `json:"module"`
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Instances []struct {
+			IndexKey interface{} `json:"index_key"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+// StateResources returns the addresses of the managed resources remaining in the state of a working directory.
+// It reads the state file directly, so it does not wait for the running command in the terrarium.
+// It returns no resources if the state file does not exist (e.g., not applied yet).
+func StateResources(workingDir string) ([]string, error) {
+
+	data, err := os.ReadFile(filepath.Join(workingDir, stateFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read the state file: %w", err)
+	}
+
+	var state tfState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode the state file: %w", err)
+	}
+
+	var addresses []string
+	for _, r := range state.Resources {
+		if r.Mode != "managed" {
+			continue
+		}
+		address := r.Type + "." + r.Name
+		if r.Module != "" {
+			address = r.Module + "." + address
+		}
+		for _, instance := range r.Instances {
+			switch key := instance.IndexKey.(type) {
+			case nil:
+				addresses = append(addresses, address)
+			case string:
+				addresses = append(addresses, fmt.Sprintf("%s[%q]", address, key))
+			default:
+				addresses = append(addresses, fmt.Sprintf("%s[%v]", address, key))
+			}
+		}
+	}
+
+	return addresses, nil
+}
+
+// importBlockTarget matches the target address of an import block (e.g., to = aws_route_table.imported_route_table).
+var importBlockTarget = regexp.MustCompile(`(?m)^\s*to\s*=\s*([^\s#]+)`)
+
+// ImportedResources returns the addresses of the resources imported by the import blocks in imports.tf of a working directory.
+// The imported resources already existed before applying, so they should be removed from the state before destroying.
+func ImportedResources(workingDir string) ([]string, error) {
+
+	data, err := os.ReadFile(filepath.Join(workingDir, "imports.tf"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read imports.tf: %w", err)
+	}
+
+	var addresses []string
+	for _, match := range importBlockTarget.FindAllStringSubmatch(string(data), -1) {
+		addresses = append(addresses, match[1])
+	}
+	return addresses, nil
+}
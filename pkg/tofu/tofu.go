@@ -3,15 +3,14 @@ package tofu
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
 	"github.com/cloud-barista/mc-terrarium/pkg/config"
@@ -21,247 +20,190 @@ import (
 const (
 	statusFileName = "runningStatusMap.db"
 	terrariumDir   = ".terrarium"
+
+	// Time to wait for the tofu process to exit after SIGINT before sending SIGKILL
+	cancelGracePeriod = 60 * time.Second
 )
 
-// Manage the running status of tofu commands.
-var requestStatusMap sync.Map
+// GetTofuVersion returns the selected IaC engine and its version.
+func GetTofuVersion() (model.EngineVersion, error) {
 
-// Save the running status map to file
-func SaveRunningStatusMap() error {
-	projectRoot := config.Terrarium.Root
-	statusFilePath := fmt.Sprintf("%s/%s/%s", projectRoot, terrariumDir, statusFileName)
+	e := getExecutor()
+	log.Debug().Msgf("Executing command: %s version", e.Binary())
 
-	// Create the file to store the running status map
-	file, err := os.Create(statusFilePath)
+	output, err := e.Version(context.Background())
 	if err != nil {
-		return fmt.Errorf("failed to create status file: %w", err)
-	}
-	defer file.Close()
-
-	// Encode sync.Map to a JSON file
-	tempMap := make(map[string]string)
-	requestStatusMap.Range(func(key, value interface{}) bool {
-		tempMap[key.(string)] = value.(string)
-		return true
-	})
-
-	encoder := json.NewEncoder(file)
-	if err := encoder.Encode(tempMap); err != nil {
-		return fmt.Errorf("failed to encode status map: %w", err)
-	}
-
-	return nil
-}
-
-// Load the running status map from file
-func LoadRunningStatusMap() error {
-	projectRoot := config.Terrarium.Root
-	statusFilePath := fmt.Sprintf("%s/%s/%s", projectRoot, terrariumDir, statusFileName)
-
-	// Check and open the status file
-	if _, err := os.Stat(statusFilePath); os.IsNotExist(err) {
-		return fmt.Errorf("status file does not exist: %w", err)
+		return model.EngineVersion{}, err
 	}
 
-	file, err := os.Open(statusFilePath)
+	version, err := ParseVersion(output)
 	if err != nil {
-		return fmt.Errorf("failed to open status file: %w", err)
+		return model.EngineVersion{}, err
 	}
-	defer file.Close()
 
-	// Decode JSON file to sync.Map
-	var tempMap map[string]string
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&tempMap); err != nil {
-		return fmt.Errorf("failed to decode status map: %w", err)
+	engineVersion := model.EngineVersion{
+		Engine:              e.Engine(),
+		Binary:              e.Binary(),
+		Version:             version,
+		MinVersion:          config.Terrarium.Executor.MinVersion,
+		SatisfiesMinVersion: true,
+		Raw:                 strings.TrimSpace(output),
 	}
-
-	for key, value := range tempMap {
-		requestStatusMap.Store(key, value)
+	if engineVersion.MinVersion != "" {
+		required, err := ParseVersion(engineVersion.MinVersion)
+		if err != nil {
+			return model.EngineVersion{}, fmt.Errorf("invalid minimum version: %w", err)
+		}
+		engineVersion.SatisfiesMinVersion = compareVersion(version, required) >= 0
 	}
 
-	return nil
+	return engineVersion, nil
 }
 
-// Set the running status for a given trId.
-func setRunningStatus(trId, status string) {
-	requestStatusMap.Store(trId, status)
-}
+// ExecuteTofuCommand executes a given tofu CLI command with arguments and returns the result.
+// The command is queued and run in order with the other requests in the same terrarium.
+// It also logs the full command being executed and records the job of the request.
+// Example usage:
+// - ExecuteTofuCommand("tr01", "sql-db", "reqId", "version")
+// - ExecuteTofuCommand("tr01", "sql-db", "reqId", "apply", "-var=\"image_id=ami-abc123\"")
+// - ExecuteTofuCommand("tr01", "sql-db", "reqId", "import", "aws_vpc.my-imported-vpc", "vpc-a01106c2")
+func ExecuteTofuCommand(trId, enrichments, reqId string, args ...string) (string, error) {
 
-// Get the running status for a given trId.
-func getRunningStatus(trId string) (string, bool) {
-	value, exists := requestStatusMap.Load(trId)
-	if !exists {
-		return "", false
+	t, err := getScheduler().submit(trId, enrichments, reqId, args)
+	if err != nil {
+		return "", err
 	}
-	return value.(string), true
-}
-
-func GetTofuVersion() (string, error) {
-
-	var outputBuffer bytes.Buffer
-
-	tf := "tofu"
-	args := []string{"version"}
-	fullCommand := fmt.Sprintf("%s %s", tf, args)
-	log.Debug().Msgf("Executing command: %s", fullCommand)
 
-	cmd := exec.Command(tf, args...)
-	cmd.Stdout = io.MultiWriter(os.Stdout, &outputBuffer)
-	cmd.Stderr = io.MultiWriter(os.Stderr, &outputBuffer)
+	// Wait for the command to be completed
+	result := <-t.done
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to execute command: %s. Error: %v", fullCommand, err)
-	}
-
-	return outputBuffer.String(), nil
+	return result.output, result.err
 }
 
-// ExecuteTofuCommand executes a given tofu CLI command with arguments and returns the result.
-// It also logs the full command being executed.
+// ExecuteTofuCommandAsync executes a given tofu CLI command with arguments asynchronously.
+// The command is queued and run in order with the other requests in the same terrarium.
+// It also logs the full command being executed and records the job of the request.
 // Example usage:
-// - ExecuteTofuCommand("version")
-// - ExecuteTofuCommand("apply", "-var=\"image_id=ami-abc123\"")
-// - ExecuteTofuCommand("import", "aws_vpc.my-imported-vpc", "vpc-a01106c2")
-func ExecuteTofuCommand(trId, reqId string, args ...string) (string, error) {
-
-	currentStatus, exists := getRunningStatus(trId)
-	if exists && currentStatus == "Running" {
-		return "", errors.New("a previous request is still in progress")
-	}
-	setRunningStatus(trId, "Running")
+// - ExecuteTofuCommandAsync("tr01", "vpn/gcp-aws", "reqId", "apply", "-auto-approve")
+// - ExecuteTofuCommandAsync("tr01", "vpn/gcp-aws", "reqId", "destroy", "-auto-approve")
+func ExecuteTofuCommandAsync(trId, enrichments, reqId string, args ...string) (string, error) {
 
-	defer func() {
-		if r := recover(); r != nil {
-			setRunningStatus(trId, "Failed")
-		}
-	}()
-
-	// Execute the command and setup
-	output, err := executeCommand(reqId, args)
+	_, err := getScheduler().submit(trId, enrichments, reqId, args)
 	if err != nil {
-		log.Error().Msgf("Command execution failed: %v", err)
-		setRunningStatus(trId, "Failed")
-		return output, err
+		return "", err
 	}
-	// log.Debug().Msgf("Command output: %s", output)
-	setRunningStatus(trId, "Success")
-
-	// log.Debug().Msgf("Command output: %s", output)
 
-	// Return the result
-	return output, nil
+	res := fmt.Sprintf("Request (reqId: %s) in progress. Please use the status check API with the request ID.", reqId)
+	return res, nil
 }
 
-// ExecuteTofuCommandAsync executes a given tofu CLI command with arguments and returns the result.
-// It also logs the full command being executed.
-// Example usage:
-// - ExecuteTofuCommandAsync("version")
-// - ExecuteTofuCommandAsync("apply", "-var=\"image_id=ami-abc123\"")
-// - ExecuteTofuCommandAsync("import", "aws_vpc.my-imported-vpc", "vpc-a01106c2")
-// ExecuteTofuCommandAsync executes a given tofu CLI command with arguments asynchronously.
-func ExecuteTofuCommandAsync(trId string, reqId string, args ...string) (string, error) {
-	currentStatus, exists := getRunningStatus(trId)
-	if exists && currentStatus == "Running" {
-		return "", errors.New("a previous request is still in progress")
+// runningLogFilePath returns the path of the log file of a request
+// if the working directory is given by the global option (i.e., -chdir=...).
+func runningLogFilePath(reqId string, args []string) string {
+	path := chdirOf(args)
+	if path == "" {
+		return ""
 	}
-	setRunningStatus(trId, "Running")
+	return fmt.Sprintf("%s/runningLogs/%s.log", path, reqId)
+}
 
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				setRunningStatus(trId, "Failed")
-			}
-		}()
+// chdirOf returns the working directory given by the global option (i.e., -chdir=...), which is empty if not given.
+func chdirOf(args []string) string {
+	if len(args) == 0 || !strings.HasPrefix(args[0], "-chdir=") {
+		return ""
+	}
+	return strings.SplitN(args[0], "=", 2)[1]
+}
 
-		// Execute the command and setup
-		_, err := executeCommand(reqId, args)
-		if err != nil {
-			log.Error().Msgf("Command execution failed: %v", err)
-			setRunningStatus(trId, "Failed")
-			return
+// progressOperation returns the operation (i.e., apply or destroy) of which progress
+// can be parsed from the machine-readable output (-json).
+func progressOperation(args []string) (string, bool) {
+	subcommand, subArgs := splitSubcommand(args)
+	if subcommand != "apply" && subcommand != "destroy" {
+		return "", false
+	}
+	for _, arg := range subArgs {
+		if arg == "-json" {
+			return subcommand, true
 		}
-		// log.Debug().Msgf("Command output: %s", output)
-		setRunningStatus(trId, "Success")
-	}()
-
-	res := fmt.Sprintf("Request (reqId: %s) in progress. Please use the status check API with the request ID.", reqId)
-	return res, nil
+	}
+	return "", false
 }
 
-// executeCommand executes the tofu command with given arguments.
-func executeCommand(reqId string, args []string) (string, error) {
+// executeCommand executes the tofu command with given arguments by the executor.
+// When the context is cancelled, the executor interrupts the command gracefully.
+// If the command fails, it returns the output with a CommandError.
+func executeCommand(ctx context.Context, reqId string, args []string) (string, error) {
 	var logFile *os.File
 	var outputBuffer bytes.Buffer
 	var err error
 
-	tf := "tofu"
-	fullCommand := fmt.Sprintf("%s %s", tf, strings.Join(args, " "))
+	e := getExecutor()
+
+	fullCommand := fmt.Sprintf("%s %s", e.Binary(), strings.Join(args, " "))
 	log.Debug().Msgf("Executing command: %s", fullCommand)
 
-	arg := args[0]
-	if strings.HasPrefix(arg, "-chdir=") {
-		path := strings.SplitN(arg, "=", 2)[1]
-		// Create the runningLogs directory path
-		logDir := fmt.Sprintf("%s/runningLogs", path)
-		// Create the directory if it does not exist
-		if err := os.MkdirAll(logDir, 0755); err != nil {
-			return "", fmt.Errorf("failed to create log directory: %v", err)
+	if err := checkMinVersion(ctx); err != nil {
+		return "", fmt.Errorf("failed to check the version of %s: %w", e.Engine(), err)
+	}
+
+	if runningLogFile := runningLogFilePath(reqId, args); runningLogFile != "" {
+		// Create the runningLogs directory if it does not exist
+		if err := os.MkdirAll(filepath.Dir(runningLogFile), 0755); err != nil {
+			return "", fmt.Errorf("failed to create log directory: %w", err)
 		}
-		// Set the log file path
-		runningLogFile := fmt.Sprintf("%s/%s.log", logDir, reqId)
+		// Open the log file
 		logFile, err = os.OpenFile(runningLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
-			return "", fmt.Errorf("failed to open log file: %v", err)
+			return "", fmt.Errorf("failed to open log file: %w", err)
 		}
 		defer logFile.Close()
 	}
 
-	cmd := exec.Command(tf, args...)
+	var stdout, stderr io.Writer
 	if logFile != nil {
-		cmd.Stdout = io.MultiWriter(os.Stdout, logFile, &outputBuffer)
-		cmd.Stderr = io.MultiWriter(os.Stderr, logFile, &outputBuffer)
+		shared := &syncWriter{w: io.MultiWriter(logFile, &outputBuffer)}
+		stdout = io.MultiWriter(os.Stdout, shared)
+		stderr = io.MultiWriter(os.Stderr, shared)
 	} else {
-		cmd.Stdout = &outputBuffer
-		cmd.Stderr = &outputBuffer
+		shared := &syncWriter{w: &outputBuffer}
+		stdout = shared
+		stderr = shared
 	}
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to execute command: %s. Error: %v", fullCommand, err)
+	// Parse the machine-readable output (-json) to record the progress
+	// and keep the human-readable messages in the log and output
+	var progress *progressWriter
+	if operation, ok := progressOperation(args); ok {
+		progress = newProgressWriter(reqId, operation, stdout)
+		stdout = progress
 	}
 
-	// if logFile != nil {
-	// 	log.Debug().Msgf("Command output is also logged to: %s", logFile.Name())
-	// }
-
-	return outputBuffer.String(), nil
-}
-
-func GetRunningStatus(trId, statusLogFile string) (string, error) {
-
-	status, exists := getRunningStatus(trId)
-	if !exists {
-		return "", errors.New("no request found")
+	err = e.Run(ctx, args, stdout, stderr)
+	if progress != nil {
+		progress.Flush()
 	}
-	log.Debug().Msgf("Request status: %s", status)
-
-	_, err := os.Stat(statusLogFile)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return "", errors.New("status log file does not exist")
+		// Keep the output and the diagnostics to explain the failure
+		output := outputBuffer.String()
+		subcommand, _ := splitSubcommand(args)
+		cmdErr := &CommandError{Subcommand: subcommand, Output: output, Diagnostics: ParseDiagnostics(output)}
+		if progress != nil {
+			if diagnostics := progress.snapshot().Diagnostics; len(diagnostics) > 0 {
+				cmdErr.Diagnostics = diagnostics
+			}
 		}
-		return "", fmt.Errorf("failed to check status log file: %v", err)
-	}
-
-	// Read the status from the log file
-	statusBytes, err := os.ReadFile(statusLogFile)
-	if err != nil {
-		return "", fmt.Errorf("failed to read status log file: %v", err)
+		if ctx.Err() != nil {
+			cmdErr.Err = fmt.Errorf("cancelled command: %s. Error: %w (%w)", fullCommand, ctx.Err(), err)
+		} else {
+			cmdErr.Err = fmt.Errorf("failed to execute command: %s. Error: %w", fullCommand, err)
+		}
+		return output, cmdErr
 	}
 
-	statusReport := fmt.Sprintf("[Request status: %s]\n%s", status, string(statusBytes))
+	cleanUpPlans(args)
 
-	return statusReport, nil
+	return outputBuffer.String(), nil
 }
 
 func CopyFile(src string, des string) error {
@@ -363,34 +305,6 @@ func SaveTfVarsToFile(tfVars interface{}, filePath string) error {
 	return nil
 }
 
-func SaveGcpAwsTfVarsToFile(tfVars model.TfVarsGcpAwsVpnTunnel, filePath string) error {
-	tfVarsBytes, err := json.MarshalIndent(tfVars, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	err = os.WriteFile(filePath, tfVarsBytes, 0644)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func SaveGcpAzureTfVarsToFile(tfVars model.TfVarsGcpAzureVpnTunnel, filePath string) error {
-	tfVarsBytes, err := json.MarshalIndent(tfVars, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	err = os.WriteFile(filePath, tfVarsBytes, 0644)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 func SaveTestEnvTfVarsToFile(tfVars model.TfVarsTestEnv, filePath string) error {
 	tfVarsBytes, err := json.MarshalIndent(tfVars, "", "  ")
 	if err != nil {
@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+)
+
+// Timeout of resolving the host of a webhook URL on registration
+const resolveTimeout = 5 * time.Second
+
+var (
+	// Metadata services of the clouds, which are never allowed even if the private addresses are allowed
+	metadataIPs = []net.IP{
+		net.ParseIP("169.254.169.254"), // AWS, Azure, GCP, NCP and so on
+		net.ParseIP("fd00:ec2::254"),   // AWS (IPv6)
+		net.ParseIP("100.100.100.200"), // Alibaba Cloud
+	}
+	// Shared address space (RFC 6598), which is internal to a provider like the private addresses
+	sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+)
+
+// newHTTPClient returns the client of the deliveries, which dials only the allowed addresses (see checkIP)
+// by the resolved IP, not to be used to reach the internal services of the server (SSRF), and does not follow redirects.
+func newHTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   dialControl,
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			// No proxy, which would be dialed instead of the webhook
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// dialControl checks the resolved IP of a connection to a webhook before connecting,
+// so that a host resolved to another address after the registration (DNS rebinding) is rejected too.
+func dialControl(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid address to dial (%s)", address)
+	}
+	return checkIP(ip)
+}
+
+// checkHost resolves the host of a webhook URL, and checks if all the addresses of it are allowed.
+func checkHost(host string) error {
+
+	if ip := net.ParseIP(host); ip != nil {
+		return checkIP(ip)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve the host (%s): %w", host, err)
+	}
+	for _, addr := range addrs {
+		if err := checkIP(addr.IP); err != nil {
+			return fmt.Errorf("the host (%s) is resolved to %w", host, err)
+		}
+	}
+	return nil
+}
+
+// checkIP checks if a webhook can be delivered to an IP address, which rejects the loopback, link-local
+// (e.g., 169.254.169.254 of the metadata services), unspecified and multicast addresses,
+// and the private ones unless they are allowed (see config.WebhookConfig).
+func checkIP(ip net.IP) error {
+
+	for _, metadataIP := range metadataIPs {
+		if ip.Equal(metadataIP) {
+			return fmt.Errorf("a not allowed address (%s): a metadata service", ip)
+		}
+	}
+
+	switch {
+	case ip.IsLoopback():
+		return fmt.Errorf("a not allowed address (%s): a loopback address", ip)
+	case ip.IsLinkLocalUnicast(), ip.IsLinkLocalMulticast(), ip.IsInterfaceLocalMulticast():
+		return fmt.Errorf("a not allowed address (%s): a link-local address", ip)
+	case ip.IsUnspecified(), ip.IsMulticast():
+		return fmt.Errorf("a not allowed address (%s): not a unicast address", ip)
+	case ip.IsPrivate(), sharedAddressSpace.Contains(ip):
+		if !config.Terrarium.Webhook.AllowPrivate {
+			return fmt.Errorf("a not allowed address (%s): a private address", ip)
+		}
+	}
+	return nil
+}
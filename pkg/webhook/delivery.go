@@ -0,0 +1,500 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/cloud-barista/mc-terrarium/pkg/replica"
+	"github.com/cloud-barista/mc-terrarium/pkg/store"
+	"github.com/rs/zerolog/log"
+)
+
+// Headers of a delivery. The signature is "sha256=" and the hex-encoded HMAC-SHA256 of
+// "{timestamp}.{body}" by the secret of the webhook (see Sign), where the timestamp is in the Unix seconds.
+const (
+	HeaderEvent     = "X-Terrarium-Event"
+	HeaderDelivery  = "X-Terrarium-Delivery"
+	HeaderTimestamp = "X-Terrarium-Timestamp"
+	HeaderSignature = "X-Terrarium-Signature"
+)
+
+// Status of a delivery
+const (
+	DeliveryStatusPending   = "Pending"
+	DeliveryStatusRetrying  = "Retrying"
+	DeliveryStatusDelivered = "Delivered"
+	// Failed after all the attempts or by a permanent error (e.g., 404), and moved to the dead letters
+	DeliveryStatusDead = "Dead"
+)
+
+const (
+	// Default settings of the deliveries (see config.WebhookConfig)
+	defaultMaxAttempts = 5
+	defaultBackoff     = 1 * time.Second
+	defaultTimeout     = 10 * time.Second
+	// Max backoff between the attempts
+	maxBackoff = 10 * time.Minute
+
+	// Interval to resume the retries of the deliveries of the stopped servers (see resumeRetries)
+	retryScanInterval = time.Minute
+
+	// Max number of the recent deliveries kept in memory per webhook
+	maxRecentDeliveries = 50
+	// Max length of a response body kept as the error of an attempt
+	maxErrorBodyLength = 256
+)
+
+// Context of the deliveries, where the retries stop when it is done (i.e., the server stops)
+var deliveryCtx = context.Background()
+
+var httpClient = newHTTPClient()
+
+// Recent deliveries per webhook in this server, which are the newest last
+var recent = struct {
+	mu         sync.Mutex
+	deliveries map[string][]*model.WebhookDelivery
+}{
+	deliveries: make(map[string][]*model.WebhookDelivery),
+}
+
+// Sign returns the signature of a delivery body at a timestamp by the secret of a webhook.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver starts to deliver an event to a webhook in the background.
+func deliver(wh model.Webhook, event model.WebhookEvent) model.WebhookDelivery {
+	d := &model.WebhookDelivery{
+		Id:        event.Id + "-" + wh.Id,
+		WebhookId: wh.Id,
+		Url:       wh.Url,
+		Event:     event,
+		Status:    DeliveryStatusPending,
+		CreatedAt: time.Now(),
+	}
+	return redeliver(wh, d)
+}
+
+// redeliver starts to deliver a delivery in the background, which continues from its last attempt if any.
+func redeliver(wh model.Webhook, d *model.WebhookDelivery) model.WebhookDelivery {
+
+	recent.mu.Lock()
+	list := append(recent.deliveries[wh.Id], d)
+	if len(list) > maxRecentDeliveries {
+		list = list[len(list)-maxRecentDeliveries:]
+	}
+	recent.deliveries[wh.Id] = list
+	snapshot := *d
+	recent.mu.Unlock()
+
+	go attempt(wh, d)
+	return snapshot
+}
+
+// attempt posts a delivery until it succeeds, retrying with the exponential backoff,
+// and moves it to the dead letters if it failed after all the attempts or by a permanent error.
+// It continues from the last attempt of a resumed delivery (see resumeRetries). A delivery to retry is kept
+// in the store until it is done, so that the retries survive the stop of this server.
+func attempt(wh model.Webhook, d *model.WebhookDelivery) {
+
+	maxAttempts := config.Terrarium.Webhook.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	body, err := json.Marshal(d.Event)
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to encode the event (id: %s)", d.Event.Id)
+		return
+	}
+
+	recent.mu.Lock()
+	first := d.Attempts + 1
+	var wait time.Duration
+	if d.NextAttemptAt != nil {
+		wait = time.Until(*d.NextAttemptAt)
+	}
+	saved := first > 1
+	recent.mu.Unlock()
+
+	for n := first; ; n++ {
+		if wait > 0 {
+			select {
+			case <-deliveryCtx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+
+		statusCode, err := post(wh, d, body)
+		now := time.Now()
+
+		recent.mu.Lock()
+		d.Attempts = n
+		d.LastAttemptAt = &now
+		d.LastStatusCode = statusCode
+		d.LastError = ""
+		d.NextAttemptAt = nil
+		if err == nil {
+			d.Status = DeliveryStatusDelivered
+			d.DeliveredAt = &now
+			recent.mu.Unlock()
+			if saved {
+				deleteRetry(d.Id)
+			}
+			return
+		}
+		d.LastError = err.Error()
+		retry := n < maxAttempts && retryable(statusCode)
+		if retry {
+			wait = backoffAfter(n)
+			next := now.Add(wait)
+			d.Status = DeliveryStatusRetrying
+			d.NextAttemptAt = &next
+		} else {
+			d.Status = DeliveryStatusDead
+		}
+		snapshot := *d
+		recent.mu.Unlock()
+
+		if !retry {
+			log.Warn().Err(err).Msgf("failed to deliver the event (id: %s, type: %s) to the webhook (webhookId: %s) after %d attempt(s)",
+				d.Event.Id, d.Event.Type, wh.Id, n)
+			saveDeadLetter(snapshot)
+			if saved {
+				deleteRetry(d.Id)
+			}
+			return
+		}
+		log.Debug().Err(err).Msgf("retry to deliver the event (id: %s) to the webhook (webhookId: %s) after %s", d.Event.Id, wh.Id, wait)
+		saved = saveRetry(snapshot) || saved
+	}
+}
+
+// backoffAfter returns the backoff after the nth failed attempt, which is doubled on each retry up to maxBackoff.
+func backoffAfter(n int) time.Duration {
+	backoff := time.Duration(config.Terrarium.Webhook.BackoffMilliSec) * time.Millisecond
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+	for i := 1; i < n && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+// post sends a delivery to a webhook once, and returns the status code of the response (0 if no response).
+func post(wh model.Webhook, d *model.WebhookDelivery, body []byte) (int, error) {
+
+	timeout := time.Duration(config.Terrarium.Webhook.TimeoutMilliSec) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(deliveryCtx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "mc-terrarium-webhook")
+	req.Header.Set(HeaderEvent, d.Event.Type)
+	req.Header.Set(HeaderDelivery, d.Id)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(wh.Secret, timestamp, body))
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		text, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBodyLength))
+		return res.StatusCode, fmt.Errorf("unexpected status (%s): %s", res.Status, bytes.TrimSpace(text))
+	}
+	return res.StatusCode, nil
+}
+
+// retryable returns whether a failed attempt can be retried by the status code,
+// where no response (0), timeouts, rate limits and server errors are temporary.
+func retryable(statusCode int) bool {
+	return statusCode == 0 || statusCode == http.StatusRequestTimeout ||
+		statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// saveDeadLetter keeps a dead delivery in the store to inspect and redeliver it,
+// unless the webhook has been deleted in the meantime.
+func saveDeadLetter(d model.WebhookDelivery) {
+
+	if _, err := get(d.WebhookId); err != nil {
+		return
+	}
+
+	value, err := json.Marshal(d)
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to encode the dead letter (deliveryId: %s)", d.Id)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if _, err := whStore.Put(ctx, deadLetterKey(d.WebhookId, d.Id), value); err != nil {
+		log.Error().Err(err).Msgf("failed to save the dead letter (deliveryId: %s)", d.Id)
+	}
+}
+
+// retryRecord is a delivery to retry in the store with the server retrying it.
+type retryRecord struct {
+	Delivery model.WebhookDelivery `json:"delivery"`
+	Server   string                `json:"server"`
+}
+
+// saveRetry keeps a delivery to retry by this server in the store, and returns whether it is saved.
+func saveRetry(d model.WebhookDelivery) bool {
+
+	value, err := json.Marshal(retryRecord{Delivery: d, Server: replica.Id()})
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to encode the delivery to retry (deliveryId: %s)", d.Id)
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if _, err := whStore.Put(ctx, retryKeyPrefix+d.Id, value); err != nil {
+		log.Error().Err(err).Msgf("failed to save the delivery to retry (deliveryId: %s)", d.Id)
+		return false
+	}
+	return true
+}
+
+// deleteRetry deletes a delivery to retry from the store after it is done.
+func deleteRetry(deliveryId string) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := whStore.Delete(ctx, retryKeyPrefix+deliveryId); err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Error().Err(err).Msgf("failed to delete the delivery to retry (deliveryId: %s)", deliveryId)
+	}
+}
+
+// resumeRetries takes over the deliveries to retry of the stopped servers (e.g., this server before a restart),
+// and continues them in the background. Each delivery is taken by a server by compare-and-swap.
+func resumeRetries() {
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	kvs, err := whStore.List(ctx, retryKeyPrefix)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to read the deliveries to retry")
+		return
+	}
+
+	for _, kv := range kvs {
+		var rec retryRecord
+		if err := json.Unmarshal(kv.Value, &rec); err != nil {
+			log.Warn().Err(err).Msgf("failed to decode the delivery to retry (%s), skipping", kv.Key)
+			continue
+		}
+		if replica.Alive(rec.Server) {
+			continue
+		}
+
+		wh, err := get(rec.Delivery.WebhookId)
+		if err != nil {
+			// The webhook has been deleted in the meantime
+			if err := whStore.Delete(ctx, kv.Key); err != nil && !errors.Is(err, store.ErrNotFound) {
+				log.Error().Err(err).Msgf("failed to delete the delivery to retry (deliveryId: %s)", rec.Delivery.Id)
+			}
+			continue
+		}
+
+		rec.Server = replica.Id()
+		value, err := json.Marshal(rec)
+		if err != nil {
+			continue
+		}
+		if _, err := whStore.CompareAndSwap(ctx, kv.Key, kv.Revision, value); err != nil {
+			// Taken by another server in the meantime
+			continue
+		}
+
+		log.Info().Msgf("resume to deliver the event (id: %s) to the webhook (webhookId: %s) after %d attempt(s)",
+			rec.Delivery.Event.Id, wh.Id, rec.Delivery.Attempts)
+		d := rec.Delivery
+		redeliver(wh, &d)
+	}
+}
+
+// deadLetterKey returns the key of a dead letter in the store.
+func deadLetterKey(webhookId, deliveryId string) string {
+	return deadLetterKeyPrefix + webhookId + "/" + deliveryId
+}
+
+// forgetDeliveries drops the recent deliveries of a deleted webhook.
+func forgetDeliveries(webhookId string) {
+	recent.mu.Lock()
+	defer recent.mu.Unlock()
+	delete(recent.deliveries, webhookId)
+}
+
+// ListDeliveries returns the recent deliveries of a webhook in this server, the newest first,
+// which the tenant (empty for all the tenants) can access.
+func ListDeliveries(webhookId, tenantName string) ([]model.WebhookDelivery, error) {
+
+	if _, err := Get(webhookId, tenantName); err != nil {
+		return nil, err
+	}
+
+	recent.mu.Lock()
+	defer recent.mu.Unlock()
+
+	list := recent.deliveries[webhookId]
+	deliveries := make([]model.WebhookDelivery, 0, len(list))
+	for i := len(list) - 1; i >= 0; i-- {
+		deliveries = append(deliveries, *list[i])
+	}
+	return deliveries, nil
+}
+
+// ListDeadLetters returns the dead deliveries of a webhook, which the tenant (empty for all the tenants) can access.
+func ListDeadLetters(webhookId, tenantName string) ([]model.WebhookDelivery, error) {
+
+	if _, err := Get(webhookId, tenantName); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	kvs, err := whStore.List(ctx, deadLetterKeyPrefix+webhookId+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the dead letters of the webhook (webhookId: %s): %w", webhookId, err)
+	}
+
+	deliveries := make([]model.WebhookDelivery, 0, len(kvs))
+	for _, kv := range kvs {
+		var d model.WebhookDelivery
+		if err := json.Unmarshal(kv.Value, &d); err != nil {
+			return nil, fmt.Errorf("failed to decode the dead letter (key: %s): %w", kv.Key, err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+// getDeadLetter returns a dead delivery of a webhook.
+func getDeadLetter(ctx context.Context, webhookId, deliveryId string) (model.WebhookDelivery, error) {
+
+	kv, err := whStore.Get(ctx, deadLetterKey(webhookId, deliveryId))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return model.WebhookDelivery{}, fmt.Errorf("%w (webhookId: %s, deliveryId: %s)", ErrDeliveryNotFound, webhookId, deliveryId)
+		}
+		return model.WebhookDelivery{}, err
+	}
+
+	var d model.WebhookDelivery
+	if err := json.Unmarshal(kv.Value, &d); err != nil {
+		return model.WebhookDelivery{}, fmt.Errorf("failed to decode the dead letter (deliveryId: %s): %w", deliveryId, err)
+	}
+	return d, nil
+}
+
+// Redeliver removes a dead delivery from the dead letters and delivers it again with the same ID,
+// which the tenant (empty for all the tenants) can access.
+func Redeliver(webhookId, deliveryId, tenantName string) (model.WebhookDelivery, error) {
+
+	if _, err := Get(webhookId, tenantName); err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	wh, err := get(webhookId)
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	d, err := getDeadLetter(ctx, webhookId, deliveryId)
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	if err := whStore.Delete(ctx, deadLetterKey(webhookId, deliveryId)); err != nil {
+		return model.WebhookDelivery{}, fmt.Errorf("failed to delete the dead letter (deliveryId: %s): %w", deliveryId, err)
+	}
+
+	// Deliver it to the current URL of the webhook
+	return redeliver(wh, &model.WebhookDelivery{
+		Id:        d.Id,
+		WebhookId: wh.Id,
+		Url:       wh.Url,
+		Event:     d.Event,
+		Status:    DeliveryStatusPending,
+		CreatedAt: time.Now(),
+	}), nil
+}
+
+// DeleteDeadLetter deletes a dead delivery of a webhook, which the tenant (empty for all the tenants) can access.
+func DeleteDeadLetter(webhookId, deliveryId, tenantName string) error {
+
+	if _, err := Get(webhookId, tenantName); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if err := whStore.Delete(ctx, deadLetterKey(webhookId, deliveryId)); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("%w (webhookId: %s, deliveryId: %s)", ErrDeliveryNotFound, webhookId, deliveryId)
+		}
+		return fmt.Errorf("failed to delete the dead letter (deliveryId: %s): %w", deliveryId, err)
+	}
+	return nil
+}
+
+// Ping delivers a test event (webhook.ping) to a webhook, which the tenant (empty for all the tenants) can access.
+func Ping(webhookId, tenantName string) (model.WebhookDelivery, error) {
+
+	if _, err := Get(webhookId, tenantName); err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	wh, err := get(webhookId)
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+
+	id, err := generateEventId()
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+
+	now := time.Now()
+	return deliver(wh, model.WebhookEvent{
+		Id:          id,
+		Type:        EventPing,
+		Time:        now,
+		TerrariumId: wh.TerrariumId,
+		Message:     fmt.Sprintf("the webhook (webhookId: %s) is reachable", wh.Id),
+	}), nil
+}
@@ -0,0 +1,245 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/config"
+	"github.com/cloud-barista/mc-terrarium/pkg/replica"
+	"github.com/cloud-barista/mc-terrarium/pkg/store"
+	"github.com/cloud-barista/mc-terrarium/pkg/terrarium"
+	"github.com/cloud-barista/mc-terrarium/pkg/tofu"
+	"github.com/rs/zerolog/log"
+)
+
+// Types of the job events, by the status of a finished job
+const (
+	EventJobStarted     = "job.started"
+	EventJobSucceeded   = "job.succeeded"
+	EventJobFailed      = "job.failed"
+	EventJobCancelled   = "job.cancelled"
+	EventJobInterrupted = "job.interrupted"
+	// A test event to check a webhook
+	EventPing = "webhook.ping"
+)
+
+// EventTypes are the types of the events which can be subscribed to.
+var EventTypes = []string{
+	EventJobStarted,
+	EventJobSucceeded,
+	EventJobFailed,
+	EventJobCancelled,
+	EventJobInterrupted,
+	terrarium.EventStateChanged,
+	terrarium.EventExpiring,
+	terrarium.EventExpired,
+	terrarium.EventReaped,
+	terrarium.EventReapFailed,
+	terrarium.EventErased,
+	EventPing,
+}
+
+// Max number of the events waiting to be dispatched, over which the events are dropped
+const maxQueuedEvents = 1000
+
+// queuedEvent is an event with the tenant of its terrarium, waiting to be dispatched to the webhooks.
+type queuedEvent struct {
+	event  model.WebhookEvent
+	tenant string
+}
+
+// Events waiting to be dispatched in order, which is nil until the dispatcher is started
+var events chan queuedEvent
+
+// Start opens the store of the webhooks, and starts to dispatch the events to them until the context is done.
+// The leader of the servers sharing the store resumes the retries of the deliveries of the stopped servers.
+// It should be started before the listeners are added (see OnJobStarted, OnJobFinished and OnTerrariumEvent).
+func Start(ctx context.Context) error {
+
+	s, err := store.Open(config.Terrarium.Store, storeName)
+	if err != nil {
+		return fmt.Errorf("failed to open the store of the webhooks: %w", err)
+	}
+	whStore = s
+	deliveryCtx = ctx
+	events = make(chan queuedEvent, maxQueuedEvents)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case q := <-events:
+				dispatch(q.event, q.tenant)
+			}
+		}
+	}()
+
+	go func() {
+		if replica.IsLeader() {
+			resumeRetries()
+		}
+		ticker := time.NewTicker(retryScanInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if replica.IsLeader() {
+					resumeRetries()
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Close closes the store of the webhooks.
+func Close() error {
+	if whStore == nil {
+		return nil
+	}
+	return whStore.Close()
+}
+
+// OnJobStarted publishes the job.started event of a job. It should be added as a listener of the started jobs.
+func OnJobStarted(job model.JobInfo) {
+	publishJob(EventJobStarted, job)
+}
+
+// OnJobFinished publishes the event of a finished job by its status (e.g., job.succeeded).
+// It should be added as a listener of the finished jobs.
+func OnJobFinished(job model.JobInfo) {
+
+	eventType := EventJobFailed
+	switch job.Status {
+	case tofu.JobStatusSuccess:
+		eventType = EventJobSucceeded
+	case tofu.JobStatusCancelled:
+		eventType = EventJobCancelled
+	case tofu.JobStatusInterrupted:
+		eventType = EventJobInterrupted
+	}
+	publishJob(eventType, job)
+}
+
+// publishJob publishes an event of a job with the job record.
+func publishJob(eventType string, job model.JobInfo) {
+
+	// The tenant is empty for a job out of the terrariums (e.g., the test environment)
+	tenantName, _ := terrarium.TenantOf(job.TerrariumId)
+
+	publish(model.WebhookEvent{
+		Type:        eventType,
+		TerrariumId: job.TerrariumId,
+		Enrichments: job.Enrichments,
+		Message: fmt.Sprintf("the request (reqId: %s) to %s the enrichment (trId: %s, enrichments: %s) is %s",
+			job.Id, job.Subcommand, job.TerrariumId, job.Enrichments, job.Status),
+		Job: &job,
+	}, tenantName)
+}
+
+// OnTerrariumEvent publishes a terrarium event (e.g., terrarium.expiring, enrichment.state_changed).
+// It should be added as a listener of the terrarium events.
+func OnTerrariumEvent(event model.TerrariumEvent) {
+	publish(model.WebhookEvent{
+		Type:        event.Type,
+		Time:        event.Time,
+		TerrariumId: event.TerrariumId,
+		Enrichments: event.Enrichments,
+		Message:     event.Message,
+		From:        event.From,
+		To:          event.To,
+	}, event.Tenant)
+}
+
+// publish queues an event to be dispatched without blocking the caller (e.g., a job),
+// and drops it if the dispatcher is not started or too many events are waiting.
+func publish(event model.WebhookEvent, tenantName string) {
+
+	if events == nil {
+		return
+	}
+
+	id, err := generateEventId()
+	if err != nil {
+		log.Error().Err(err).Msgf("dropped the event (type: %s, trId: %s)", event.Type, event.TerrariumId)
+		return
+	}
+	event.Id = id
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	select {
+	case events <- queuedEvent{event: event, tenant: tenantName}:
+	default:
+		log.Error().Msgf("dropped the event (type: %s, trId: %s) since %d events are waiting to be dispatched",
+			event.Type, event.TerrariumId, maxQueuedEvents)
+	}
+}
+
+// dispatch delivers an event to the webhooks subscribing to it.
+// The webhooks of a terrarium are removed after its last event (i.e., erased or reaped).
+func dispatch(event model.WebhookEvent, tenantName string) {
+
+	webhooks, err := list()
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to dispatch the event (id: %s, type: %s)", event.Id, event.Type)
+		return
+	}
+
+	for _, wh := range webhooks {
+		if subscribes(wh, event, tenantName) {
+			deliver(wh, event)
+		}
+	}
+
+	if event.Type == terrarium.EventErased || event.Type == terrarium.EventReaped {
+		if err := removeOfTerrarium(event.TerrariumId); err != nil {
+			log.Error().Err(err).Msgf("failed to remove the webhooks of the erased terrarium (trId: %s)", event.TerrariumId)
+		}
+	}
+}
+
+// subscribes returns whether a webhook subscribes to an event of a terrarium of a tenant.
+func subscribes(wh model.Webhook, event model.WebhookEvent, tenantName string) bool {
+
+	if wh.TerrariumId != "" && wh.TerrariumId != event.TerrariumId {
+		return false
+	}
+	if wh.Tenant != "" && wh.Tenant != tenantName {
+		return false
+	}
+	if len(wh.Events) == 0 {
+		return true
+	}
+	for _, pattern := range wh.Events {
+		if matches(pattern, event.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns whether an event type matches a pattern, which is the type or ends with '*' (e.g., job.*).
+func matches(pattern, eventType string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(eventType, prefix)
+	}
+	return pattern == eventType
+}
+
+// knownPattern returns whether a pattern matches any of the event types.
+func knownPattern(pattern string) bool {
+	for _, eventType := range EventTypes {
+		if matches(pattern, eventType) {
+			return true
+		}
+	}
+	return false
+}
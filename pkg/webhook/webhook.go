@@ -0,0 +1,279 @@
+// Package webhook provides the webhooks, which receive the events of the jobs and the terrariums
+// (e.g., job.succeeded, enrichment.state_changed, terrarium.expiring) instead of polling the requests.
+// A webhook subscribes to the events of all the terrariums of its tenant (global) or of a terrarium,
+// and each event is delivered as a signed JSON with retries, and moved to the dead letters if it failed.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/store"
+)
+
+const (
+	// Name of the store of the webhooks (i.e., .terrarium/webhooks.bolt or the keys under <prefix>webhooks/ in etcd)
+	storeName = "webhooks"
+	// Prefixes of the keys of the webhooks, the dead letters and the deliveries to retry in the store
+	webhookKeyPrefix    = "webhooks/"
+	deadLetterKeyPrefix = "webhook-dead-letters/"
+	retryKeyPrefix      = "webhook-retries/"
+
+	// Timeout of an operation on the store
+	storeTimeout = 10 * time.Second
+
+	// Prefix and length of the random part of a webhook ID (e.g., wh-k3x9q2ab)
+	idPrefix = "wh-"
+	idLength = 8
+	idChars  = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+	// Length of a generated secret in bytes
+	secretLength = 32
+	// Prefix and length (in bytes) of the random part of an event ID (e.g., ev-0f8c...)
+	eventIdPrefix = "ev-"
+	eventIdLength = 16
+)
+
+var (
+	// ErrWebhookNotFound is returned when a webhook does not exist.
+	ErrWebhookNotFound = errors.New("not existed the webhook")
+	// ErrInvalidWebhook is returned when a webhook request is not valid.
+	ErrInvalidWebhook = errors.New("invalid webhook")
+	// ErrDeliveryNotFound is returned when a delivery does not exist in the dead letters.
+	ErrDeliveryNotFound = errors.New("not existed the delivery")
+)
+
+// Store of the webhooks, the dead letters and the deliveries to retry, which is apart from the store of the terrarium info
+// not to rewrite the terrarium info (e.g., of the JSON store) with the dead letters and to list it past them.
+var whStore store.Store
+
+// Create subscribes to the events by a webhook for a tenant (empty for all the tenants),
+// which is for a terrarium if trId is not empty. The secret is returned only by it.
+func Create(req model.WebhookRequest, tenantName, trId string) (model.Webhook, error) {
+
+	if err := validate(req); err != nil {
+		return model.Webhook{}, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		b := make([]byte, secretLength)
+		if _, err := rand.Read(b); err != nil {
+			return model.Webhook{}, fmt.Errorf("failed to generate a secret: %w", err)
+		}
+		secret = hex.EncodeToString(b)
+	}
+
+	id, err := generateId()
+	if err != nil {
+		return model.Webhook{}, err
+	}
+
+	wh := model.Webhook{
+		Id:          id,
+		Url:         req.Url,
+		TerrariumId: trId,
+		Tenant:      tenantName,
+		Events:      req.Events,
+		Description: req.Description,
+		Secret:      secret,
+		CreatedAt:   time.Now(),
+	}
+	value, err := json.Marshal(wh)
+	if err != nil {
+		return model.Webhook{}, fmt.Errorf("failed to encode the webhook: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if _, err := whStore.Create(ctx, webhookKeyPrefix+id, value); err != nil {
+		return model.Webhook{}, fmt.Errorf("failed to save the webhook (webhookId: %s): %w", id, err)
+	}
+
+	return wh, nil
+}
+
+// validate checks the URL and the event types of a webhook request,
+// where the host of the URL should be resolved to the allowed addresses (see checkIP).
+func validate(req model.WebhookRequest) error {
+
+	u, err := url.Parse(req.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w (url: %s): it must be an absolute http or https URL", ErrInvalidWebhook, req.Url)
+	}
+	if err := checkHost(u.Hostname()); err != nil {
+		return fmt.Errorf("%w (url: %s): %s", ErrInvalidWebhook, req.Url, err)
+	}
+
+	for _, pattern := range req.Events {
+		if !knownPattern(pattern) {
+			return fmt.Errorf("%w (events: %s): it must be one of [%s] or end with '*' (e.g., job.*)",
+				ErrInvalidWebhook, pattern, strings.Join(EventTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// generateId returns a random webhook ID (e.g., wh-k3x9q2ab).
+func generateId() (string, error) {
+	var sb strings.Builder
+	sb.WriteString(idPrefix)
+	for i := 0; i < idLength; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(idChars))))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate a webhook ID: %w", err)
+		}
+		sb.WriteByte(idChars[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// generateEventId generates a random ID of an event, which is unique across the events published at the same time.
+func generateEventId() (string, error) {
+	b := make([]byte, eventIdLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate an event ID: %w", err)
+	}
+	return eventIdPrefix + hex.EncodeToString(b), nil
+}
+
+// Get returns a webhook without the secret if the tenant (empty for all the tenants) can access it.
+func Get(id, tenantName string) (model.Webhook, error) {
+
+	wh, err := get(id)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	if tenantName != "" && wh.Tenant != tenantName {
+		return model.Webhook{}, fmt.Errorf("%w (webhookId: %s)", ErrWebhookNotFound, id)
+	}
+
+	wh.Secret = ""
+	return wh, nil
+}
+
+// get returns a webhook with the secret.
+func get(id string) (model.Webhook, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	kv, err := whStore.Get(ctx, webhookKeyPrefix+id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return model.Webhook{}, fmt.Errorf("%w (webhookId: %s)", ErrWebhookNotFound, id)
+		}
+		return model.Webhook{}, err
+	}
+
+	var wh model.Webhook
+	if err := json.Unmarshal(kv.Value, &wh); err != nil {
+		return model.Webhook{}, fmt.Errorf("failed to decode the webhook (webhookId: %s): %w", id, err)
+	}
+	return wh, nil
+}
+
+// List returns the webhooks without the secrets, which the tenant (empty for all the tenants) can access,
+// of a terrarium if trId is not empty. They are sorted by the creation time.
+func List(tenantName, trId string) ([]model.Webhook, error) {
+
+	all, err := list()
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := []model.Webhook{}
+	for _, wh := range all {
+		if (tenantName != "" && wh.Tenant != tenantName) || (trId != "" && wh.TerrariumId != trId) {
+			continue
+		}
+		wh.Secret = ""
+		webhooks = append(webhooks, wh)
+	}
+	return webhooks, nil
+}
+
+// list returns all the webhooks with the secrets sorted by the creation time.
+func list() ([]model.Webhook, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	kvs, err := whStore.List(ctx, webhookKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the webhooks: %w", err)
+	}
+
+	webhooks := make([]model.Webhook, 0, len(kvs))
+	for _, kv := range kvs {
+		var wh model.Webhook
+		if err := json.Unmarshal(kv.Value, &wh); err != nil {
+			return nil, fmt.Errorf("failed to decode the webhook (key: %s): %w", kv.Key, err)
+		}
+		webhooks = append(webhooks, wh)
+	}
+	sort.SliceStable(webhooks, func(i, j int) bool { return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt) })
+	return webhooks, nil
+}
+
+// Delete unsubscribes a webhook which the tenant (empty for all the tenants) can access,
+// and deletes its deliveries and dead letters.
+func Delete(id, tenantName string) error {
+
+	if _, err := Get(id, tenantName); err != nil {
+		return err
+	}
+	return remove(id)
+}
+
+// remove deletes a webhook with its deliveries and dead letters.
+func remove(id string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if err := whStore.Delete(ctx, webhookKeyPrefix+id); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("failed to delete the webhook (webhookId: %s): %w", id, err)
+	}
+
+	kvs, err := whStore.List(ctx, deadLetterKeyPrefix+id+"/")
+	if err != nil {
+		return fmt.Errorf("failed to read the dead letters of the webhook (webhookId: %s): %w", id, err)
+	}
+	for _, kv := range kvs {
+		if err := whStore.Delete(ctx, kv.Key); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("failed to delete the dead letter (key: %s): %w", kv.Key, err)
+		}
+	}
+
+	forgetDeliveries(id)
+	return nil
+}
+
+// removeOfTerrarium deletes the webhooks of a terrarium (e.g., when it is erased).
+func removeOfTerrarium(trId string) error {
+
+	webhooks, err := list()
+	if err != nil {
+		return err
+	}
+	for _, wh := range webhooks {
+		if wh.TerrariumId != trId {
+			continue
+		}
+		if err := remove(wh.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/cloud-barista/mc-terrarium/pkg/api/rest/model"
+	"github.com/cloud-barista/mc-terrarium/pkg/replica"
+	"github.com/cloud-barista/mc-terrarium/pkg/store"
+)
+
+// The events published at the same time have distinct IDs.
+func TestGenerateEventId(t *testing.T) {
+	format := regexp.MustCompile(`^ev-[0-9a-f]{32}$`)
+
+	ids := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id, err := generateEventId()
+		if err != nil {
+			t.Fatalf("generateEventId() error = %v", err)
+		}
+		if !format.MatchString(id) {
+			t.Errorf("generateEventId() = %s, want %s", id, format)
+		}
+		if ids[id] {
+			t.Fatalf("generateEventId() = %s, which is generated twice", id)
+		}
+		ids[id] = true
+	}
+}
+
+func TestBackoffAfter(t *testing.T) {
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{n: 1, want: defaultBackoff},
+		{n: 2, want: 2 * defaultBackoff},
+		{n: 4, want: 8 * defaultBackoff},
+		{n: 100, want: maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoffAfter(tt.n); got != tt.want {
+			t.Errorf("backoffAfter(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+// The deliveries to retry of this server are kept, and the ones of a deleted webhook are dropped.
+func TestResumeRetries(t *testing.T) {
+	s, err := store.NewBoltStore(filepath.Join(t.TempDir(), storeName+".bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	whStore = s
+	t.Cleanup(func() {
+		whStore = nil
+		s.Close()
+	})
+
+	records := []retryRecord{
+		{Delivery: model.WebhookDelivery{Id: "ev-1-wh-running", WebhookId: "wh-running", Attempts: 1}, Server: replica.Id()},
+		{Delivery: model.WebhookDelivery{Id: "ev-1-wh-deleted", WebhookId: "wh-deleted", Attempts: 1}, Server: "server-stopped"},
+	}
+	for _, rec := range records {
+		value, err := json.Marshal(rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Put(context.Background(), retryKeyPrefix+rec.Delivery.Id, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resumeRetries()
+
+	tests := []struct {
+		deliveryId string
+		wantKept   bool
+	}{
+		{deliveryId: "ev-1-wh-running", wantKept: true},
+		{deliveryId: "ev-1-wh-deleted", wantKept: false},
+	}
+	for _, tt := range tests {
+		_, err := s.Get(context.Background(), retryKeyPrefix+tt.deliveryId)
+		if kept := err == nil; kept != tt.wantKept {
+			t.Errorf("delivery %s kept = %v, want %v (err: %v)", tt.deliveryId, kept, tt.wantKept, err)
+		}
+	}
+}